@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +13,8 @@ import (
 	"globe-expedition-journal/internal/api"
 	"globe-expedition-journal/internal/config"
 	"globe-expedition-journal/internal/database"
+	"globe-expedition-journal/internal/logging"
+	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/models"
 	"globe-expedition-journal/internal/seed"
 )
@@ -21,33 +23,131 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Validate configuration
+	// Structured logging; the standard log package is routed through it too
+	logger := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	// Validate configuration. Settings unsafe to serve in production stop the
+	// server whatever else is wrong; other mistakes are only warned about.
+	if err := cfg.ValidateProduction(); err != nil {
+		fatal(logger, "refusing to start", err)
+	}
 	if err := cfg.Validate(); err != nil {
-		log.Printf("Warning: %v", err)
+		logger.Warn("invalid configuration", "error", err)
 	}
 
 	// Connect to database
 	_, err := database.Connect(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal(logger, "failed to connect to database", err)
 	}
 	defer database.Close()
 
 	// Run migrations
 	if err := database.Migrate(models.AllModels()...); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		fatal(logger, "failed to run migrations", err)
+	}
+	if cfg.UniqueEntryTitles {
+		created, err := models.MigrateUniqueEntryTitles(database.GetDB())
+		if err != nil {
+			logger.Warn("failed to add unique scrapbook entry title index", "error", err)
+		} else if !created {
+			logger.Warn("skipping unique scrapbook entry title index: existing entries share a title within a country")
+		}
 	}
 
 	// Seed initial data
 	if err := seed.Countries(database.GetDB()); err != nil {
-		log.Printf("Warning: failed to seed countries: %v", err)
+		logger.Warn("failed to seed countries", "error", err)
+	}
+	if err := seed.CountryTranslations(database.GetDB()); err != nil {
+		logger.Warn("failed to seed country translations", "error", err)
+	}
+	if err := seed.CountryAliases(database.GetDB()); err != nil {
+		logger.Warn("failed to seed country aliases", "error", err)
+	}
+	if err := seed.CountryISOCodes3(database.GetDB()); err != nil {
+		logger.Warn("failed to seed country alpha-3 codes", "error", err)
+	}
+	if err := seed.CountryCentroids(database.GetDB()); err != nil {
+		logger.Warn("failed to seed country centroids", "error", err)
+	}
+	if err := seed.Platform(database.GetDB(), cfg); err != nil {
+		logger.Warn("failed to seed LTI platform", "error", err)
+	}
+
+	// Session manager shared by the API and LTI launches. Production also
+	// enforces the secret's minimum length here, where tokens are signed.
+	minSecretLength := 0
+	if cfg.IsProduction() {
+		minSecretLength = cfg.SessionSecretMinLength
+	}
+	sessionManager, err := lti.NewSessionManagerWithMinLength(cfg.SessionSecret, cfg.SessionMaxAge, minSecretLength)
+	if err != nil {
+		fatal(logger, "refusing to start", err)
 	}
 
 	// Create router with configuration
 	routerCfg := api.RouterConfig{
+		SessionManager: sessionManager,
+
 		SessionSecret: cfg.SessionSecret,
 		SessionMaxAge: cfg.SessionMaxAge,
-		DemoMode:      cfg.DemoMode,
+		SessionLeeway: time.Duration(cfg.SessionLeeway) * time.Second,
+
+		PreviousSessionSecrets: cfg.PreviousSessionSecrets,
+
+		DemoMode:    cfg.DemoMode,
+		DemoUserTTL: cfg.DemoUserTTL,
+
+		DemoSessionTTL: cfg.DemoSessionTTL,
+		DemoLoginLimit: cfg.DemoLoginLimit,
+
+		DefaultTimezone: cfg.DefaultTimezone,
+
+		RejectAnimatedUploads: cfg.RejectAnimatedUploads,
+		PreserveUploadNames:   cfg.PreserveUploadNames,
+		MaxUploadsPerDay:      cfg.MaxUploadsPerDay,
+		MaxMediaPerEntry:      cfg.MaxMediaPerEntry,
+
+		UploadAllowedExtensions: cfg.UploadAllowedExtensions,
+
+		UploadCacheMaxAge: time.Duration(cfg.UploadCacheMaxAge) * time.Second,
+
+		LTIStateStore: cfg.LTIStateStore,
+
+		PublicBaseURL:  cfg.PublicBaseURL,
+		TrustedProxies: cfg.TrustedProxies,
+		// Forwarded headers from arbitrary clients are only trusted in dev
+		TrustForwardedHeaders: cfg.IsDevelopment(),
+		RedirectOrigins:       cfg.RedirectOrigins,
+
+		Logger: logger,
+
+		MaxPageSize: cfg.MaxPageSize,
+
+		MaxNotesLength: cfg.MaxNotesLength,
+		StripHTML:      cfg.StripHTML,
+
+		MaxVisitsPerUser:  cfg.MaxVisitsPerUser,
+		MaxEntriesPerUser: cfg.MaxEntriesPerUser,
+
+		ReadOnly: cfg.ReadOnly,
+
+		OwnerComments: cfg.OwnerComments,
+
+		WebhookURL:    cfg.WebhookURL,
+		WebhookSecret: cfg.WebhookSecret,
+
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUsername: cfg.SMTPUsername,
+		SMTPPassword: cfg.SMTPPassword,
+		SMTPFrom:     cfg.SMTPFrom,
+	}
+	if cfg.FrontendDir != "" {
+		routerCfg.Frontend = os.DirFS(cfg.FrontendDir)
+		logger.Info("serving frontend", "dir", cfg.FrontendDir)
 	}
 	router := api.NewRouterWithConfig(database.GetDB(), routerCfg)
 
@@ -60,9 +160,9 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Globe Expedition Journal starting on %s", addr)
+		logger.Info("Globe Expedition Journal starting", "addr", addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal(logger, "failed to start server", err)
 		}
 	}()
 
@@ -71,15 +171,23 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
 
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Graceful shutdown, giving in-flight requests such as large uploads time
+	// to finish. Uploads are written atomically, so one cut off when the
+	// timeout expires never leaves a partial file behind.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal(logger, "server forced to shutdown", err)
 	}
 
-	log.Println("Server exited")
+	logger.Info("server exited")
+}
+
+// fatal logs err and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/seed"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// isoCodePattern matches an ISO 3166-1 alpha-2 or alpha-3 code
+var isoCodePattern = regexp.MustCompile(`^[A-Z]{2,3}$`)
+
+// isoCode3Pattern matches an ISO 3166-1 alpha-3 code
+var isoCode3Pattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// AdminCountryHandler handles country management endpoints for admins
+type AdminCountryHandler struct {
+	db *gorm.DB
+}
+
+// NewAdminCountryHandler creates a new admin country handler
+func NewAdminCountryHandler(db *gorm.DB) *AdminCountryHandler {
+	return &AdminCountryHandler{db: db}
+}
+
+// CountryRequest represents the request body for creating or updating a country
+type CountryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ISOCode  string `json:"isoCode" binding:"required"` // 2-3 uppercase letters, unique
+	ISOCode3 string `json:"isoCode3"`                   // Optional alpha-3 code, 3 uppercase letters
+	Region   string `json:"region"`
+	Aliases  string `json:"aliases"` // Comma-separated alternate names for search
+}
+
+// bindCountryRequest binds and validates a country request.
+// Writes a 400 response and returns false if the request is invalid.
+func bindCountryRequest(c *gin.Context) (CountryRequest, bool) {
+	var req CountryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.ISOCode = strings.TrimSpace(req.ISOCode)
+	req.ISOCode3 = strings.TrimSpace(req.ISOCode3)
+	req.Region = strings.TrimSpace(req.Region)
+	req.Aliases = joinTags(splitTags(req.Aliases))
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return req, false
+	}
+	if !isoCodePattern.MatchString(req.ISOCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "isoCode must be 2-3 uppercase letters"})
+		return req, false
+	}
+	if req.ISOCode3 != "" && !isoCode3Pattern.MatchString(req.ISOCode3) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "isoCode3 must be 3 uppercase letters"})
+		return req, false
+	}
+	return req, true
+}
+
+// isoCodeTaken reports whether another country already uses code
+func (h *AdminCountryHandler) isoCodeTaken(code string, exceptID uint) (bool, error) {
+	var count int64
+	err := h.db.Model(&models.Country{}).
+		Where("iso_code = ? AND id <> ?", code, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateCountry adds a country
+// POST /api/v1/admin/countries
+func (h *AdminCountryHandler) CreateCountry(c *gin.Context) {
+	req, ok := bindCountryRequest(c)
+	if !ok {
+		return
+	}
+
+	taken, err := h.isoCodeTaken(req.ISOCode, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create country"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "isoCode already in use"})
+		return
+	}
+
+	country := models.Country{
+		Name:     req.Name,
+		ISOCode:  req.ISOCode,
+		ISOCode3: req.ISOCode3,
+		Region:   req.Region,
+		Aliases:  req.Aliases,
+	}
+	if err := h.db.Create(&country).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create country"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toCountryResponse(&country))
+}
+
+// UpdateCountry replaces a country's name, ISO codes, region and aliases
+// PUT /api/v1/admin/countries/:id
+func (h *AdminCountryHandler) UpdateCountry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid country ID"})
+		return
+	}
+
+	req, ok := bindCountryRequest(c)
+	if !ok {
+		return
+	}
+
+	var country models.Country
+	if err := h.db.First(&country, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "country not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	taken, err := h.isoCodeTaken(req.ISOCode, country.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update country"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "isoCode already in use"})
+		return
+	}
+
+	country.Name = req.Name
+	country.ISOCode = req.ISOCode
+	country.ISOCode3 = req.ISOCode3
+	country.Region = req.Region
+	country.Aliases = req.Aliases
+	if err := h.db.Save(&country).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update country"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCountryResponse(&country))
+}
+
+// DeleteCountry removes a country and its translations. Countries referenced
+// by any visit or scrapbook entry, including soft-deleted ones, are kept so
+// user data is never orphaned.
+// DELETE /api/v1/admin/countries/:id
+func (h *AdminCountryHandler) DeleteCountry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid country ID"})
+		return
+	}
+
+	var country models.Country
+	if err := h.db.First(&country, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "country not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	var visits, entries int64
+	if err := h.db.Unscoped().Model(&models.Visit{}).
+		Where("country_id = ?", country.ID).Count(&visits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete country"})
+		return
+	}
+	if err := h.db.Unscoped().Model(&models.ScrapbookEntry{}).
+		Where("country_id = ?", country.ID).Count(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete country"})
+		return
+	}
+	if visits > 0 || entries > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "country is referenced by user data",
+			"visits":  visits,
+			"entries": entries,
+		})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("country_id = ?", country.ID).Delete(&models.CountryTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&country).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete country"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "country deleted"})
+}
+
+// ReseedCountries brings the country catalog up to date with the seed list,
+// adding missing countries and updating changed names and regions by ISO
+// code. Countries added by admins are kept.
+// POST /api/v1/admin/countries/reseed
+func (h *AdminCountryHandler) ReseedCountries(c *gin.Context) {
+	result, err := seed.ReseedCountries(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reseed countries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupAdminCountryTest(t *testing.T) (*gorm.DB, *gin.Engine) {
+	db := setupCountryTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.Visit{}, &models.ScrapbookEntry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	seedCountries(t, db)
+
+	handler := NewAdminCountryHandler(db)
+	router := gin.New()
+	router.POST("/api/v1/admin/countries", handler.CreateCountry)
+	router.PUT("/api/v1/admin/countries/:id", handler.UpdateCountry)
+	router.DELETE("/api/v1/admin/countries/:id", handler.DeleteCountry)
+	router.POST("/api/v1/admin/countries/reseed", handler.ReseedCountries)
+	return db, router
+}
+
+func sendAdminCountryRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminCountryHandler_CreateCountry(t *testing.T) {
+	db, router := setupAdminCountryTest(t)
+
+	w := sendAdminCountryRequest(router, http.MethodPost, "/api/v1/admin/countries",
+		`{"name":"Iceland","isoCode":"IS","isoCode3":"ISL","region":"Europe","aliases":" Island, ,Lýðveldið Ísland"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response CountryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.ID == 0 || response.Name != "Iceland" || response.ISOCode != "IS" || response.ISOCode3 != "ISL" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	var stored models.Country
+	if err := db.Where("iso_code = ?", "IS").First(&stored).Error; err != nil {
+		t.Fatalf("expected country to be stored: %v", err)
+	}
+	if stored.Aliases != "Island,Lýðveldið Ísland" {
+		t.Errorf("expected normalized aliases, got %q", stored.Aliases)
+	}
+}
+
+func TestAdminCountryHandler_CreateCountry_Validation(t *testing.T) {
+	_, router := setupAdminCountryTest(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing name", `{"isoCode":"IS"}`, http.StatusBadRequest},
+		{"blank name", `{"name":"  ","isoCode":"IS"}`, http.StatusBadRequest},
+		{"lowercase code", `{"name":"Iceland","isoCode":"is"}`, http.StatusBadRequest},
+		{"code too long", `{"name":"Iceland","isoCode":"ISLA"}`, http.StatusBadRequest},
+		{"code too short", `{"name":"Iceland","isoCode":"I"}`, http.StatusBadRequest},
+		{"lowercase alpha-3 code", `{"name":"Iceland","isoCode":"IS","isoCode3":"isl"}`, http.StatusBadRequest},
+		{"short alpha-3 code", `{"name":"Iceland","isoCode":"IS","isoCode3":"IS"}`, http.StatusBadRequest},
+		{"duplicate code", `{"name":"France again","isoCode":"FR"}`, http.StatusConflict},
+		{"alpha-3 code", `{"name":"Iceland","isoCode":"ISL"}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendAdminCountryRequest(router, http.MethodPost, "/api/v1/admin/countries", tt.body)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminCountryHandler_UpdateCountry(t *testing.T) {
+	db, router := setupAdminCountryTest(t)
+
+	var france models.Country
+	db.Where("iso_code = ?", "FR").First(&france)
+	path := fmt.Sprintf("/api/v1/admin/countries/%d", france.ID)
+
+	w := sendAdminCountryRequest(router, http.MethodPut, path, `{"name":"France","isoCode":"DE"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a code used by another country, got %d", w.Code)
+	}
+
+	w = sendAdminCountryRequest(router, http.MethodPut, path, `{"name":"French Republic","isoCode":"FR","region":"Europe"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	db.First(&france, france.ID)
+	if france.Name != "French Republic" {
+		t.Errorf("expected updated name, got %q", france.Name)
+	}
+
+	w = sendAdminCountryRequest(router, http.MethodPut, "/api/v1/admin/countries/9999", `{"name":"Nowhere","isoCode":"NW"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAdminCountryHandler_DeleteCountry_RefusesReferenced(t *testing.T) {
+	db, router := setupAdminCountryTest(t)
+
+	user := models.User{CanvasUserID: "canvas-1", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(&user)
+
+	var france, japan, brazil models.Country
+	db.Where("iso_code = ?", "FR").First(&france)
+	db.Where("iso_code = ?", "JP").First(&japan)
+	db.Where("iso_code = ?", "BR").First(&brazil)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: france.ID})
+	entry := models.ScrapbookEntry{UserID: user.ID, CountryID: japan.ID, Title: "Kyoto"}
+	db.Create(&entry)
+	db.Delete(&entry) // Soft-deleted entries still count
+
+	for _, country := range []models.Country{france, japan} {
+		w := sendAdminCountryRequest(router, http.MethodDelete, fmt.Sprintf("/api/v1/admin/countries/%d", country.ID), "")
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: expected status 409, got %d", country.Name, w.Code)
+		}
+
+		var count int64
+		db.Model(&models.Country{}).Where("id = ?", country.ID).Count(&count)
+		if count != 1 {
+			t.Errorf("%s: expected referenced country to be kept", country.Name)
+		}
+	}
+
+	models.NewCountryTranslationRepository(db).Upsert(brazil.ID, "fr", "Brésil")
+	w := sendAdminCountryRequest(router, http.MethodDelete, fmt.Sprintf("/api/v1/admin/countries/%d", brazil.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for an unreferenced country, got %d", w.Code)
+	}
+
+	var remaining int64
+	db.Model(&models.Country{}).Where("id = ?", brazil.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Error("expected country to be deleted")
+	}
+	db.Model(&models.CountryTranslation{}).Where("country_id = ?", brazil.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Error("expected translations to be deleted with the country")
+	}
+}
+
+func TestAdminCountryHandler_ReseedCountries(t *testing.T) {
+	db, router := setupAdminCountryTest(t)
+
+	w := sendAdminCountryRequest(router, http.MethodPost, "/api/v1/admin/countries/reseed", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Added   int `json:"added"`
+		Updated int `json:"updated"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Added == 0 {
+		t.Fatal("expected the missing catalog countries to be added")
+	}
+
+	// The five pre-seeded countries are matched by ISO code, not duplicated
+	var total, france int64
+	db.Model(&models.Country{}).Count(&total)
+	db.Model(&models.Country{}).Where("iso_code = ?", "FR").Count(&france)
+	if int(total) != 5+response.Added || france != 1 {
+		t.Errorf("expected %d countries with one France, got %d and %d", 5+response.Added, total, france)
+	}
+	var kenya models.Country
+	if err := db.Where("iso_code = ?", "KE").First(&kenya).Error; err != nil || kenya.Name != "Kenya" {
+		t.Errorf("expected Kenya to be added, got %+v (%v)", kenya, err)
+	}
+
+	w = sendAdminCountryRequest(router, http.MethodPost, "/api/v1/admin/countries/reseed", "")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Added != 0 || response.Updated != 0 {
+		t.Errorf("expected a second reseed to change nothing, got %+v", response)
+	}
+}
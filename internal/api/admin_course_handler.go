@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminCourseHandler handles course management endpoints for instructors.
+// Every endpoint is scoped to the course in the instructor's session.
+type AdminCourseHandler struct {
+	db          *gorm.DB
+	storage     storage.Storage
+	maxPageSize int // Largest page size list requests may ask for
+}
+
+// NewAdminCourseHandler creates a new admin course handler. The storage is
+// used to remove uploaded media when data is purged and may be nil.
+func NewAdminCourseHandler(db *gorm.DB, s storage.Storage) *AdminCourseHandler {
+	return &AdminCourseHandler{db: db, storage: s, maxPageSize: defaultMaxPageSize}
+}
+
+// SetMaxPageSize sets the largest page size list requests may ask for;
+// non-positive values keep the default
+func (h *AdminCourseHandler) SetMaxPageSize(n int) {
+	h.maxPageSize = pageSizeCap(n)
+}
+
+// CourseUserResponse represents a user who has data in a course
+type CourseUserResponse struct {
+	ID          uint   `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	VisitCount  int64  `json:"visitCount"`
+	EntryCount  int64  `json:"entryCount"`
+}
+
+// sessionCourse returns the course in the session. Writes a 403 response
+// and returns false if the session was not launched from a course.
+func sessionCourse(c *gin.Context) (string, bool) {
+	courseID, _ := middleware.GetCourseID(c)
+	if courseID == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session is not associated with a course"})
+		return "", false
+	}
+	return courseID, true
+}
+
+// courseUserIDs is a subquery selecting users with visits or entries in a course
+func courseUserIDs(db *gorm.DB, courseID string) *gorm.DB {
+	return db.Raw("SELECT user_id FROM visits WHERE course_id = ? AND deleted_at IS NULL "+
+		"UNION SELECT user_id FROM scrapbook_entries WHERE course_id = ? AND deleted_at IS NULL",
+		courseID, courseID)
+}
+
+// courseLaunchUserIDs is a subquery selecting users who have successfully
+// launched into a course, whether or not they have created anything there
+func courseLaunchUserIDs(db *gorm.DB, courseID string) *gorm.DB {
+	return db.Model(&models.LaunchEvent{}).Select("user_id").
+		Where("course_id = ? AND success = ? AND user_id IS NOT NULL", courseID, true)
+}
+
+// courseInstructorIDs is a subquery selecting users who have launched into a
+// course as an instructor
+func courseInstructorIDs(db *gorm.DB, courseID string) *gorm.DB {
+	return courseLaunchUserIDs(db, courseID).Where("role = ?", "instructor")
+}
+
+// ListCourseUsers returns the users with visits or scrapbook entries in the
+// instructor's course, with their counts in that course
+// GET /api/v1/admin/course/users
+// Query params: page, pageSize or limit, offset (optional) - page through users
+func (h *AdminCourseHandler) ListCourseUsers(c *gin.Context) {
+	courseID, _ := middleware.GetCourseID(c) // Checked by RequireCourse
+
+	p, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.User{}).Where("id IN (?)", courseUserIDs(h.db, courseID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch course users"})
+		return
+	}
+
+	users := []CourseUserResponse{}
+	err := p.apply(query.
+		Select("users.id, users.display_name, users.email, "+
+			"(SELECT COUNT(*) FROM visits WHERE visits.user_id = users.id AND visits.course_id = ? AND visits.deleted_at IS NULL) AS visit_count, "+
+			"(SELECT COUNT(*) FROM scrapbook_entries WHERE scrapbook_entries.user_id = users.id AND scrapbook_entries.course_id = ? AND scrapbook_entries.deleted_at IS NULL) AS entry_count",
+			courseID, courseID).
+		Order("users.display_name ASC, users.id ASC")).
+		Scan(&users).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch course users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("users", users, total, p))
+}
+
+// PurgeCourseUserData permanently deletes one user's visits and scrapbook
+// entries in the instructor's course, along with their uploaded media, the
+// comments on those entries and the feedback the user received in the
+// course. Data the user has in other courses is left alone. Users without data in the
+// course are reported as not found.
+// DELETE /api/v1/admin/course/users/:id/data
+func (h *AdminCourseHandler) PurgeCourseUserData(c *gin.Context) {
+	courseID, _ := middleware.GetCourseID(c) // Checked by RequireCourse
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var inCourse int64
+	if err := h.db.Model(&models.User{}).
+		Where("id = ? AND id IN (?)", userID, courseUserIDs(h.db, courseID)).
+		Count(&inCourse).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge course data"})
+		return
+	}
+	if inCourse == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found in this course"})
+		return
+	}
+
+	var filenames []string
+	var visitsDeleted, entriesDeleted int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var entryIDs []uint
+		if err := tx.Unscoped().Model(&models.ScrapbookEntry{}).
+			Where("user_id = ? AND course_id = ?", userID, courseID).
+			Pluck("id", &entryIDs).Error; err != nil {
+			return err
+		}
+
+		if len(entryIDs) > 0 {
+			// Only the user's own uploads go, even if an entry links to someone else's
+			if err := tx.Model(&models.Upload{}).Where("entry_id IN ? AND user_id = ?", entryIDs, userID).
+				Pluck("filename", &filenames).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("entry_id IN ? AND user_id = ?", entryIDs, userID).Delete(&models.Upload{}).Error; err != nil {
+				return err
+			}
+			var mediaURLs []string
+			if err := tx.Unscoped().Model(&models.ScrapbookEntry{}).
+				Where("id IN ? AND media_url <> ''", entryIDs).
+				Pluck("media_url", &mediaURLs).Error; err != nil {
+				return err
+			}
+			itemURLs, err := deleteEntryMedia(tx, entryIDs)
+			if err != nil {
+				return err
+			}
+			released, err := releaseOwnedUploads(tx, h.storage, uint(userID), append(mediaURLs, itemURLs...))
+			if err != nil {
+				return err
+			}
+			filenames = append(filenames, released...)
+
+			if err := tx.Where("entry_id IN ?", entryIDs).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("learner_user_id = ? AND course_id = ?", userID, courseID).Delete(&models.Feedback{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("user_id = ? AND course_id = ?", userID, courseID).Delete(&models.Visit{})
+		if result.Error != nil {
+			return result.Error
+		}
+		visitsDeleted = result.RowsAffected
+
+		result = tx.Unscoped().Where("user_id = ? AND course_id = ?", userID, courseID).Delete(&models.ScrapbookEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		entriesDeleted = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge course data"})
+		return
+	}
+
+	// Files cannot be rolled back, so they are only removed once the data is gone
+	deleteStoredFiles(h.storage, filenames)
+
+	c.JSON(http.StatusOK, gin.H{"visitsDeleted": visitsDeleted, "entriesDeleted": entriesDeleted})
+}
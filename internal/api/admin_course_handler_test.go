@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// courseTestUsers are users seeded for the course admin tests
+type courseTestUsers struct {
+	instructor, learnerA, learnerB, both models.User
+}
+
+// setupAdminCourseTest seeds learnerA in course-a, learnerB in course-b and
+// a user with data in both, and returns a router for the course endpoints
+func setupAdminCourseTest(t *testing.T, s storage.Storage) (*gorm.DB, *gin.Engine, *lti.SessionManager, courseTestUsers) {
+	db := setupTestDB(t)
+
+	var users courseTestUsers
+	for i, u := range []*models.User{&users.instructor, &users.learnerA, &users.learnerB, &users.both} {
+		*u = models.User{
+			CanvasUserID:      fmt.Sprintf("canvas-%d", i),
+			CanvasInstanceURL: "https://canvas.example.com",
+			DisplayName:       []string{"Instructor", "Ada", "Bea", "Cy"}[i],
+		}
+		db.Create(u)
+	}
+
+	db.Create(&models.Visit{UserID: users.learnerA.ID, CountryID: 1, CourseID: "course-a"})
+	db.Create(&models.Visit{UserID: users.learnerA.ID, CountryID: 2, CourseID: "course-a"})
+	db.Create(&models.ScrapbookEntry{UserID: users.learnerA.ID, CountryID: 1, CourseID: "course-a", Title: "A"})
+	db.Create(&models.Visit{UserID: users.learnerB.ID, CountryID: 1, CourseID: "course-b"})
+	db.Create(&models.ScrapbookEntry{UserID: users.both.ID, CountryID: 1, CourseID: "course-a", Title: "Cy in A"})
+	db.Create(&models.Visit{UserID: users.both.ID, CountryID: 3, CourseID: "course-b"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	handler := NewAdminCourseHandler(db, s)
+
+	router := gin.New()
+	admin := router.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(sm), middleware.RequireInstructor(), middleware.RequireCourse())
+	{
+		admin.GET("/course/users", handler.ListCourseUsers)
+		admin.DELETE("/course/users/:id/data", handler.PurgeCourseUserData)
+	}
+	return db, router, sm, users
+}
+
+func sendAdminCourseRequest(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminCourseHandler_ListCourseUsers(t *testing.T) {
+	_, router, sm, users := setupAdminCourseTest(t, nil)
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	w := sendAdminCourseRequest(router, http.MethodGet, "/api/v1/admin/course/users", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Users []CourseUserResponse `json:"users"`
+		Total int64                `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 2 || len(response.Users) != 2 {
+		t.Fatalf("expected 2 users in course-a, got %+v", response)
+	}
+	ada, cy := response.Users[0], response.Users[1]
+	if ada.ID != users.learnerA.ID || ada.VisitCount != 2 || ada.EntryCount != 1 {
+		t.Errorf("unexpected counts for learnerA: %+v", ada)
+	}
+	// Only course-a data is counted for a user in both courses
+	if cy.ID != users.both.ID || cy.VisitCount != 0 || cy.EntryCount != 1 {
+		t.Errorf("unexpected counts for user in both courses: %+v", cy)
+	}
+}
+
+func TestAdminCourseHandler_RequiresCourseInSession(t *testing.T) {
+	_, router, sm, users := setupAdminCourseTest(t, nil)
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "", "instructor")
+
+	w := sendAdminCourseRequest(router, http.MethodGet, "/api/v1/admin/course/users", token)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without a course, got %d", w.Code)
+	}
+
+	path := fmt.Sprintf("/api/v1/admin/course/users/%d/data", users.learnerA.ID)
+	w = sendAdminCourseRequest(router, http.MethodDelete, path, token)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without a course, got %d", w.Code)
+	}
+}
+
+func TestAdminCourseHandler_PurgeCourseUserData(t *testing.T) {
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	db, router, sm, users := setupAdminCourseTest(t, s)
+
+	mediaURL := uploadOwnedMedia(t, db, s, users.both.ID)
+	db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", users.both.ID).Update("media_url", mediaURL)
+
+	var entry, otherEntry models.ScrapbookEntry
+	db.Where("user_id = ?", users.both.ID).First(&entry)
+	db.Where("user_id = ?", users.learnerA.ID).First(&otherEntry)
+	db.Create(&models.Comment{EntryID: entry.ID, AuthorUserID: users.instructor.ID, Body: "Nice photo"})
+	db.Create(&models.Comment{EntryID: otherEntry.ID, AuthorUserID: users.instructor.ID, Body: "Keep going"})
+	db.Create(&models.Feedback{CourseID: "course-a", LearnerUserID: users.both.ID, InstructorUserID: users.instructor.ID, Comment: "Good work"})
+	db.Create(&models.Feedback{CourseID: "course-b", LearnerUserID: users.both.ID, InstructorUserID: users.instructor.ID, Comment: "Other course"})
+
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	path := fmt.Sprintf("/api/v1/admin/course/users/%d/data", users.both.ID)
+	w := sendAdminCourseRequest(router, http.MethodDelete, path, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]int64
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["visitsDeleted"] != 0 || response["entriesDeleted"] != 1 {
+		t.Errorf("unexpected deletion counts: %v", response)
+	}
+
+	var entries, otherCourseVisits int64
+	db.Unscoped().Model(&models.ScrapbookEntry{}).Where("user_id = ?", users.both.ID).Count(&entries)
+	db.Model(&models.Visit{}).Where("user_id = ? AND course_id = ?", users.both.ID, "course-b").Count(&otherCourseVisits)
+	if entries != 0 {
+		t.Errorf("expected course-a entries removed, got %d", entries)
+	}
+	if otherCourseVisits != 1 {
+		t.Errorf("expected course-b data to remain, got %d visits", otherCourseVisits)
+	}
+	if s.Exists(mediaURL) {
+		t.Error("expected entry media to be removed")
+	}
+
+	var comments, otherComments, feedback, otherFeedback int64
+	db.Model(&models.Comment{}).Where("entry_id = ?", entry.ID).Count(&comments)
+	db.Model(&models.Comment{}).Where("entry_id = ?", otherEntry.ID).Count(&otherComments)
+	db.Model(&models.Feedback{}).Where("learner_user_id = ? AND course_id = ?", users.both.ID, "course-a").Count(&feedback)
+	db.Model(&models.Feedback{}).Where("learner_user_id = ? AND course_id = ?", users.both.ID, "course-b").Count(&otherFeedback)
+	if comments != 0 || feedback != 0 {
+		t.Errorf("expected comments and feedback in course-a removed, got %d comments and %d feedback", comments, feedback)
+	}
+	if otherComments != 1 || otherFeedback != 1 {
+		t.Errorf("expected other users' comments and other courses' feedback to remain, got %d and %d", otherComments, otherFeedback)
+	}
+}
+
+func TestAdminCourseHandler_PurgeCourseUserData_OtherCourse(t *testing.T) {
+	db, router, sm, users := setupAdminCourseTest(t, nil)
+
+	// An instructor of course-a cannot reach a learner who only has course-b data
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	path := fmt.Sprintf("/api/v1/admin/course/users/%d/data", users.learnerB.ID)
+	w := sendAdminCourseRequest(router, http.MethodDelete, path, token)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var visits int64
+	db.Model(&models.Visit{}).Where("user_id = ?", users.learnerB.ID).Count(&visits)
+	if visits != 1 {
+		t.Errorf("expected learnerB's data to remain, got %d visits", visits)
+	}
+
+	w = sendAdminCourseRequest(router, http.MethodGet, "/api/v1/admin/course/users", token)
+	var response struct {
+		Users []CourseUserResponse `json:"users"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	for _, u := range response.Users {
+		if u.ID == users.learnerB.ID {
+			t.Error("course-a listing includes a course-b learner")
+		}
+	}
+
+	if w := sendAdminCourseRequest(router, http.MethodDelete, "/api/v1/admin/course/users/abc/data", token); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid ID, got %d", w.Code)
+	}
+}
+
+func TestAdminCourseHandler_RequiresInstructor(t *testing.T) {
+	_, router, sm, users := setupAdminCourseTest(t, nil)
+	token, _ := sm.CreateToken(users.learnerA.ID, "canvas-1", "course-a", "learner")
+
+	w := sendAdminCourseRequest(router, http.MethodGet, "/api/v1/admin/course/users", token)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a learner, got %d", w.Code)
+	}
+}
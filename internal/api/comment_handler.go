@@ -0,0 +1,235 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"globe-expedition-journal/internal/mail"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CommentHandler handles comments on scrapbook entries
+type CommentHandler struct {
+	db            *gorm.DB
+	mailer        mail.Sender
+	ownerComments bool
+	wg            sync.WaitGroup // Pending notification emails
+}
+
+// NewCommentHandler creates a new comment handler that sends no email
+func NewCommentHandler(db *gorm.DB) *CommentHandler {
+	return &CommentHandler{db: db, mailer: mail.NopSender{}}
+}
+
+// SetMailer emails entry owners when a comment is added; nil disables it
+func (h *CommentHandler) SetMailer(m mail.Sender) {
+	if m == nil {
+		m = mail.NopSender{}
+	}
+	h.mailer = m
+}
+
+// SetOwnerComments lets entry owners reply to comments on their own entries.
+// By default only instructors of the entry's course can comment.
+func (h *CommentHandler) SetOwnerComments(enabled bool) {
+	h.ownerComments = enabled
+}
+
+// CommentResponse represents a comment in API responses
+type CommentResponse struct {
+	ID         uint   `json:"id"`
+	EntryID    uint   `json:"entryId"`
+	AuthorID   uint   `json:"authorId"`
+	AuthorName string `json:"authorName,omitempty"`
+	Body       string `json:"body"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// CreateCommentRequest represents the request body for commenting on an entry
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+func toCommentResponse(comment *models.Comment, loc *time.Location) CommentResponse {
+	return CommentResponse{
+		ID:         comment.ID,
+		EntryID:    comment.EntryID,
+		AuthorID:   comment.AuthorUserID,
+		AuthorName: comment.Author.DisplayName,
+		Body:       comment.Body,
+		CreatedAt:  formatTimestamp(comment.CreatedAt, loc),
+	}
+}
+
+// findEntry loads the entry named by the :id param. Writes an error
+// response and returns false if the ID is invalid or the entry is missing.
+func (h *CommentHandler) findEntry(c *gin.Context) (*models.ScrapbookEntry, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return nil, false
+	}
+
+	var entry models.ScrapbookEntry
+	if err := h.db.First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entry"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// teachesEntryCourse reports whether the session is an instructor's in the
+// course the entry was created in
+func teachesEntryCourse(c *gin.Context, entry *models.ScrapbookEntry) bool {
+	courseID, _ := middleware.GetCourseID(c)
+	return middleware.IsInstructor(c) && courseID != "" && courseID == entry.CourseID
+}
+
+// ListComments returns the comments on an entry, oldest first. Only the
+// entry's owner and instructors of its course can see them.
+// GET /api/v1/scrapbook/entries/:id/comments
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	entry, ok := h.findEntry(c)
+	if !ok {
+		return
+	}
+	// Entries the user may not see are reported as missing
+	if entry.UserID != userID && !teachesEntryCourse(c, entry) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	var comments []models.Comment
+	if err := h.db.Preload("Author").Where("entry_id = ?", entry.ID).
+		Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch comments"})
+		return
+	}
+
+	response := make([]CommentResponse, len(comments))
+	for i := range comments {
+		response[i] = toCommentResponse(&comments[i], loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": response})
+}
+
+// CreateComment adds an instructor's comment to an entry in their course and
+// emails the entry's owner. When owner comments are enabled the owner may
+// also comment on their own entry; other learners cannot comment.
+// POST /api/v1/scrapbook/entries/:id/comments
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	instructor := middleware.IsInstructor(c)
+	if !instructor && !h.ownerComments {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+	if instructor {
+		if _, ok := sessionCourse(c); !ok {
+			return
+		}
+	}
+
+	entry, ok := h.findEntry(c)
+	if !ok {
+		return
+	}
+	// Entries the user may not comment on are reported as missing
+	ownEntry := h.ownerComments && entry.UserID == userID
+	if !ownEntry && !teachesEntryCourse(c, entry) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "comment body must not be blank"})
+		return
+	}
+
+	comment := models.Comment{EntryID: entry.ID, AuthorUserID: userID, Body: body}
+	if err := h.db.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create comment"})
+		return
+	}
+	h.db.First(&comment.Author, userID)
+
+	// Owners are not emailed about their own comments
+	if entry.UserID != userID {
+		h.notifyOwner(entry, &comment)
+	}
+
+	c.JSON(http.StatusCreated, toCommentResponse(&comment, loc))
+}
+
+// Wait blocks until all pending notification emails have been sent
+func (h *CommentHandler) Wait() {
+	h.wg.Wait()
+}
+
+// notifyOwner emails the entry's owner about a new comment in the background
+// so a slow mail server does not hold up the request. Owners without an email
+// address are skipped, and failures are only logged.
+func (h *CommentHandler) notifyOwner(entry *models.ScrapbookEntry, comment *models.Comment) {
+	var owner models.User
+	if err := h.db.First(&owner, entry.UserID).Error; err != nil || owner.Email == "" {
+		return
+	}
+
+	author := comment.Author.DisplayName
+	if author == "" {
+		author = "Your instructor"
+	}
+	msg := mail.Message{
+		To:      owner.Email,
+		Subject: fmt.Sprintf("New comment on %q", entry.Title),
+		Body:    fmt.Sprintf("%s commented on your scrapbook entry %q:\n\n%s\n", author, entry.Title, comment.Body),
+	}
+	entryID := entry.ID
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.mailer.Send(msg); err != nil {
+			slog.Warn("failed to email comment notification", "entry_id", entryID, "error", err)
+		}
+	}()
+}
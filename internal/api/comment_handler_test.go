@@ -0,0 +1,287 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/mail"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// recordingSender records sent messages instead of delivering them
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (s *recordingSender) Send(msg mail.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// commentTestData holds the users and entry seeded for the comment tests
+type commentTestData struct {
+	instructor, owner, classmate models.User
+	entry                        models.ScrapbookEntry
+	handler                      *CommentHandler
+}
+
+func setupCommentTest(t *testing.T) (*gorm.DB, *gin.Engine, *lti.SessionManager, *recordingSender, commentTestData) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.ScrapbookEntry{}, &models.Comment{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var data commentTestData
+	for i, u := range []*models.User{&data.instructor, &data.owner, &data.classmate} {
+		*u = models.User{
+			CanvasUserID:      fmt.Sprintf("canvas-%d", i),
+			CanvasInstanceURL: "https://canvas.example.com",
+			DisplayName:       []string{"Prof. Lee", "Ada", "Bea"}[i],
+			Email:             []string{"lee@example.edu", "ada@example.edu", ""}[i],
+		}
+		db.Create(u)
+	}
+	data.entry = models.ScrapbookEntry{UserID: data.owner.ID, CountryID: 1, CourseID: "course-a", Title: "Eiffel Tower"}
+	db.Create(&data.entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	sender := &recordingSender{}
+	handler := NewCommentHandler(db)
+	handler.SetMailer(sender)
+	data.handler = handler
+
+	router := gin.New()
+	auth := router.Group("/api/v1")
+	auth.Use(middleware.AuthMiddleware(sm))
+	{
+		auth.GET("/scrapbook/entries/:id/comments", handler.ListComments)
+		auth.POST("/scrapbook/entries/:id/comments", handler.CreateComment)
+	}
+	return db, router, sm, sender, data
+}
+
+func sendCommentRequest(router *gin.Engine, method string, entryID uint, token, body string) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/api/v1/scrapbook/entries/%d/comments", entryID)
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCommentHandler_CreateComment(t *testing.T) {
+	_, router, sm, sender, data := setupCommentTest(t)
+	token, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	w := sendCommentRequest(router, http.MethodPost, data.entry.ID, token, `{"body": "  Lovely photo!  "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response CommentResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Body != "Lovely photo!" || response.AuthorID != data.instructor.ID || response.AuthorName != "Prof. Lee" || response.EntryID != data.entry.ID {
+		t.Errorf("unexpected comment: %+v", response)
+	}
+
+	data.handler.Wait()
+	if len(sender.messages) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(sender.messages))
+	}
+	msg := sender.messages[0]
+	if msg.To != "ada@example.edu" || !strings.Contains(msg.Subject, "Eiffel Tower") || !strings.Contains(msg.Body, "Lovely photo!") {
+		t.Errorf("unexpected email: %+v", msg)
+	}
+}
+
+// blockingSender holds every send until release is closed
+type blockingSender struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+func (s *blockingSender) Send(msg mail.Message) error {
+	<-s.release
+	s.sent <- msg
+	return nil
+}
+
+func TestCommentHandler_CreateComment_EmailsInBackground(t *testing.T) {
+	_, router, sm, _, data := setupCommentTest(t)
+	sender := &blockingSender{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+	data.handler.SetMailer(sender)
+	token, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	// The response must not wait on the mail server
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, token, `{"body": "Nice"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	close(sender.release)
+	data.handler.Wait()
+	select {
+	case msg := <-sender.sent:
+		if msg.To != "ada@example.edu" {
+			t.Errorf("unexpected recipient %q", msg.To)
+		}
+	default:
+		t.Error("expected the email to be sent")
+	}
+}
+
+func TestCommentHandler_CreateComment_NoEmailWithoutAddress(t *testing.T) {
+	db, router, sm, sender, data := setupCommentTest(t)
+	entry := models.ScrapbookEntry{UserID: data.classmate.ID, CountryID: 1, CourseID: "course-a", Title: "Louvre"}
+	db.Create(&entry)
+	token, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	if w := sendCommentRequest(router, http.MethodPost, entry.ID, token, `{"body": "Nice"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	data.handler.Wait()
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no email for an owner without an address, got %+v", sender.messages)
+	}
+}
+
+func TestCommentHandler_CreateComment_Forbidden(t *testing.T) {
+	_, router, sm, sender, data := setupCommentTest(t)
+
+	learner, _ := sm.CreateToken(data.owner.ID, "canvas-1", "course-a", "learner")
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, learner, `{"body": "Hi"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a learner, got %d", w.Code)
+	}
+
+	otherCourse, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-b", "instructor")
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, otherCourse, `{"body": "Hi"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for another course's instructor, got %d", w.Code)
+	}
+
+	noCourse, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "", "instructor")
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, noCourse, `{"body": "Hi"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without a course, got %d", w.Code)
+	}
+
+	data.handler.Wait()
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no email, got %+v", sender.messages)
+	}
+}
+
+func TestCommentHandler_CreateComment_OwnerComments(t *testing.T) {
+	db, router, sm, sender, data := setupCommentTest(t)
+	handler := NewCommentHandler(db)
+	handler.SetMailer(sender)
+	handler.SetOwnerComments(true)
+	router = gin.New()
+	router.POST("/api/v1/scrapbook/entries/:id/comments", middleware.AuthMiddleware(sm), handler.CreateComment)
+
+	owner, _ := sm.CreateToken(data.owner.ID, "canvas-1", "course-a", "learner")
+	w := sendCommentRequest(router, http.MethodPost, data.entry.ID, owner, `{"body": "Thanks!"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 for the owner, got %d: %s", w.Code, w.Body.String())
+	}
+	var response CommentResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.AuthorID != data.owner.ID || response.Body != "Thanks!" {
+		t.Errorf("unexpected comment: %+v", response)
+	}
+	handler.Wait()
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no email for the owner's own comment, got %+v", sender.messages)
+	}
+
+	// A classmate still cannot comment on an entry that is not theirs
+	classmate, _ := sm.CreateToken(data.classmate.ID, "canvas-2", "course-a", "learner")
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, classmate, `{"body": "Hi"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a classmate, got %d", w.Code)
+	}
+
+	instructor, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+	if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, instructor, `{"body": "Great"}`); w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 for the course instructor, got %d", w.Code)
+	}
+
+	var count int64
+	db.Model(&models.Comment{}).Where("entry_id = ?", data.entry.ID).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 comments, got %d", count)
+	}
+}
+
+func TestCommentHandler_CreateComment_InvalidBody(t *testing.T) {
+	_, router, sm, _, data := setupCommentTest(t)
+	token, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	for _, body := range []string{`{}`, `{"body": "   "}`, `{"body": "` + strings.Repeat("a", 5001) + `"}`} {
+		if w := sendCommentRequest(router, http.MethodPost, data.entry.ID, token, body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	}
+	if w := sendCommentRequest(router, http.MethodPost, 9999, token, `{"body": "Hi"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing entry, got %d", w.Code)
+	}
+}
+
+func TestCommentHandler_ListComments_Visibility(t *testing.T) {
+	db, router, sm, _, data := setupCommentTest(t)
+	db.Create(&models.Comment{EntryID: data.entry.ID, AuthorUserID: data.instructor.ID, Body: "First"})
+	db.Create(&models.Comment{EntryID: data.entry.ID, AuthorUserID: data.instructor.ID, Body: "Second"})
+
+	owner, _ := sm.CreateToken(data.owner.ID, "canvas-1", "course-a", "learner")
+	instructor, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-a", "instructor")
+	classmate, _ := sm.CreateToken(data.classmate.ID, "canvas-2", "course-a", "learner")
+	otherInstructor, _ := sm.CreateToken(data.instructor.ID, "canvas-0", "course-b", "instructor")
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"owner", owner, http.StatusOK},
+		{"course instructor", instructor, http.StatusOK},
+		{"classmate", classmate, http.StatusNotFound},
+		{"other course instructor", otherInstructor, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendCommentRequest(router, http.MethodGet, data.entry.ID, tt.token, "")
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var response struct {
+				Comments []CommentResponse `json:"comments"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if len(response.Comments) != 2 || response.Comments[0].Body != "First" || response.Comments[1].Body != "Second" {
+				t.Errorf("expected comments oldest first, got %+v", response.Comments)
+			}
+			if response.Comments[0].AuthorName != "Prof. Lee" {
+				t.Errorf("expected author name, got %q", response.Comments[0].AuthorName)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
+	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,64 +16,147 @@ import (
 
 // CountryHandler handles country-related API endpoints
 type CountryHandler struct {
-	db *gorm.DB
+	db           *gorm.DB
+	translations *models.CountryTranslationRepository
+	popular      popularCache
+	maxPageSize  int // Largest page size list requests may ask for
 }
 
 // NewCountryHandler creates a new country handler
 func NewCountryHandler(db *gorm.DB) *CountryHandler {
-	return &CountryHandler{db: db}
+	return &CountryHandler{
+		db:           db,
+		translations: models.NewCountryTranslationRepository(db),
+		maxPageSize:  defaultMaxPageSize,
+	}
+}
+
+// SetMaxPageSize sets the largest page size list requests may ask for;
+// non-positive values keep the default
+func (h *CountryHandler) SetMaxPageSize(n int) {
+	h.maxPageSize = pageSizeCap(n)
 }
 
 // CountryResponse represents a country in API responses
 type CountryResponse struct {
-	ID      uint   `json:"id"`
-	Name    string `json:"name"`
-	ISOCode string `json:"isoCode"`
-	Region  string `json:"region,omitempty"`
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ISOCode  string `json:"isoCode"`
+	ISOCode3 string `json:"isoCode3,omitempty"`
+	Region   string `json:"region,omitempty"`
 }
 
 // CountryListResponse represents the response for listing countries
 type CountryListResponse struct {
-	Countries []CountryResponse `json:"countries"`
-	Total     int64             `json:"total"`
+	Countries  []CountryResponse `json:"countries"`
+	Total      int64             `json:"total"`
+	Pagination *PageInfo         `json:"pagination,omitempty"` // Set when page/pageSize or limit/offset is given
 }
 
 // toCountryResponse converts a model to a response
 func toCountryResponse(c *models.Country) CountryResponse {
 	return CountryResponse{
-		ID:      c.ID,
-		Name:    c.Name,
-		ISOCode: c.ISOCode,
-		Region:  c.Region,
+		ID:       c.ID,
+		Name:     c.Name,
+		ISOCode:  c.ISOCode,
+		ISOCode3: c.ISOCode3,
+		Region:   c.Region,
+	}
+}
+
+// localizeNames replaces country names with their translation for locale.
+// Countries without a translation keep their English name.
+func (h *CountryHandler) localizeNames(locale string, countries []models.Country) error {
+	if locale == "" || len(countries) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(countries))
+	for i, country := range countries {
+		ids[i] = country.ID
 	}
+	names, err := h.translations.NamesForLocale(locale, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range countries {
+		if name, ok := names[countries[i].ID]; ok {
+			countries[i].Name = name
+		}
+	}
+	return nil
 }
 
-// ListCountries returns all countries
+// localizeName replaces a single country's name with its translation for locale
+func (h *CountryHandler) localizeName(locale string, country *models.Country) error {
+	countries := []models.Country{*country}
+	if err := h.localizeNames(locale, countries); err != nil {
+		return err
+	}
+	country.Name = countries[0].Name
+	return nil
+}
+
+// ListCountries returns all countries, ordered by English name
 // GET /api/v1/countries
+// Query params: region (optional) - filter by region,
+// locale (optional) - localize names; defaults to the session locale, then Accept-Language,
+// page, pageSize or limit, offset (optional) - page through countries
 func (h *CountryHandler) ListCountries(c *gin.Context) {
 	// Optional filters
 	region := c.Query("region")
+	locale := requestLocale(c)
+
+	page, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
+	}
 
 	var countries []models.Country
 	query := h.db.Model(&models.Country{})
+	versionQuery := h.db.Model(&models.Country{})
 
 	if region != "" {
 		query = query.Where("region = ?", region)
+		versionQuery = versionQuery.Where("region = ?", region)
 	}
 
-	// Get total count
-	var total int64
-	query.Count(&total)
+	// Get total count and last update for the ETag
+	version, err := queryVersion(versionQuery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+		return
+	}
+	versions := []tableVersion{version}
+	if locale != "" {
+		// Localized names also change when translations do
+		translations, err := queryVersion(h.db.Model(&models.CountryTranslation{}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+			return
+		}
+		versions = append(versions, translations)
+	}
+	scope := fmt.Sprintf("countries:%s:%s:%d:%d", region, models.NormalizeLocale(locale), page.Limit(), page.Offset())
+	if checkNotModified(c, etagForVersion(scope, versions...)) {
+		return
+	}
 
 	// Get countries (ordered by name)
-	if err := query.Order("name ASC").Find(&countries).Error; err != nil {
+	if err := page.apply(query.Order("name ASC")).Find(&countries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+		return
+	}
+	if err := h.localizeNames(locale, countries); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
 		return
 	}
 
 	response := CountryListResponse{
-		Countries: make([]CountryResponse, len(countries)),
-		Total:     total,
+		Countries:  make([]CountryResponse, len(countries)),
+		Total:      version.Count,
+		Pagination: page.Info(version.Count),
 	}
 
 	for i, country := range countries {
@@ -81,6 +168,7 @@ func (h *CountryHandler) ListCountries(c *gin.Context) {
 
 // GetCountry returns a specific country by ID
 // GET /api/v1/countries/:id
+// Query params: locale (optional) - localize the name
 func (h *CountryHandler) GetCountry(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -99,11 +187,215 @@ func (h *CountryHandler) GetCountry(c *gin.Context) {
 		return
 	}
 
+	locale := requestLocale(c)
+	etagParts := []string{"country", strconv.FormatUint(uint64(country.ID), 10), country.UpdatedAt.Format(time.RFC3339Nano), models.NormalizeLocale(locale)}
+	if locale != "" {
+		// The localized name also changes when the country's translations do
+		translations, err := queryVersion(h.db.Model(&models.CountryTranslation{}).Where("country_id = ?", country.ID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+			return
+		}
+		etagParts = append(etagParts, strconv.FormatInt(translations.Count, 10), translations.LastUpdated.String)
+	}
+	if checkNotModified(c, weakETag(etagParts...)) {
+		return
+	}
+
+	if err := h.localizeName(locale, &country); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
 	c.JSON(http.StatusOK, toCountryResponse(&country))
 }
 
+// ListUnvisitedCountries returns the countries the authenticated user has
+// no visit for, ordered by name
+// GET /api/v1/countries/unvisited
+// Query params: region (optional) - filter by region, locale (optional) - localize names
+func (h *CountryHandler) ListUnvisitedCountries(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	// Left anti-join: keep countries with no matching (non-deleted) visit
+	query := h.db.Model(&models.Country{}).
+		Joins("LEFT JOIN visits ON visits.country_id = countries.id AND visits.user_id = ? AND visits.deleted_at IS NULL", userID).
+		Where("visits.id IS NULL")
+
+	if region := c.Query("region"); region != "" {
+		query = query.Where("countries.region = ?", region)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+		return
+	}
+
+	var countries []models.Country
+	if err := query.Select("countries.*").Order("countries.name ASC").Find(&countries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+		return
+	}
+	if err := h.localizeNames(requestLocale(c), countries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+		return
+	}
+
+	response := CountryListResponse{
+		Countries: make([]CountryResponse, len(countries)),
+		Total:     total,
+	}
+
+	for i, country := range countries {
+		response.Countries[i] = toCountryResponse(&country)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CountryOverviewResponse represents a country with the user's activity in it
+type CountryOverviewResponse struct {
+	Country        CountryResponse `json:"country"`
+	Visited        bool            `json:"visited"`
+	VisitCount     int64           `json:"visitCount"`
+	EntryCount     int64           `json:"entryCount"`
+	FirstVisitedAt string          `json:"firstVisitedAt,omitempty"`
+	LastVisitedAt  string          `json:"lastVisitedAt,omitempty"`
+}
+
+// GetCountryOverview returns a country with the authenticated user's visit
+// and scrapbook entry counts and first/last visit dates
+// GET /api/v1/countries/:id/overview
+// Query params: locale (optional) - localize the name
+func (h *CountryHandler) GetCountryOverview(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid country ID"})
+		return
+	}
+
+	var country models.Country
+	if err := h.db.First(&country, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "country not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	if err := h.localizeName(requestLocale(c), &country); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	response := CountryOverviewResponse{Country: toCountryResponse(&country)}
+
+	h.db.Model(&models.Visit{}).
+		Where("user_id = ? AND country_id = ?", userID, country.ID).
+		Count(&response.VisitCount)
+	h.db.Model(&models.ScrapbookEntry{}).
+		Where("user_id = ? AND country_id = ?", userID, country.ID).
+		Count(&response.EntryCount)
+
+	if response.VisitCount > 0 {
+		response.Visited = true
+
+		var first, last models.Visit
+		if err := h.db.Where("user_id = ? AND country_id = ?", userID, country.ID).
+			Order("visited_at ASC").First(&first).Error; err == nil {
+			response.FirstVisitedAt = formatTimestamp(first.VisitedAt, loc)
+		}
+		if err := h.db.Where("user_id = ? AND country_id = ?", userID, country.ID).
+			Order("visited_at DESC").First(&last).Error; err == nil {
+			response.LastVisitedAt = formatTimestamp(last.VisitedAt, loc)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CountryClassStatsResponse represents a country with activity across a course
+type CountryClassStatsResponse struct {
+	Country      CountryResponse `json:"country"`
+	CourseID     string          `json:"courseId"`
+	LearnerCount int64           `json:"learnerCount"` // Distinct users with a visit in the course
+	EntryCount   int64           `json:"entryCount"`
+}
+
+// GetCountryClassStats returns how many learners in a course have visited a
+// country and how many scrapbook entries the course has for it. Only data
+// tagged with the session's course is counted; RequireCourse rejects a
+// courseId query parameter naming any other course.
+// GET /api/v1/countries/:id/class-stats
+// Query params: locale (optional) - localize the name
+func (h *CountryHandler) GetCountryClassStats(c *gin.Context) {
+	if _, ok := middleware.GetUserID(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	courseID, _ := middleware.GetCourseID(c) // Checked by RequireCourse
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid country ID"})
+		return
+	}
+
+	var country models.Country
+	if err := h.db.First(&country, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "country not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	if err := h.localizeName(requestLocale(c), &country); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	response := CountryClassStatsResponse{Country: toCountryResponse(&country), CourseID: courseID}
+
+	if err := h.db.Model(&models.Visit{}).
+		Where("course_id = ? AND country_id = ?", courseID, country.ID).
+		Distinct("user_id").Count(&response.LearnerCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch class stats"})
+		return
+	}
+	if err := h.db.Model(&models.ScrapbookEntry{}).
+		Where("course_id = ? AND country_id = ?", courseID, country.ID).
+		Count(&response.EntryCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch class stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // GetCountryByCode returns a country by ISO code
 // GET /api/v1/countries/code/:code
+// Query params: locale (optional) - localize the name
 func (h *CountryHandler) GetCountryByCode(c *gin.Context) {
 	code := c.Param("code")
 	if code == "" {
@@ -121,12 +413,54 @@ func (h *CountryHandler) GetCountryByCode(c *gin.Context) {
 		return
 	}
 
+	if err := h.localizeName(requestLocale(c), &country); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCountryResponse(&country))
+}
+
+// GetCountryByCode3 returns a country by ISO 3166-1 alpha-3 code
+// GET /api/v1/countries/code3/:code
+// Query params: locale (optional) - localize the name
+func (h *CountryHandler) GetCountryByCode3(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing country code"})
+		return
+	}
+
+	var country models.Country
+	if err := h.db.Where("iso_code3 = ?", code).First(&country).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "country not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
+	if err := h.localizeName(requestLocale(c), &country); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch country"})
+		return
+	}
+
 	c.JSON(http.StatusOK, toCountryResponse(&country))
 }
 
 // ListRegions returns all unique regions
 // GET /api/v1/countries/regions
 func (h *CountryHandler) ListRegions(c *gin.Context) {
+	version, err := queryVersion(h.db.Model(&models.Country{}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch regions"})
+		return
+	}
+	if checkNotModified(c, etagForVersion("regions", version)) {
+		return
+	}
+
 	var regions []string
 	if err := h.db.Model(&models.Country{}).Distinct().Pluck("region", &regions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch regions"})
@@ -136,8 +470,78 @@ func (h *CountryHandler) ListRegions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"regions": regions})
 }
 
-// SearchCountries searches countries by name
+// SearchHighlight describes why a country matched a search
+type SearchHighlight struct {
+	Field string `json:"field"` // "name", "isoCode" or "alias"
+	// Offsets is the [start, end) character range of the match within the
+	// returned field, or within Alias. Absent when only the untranslated
+	// English name matched.
+	Offsets []int  `json:"offsets,omitempty"`
+	Alias   string `json:"alias,omitempty"` // Matching alternate name, alias only
+}
+
+// CountrySearchResult is a search match, with a highlight when requested
+type CountrySearchResult struct {
+	CountryResponse
+	Highlight *SearchHighlight `json:"highlight,omitempty"`
+}
+
+// searchHighlight reports which field of a returned country contains query,
+// preferring the name, then the ISO code, then aliases. Matching ignores case
+// and diacritics like the search itself.
+func searchHighlight(country CountryResponse, aliases, query string) *SearchHighlight {
+	if start, end, ok := matchOffsets(country.Name, query); ok {
+		return &SearchHighlight{Field: "name", Offsets: []int{start, end}}
+	}
+	if start, end, ok := matchOffsets(country.ISOCode, query); ok {
+		return &SearchHighlight{Field: "isoCode", Offsets: []int{start, end}}
+	}
+	for _, alias := range splitTags(aliases) {
+		if start, end, ok := matchOffsets(alias, query); ok {
+			return &SearchHighlight{Field: "alias", Offsets: []int{start, end}, Alias: alias}
+		}
+	}
+	// Matched on the English name, which a localized response does not show
+	return &SearchHighlight{Field: "name"}
+}
+
+// matchOffsets returns the character range of the first occurrence of sub
+// in s, ignoring case and diacritics
+func matchOffsets(s, sub string) (start, end int, ok bool) {
+	haystack := []rune(foldText(s))
+	needle := []rune(foldText(sub))
+	if len(needle) == 0 {
+		return 0, 0, false
+	}
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			return i, i + len(needle), true
+		}
+	}
+	return 0, 0, false
+}
+
+// countryMatches reports whether a country's English name, ISO code or any
+// alias contains query, ignoring case and diacritics
+func countryMatches(country *models.Country, query string) bool {
+	if foldContains(country.Name, query) || foldContains(country.ISOCode, query) {
+		return true
+	}
+	for _, alias := range splitTags(country.Aliases) {
+		if foldContains(alias, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchCountries searches countries by name, ISO code or alias, ignoring
+// case and diacritics
 // GET /api/v1/countries/search?q=query
+// Query params: locale (optional) - localize names; matching is on English names, ISO codes and aliases,
+// highlight (optional) - "true" to include which field matched and where,
+// page, pageSize or limit, offset (optional) - page through matches, e.g. limit=5 for
+// autocomplete; all matches are returned otherwise
 func (h *CountryHandler) SearchCountries(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -145,21 +549,245 @@ func (h *CountryHandler) SearchCountries(c *gin.Context) {
 		return
 	}
 
+	page, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
+	}
+
+	// The country table is small, so matching happens in Go where case and
+	// diacritic folding behave the same on SQLite and Postgres
+	var all []models.Country
+	if err := h.db.Order("name ASC").Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search countries"})
+		return
+	}
+
 	var countries []models.Country
-	searchPattern := "%" + query + "%"
+	for i := range all {
+		if countryMatches(&all[i], query) {
+			countries = append(countries, all[i])
+		}
+	}
+	total := int64(len(countries))
+	countries = pageOf(page, countries)
 
-	if err := h.db.Where("name LIKE ? OR iso_code LIKE ?", searchPattern, searchPattern).
-		Order("name ASC").
-		Limit(20).
-		Find(&countries).Error; err != nil {
+	if err := h.localizeNames(requestLocale(c), countries); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search countries"})
 		return
 	}
 
-	response := make([]CountryResponse, len(countries))
+	highlight := c.Query("highlight") == "true"
+	response := make([]CountrySearchResult, len(countries))
 	for i, country := range countries {
-		response[i] = toCountryResponse(&country)
+		response[i] = CountrySearchResult{CountryResponse: toCountryResponse(&country)}
+		if highlight {
+			response[i].Highlight = searchHighlight(response[i].CountryResponse, country.Aliases, query)
+		}
 	}
 
+	c.JSON(http.StatusOK, listResponse("countries", response, total, page))
+}
+
+const (
+	// defaultPopularLimit is the number of popular countries returned without ?limit=
+	defaultPopularLimit = 10
+	// maxPopularLimit is the largest ?limit= accepted for popular countries
+	maxPopularLimit = 100
+	// popularCacheTTL is how long the popular countries ranking is reused
+	popularCacheTTL = time.Minute
+)
+
+// popularCacheControl is the Cache-Control policy for the popular ranking
+const popularCacheControl = "public, max-age=60"
+
+// PopularCountryResponse is a country with its total visit count
+type PopularCountryResponse struct {
+	CountryResponse
+	VisitCount int64 `json:"visitCount"`
+}
+
+// popularCountryRow is a ranked country as loaded from the database
+type popularCountryRow struct {
+	ID         uint
+	Name       string
+	ISOCode    string
+	ISOCode3   string
+	Region     string
+	VisitCount int64
+}
+
+// popularCache holds the most recent ranking of the top maxPopularLimit countries
+type popularCache struct {
+	mu        sync.Mutex
+	rows      []popularCountryRow
+	fetchedAt time.Time
+}
+
+// ListPopularCountries returns countries ranked by visits across all users,
+// most visited first. Countries nobody has visited are omitted.
+// GET /api/v1/countries/popular
+// Query params: limit (optional) - 1 to 100, default 10
+func (h *CountryHandler) ListPopularCountries(c *gin.Context) {
+	limit := defaultPopularLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxPopularLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxPopularLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	rows, err := h.popularRanking()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch popular countries"})
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	countries := make([]models.Country, len(rows))
+	for i, row := range rows {
+		countries[i] = models.Country{ID: row.ID, Name: row.Name, ISOCode: row.ISOCode, ISOCode3: row.ISOCode3, Region: row.Region}
+	}
+	if err := h.localizeNames(requestLocale(c), countries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch popular countries"})
+		return
+	}
+
+	response := make([]PopularCountryResponse, len(rows))
+	for i, row := range rows {
+		response[i] = PopularCountryResponse{
+			CountryResponse: toCountryResponse(&countries[i]),
+			VisitCount:      row.VisitCount,
+		}
+	}
+
+	c.Header("Cache-Control", popularCacheControl)
+	c.Header("Vary", countryVary)
 	c.JSON(http.StatusOK, gin.H{"countries": response})
 }
+
+// popularRanking returns the top maxPopularLimit countries by visit count,
+// reusing the last ranking for popularCacheTTL since it changes slowly
+func (h *CountryHandler) popularRanking() ([]popularCountryRow, error) {
+	h.popular.mu.Lock()
+	defer h.popular.mu.Unlock()
+
+	if h.popular.rows != nil && time.Since(h.popular.fetchedAt) < popularCacheTTL {
+		return h.popular.rows, nil
+	}
+
+	rows := []popularCountryRow{}
+	err := h.db.Table("countries").
+		Select("countries.id, countries.name, countries.iso_code, countries.iso_code3, countries.region, COUNT(visits.id) AS visit_count").
+		Joins("JOIN visits ON visits.country_id = countries.id AND visits.deleted_at IS NULL").
+		Group("countries.id, countries.name, countries.iso_code, countries.iso_code3, countries.region").
+		Order("visit_count DESC, countries.name ASC").
+		Limit(maxPopularLimit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	h.popular.rows = rows
+	h.popular.fetchedAt = time.Now()
+	return rows, nil
+}
+
+const (
+	// defaultSuggestionLimit is the number of suggestions returned without ?limit=
+	defaultSuggestionLimit = 10
+	// maxSuggestionLimit is the largest ?limit= accepted for suggestions
+	maxSuggestionLimit = 50
+)
+
+// Reasons a country is suggested
+const (
+	suggestionReasonRegion  = "region"
+	suggestionReasonPopular = "popular"
+)
+
+// SuggestionResponse is an unvisited country suggested to the user
+type SuggestionResponse struct {
+	CountryResponse
+	Reason string `json:"reason"`
+}
+
+// suggestionRow is a candidate country as loaded from the database
+type suggestionRow struct {
+	ID           uint
+	Name         string
+	ISOCode      string
+	ISOCode3     string
+	Region       string
+	VisitCount   int64
+	RegionVisits int64
+}
+
+// ListSuggestions suggests countries the authenticated user has not visited.
+// Countries in the regions the user visits most come first, then globally
+// popular countries, so users without visits get the popular ones.
+// GET /api/v1/me/suggestions
+// Query params: limit (optional) - 1 to 50, default 10, locale (optional) - localize names
+func (h *CountryHandler) ListSuggestions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	limit := defaultSuggestionLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxSuggestionLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxSuggestionLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	userRegions := h.db.Table("visits").
+		Select("countries.region, COUNT(visits.id) AS region_visits").
+		Joins("JOIN countries ON countries.id = visits.country_id").
+		Where("visits.user_id = ? AND visits.deleted_at IS NULL", userID).
+		Group("countries.region")
+	userCountries := h.db.Model(&models.Visit{}).Select("country_id").Where("user_id = ?", userID)
+
+	rows := []suggestionRow{}
+	err := h.db.Table("countries").
+		Select("countries.id, countries.name, countries.iso_code, countries.iso_code3, countries.region, "+
+			"COUNT(visits.id) AS visit_count, COALESCE(MAX(user_regions.region_visits), 0) AS region_visits").
+		Joins("LEFT JOIN visits ON visits.country_id = countries.id AND visits.deleted_at IS NULL").
+		Joins("LEFT JOIN (?) AS user_regions ON user_regions.region = countries.region", userRegions).
+		Where("countries.id NOT IN (?)", userCountries).
+		Group("countries.id, countries.name, countries.iso_code, countries.iso_code3, countries.region").
+		Order("region_visits DESC, visit_count DESC, countries.name ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch suggestions"})
+		return
+	}
+
+	countries := make([]models.Country, len(rows))
+	for i, row := range rows {
+		countries[i] = models.Country{ID: row.ID, Name: row.Name, ISOCode: row.ISOCode, ISOCode3: row.ISOCode3, Region: row.Region}
+	}
+	if err := h.localizeNames(requestLocale(c), countries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch suggestions"})
+		return
+	}
+
+	response := make([]SuggestionResponse, len(rows))
+	for i, row := range rows {
+		reason := suggestionReasonPopular
+		if row.RegionVisits > 0 {
+			reason = suggestionReasonRegion
+		}
+		response[i] = SuggestionResponse{CountryResponse: toCountryResponse(&countries[i]), Reason: reason}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": response})
+}
@@ -2,10 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +25,7 @@ func setupCountryTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.Country{})
+	err = db.AutoMigrate(&models.Country{}, &models.CountryTranslation{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -29,11 +35,11 @@ func setupCountryTestDB(t *testing.T) *gorm.DB {
 
 func seedCountries(t *testing.T, db *gorm.DB) {
 	countries := []models.Country{
-		{Name: "France", ISOCode: "FR", Region: "Europe"},
-		{Name: "Germany", ISOCode: "DE", Region: "Europe"},
-		{Name: "Japan", ISOCode: "JP", Region: "Asia"},
-		{Name: "Brazil", ISOCode: "BR", Region: "South America"},
-		{Name: "Canada", ISOCode: "CA", Region: "North America"},
+		{Name: "France", ISOCode: "FR", ISOCode3: "FRA", Region: "Europe"},
+		{Name: "Germany", ISOCode: "DE", ISOCode3: "DEU", Region: "Europe"},
+		{Name: "Japan", ISOCode: "JP", ISOCode3: "JPN", Region: "Asia"},
+		{Name: "Brazil", ISOCode: "BR", ISOCode3: "BRA", Region: "South America"},
+		{Name: "Canada", ISOCode: "CA", ISOCode3: "CAN", Region: "North America"},
 	}
 
 	for _, c := range countries {
@@ -222,6 +228,55 @@ func TestCountryHandler_GetCountryByCode_NotFound(t *testing.T) {
 	}
 }
 
+func TestCountryHandler_GetCountryByCode3(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/code3/:code", handler.GetCountryByCode3)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/code3/FRA", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response CountryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if response.Name != "France" || response.ISOCode != "FR" || response.ISOCode3 != "FRA" {
+		t.Errorf("expected France FR/FRA, got %+v", response)
+	}
+}
+
+func TestCountryHandler_GetCountryByCode3_NotFound(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/code3/:code", handler.GetCountryByCode3)
+
+	for _, code := range []string{"XXX", "FR"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/code3/"+code, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 for %s, got %d", code, w.Code)
+		}
+	}
+}
+
 func TestCountryHandler_ListRegions(t *testing.T) {
 	db := setupCountryTestDB(t)
 	seedCountries(t, db)
@@ -361,3 +416,1109 @@ func TestCountryHandler_ListCountries_Empty(t *testing.T) {
 		t.Errorf("expected 0 countries, got %d", response.Total)
 	}
 }
+
+func TestCountryHandler_ListCountries_ETag(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" || etag[:2] != "W/" {
+		t.Fatalf("expected weak ETag, got '%s'", etag)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("expected Cache-Control header to be set")
+	}
+
+	// Conditional request with matching ETag
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Error("expected empty body for 304")
+	}
+
+	// ETag changes when data changes
+	db.Create(&models.Country{Name: "Kenya", ISOCode: "KE", Region: "Africa"})
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 after data change, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("expected ETag to change after data change")
+	}
+}
+
+func TestCountryHandler_ETagChangesWithTranslations(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+	router.GET("/api/v1/countries/:id", handler.GetCountry)
+
+	for _, path := range []string{"/api/v1/countries?locale=fr", "/api/v1/countries/1?locale=fr"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			etag := w.Header().Get("ETag")
+
+			// Translating a name leaves the countries table untouched
+			var country models.Country
+			db.First(&country, 1)
+			if err := models.NewCountryTranslationRepository(db).Upsert(country.ID, "fr", "Nom traduit "+path); err != nil {
+				t.Fatalf("failed to add translation: %v", err)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200 after a translation change, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "Nom traduit "+path) {
+				t.Errorf("expected the new translation in the response, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCountryHandler_ListCountries_ETagPerRegion(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	allETag := w.Header().Get("ETag")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries?region=Europe", nil)
+	req.Header.Set("If-None-Match", allETag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected filtered listing not to match unfiltered ETag, got %d", w.Code)
+	}
+}
+
+func TestCountryHandler_GetCountry_ETag(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/:id", handler.GetCountry)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+}
+
+func TestCountryHandler_ListRegions_ETag(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/regions", handler.ListRegions)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/regions", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries/regions", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for matching ETag in list, got %d", w.Code)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"empty header", "", `W/"abc"`, false},
+		{"exact match", `W/"abc"`, `W/"abc"`, true},
+		{"strong form matches weak", `"abc"`, `W/"abc"`, true},
+		{"wildcard", "*", `W/"abc"`, true},
+		{"list match", `"x", W/"abc"`, `W/"abc"`, true},
+		{"no match", `W/"xyz"`, `W/"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, tt.etag); got != tt.want {
+				t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func createCountryOverviewTestRouter(t *testing.T) (*gorm.DB, *gin.Engine, string) {
+	db := setupCountryTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.Visit{}, &models.ScrapbookEntry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	seedCountries(t, db)
+
+	user := &models.User{CanvasUserID: "canvas-123", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(user)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	auth := router.Group("/api/v1")
+	auth.Use(middleware.AuthMiddleware(sm))
+	auth.GET("/countries/:id/overview", handler.GetCountryOverview)
+	auth.GET("/countries/unvisited", handler.ListUnvisitedCountries)
+	auth.GET("/me/suggestions", handler.ListSuggestions)
+
+	return db, router, token
+}
+
+func TestCountryHandler_GetCountryOverview(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	first := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 8, 20, 0, 0, 0, 0, time.UTC)
+	db.Create(&models.Visit{UserID: 1, CountryID: 1, VisitedAt: last})
+	db.Create(&models.Visit{UserID: 1, CountryID: 1, VisitedAt: first})
+	db.Create(&models.Visit{UserID: 2, CountryID: 1, VisitedAt: time.Now()}) // another user
+	db.Create(&models.ScrapbookEntry{UserID: 1, CountryID: 1, Title: "Paris"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/1/overview", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response CountryOverviewResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Country.ISOCode != "FR" {
+		t.Errorf("expected country FR, got '%s'", response.Country.ISOCode)
+	}
+	if !response.Visited || response.VisitCount != 2 {
+		t.Errorf("expected 2 visits, got visited=%v count=%d", response.Visited, response.VisitCount)
+	}
+	if response.EntryCount != 1 {
+		t.Errorf("expected 1 entry, got %d", response.EntryCount)
+	}
+	if response.FirstVisitedAt != first.Format(time.RFC3339) {
+		t.Errorf("expected first visit %s, got %s", first.Format(time.RFC3339), response.FirstVisitedAt)
+	}
+	if response.LastVisitedAt != last.Format(time.RFC3339) {
+		t.Errorf("expected last visit %s, got %s", last.Format(time.RFC3339), response.LastVisitedAt)
+	}
+}
+
+func TestCountryHandler_GetCountryOverview_NotVisited(t *testing.T) {
+	_, router, token := createCountryOverviewTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/2/overview", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response CountryOverviewResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Visited || response.VisitCount != 0 || response.FirstVisitedAt != "" {
+		t.Errorf("expected unvisited overview, got %+v", response)
+	}
+}
+
+func TestCountryHandler_GetCountryOverview_NotFound(t *testing.T) {
+	_, router, token := createCountryOverviewTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/999/overview", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+// createClassStatsTestRouter returns a router for the class stats endpoint
+// and a session manager for minting tokens
+func createClassStatsTestRouter(t *testing.T) (*gorm.DB, *gin.Engine, *lti.SessionManager) {
+	db := setupCountryTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.Visit{}, &models.ScrapbookEntry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	seedCountries(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	auth := router.Group("/api/v1")
+	auth.Use(middleware.AuthMiddleware(sm))
+	auth.GET("/countries/:id/class-stats", middleware.RequireInstructor(), middleware.RequireCourse(), handler.GetCountryClassStats)
+
+	return db, router, sm
+}
+
+func TestCountryHandler_GetCountryClassStats(t *testing.T) {
+	db, router, sm := createClassStatsTestRouter(t)
+
+	// Two learners visited France in course-1, one of them twice
+	db.Create(&models.Visit{UserID: 2, CountryID: 1, CourseID: "course-1"})
+	db.Create(&models.Visit{UserID: 2, CountryID: 1, CourseID: "course-1"})
+	db.Create(&models.Visit{UserID: 3, CountryID: 1, CourseID: "course-1"})
+	db.Create(&models.ScrapbookEntry{UserID: 2, CountryID: 1, CourseID: "course-1", Title: "Paris"})
+	db.Create(&models.ScrapbookEntry{UserID: 3, CountryID: 1, CourseID: "course-1", Title: "Lyon"})
+	db.Create(&models.ScrapbookEntry{UserID: 3, CountryID: 1, CourseID: "course-1", Title: "Nice"})
+	// Other courses, untagged data and other countries are not counted
+	db.Create(&models.Visit{UserID: 4, CountryID: 1, CourseID: "course-2"})
+	db.Create(&models.Visit{UserID: 5, CountryID: 1})
+	db.Create(&models.Visit{UserID: 2, CountryID: 2, CourseID: "course-1"})
+	db.Create(&models.ScrapbookEntry{UserID: 4, CountryID: 1, CourseID: "course-2", Title: "Elsewhere"})
+
+	token, _ := sm.CreateToken(1, "canvas-1", "course-1", "instructor")
+
+	for _, path := range []string{"/api/v1/countries/1/class-stats", "/api/v1/countries/1/class-stats?courseId=course-1"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+
+		var response CountryClassStatsResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		if response.Country.ISOCode != "FR" || response.CourseID != "course-1" {
+			t.Errorf("unexpected country or course: %+v", response)
+		}
+		if response.LearnerCount != 2 {
+			t.Errorf("expected 2 learners, got %d", response.LearnerCount)
+		}
+		if response.EntryCount != 3 {
+			t.Errorf("expected 3 entries, got %d", response.EntryCount)
+		}
+	}
+}
+
+func TestCountryHandler_GetCountryClassStats_Errors(t *testing.T) {
+	_, router, sm := createClassStatsTestRouter(t)
+
+	instructor, _ := sm.CreateToken(1, "canvas-1", "course-1", "instructor")
+	learner, _ := sm.CreateToken(2, "canvas-2", "course-1", "learner")
+	noCourse, _ := sm.CreateToken(1, "canvas-1", "", "instructor")
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"learner", "/api/v1/countries/1/class-stats", learner, http.StatusForbidden},
+		{"other course", "/api/v1/countries/1/class-stats?courseId=course-2", instructor, http.StatusForbidden},
+		{"no course in session", "/api/v1/countries/1/class-stats", noCourse, http.StatusForbidden},
+		{"unknown country", "/api/v1/countries/999/class-stats", instructor, http.StatusNotFound},
+		{"invalid ID", "/api/v1/countries/abc/class-stats", instructor, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: tt.token})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCountryHandler_GetCountryOverview_Unauthenticated(t *testing.T) {
+	_, router, _ := createCountryOverviewTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/1/overview", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
+
+func getUnvisitedCountries(t *testing.T, router *gin.Engine, token, query string) CountryListResponse {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/unvisited"+query, nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response CountryListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func TestCountryHandler_ListUnvisitedCountries_Some(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	db.Create(&models.Visit{UserID: 1, CountryID: 1, VisitedAt: time.Now()}) // France
+	db.Create(&models.Visit{UserID: 1, CountryID: 1, VisitedAt: time.Now()}) // France again
+	db.Create(&models.Visit{UserID: 1, CountryID: 3, VisitedAt: time.Now()}) // Japan
+	db.Create(&models.Visit{UserID: 2, CountryID: 2, VisitedAt: time.Now()}) // another user's Germany
+
+	response := getUnvisitedCountries(t, router, token, "")
+
+	if response.Total != 3 || len(response.Countries) != 3 {
+		t.Fatalf("expected 3 unvisited countries, got total %d with %d results", response.Total, len(response.Countries))
+	}
+	want := []string{"Brazil", "Canada", "Germany"}
+	for i, name := range want {
+		if response.Countries[i].Name != name {
+			t.Errorf("expected country %d to be %s, got %s", i, name, response.Countries[i].Name)
+		}
+	}
+
+	europe := getUnvisitedCountries(t, router, token, "?region=Europe")
+	if europe.Total != 1 || europe.Countries[0].ISOCode != "DE" {
+		t.Errorf("expected only Germany unvisited in Europe, got %+v", europe)
+	}
+}
+
+func TestCountryHandler_ListUnvisitedCountries_All(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	for id := uint(1); id <= 5; id++ {
+		db.Create(&models.Visit{UserID: 1, CountryID: id, VisitedAt: time.Now()})
+	}
+
+	response := getUnvisitedCountries(t, router, token, "")
+
+	if response.Total != 0 || len(response.Countries) != 0 {
+		t.Errorf("expected no unvisited countries, got total %d", response.Total)
+	}
+}
+
+func TestCountryHandler_ListUnvisitedCountries_None(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	// A deleted visit does not count
+	visit := &models.Visit{UserID: 1, CountryID: 1, VisitedAt: time.Now()}
+	db.Create(visit)
+	db.Delete(visit)
+
+	response := getUnvisitedCountries(t, router, token, "")
+
+	if response.Total != 5 || len(response.Countries) != 5 {
+		t.Errorf("expected all 5 countries unvisited, got total %d with %d results", response.Total, len(response.Countries))
+	}
+}
+
+// seedFrenchNames translates all seeded countries except Canada into French
+func seedFrenchNames(t *testing.T, db *gorm.DB) {
+	repo := models.NewCountryTranslationRepository(db)
+	for code, name := range map[string]string{"FR": "France", "DE": "Allemagne", "JP": "Japon", "BR": "Brésil"} {
+		var country models.Country
+		db.Where("iso_code = ?", code).First(&country)
+		if err := repo.Upsert(country.ID, "fr", name); err != nil {
+			t.Fatalf("failed to seed translation: %v", err)
+		}
+	}
+}
+
+func countryNamesByCode(t *testing.T, body []byte) map[string]string {
+	var response CountryListResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	names := make(map[string]string)
+	for _, country := range response.Countries {
+		names[country.ISOCode] = country.Name
+	}
+	return names
+}
+
+func TestCountryHandler_ListCountries_Localized(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	seedFrenchNames(t, db)
+
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		wantDE         string
+	}{
+		{"no locale", "", "", "Germany"},
+		{"query param", "?locale=fr", "", "Allemagne"},
+		{"regional falls back to language", "?locale=fr-CA", "", "Allemagne"},
+		{"accept-language", "", "de-DE;q=0.5, fr;q=0.9", "Allemagne"},
+		{"query overrides header", "?locale=en", "fr", "Germany"},
+		{"untranslated locale", "?locale=es", "", "Germany"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/countries"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			names := countryNamesByCode(t, w.Body.Bytes())
+			if names["DE"] != tt.wantDE {
+				t.Errorf("expected DE name %q, got %q", tt.wantDE, names["DE"])
+			}
+			// Canada has no French name and always falls back to English
+			if names["CA"] != "Canada" {
+				t.Errorf("expected CA name 'Canada', got %q", names["CA"])
+			}
+		})
+	}
+}
+
+func TestCountryHandler_ListCountries_SessionLocale(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	seedFrenchNames(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateTokenWithLocale(1, "canvas-123", "course-1", "learner", "fr-FR")
+
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	router.Use(middleware.OptionalAuthMiddleware(sm))
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries", nil)
+	req.Header.Set("Accept-Language", "en-US")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	names := countryNamesByCode(t, w.Body.Bytes())
+	if names["JP"] != "Japon" {
+		t.Errorf("expected session locale to win over Accept-Language, got %q", names["JP"])
+	}
+}
+
+func TestCountryHandler_ListCountries_ETagPerLocale(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	seedFrenchNames(t, db)
+
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	etags := make(map[string]string)
+	for _, query := range []string{"", "?locale=fr"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/countries"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		etags[query] = w.Header().Get("ETag")
+
+		if vary := w.Header().Get("Vary"); !strings.Contains(vary, "Accept-Language") {
+			t.Errorf("expected Vary to include Accept-Language, got %q", vary)
+		}
+	}
+
+	if etags[""] == etags["?locale=fr"] {
+		t.Error("expected ETag to differ between locales")
+	}
+}
+
+func TestCountryHandler_GetCountry_Localized(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	seedFrenchNames(t, db)
+
+	handler := NewCountryHandler(db)
+	router := gin.New()
+	router.GET("/api/v1/countries/:id", handler.GetCountry)
+	router.GET("/api/v1/countries/code/:code", handler.GetCountryByCode)
+
+	var japan models.Country
+	db.Where("iso_code = ?", "JP").First(&japan)
+
+	for _, path := range []string{
+		fmt.Sprintf("/api/v1/countries/%d?locale=fr", japan.ID),
+		"/api/v1/countries/code/JP?locale=fr",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response CountryResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Name != "Japon" {
+			t.Errorf("%s: expected 'Japon', got %q", path, response.Name)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/code/CA?locale=fr", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response CountryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Name != "Canada" {
+		t.Errorf("expected fallback to 'Canada', got %q", response.Name)
+	}
+}
+
+func TestCountryHandler_SearchCountries_Highlight(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	search := func(query string) map[string]*SearchHighlight {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?highlight=true&"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response struct {
+			Countries []CountrySearchResult `json:"countries"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		highlights := make(map[string]*SearchHighlight)
+		for _, c := range response.Countries {
+			highlights[c.ISOCode] = c.Highlight
+		}
+		return highlights
+	}
+
+	// Name match, case-insensitive
+	highlights := search("q=MAN")
+	germany := highlights["DE"]
+	if germany == nil || germany.Field != "name" || fmt.Sprint(germany.Offsets) != "[3 6]" {
+		t.Errorf("expected name match at [3 6] for Germany, got %+v", germany)
+	}
+
+	// ISO code match
+	highlights = search("q=br")
+	brazil := highlights["BR"]
+	if brazil == nil || brazil.Field != "name" || fmt.Sprint(brazil.Offsets) != "[0 2]" {
+		t.Errorf("expected name to be preferred for Brazil, got %+v", brazil)
+	}
+	highlights = search("q=JP")
+	japan := highlights["JP"]
+	if japan == nil || japan.Field != "isoCode" || fmt.Sprint(japan.Offsets) != "[0 2]" {
+		t.Errorf("expected isoCode match at [0 2] for Japan, got %+v", japan)
+	}
+
+	// A localized name no longer contains the English match
+	models.NewCountryTranslationRepository(db).Upsert(1, "de", "Frankreich")
+	highlights = search("q=franc&locale=de")
+	france := highlights["FR"]
+	if france == nil || france.Field != "name" || france.Offsets != nil {
+		t.Errorf("expected name match without offsets for a translated name, got %+v", france)
+	}
+}
+
+func TestCountryHandler_SearchCountries_NoHighlightByDefault(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q=JP", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if strings.Contains(w.Body.String(), "highlight") {
+		t.Errorf("expected no highlight without highlight=true, got %s", w.Body.String())
+	}
+}
+
+func TestCountryHandler_SearchCountries_Aliases(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	db.Create(&models.Country{Name: "United States", ISOCode: "US", Region: "North America", Aliases: "USA,America"})
+	db.Create(&models.Country{Name: "United Kingdom", ISOCode: "GB", Region: "Europe", Aliases: "UK,Britain"})
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"USA", "United States"},
+		{"UK", "United Kingdom"},
+		{"britain", "United Kingdom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?highlight=true&q="+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var response struct {
+				Countries []CountrySearchResult `json:"countries"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if len(response.Countries) != 1 || response.Countries[0].Name != tt.want {
+				t.Fatalf("expected only %s, got %+v", tt.want, response.Countries)
+			}
+			if h := response.Countries[0].Highlight; h == nil || h.Field != "alias" {
+				t.Errorf("expected alias highlight, got %+v", h)
+			}
+		})
+	}
+}
+
+func TestCountryHandler_ListCountries_Paginated(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries?page=2&pageSize=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response CountryListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Ordered by name: Brazil, Canada, France, Germany, Japan
+	if len(response.Countries) != 2 || response.Countries[0].Name != "France" || response.Countries[1].Name != "Germany" {
+		t.Errorf("expected France and Germany on page 2, got %+v", response.Countries)
+	}
+	want := PageInfo{Page: 2, PageSize: 2, Total: 5, TotalPages: 3}
+	if response.Pagination == nil || *response.Pagination != want {
+		t.Errorf("expected pagination %+v, got %+v", want, response.Pagination)
+	}
+
+	// Pages must not share an ETag
+	etag := w.Header().Get("ETag")
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries?page=3&pageSize=2", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected a different page to miss the cache, got %d", w.Code)
+	}
+}
+
+func TestCountryHandler_ListCountries_InvalidPagination(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries", handler.ListCountries)
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	for _, path := range []string{
+		"/api/v1/countries?page=0",
+		"/api/v1/countries?pageSize=1000",
+		"/api/v1/countries/search?q=a&pageSize=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestCountryHandler_SearchCountries_NotTruncated(t *testing.T) {
+	db := setupCountryTestDB(t)
+	for i := 0; i < 25; i++ {
+		db.Create(&models.Country{Name: fmt.Sprintf("Testland %02d", i), ISOCode: fmt.Sprintf("T%02d", i)})
+	}
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	var response struct {
+		Countries  []CountrySearchResult `json:"countries"`
+		Total      int64                 `json:"total"`
+		Pagination *PageInfo             `json:"pagination"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q=testland", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Countries) != 25 || response.Total != 25 {
+		t.Errorf("expected all 25 matches, got %d of %d", len(response.Countries), response.Total)
+	}
+	if response.Pagination != nil {
+		t.Errorf("expected no pagination metadata without paging, got %+v", response.Pagination)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q=testland&page=3&pageSize=10", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Countries) != 5 || response.Countries[0].Name != "Testland 20" {
+		t.Errorf("expected the last 5 matches on page 3, got %+v", response.Countries)
+	}
+	if response.Pagination == nil || response.Pagination.TotalPages != 3 {
+		t.Errorf("expected 3 total pages, got %+v", response.Pagination)
+	}
+}
+
+func TestCountryHandler_SearchCountries_CaseAndDiacritics(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+	db.Create(&models.Country{Name: "Perú", ISOCode: "PE", Region: "South America"})
+	db.Create(&models.Country{Name: "Türkiye", ISOCode: "TR", Region: "Europe", Aliases: "Turkey"})
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"peru", "Perú"},
+		{"PERU", "Perú"},
+		{"Perú", "Perú"},
+		{"jApAn", "Japan"},
+		{"turkiye", "Türkiye"},
+		{"TÜRK", "Türkiye"},
+		{"gérmany", "Germany"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q="+url.QueryEscape(tt.query), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var response struct {
+				Countries []CountrySearchResult `json:"countries"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if len(response.Countries) != 1 || response.Countries[0].Name != tt.want {
+				t.Errorf("expected only %s, got %+v", tt.want, response.Countries)
+			}
+		})
+	}
+}
+
+func TestCountryHandler_SearchCountries_Limit(t *testing.T) {
+	db := setupCountryTestDB(t)
+	seedCountries(t, db)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/search", handler.SearchCountries)
+
+	// "a" matches all five seeded countries
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q=a&limit=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Countries []CountrySearchResult `json:"countries"`
+		Total     int64                 `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Countries) != 2 {
+		t.Errorf("expected 2 countries with limit=2, got %d", len(response.Countries))
+	}
+	if response.Total != 5 {
+		t.Errorf("expected total to count every match, got %d", response.Total)
+	}
+
+	for _, limit := range []string{"0", "-3", "many", "1000"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/search?q=a&limit="+limit, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: expected status 400, got %d", limit, w.Code)
+		}
+	}
+}
+
+func TestCountryHandler_ListPopularCountries(t *testing.T) {
+	db := setupCountryTestDB(t)
+	if err := db.AutoMigrate(&models.Visit{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	seedCountries(t, db)
+
+	idByCode := map[string]uint{}
+	var countries []models.Country
+	db.Find(&countries)
+	for _, country := range countries {
+		idByCode[country.ISOCode] = country.ID
+	}
+
+	// Japan 3 visits, France 2, Brazil 1 (plus a deleted visit), Germany and Canada none
+	visits := []struct {
+		user uint
+		code string
+	}{
+		{1, "JP"}, {2, "JP"}, {3, "JP"},
+		{1, "FR"}, {2, "FR"},
+		{3, "BR"},
+	}
+	for _, v := range visits {
+		db.Create(&models.Visit{UserID: v.user, CountryID: idByCode[v.code], VisitedAt: time.Now()})
+	}
+	deleted := models.Visit{UserID: 1, CountryID: idByCode["BR"], VisitedAt: time.Now()}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	handler := NewCountryHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/countries/popular", handler.ListPopularCountries)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/popular", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != popularCacheControl {
+		t.Errorf("expected Cache-Control %q, got %q", popularCacheControl, w.Header().Get("Cache-Control"))
+	}
+
+	var response struct {
+		Countries []PopularCountryResponse `json:"countries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	want := []struct {
+		code   string
+		visits int64
+	}{{"JP", 3}, {"FR", 2}, {"BR", 1}}
+	if len(response.Countries) != len(want) {
+		t.Fatalf("expected %d countries, got %+v", len(want), response.Countries)
+	}
+	for i, w := range want {
+		got := response.Countries[i]
+		if got.ISOCode != w.code || got.VisitCount != w.visits {
+			t.Errorf("position %d: expected %s with %d visits, got %s with %d", i, w.code, w.visits, got.ISOCode, got.VisitCount)
+		}
+	}
+
+	// limit caps the ranking
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/countries/popular?limit=1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	response.Countries = nil
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Countries) != 1 || response.Countries[0].ISOCode != "JP" {
+		t.Errorf("expected only JP with limit=1, got %+v", response.Countries)
+	}
+
+	for _, limit := range []string{"0", "101", "ten"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/countries/popular?limit="+limit, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: expected status 400, got %d", limit, w.Code)
+		}
+	}
+}
+
+func TestCountryHandler_ListPopularCountries_Cached(t *testing.T) {
+	db := setupCountryTestDB(t)
+	if err := db.AutoMigrate(&models.Visit{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	seedCountries(t, db)
+
+	var japan models.Country
+	db.Where("iso_code = ?", "JP").First(&japan)
+	db.Create(&models.Visit{UserID: 1, CountryID: japan.ID, VisitedAt: time.Now()})
+
+	handler := NewCountryHandler(db)
+	if rows, _ := handler.popularRanking(); len(rows) != 1 {
+		t.Fatalf("expected 1 ranked country, got %d", len(rows))
+	}
+
+	// A new visit is not reflected until the cached ranking expires
+	var france models.Country
+	db.Where("iso_code = ?", "FR").First(&france)
+	db.Create(&models.Visit{UserID: 1, CountryID: france.ID, VisitedAt: time.Now()})
+
+	if rows, _ := handler.popularRanking(); len(rows) != 1 {
+		t.Errorf("expected cached ranking of 1 country, got %d", len(rows))
+	}
+
+	handler.popular.fetchedAt = time.Now().Add(-popularCacheTTL)
+	if rows, _ := handler.popularRanking(); len(rows) != 2 {
+		t.Errorf("expected refreshed ranking of 2 countries, got %d", len(rows))
+	}
+}
+
+func getSuggestions(t *testing.T, router *gin.Engine, token string) []SuggestionResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/suggestions?limit=3", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Suggestions []SuggestionResponse `json:"suggestions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Suggestions
+}
+
+func TestCountryHandler_ListSuggestions_NoHistory(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	// Other users make Japan the most popular country, then Canada
+	db.Create(&models.Visit{UserID: 2, CountryID: 3})
+	db.Create(&models.Visit{UserID: 3, CountryID: 3})
+	db.Create(&models.Visit{UserID: 2, CountryID: 5})
+
+	suggestions := getSuggestions(t, router, token)
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+	for i, code := range []string{"JP", "CA", "BR"} {
+		if suggestions[i].ISOCode != code || suggestions[i].Reason != "popular" {
+			t.Errorf("expected popular %s at %d, got %s (%s)", code, i, suggestions[i].ISOCode, suggestions[i].Reason)
+		}
+	}
+}
+
+func TestCountryHandler_ListSuggestions_FromVisitedRegions(t *testing.T) {
+	db, router, token := createCountryOverviewTestRouter(t)
+
+	db.Create(&models.Visit{UserID: 1, CountryID: 1}) // France, Europe
+	db.Create(&models.Visit{UserID: 2, CountryID: 3}) // Japan is popular elsewhere
+
+	suggestions := getSuggestions(t, router, token)
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+	if suggestions[0].ISOCode != "DE" || suggestions[0].Reason != "region" {
+		t.Errorf("expected Germany from a visited region first, got %s (%s)", suggestions[0].ISOCode, suggestions[0].Reason)
+	}
+	if suggestions[1].ISOCode != "JP" || suggestions[1].Reason != "popular" {
+		t.Errorf("expected popular Japan next, got %s (%s)", suggestions[1].ISOCode, suggestions[1].Reason)
+	}
+	for _, s := range suggestions {
+		if s.ISOCode == "FR" {
+			t.Error("visited country was suggested")
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// courseReportHeader is the header row of the course report CSV
+var courseReportHeader = []string{"name", "email", "countries_visited", "scrapbook_entries", "photos_uploaded", "last_activity"}
+
+// CourseReportHandler handles the instructor's engagement report for a course
+type CourseReportHandler struct {
+	db *gorm.DB
+}
+
+// NewCourseReportHandler creates a new course report handler
+func NewCourseReportHandler(db *gorm.DB) *CourseReportHandler {
+	return &CourseReportHandler{db: db}
+}
+
+// courseReportRow is one learner's engagement in a course
+type courseReportRow struct {
+	ID               uint
+	DisplayName      string
+	Email            string
+	CountriesVisited int64
+	EntryCount       int64
+	PhotoCount       int64
+
+	// Latest activity per source. MAX over timestamps comes back as text
+	// from some drivers, so these are parsed by lastActivity.
+	LastVisit  sql.NullString
+	LastEntry  sql.NullString
+	LastLaunch sql.NullString
+}
+
+// dbTimeLayouts are the forms a timestamp aggregate may come back in
+var dbTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// lastActivity returns the latest of the row's activity times, or the zero
+// time if there is none
+func (r *courseReportRow) lastActivity() time.Time {
+	var latest time.Time
+	for _, value := range []sql.NullString{r.LastVisit, r.LastEntry, r.LastLaunch} {
+		if !value.Valid {
+			continue
+		}
+		for _, layout := range dbTimeLayouts {
+			if t, err := time.Parse(layout, value.String); err == nil {
+				if t.After(latest) {
+					latest = t
+				}
+				break
+			}
+		}
+	}
+	return latest
+}
+
+// record returns the row's CSV cells, with the last activity date in loc
+func (r *courseReportRow) record(loc *time.Location) []string {
+	lastActivity := ""
+	if t := r.lastActivity(); !t.IsZero() {
+		lastActivity = t.In(loc).Format(time.DateOnly)
+	}
+	return []string{
+		csvCell(r.DisplayName),
+		csvCell(r.Email),
+		strconv.FormatInt(r.CountriesVisited, 10),
+		strconv.FormatInt(r.EntryCount, 10),
+		strconv.FormatInt(r.PhotoCount, 10),
+		lastActivity,
+	}
+}
+
+// ExportReport streams a CSV with one row per learner in the instructor's
+// course: name, email, distinct countries visited, scrapbook entries,
+// photos uploaded to those entries and the date of their last activity.
+// Learners who have launched the course but not created anything yet are
+// included with zero counts; users who launched it as instructors are not.
+// GET /api/v1/course/report.csv
+// Query params: tz (optional) - zone last activity dates are rendered in
+func (h *CourseReportHandler) ExportReport(c *gin.Context) {
+	instructorID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	courseID, _ := middleware.GetCourseID(c) // Checked by RequireCourse
+
+	rows, err := h.reportQuery(courseID, instructorID).Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build course report"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="course-report.csv"`)
+	c.Status(http.StatusOK)
+
+	// Rows are written as they are read, so large courses never sit in memory.
+	// Once streaming has begun the status can no longer change, so failures
+	// are logged and the download is left truncated.
+	w := csv.NewWriter(c.Writer)
+	w.Write(courseReportHeader)
+	for rows.Next() {
+		var row courseReportRow
+		if err = h.db.ScanRows(rows, &row); err != nil {
+			break
+		}
+		w.Write(row.record(loc))
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		slog.Warn("course report aborted", "course_id", courseID, "error", err)
+	}
+}
+
+// reportQuery selects the report rows for a course, ordered by name. The
+// requesting instructor and anyone who launched the course as an instructor
+// are left out.
+func (h *CourseReportHandler) reportQuery(courseID string, instructorID uint) *gorm.DB {
+	lastVisits := h.db.Model(&models.Visit{}).Select("user_id, MAX(created_at) AS at").
+		Where("course_id = ?", courseID).Group("user_id")
+	lastEntries := h.db.Model(&models.ScrapbookEntry{}).Select("user_id, MAX(updated_at) AS at").
+		Where("course_id = ?", courseID).Group("user_id")
+	lastLaunches := courseLaunchUserIDs(h.db, courseID).Select("user_id, MAX(created_at) AS at").Group("user_id")
+
+	return h.db.Model(&models.User{}).
+		Joins("LEFT JOIN (?) AS last_visits ON last_visits.user_id = users.id", lastVisits).
+		Joins("LEFT JOIN (?) AS last_entries ON last_entries.user_id = users.id", lastEntries).
+		Joins("LEFT JOIN (?) AS last_launches ON last_launches.user_id = users.id", lastLaunches).
+		Where("users.id IN (?) OR users.id IN (?)", courseUserIDs(h.db, courseID), courseLaunchUserIDs(h.db, courseID)).
+		Where("users.id <> ? AND users.id NOT IN (?)", instructorID, courseInstructorIDs(h.db, courseID)).
+		Select("users.id, users.display_name, users.email, "+
+			"(SELECT COUNT(DISTINCT country_id) FROM visits WHERE visits.user_id = users.id AND visits.course_id = ? AND visits.deleted_at IS NULL) AS countries_visited, "+
+			"(SELECT COUNT(*) FROM scrapbook_entries WHERE scrapbook_entries.user_id = users.id AND scrapbook_entries.course_id = ? AND scrapbook_entries.deleted_at IS NULL) AS entry_count, "+
+			"(SELECT COUNT(*) FROM uploads WHERE uploads.user_id = users.id AND uploads.deleted_at IS NULL AND uploads.entry_id IN "+
+			"(SELECT id FROM scrapbook_entries WHERE course_id = ? AND deleted_at IS NULL)) AS photo_count, "+
+			"last_visits.at AS last_visit, last_entries.at AS last_entry, last_launches.at AS last_launch",
+			courseID, courseID, courseID).
+		Order("users.display_name ASC, users.id ASC")
+}
+
+// csvCell keeps user-entered text from being read as a formula when the
+// report is opened in a spreadsheet
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
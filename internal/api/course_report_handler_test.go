@@ -0,0 +1,142 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupCourseReportTest(t *testing.T) (*gorm.DB, *gin.Engine, *lti.SessionManager, []models.User) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.LaunchEvent{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	users := make([]models.User, 5)
+	for i := range users {
+		users[i] = models.User{
+			CanvasUserID:      fmt.Sprintf("canvas-%d", i),
+			CanvasInstanceURL: "https://canvas.example.com",
+			DisplayName:       []string{"Prof. Lee", "Ada", "Bea", "=Cy", "Dr. Kim"}[i],
+			Email:             []string{"lee@example.edu", "ada@example.edu", "", "cy@example.edu", "kim@example.edu"}[i],
+		}
+		db.Create(&users[i])
+	}
+	instructor, ada, bea, cy, coInstructor := users[0], users[1], users[2], users[3], users[4]
+
+	day := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	db.Create(&models.Visit{UserID: ada.ID, CountryID: 1, CourseID: "course-a", CreatedAt: day})
+	db.Create(&models.Visit{UserID: ada.ID, CountryID: 1, CourseID: "course-a", CreatedAt: day})
+	db.Create(&models.Visit{UserID: ada.ID, CountryID: 2, CourseID: "course-a", CreatedAt: day.AddDate(0, 0, 1)})
+	entry := models.ScrapbookEntry{UserID: ada.ID, CountryID: 1, CourseID: "course-a", Title: "Eiffel Tower"}
+	db.Create(&entry)
+	db.Model(&entry).UpdateColumn("updated_at", day.AddDate(0, 0, 2))
+	db.Create(&models.Upload{UserID: ada.ID, EntryID: &entry.ID, Filename: "a.jpg", URL: "/uploads/a.jpg", Size: 1})
+	db.Create(&models.Upload{UserID: ada.ID, Filename: "b.jpg", URL: "/uploads/b.jpg", Size: 1})
+
+	// Bea has only launched; Cy only has data in another course; Kim is a
+	// second instructor
+	db.Create(&models.LaunchEvent{Stage: models.LaunchStageLaunch, UserID: &bea.ID, CourseID: "course-a", Role: "learner", Success: true, CreatedAt: day.AddDate(0, 0, -3)})
+	db.Create(&models.LaunchEvent{Stage: models.LaunchStageLaunch, UserID: &bea.ID, CourseID: "course-a", Role: "learner", Success: true, CreatedAt: day})
+	db.Create(&models.LaunchEvent{Stage: models.LaunchStageLaunch, UserID: &instructor.ID, CourseID: "course-a", Role: "instructor", Success: true, CreatedAt: day})
+	db.Create(&models.LaunchEvent{Stage: models.LaunchStageLaunch, UserID: &coInstructor.ID, CourseID: "course-a", Role: "instructor", Success: true, CreatedAt: day})
+	db.Create(&models.Visit{UserID: coInstructor.ID, CountryID: 4, CourseID: "course-a", CreatedAt: day})
+	db.Create(&models.Visit{UserID: cy.ID, CountryID: 3, CourseID: "course-b", CreatedAt: day})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	handler := NewCourseReportHandler(db)
+
+	router := gin.New()
+	router.GET("/api/v1/course/report.csv", middleware.AuthMiddleware(sm), middleware.RequireInstructor(), middleware.RequireCourse(), handler.ExportReport)
+	return db, router, sm, users
+}
+
+func sendCourseReportRequest(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/course/report.csv", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCourseReportHandler_ExportReport(t *testing.T) {
+	_, router, sm, users := setupCourseReportTest(t)
+	token, _ := sm.CreateToken(users[0].ID, "canvas-0", "course-a", "instructor")
+
+	w := sendCourseReportRequest(router, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Errorf("expected an attachment, got %q", cd)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	want := [][]string{
+		courseReportHeader,
+		{"Ada", "ada@example.edu", "2", "1", "1", "2026-03-16"},
+		{"Bea", "", "0", "0", "0", "2026-03-14"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("unexpected report:\n got %q\nwant %q", records, want)
+	}
+}
+
+func TestCourseReportHandler_ExportReport_Access(t *testing.T) {
+	_, router, sm, users := setupCourseReportTest(t)
+
+	learner, _ := sm.CreateToken(users[1].ID, "canvas-1", "course-a", "learner")
+	if w := sendCourseReportRequest(router, learner); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a learner, got %d", w.Code)
+	}
+
+	noCourse, _ := sm.CreateToken(users[0].ID, "canvas-0", "", "instructor")
+	if w := sendCourseReportRequest(router, noCourse); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without a course, got %d", w.Code)
+	}
+
+	// Cy's formula-like name is neutralized in their own course's report
+	otherCourse, _ := sm.CreateToken(users[0].ID, "canvas-0", "course-b", "instructor")
+	w := sendCourseReportRequest(router, otherCourse)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "'=Cy" {
+		t.Errorf("unexpected course-b report: %q", records)
+	}
+}
+
+func TestCourseReportRow_LastActivity(t *testing.T) {
+	row := courseReportRow{
+		LastVisit:  sql.NullString{String: "2026-03-14 12:00:00.5+00:00", Valid: true},
+		LastEntry:  sql.NullString{String: "2026-03-16T09:30:00Z", Valid: true},
+		LastLaunch: sql.NullString{String: "2026-03-15 08:00:00", Valid: true},
+	}
+	if got, want := row.lastActivity(), time.Date(2026, 3, 16, 9, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := (&courseReportRow{}).lastActivity(); !got.IsZero() {
+		t.Errorf("expected zero time with no activity, got %v", got)
+	}
+}
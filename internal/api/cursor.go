@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidCursor is returned for cursors this server did not issue
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position of the last row of a page ordered by a
+// timestamp and then ID, newest first. Clients treat its encoding as opaque.
+type pageCursor struct {
+	at time.Time
+	id uint
+}
+
+// encode returns the opaque form of the cursor sent to clients
+func (c pageCursor) encode() string {
+	raw := strconv.FormatInt(c.at.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(c.id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encode
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	atStr, idStr, found := strings.Cut(string(raw), ":")
+	if !found {
+		return pageCursor{}, errInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(atStr, 10, 64)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	return pageCursor{at: time.Unix(0, nanos).UTC(), id: uint(id)}, nil
+}
+
+// cursorPage is a keyset page: up to limit rows after an optional cursor
+type cursorPage struct {
+	after *pageCursor // nil for the first page
+	limit int
+}
+
+// parseCursorPage reads the cursor paging query params. Cursor mode is
+// selected by a cursor param, left empty for the first page, and takes its
+// size from limit or pageSize, at most maxSize. The second result reports whether cursor mode
+// was requested. Writes a 400 response and returns false for the third
+// result if the params are invalid or mixed with page or offset.
+func parseCursorPage(c *gin.Context, maxSize int) (cursorPage, bool, bool) {
+	cursor, requested := c.GetQuery("cursor")
+	if !requested {
+		return cursorPage{}, false, true
+	}
+
+	if c.Query("page") != "" || c.Query("offset") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor cannot be combined with page or offset"})
+		return cursorPage{}, true, false
+	}
+
+	p, ok := parsePagination(c, maxSize)
+	if !ok {
+		return cursorPage{}, true, false
+	}
+	page := cursorPage{limit: p.Limit()}
+	if page.limit == 0 {
+		page.limit = defaultPageSize
+	}
+
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return cursorPage{}, true, false
+		}
+		page.after = &after
+	}
+	return page, true, true
+}
+
+// apply orders query newest first by column and then ID, restricts it to
+// rows after the cursor and fetches one extra row to tell whether another
+// page follows
+func (p cursorPage) apply(query *gorm.DB, column string) *gorm.DB {
+	if p.after != nil {
+		query = query.Where(fmt.Sprintf("%s < ? OR (%s = ? AND id < ?)", column, column),
+			p.after.at, p.after.at, p.after.id)
+	}
+	return query.Order(column + " DESC").Order("id DESC").Limit(p.limit + 1)
+}
+
+// cursorPageOf trims the extra row fetched by apply and returns the rows
+// with the cursor for the next page, or "" if this is the last page
+func cursorPageOf[T any](p cursorPage, rows []T, key func(*T) pageCursor) ([]T, string) {
+	if len(rows) <= p.limit {
+		return rows, ""
+	}
+	rows = rows[:p.limit]
+	return rows, key(&rows[len(rows)-1]).encode()
+}
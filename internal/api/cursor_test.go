@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageCursor_RoundTrip(t *testing.T) {
+	want := pageCursor{at: time.Date(2024, 3, 5, 12, 30, 0, 123456789, time.UTC), id: 42}
+
+	got, err := decodeCursor(want.encode())
+	if err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+	if !got.at.Equal(want.at) || got.id != want.id {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"not base64!", "bm9jb2xvbg", "YWJjOjE", "MTI6LTE"} {
+		if _, err := decodeCursor(s); err != errInvalidCursor {
+			t.Errorf("expected errInvalidCursor for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestParseCursorPage(t *testing.T) {
+	after := pageCursor{at: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), id: 7}
+
+	tests := []struct {
+		query         string
+		wantRequested bool
+		wantOK        bool
+		wantLimit     int
+		wantAfter     bool
+	}{
+		{"", false, true, 0, false},
+		{"?page=2", false, true, 0, false},
+		{"?cursor=", true, true, defaultPageSize, false},
+		{"?cursor=&limit=10", true, true, 10, false},
+		{"?cursor=&pageSize=10", true, true, 10, false},
+		{"?cursor=" + after.encode() + "&limit=3", true, true, 3, true},
+		{"?cursor=garbage", true, false, 0, false},
+		{"?cursor=&limit=0", true, false, 0, false},
+		{"?cursor=&page=2", true, false, 0, false},
+		{"?cursor=&offset=10", true, false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+
+			got, requested, ok := parseCursorPage(c, defaultMaxPageSize)
+			if requested != tt.wantRequested || ok != tt.wantOK {
+				t.Fatalf("expected requested=%v ok=%v, got %v %v", tt.wantRequested, tt.wantOK, requested, ok)
+			}
+			if !ok {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("expected status 400, got %d", w.Code)
+				}
+				return
+			}
+			if got.limit != tt.wantLimit || (got.after != nil) != tt.wantAfter {
+				t.Errorf("unexpected page %+v", got)
+			}
+			if tt.wantAfter && *got.after != after {
+				t.Errorf("expected cursor %+v, got %+v", after, *got.after)
+			}
+		})
+	}
+}
+
+func TestCursorPageOf(t *testing.T) {
+	key := func(n *int) pageCursor { return pageCursor{id: uint(*n)} }
+	p := cursorPage{limit: 2}
+
+	rows, next := cursorPageOf(p, []int{5, 4, 3}, key)
+	if len(rows) != 2 || next != (pageCursor{id: 4}).encode() {
+		t.Errorf("expected two rows and a cursor after 4, got %v %q", rows, next)
+	}
+
+	rows, next = cursorPageOf(p, []int{2, 1}, key)
+	if len(rows) != 2 || next != "" {
+		t.Errorf("expected the last page without a cursor, got %v %q", rows, next)
+	}
+}
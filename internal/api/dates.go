@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dateOnlyLayout is the accepted layout for visit dates without a time
+const dateOnlyLayout = "2006-01-02"
+
+// visitedAtLayouts are the accepted visit date layouts, tried in order.
+// Layouts without a zone are interpreted as UTC.
+var visitedAtLayouts = []string{
+	time.RFC3339,          // 2006-01-02T15:04:05Z07:00 (fractional seconds allowed)
+	"2006-01-02T15:04:05", // HTML datetime-local with seconds
+	"2006-01-02T15:04",    // HTML datetime-local
+	"2006-01-02 15:04:05", // SQL-style timestamp
+	dateOnlyLayout,
+}
+
+// errInvalidVisitedAt is the client-facing message for unparseable visit dates
+const errInvalidVisitedAt = "invalid visitedAt format, use RFC3339 (2006-01-02T15:04:05Z), 2006-01-02T15:04:05, 2006-01-02 15:04:05 or date-only (2006-01-02)"
+
+// ErrInvalidDate is returned when a date string matches no accepted format
+var ErrInvalidDate = errors.New("invalid date format")
+
+// parseVisitedAt parses a visit timestamp in any of visitedAtLayouts.
+// Values are normalized to UTC for storage; date-only values become midnight UTC.
+func parseVisitedAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range visitedAtLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// dateRange is an optional inclusive visited_at window from ?from= and ?to=
+type dateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// parseDateRange reads the RFC3339 from/to query parameters.
+// Writes a 400 response and returns false if either fails to parse or from is after to.
+func parseDateRange(c *gin.Context) (dateRange, bool) {
+	var r dateRange
+	var ok bool
+	if r.From, ok = parseTimeQuery(c, "from"); !ok {
+		return r, false
+	}
+	if r.To, ok = parseTimeQuery(c, "to"); !ok {
+		return r, false
+	}
+
+	if r.From != nil && r.To != nil && r.From.After(*r.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return r, false
+	}
+	return r, true
+}
+
+// parseTimeQuery parses an optional RFC3339 query parameter, normalized to UTC
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " parameter, use RFC3339 (2006-01-02T15:04:05Z)"})
+		return nil, false
+	}
+	parsed = parsed.UTC()
+	return &parsed, true
+}
+
+// apply adds the range bounds to a query on a table with a visited_at column
+func (r dateRange) apply(query *gorm.DB) *gorm.DB {
+	if r.From != nil {
+		query = query.Where("visited_at >= ?", *r.From)
+	}
+	if r.To != nil {
+		query = query.Where("visited_at <= ?", *r.To)
+	}
+	return query
+}
+
+// contextKeyDefaultLocation is the context key for the configured default time zone
+const contextKeyDefaultLocation = "default_location"
+
+// TimezoneMiddleware sets the default time zone used to render timestamps
+// when a request does not specify ?tz=
+func TimezoneMiddleware(defaultLoc *time.Location) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKeyDefaultLocation, defaultLoc)
+		c.Next()
+	}
+}
+
+// responseLocation resolves the time zone to render timestamps in: the ?tz=
+// query parameter if present, else the configured default, else UTC.
+// Writes a 400 response and returns false if ?tz= is not a valid zone.
+func responseLocation(c *gin.Context) (*time.Location, bool) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz parameter, use an IANA zone such as Europe/Paris"})
+			return nil, false
+		}
+		return loc, true
+	}
+
+	if val, exists := c.Get(contextKeyDefaultLocation); exists {
+		if loc, ok := val.(*time.Location); ok && loc != nil {
+			return loc, true
+		}
+	}
+
+	return time.UTC, true
+}
+
+// formatTimestamp formats a stored (UTC) timestamp as RFC3339 in loc
+func formatTimestamp(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.RFC3339)
+}
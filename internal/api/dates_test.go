@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseVisitedAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"RFC3339 UTC", "2024-06-15T10:00:00Z", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), false},
+		{"RFC3339 with offset", "2024-06-15T10:00:00+02:00", time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC), false},
+		{"RFC3339 fractional seconds", "2024-06-15T10:00:00.123Z", time.Date(2024, 6, 15, 10, 0, 0, 123000000, time.UTC), false},
+		{"datetime without zone", "2024-06-15T10:00:00", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), false},
+		{"datetime-local without seconds", "2024-06-15T10:00", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), false},
+		{"SQL-style timestamp", "2024-06-15 10:00:00", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), false},
+		{"date only", "2024-06-15", time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), false},
+		{"surrounding whitespace", " 2024-06-15 ", time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), false},
+		{"empty", "", time.Time{}, true},
+		{"malformed", "15/06/2024", time.Time{}, true},
+		{"invalid date", "2024-13-45", time.Time{}, true},
+		{"garbage", "not-a-date", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVisitedAt(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVisitedAt(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseVisitedAt(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVisitedAt_DateOnlyIsUTC(t *testing.T) {
+	got, err := parseVisitedAt("2024-06-15")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Location() != time.UTC {
+		t.Errorf("expected UTC location, got %v", got.Location())
+	}
+}
+
+func TestResponseLocation(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	paris, _ := time.LoadLocation("Europe/Paris")
+
+	tests := []struct {
+		name       string
+		query      string
+		defaultLoc *time.Location
+		want       string
+		wantOK     bool
+	}{
+		{"no default falls back to UTC", "", nil, "UTC", true},
+		{"configured default", "", paris, "Europe/Paris", true},
+		{"query overrides default", "?tz=Asia/Tokyo", paris, tokyo.String(), true},
+		{"invalid zone", "?tz=Mars/Olympus", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			if tt.defaultLoc != nil {
+				c.Set(contextKeyDefaultLocation, tt.defaultLoc)
+			}
+
+			loc, ok := responseLocation(c)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("expected status 400, got %d", w.Code)
+				}
+				return
+			}
+			if loc.String() != tt.want {
+				t.Errorf("expected location %s, got %s", tt.want, loc)
+			}
+		})
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom string
+		wantTo   string
+		wantOK   bool
+	}{
+		{"no bounds", "", "", "", true},
+		{"from only", "?from=2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "", true},
+		{"to only", "?to=2024-12-31T23:59:59Z", "", "2024-12-31T23:59:59Z", true},
+		{"both, offset normalized", "?from=2024-01-01T02:00:00%2B02:00&to=2024-06-30T00:00:00Z", "2024-01-01T00:00:00Z", "2024-06-30T00:00:00Z", true},
+		{"equal bounds", "?from=2024-01-01T00:00:00Z&to=2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", true},
+		{"malformed from", "?from=2024-01-01", "", "", false},
+		{"malformed to", "?to=yesterday", "", "", false},
+		{"from after to", "?from=2024-06-01T00:00:00Z&to=2024-01-01T00:00:00Z", "", "", false},
+	}
+
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+
+			got, ok := parseDateRange(c)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("expected status 400, got %d", w.Code)
+				}
+				return
+			}
+			if format(got.From) != tt.wantFrom || format(got.To) != tt.wantTo {
+				t.Errorf("expected [%s, %s], got [%s, %s]", tt.wantFrom, tt.wantTo, format(got.From), format(got.To))
+			}
+		})
+	}
+}
@@ -1,36 +1,95 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// Fixed identity used for demo users
+const (
+	demoCanvasID = "demo-user-001" // Shared demo user, kept for backward compatibility
+	demoInstance = "demo.local"
+	demoCourseID = "demo-course-001"
+
+	// demoCleanupInterval is how often inactive per-session demo users are purged
+	demoCleanupInterval = 10 * time.Minute
+
+	// defaultDemoSessionTTL is how long demo sessions last unless configured
+	defaultDemoSessionTTL = time.Hour
+
+	// demoLoginWindow is the period demo user creation is limited over
+	demoLoginWindow = time.Hour
+)
+
 // DemoHandler handles demo/development endpoints
 type DemoHandler struct {
 	db             *gorm.DB
 	sessionManager *lti.SessionManager
+	sessionTTL     time.Duration
+	logins         *rateLimiter    // Limits new demo users per client IP; nil for no limit
+	storage        storage.Storage // Where purged users' uploads are deleted from; nil to keep files
 }
 
-// NewDemoHandler creates a new demo handler
+// NewDemoHandler creates a new demo handler with one-hour sessions and no
+// limit on demo user creation
 func NewDemoHandler(db *gorm.DB, sessionManager *lti.SessionManager) *DemoHandler {
 	return &DemoHandler{
 		db:             db,
 		sessionManager: sessionManager,
+		sessionTTL:     defaultDemoSessionTTL,
+	}
+}
+
+// SetStorage sets the storage purged demo users' uploaded files are deleted
+// from
+func (h *DemoHandler) SetStorage(s storage.Storage) {
+	h.storage = s
+}
+
+// SetSessionTTL sets how long demo sessions last, in whole seconds. Demo
+// sessions never outlast the session manager's max age; durations under a
+// second are ignored.
+func (h *DemoHandler) SetSessionTTL(d time.Duration) {
+	if d >= time.Second {
+		h.sessionTTL = d
+	}
+}
+
+// SetLoginLimit caps how many new demo users each client IP may create per
+// hour. Zero disables the limit.
+func (h *DemoHandler) SetLoginLimit(perHour int) {
+	if perHour <= 0 {
+		h.logins = nil
+		return
 	}
+	h.logins = newRateLimiter(perHour, demoLoginWindow)
 }
 
 // DemoLoginRequest represents the demo login request
 type DemoLoginRequest struct {
-	Name string `json:"name"`
-	Role string `json:"role"` // "instructor" or "learner"
+	Name   string `json:"name"`
+	Locale string `json:"locale"` // Optional BCP 47 tag, e.g. "fr-FR"
+	Role   string `json:"role"`   // "instructor" or "learner"
+	Shared bool   `json:"shared"` // Use the shared demo user instead of an isolated one
+	Fresh  bool   `json:"fresh"`  // Always start a new isolated demo user
 }
 
-// DemoLogin creates a demo session without LTI (dev mode only)
+// DemoLogin creates a short-lived demo session without LTI (dev mode only).
+// A request with an existing demo session keeps its user; otherwise (or when
+// "fresh" is set) a new isolated demo user is created, subject to a per-IP
+// limit. "shared" selects the single shared demo user for scripted tests.
 // POST /api/v1/demo/login
 func (h *DemoHandler) DemoLogin(c *gin.Context) {
 	var req DemoLoginRequest
@@ -46,42 +105,46 @@ func (h *DemoHandler) DemoLogin(c *gin.Context) {
 		req.Role = "learner"
 	}
 
-	// Find or create demo user
-	var user models.User
-	demoCanvasID := "demo-user-001"
-	demoInstance := "demo.local"
-
-	err := h.db.Where("canvas_user_id = ? AND canvas_instance_url = ?",
-		demoCanvasID, demoInstance).First(&user).Error
-
-	if err == gorm.ErrRecordNotFound {
-		user = models.User{
-			CanvasUserID:      demoCanvasID,
-			CanvasInstanceURL: demoInstance,
-			DisplayName:       req.Name,
-			Email:             "demo@example.com",
-		}
-		if err := h.db.Create(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create demo user"})
+	// Find the shared demo user, reuse the session's, or create an isolated one
+	var user *models.User
+	var err error
+	if req.Shared {
+		user, err = h.findOrCreateDemoUser(req.Name)
+	} else if existing := h.currentDemoUser(c); existing != nil && !req.Fresh {
+		user = existing
+	} else {
+		if !h.allowNewDemoUser(c) {
 			return
 		}
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		user, err = h.createSessionDemoUser(req.Name)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create demo user"})
 		return
 	}
 
-	// Update name if different
-	if user.DisplayName != req.Name {
-		user.DisplayName = req.Name
-		h.db.Save(&user)
+	// Update name and locale; saving also marks the demo user as active
+	user.DisplayName = req.Name
+	if req.Locale != "" {
+		user.Locale = req.Locale
+	}
+	h.db.Save(user)
+
+	// Reused demo users may already have data
+	countriesVisited, totalEntries, err := countUserStats(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user stats"})
+		return
 	}
 
 	// Create session token
-	token, err := h.sessionManager.CreateToken(
+	token, err := h.sessionManager.CreateTokenWithTTL(
 		user.ID,
-		demoCanvasID,
-		"demo-course-001",
+		user.CanvasUserID,
+		demoCourseID,
 		req.Role,
+		user.Locale,
+		h.sessionTTL,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
@@ -92,7 +155,7 @@ func (h *DemoHandler) DemoLogin(c *gin.Context) {
 	c.SetCookie(
 		"session",
 		token,
-		86400, // 24 hours
+		int(h.sessionTTL/time.Second),
 		"/",
 		"",
 		false, // Not secure for local dev
@@ -103,11 +166,288 @@ func (h *DemoHandler) DemoLogin(c *gin.Context) {
 		"message": "Demo session created",
 		"user": MeResponse{
 			ID:          user.ID,
-			CanvasID:    demoCanvasID,
-			CourseID:    "demo-course-001",
+			CanvasID:    user.CanvasUserID,
+			CourseID:    demoCourseID,
 			Role:        req.Role,
 			DisplayName: user.DisplayName,
 			Email:       user.Email,
+			Locale:      user.Locale,
+			PictureURL:  user.PictureURL,
+
+			CountriesVisited: countriesVisited,
+			TotalEntries:     totalEntries,
 		},
 	})
 }
+
+// allowNewDemoUser applies the per-IP limit on creating demo users. Writes
+// a 429 response and returns false if the client has reached it.
+func (h *DemoHandler) allowNewDemoUser(c *gin.Context) bool {
+	if h.logins == nil {
+		return true
+	}
+	ok, retryAfter := h.logins.Allow(c.ClientIP())
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many demo logins, try again later"})
+	}
+	return ok
+}
+
+// findOrCreateDemoUser returns the shared demo user, creating it if needed
+func (h *DemoHandler) findOrCreateDemoUser(name string) (*models.User, error) {
+	var user models.User
+	err := h.db.Where("canvas_user_id = ? AND canvas_instance_url = ?",
+		demoCanvasID, demoInstance).First(&user).Error
+
+	if err == gorm.ErrRecordNotFound {
+		user = models.User{
+			CanvasUserID:      demoCanvasID,
+			CanvasInstanceURL: demoInstance,
+			DisplayName:       name,
+			Email:             "demo@example.com",
+		}
+		if err := h.db.Create(&user).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// currentDemoUser returns the isolated demo user of the request's session, if any
+func (h *DemoHandler) currentDemoUser(c *gin.Context) *models.User {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		return nil
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return nil
+	}
+	if user.CanvasInstanceURL != demoInstance || user.CanvasUserID == demoCanvasID {
+		return nil
+	}
+	return &user
+}
+
+// createSessionDemoUser creates a new demo user with a unique identity
+func (h *DemoHandler) createSessionDemoUser(name string) (*models.User, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		CanvasUserID:      "demo-" + hex.EncodeToString(suffix),
+		CanvasInstanceURL: demoInstance,
+		DisplayName:       name,
+		Email:             "demo@example.com",
+	}
+	if err := h.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// StartCleanup periodically purges per-session demo users inactive for ttl
+func (h *DemoHandler) StartCleanup(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(demoCleanupInterval)
+		for range ticker.C {
+			purged, err := h.PurgeExpiredDemoUsers(ttl)
+			if err != nil {
+				slog.Warn("failed to purge demo users", "error", err)
+			} else if purged > 0 {
+				slog.Info("purged expired demo users", "count", purged)
+			}
+		}
+	}()
+}
+
+// PurgeExpiredDemoUsers permanently deletes per-session demo users inactive
+// for more than ttl, along with everything they own: visits, scrapbook entries
+// and their media and comments, feedback, launch events and uploads. Uploaded
+// files are deleted once the rows are gone. A demo user is active when they log in or add a visit or entry.
+// The shared demo user is never purged.
+func (h *DemoHandler) PurgeExpiredDemoUsers(ttl time.Duration) (int, error) {
+	cutoff := time.Now().Add(-ttl)
+
+	recentVisits := h.db.Unscoped().Model(&models.Visit{}).Select("user_id").Where("created_at >= ?", cutoff)
+	recentEntries := h.db.Unscoped().Model(&models.ScrapbookEntry{}).Select("user_id").Where("updated_at >= ?", cutoff)
+
+	var userIDs []uint
+	if err := h.db.Unscoped().Model(&models.User{}).
+		Where("canvas_instance_url = ? AND canvas_user_id <> ? AND updated_at < ?",
+			demoInstance, demoCanvasID, cutoff).
+		Where("id NOT IN (?) AND id NOT IN (?)", recentVisits, recentEntries).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	var filenames []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Upload{}).Where("user_id IN ?", userIDs).
+			Pluck("filename", &filenames).Error; err != nil {
+			return err
+		}
+		entryIDs := tx.Unscoped().Model(&models.ScrapbookEntry{}).Select("id").Where("user_id IN ?", userIDs)
+		if err := tx.Where("entry_id IN (?)", entryIDs).Delete(&models.ScrapbookMedia{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entry_id IN (?) OR author_user_id IN ?", entryIDs, userIDs).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("learner_user_id IN ? OR instructor_user_id IN ?", userIDs, userIDs).Delete(&models.Feedback{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", userIDs).Delete(&models.LaunchEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(&models.Visit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(&models.ScrapbookEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(&models.Upload{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", userIDs).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	deleteStoredFiles(h.storage, filenames)
+
+	return len(userIDs), nil
+}
+
+// demoSample describes a sample visit and scrapbook entry for the demo seeder
+type demoSample struct {
+	ISOCode  string
+	DaysAgo  int
+	Title    string
+	Notes    string
+	Tags     string
+	HasEntry bool
+}
+
+// demoSamples is the sample data used to populate the demo user
+var demoSamples = []demoSample{
+	{ISOCode: "FR", DaysAgo: 420, Title: "Paris by Night", Notes: "Watched the Eiffel Tower sparkle on the hour.", Tags: "city,landmark", HasEntry: true},
+	{ISOCode: "JP", DaysAgo: 300, Title: "Cherry Blossoms in Kyoto", Notes: "Walked the Philosopher's Path during hanami season.", Tags: "nature,culture", HasEntry: true},
+	{ISOCode: "BR", DaysAgo: 210, Title: "Rio Carnival", Notes: "Samba parades lasted until sunrise.", Tags: "festival,music", HasEntry: true},
+	{ISOCode: "KE", DaysAgo: 150, Title: "Safari in the Maasai Mara", Notes: "Saw the great migration crossing the river.", Tags: "wildlife,nature", HasEntry: true},
+	{ISOCode: "AU", DaysAgo: 90, Title: "Great Barrier Reef", Notes: "Snorkelled among sea turtles and coral.", Tags: "ocean,nature", HasEntry: true},
+	{ISOCode: "IT", DaysAgo: 60},
+	{ISOCode: "CA", DaysAgo: 30},
+}
+
+// DemoSeedResponse represents the result of seeding demo data
+type DemoSeedResponse struct {
+	Message        string `json:"message"`
+	VisitsCreated  int    `json:"visitsCreated"`
+	EntriesCreated int    `json:"entriesCreated"`
+}
+
+// DemoSeed populates a demo user with sample visits and scrapbook entries
+// (dev mode only). Seeds the session's demo user if logged in, otherwise the
+// shared demo user. Skips seeding if the demo user already has data.
+// POST /api/v1/demo/seed
+func (h *DemoHandler) DemoSeed(c *gin.Context) {
+	var user *models.User
+	if userID, ok := middleware.GetUserID(c); ok {
+		var sessionUser models.User
+		if err := h.db.First(&sessionUser, userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if sessionUser.CanvasInstanceURL != demoInstance {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only demo users can be seeded"})
+			return
+		}
+		user = &sessionUser
+	} else {
+		sharedUser, err := h.findOrCreateDemoUser("Demo Explorer")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create demo user"})
+			return
+		}
+		user = sharedUser
+	}
+
+	// Skip if the demo user already has data
+	var visitCount, entryCount int64
+	h.db.Model(&models.Visit{}).Where("user_id = ?", user.ID).Count(&visitCount)
+	h.db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", user.ID).Count(&entryCount)
+	if visitCount > 0 || entryCount > 0 {
+		c.JSON(http.StatusOK, DemoSeedResponse{Message: "demo data already present"})
+		return
+	}
+
+	response := DemoSeedResponse{Message: "demo data seeded"}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, sample := range demoSamples {
+			var country models.Country
+			if err := tx.Where("iso_code = ?", sample.ISOCode).First(&country).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					continue // Country not in catalog, skip sample
+				}
+				return err
+			}
+
+			visitedAt := time.Now().AddDate(0, 0, -sample.DaysAgo)
+			visit := models.Visit{
+				UserID:    user.ID,
+				CountryID: country.ID,
+				CourseID:  demoCourseID,
+				VisitedAt: visitedAt,
+				Notes:     sample.Notes,
+			}
+			if err := tx.Create(&visit).Error; err != nil {
+				return err
+			}
+			response.VisitsCreated++
+
+			if !sample.HasEntry {
+				continue
+			}
+			entry := models.ScrapbookEntry{
+				UserID:    user.ID,
+				CountryID: country.ID,
+				CourseID:  demoCourseID,
+				Title:     sample.Title,
+				Notes:     sample.Notes,
+				Tags:      sample.Tags,
+				VisitedAt: visitedAt,
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			response.EntriesCreated++
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to seed demo data"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
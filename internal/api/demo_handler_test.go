@@ -0,0 +1,451 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDemoTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.Visit{}, &models.ScrapbookEntry{}, &models.ScrapbookMedia{}, &models.IdempotencyKey{}, &models.Upload{}, &models.Comment{}, &models.Feedback{}, &models.LaunchEvent{})
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	countries := []models.Country{
+		{Name: "France", ISOCode: "FR", Region: "Europe"},
+		{Name: "Japan", ISOCode: "JP", Region: "Asia"},
+		{Name: "Canada", ISOCode: "CA", Region: "North America"},
+	}
+	for _, country := range countries {
+		if err := db.Create(&country).Error; err != nil {
+			t.Fatalf("failed to create country: %v", err)
+		}
+	}
+
+	return db
+}
+
+func createDemoTestRouter(db *gorm.DB) *gin.Engine {
+	router := gin.New()
+	sm := lti.NewSessionManager("test-secret", 3600)
+	handler := NewDemoHandler(db, sm)
+
+	demo := router.Group("/api/v1/demo")
+	demo.Use(middleware.OptionalAuthMiddleware(sm))
+	{
+		demo.POST("/login", handler.DemoLogin)
+		demo.POST("/seed", handler.DemoSeed)
+	}
+
+	return router
+}
+
+func TestDemoHandler_DemoSeed(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/seed", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response DemoSeedResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Only FR, JP and CA exist in the test catalog; FR and JP have entries
+	if response.VisitsCreated != 3 {
+		t.Errorf("expected 3 visits created, got %d", response.VisitsCreated)
+	}
+	if response.EntriesCreated != 2 {
+		t.Errorf("expected 2 entries created, got %d", response.EntriesCreated)
+	}
+
+	var visitCount int64
+	db.Model(&models.Visit{}).Count(&visitCount)
+	if visitCount != 3 {
+		t.Errorf("expected 3 visits in database, got %d", visitCount)
+	}
+}
+
+func TestDemoHandler_DemoSeed_Idempotent(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/seed", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if i == 1 {
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200 on reseed, got %d", w.Code)
+			}
+
+			var response DemoSeedResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.VisitsCreated != 0 || response.EntriesCreated != 0 {
+				t.Errorf("expected nothing created on reseed, got %+v", response)
+			}
+		}
+	}
+
+	var entryCount int64
+	db.Model(&models.ScrapbookEntry{}).Count(&entryCount)
+	if entryCount != 2 {
+		t.Errorf("expected 2 entries after reseed, got %d", entryCount)
+	}
+}
+
+func TestDemoHandler_DemoSeed_SessionUser(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var login struct {
+		User MeResponse `json:"user"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+
+	seedReq := httptest.NewRequest(http.MethodPost, "/api/v1/demo/seed", nil)
+	for _, cookie := range w.Result().Cookies() {
+		seedReq.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, seedReq)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var userCount int64
+	db.Model(&models.User{}).Count(&userCount)
+	if userCount != 1 {
+		t.Errorf("expected seed to reuse the session's demo user, got %d users", userCount)
+	}
+
+	var visitCount int64
+	db.Model(&models.Visit{}).Where("user_id = ?", login.User.ID).Count(&visitCount)
+	if visitCount != 3 {
+		t.Errorf("expected 3 visits for session user, got %d", visitCount)
+	}
+}
+
+func TestDemoHandler_DemoLogin_IsolatedUsers(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	ids := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var response struct {
+			User MeResponse `json:"user"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		if !strings.HasPrefix(response.User.CanvasID, "demo-") || response.User.CanvasID == demoCanvasID {
+			t.Errorf("expected unique demo canvas ID, got '%s'", response.User.CanvasID)
+		}
+		ids[response.User.CanvasID] = true
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("expected 2 distinct demo users, got %d", len(ids))
+	}
+}
+
+func TestDemoHandler_DemoLogin_Shared(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", strings.NewReader(`{"shared":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			User MeResponse `json:"user"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		if response.User.CanvasID != demoCanvasID {
+			t.Errorf("expected shared canvas ID '%s', got '%s'", demoCanvasID, response.User.CanvasID)
+		}
+	}
+
+	var userCount int64
+	db.Model(&models.User{}).Count(&userCount)
+	if userCount != 1 {
+		t.Errorf("expected 1 shared demo user, got %d", userCount)
+	}
+}
+
+func TestDemoHandler_DemoLogin_Locale(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", strings.NewReader(`{"locale":"fr-FR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		User MeResponse `json:"user"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.User.Locale != "fr-FR" {
+		t.Errorf("expected locale 'fr-FR', got '%s'", response.User.Locale)
+	}
+
+	var user models.User
+	db.First(&user, response.User.ID)
+	if user.Locale != "fr-FR" {
+		t.Errorf("expected stored locale 'fr-FR', got '%s'", user.Locale)
+	}
+}
+
+func TestDemoHandler_PurgeExpiredDemoUsers(t *testing.T) {
+	db := setupDemoTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	handler := NewDemoHandler(db, lti.NewSessionManager("test-secret", 3600))
+	handler.SetStorage(s)
+
+	old := time.Now().Add(-48 * time.Hour)
+	expired := &models.User{CanvasUserID: "demo-expired", CanvasInstanceURL: demoInstance, CreatedAt: old, UpdatedAt: old}
+	fresh := &models.User{CanvasUserID: "demo-fresh", CanvasInstanceURL: demoInstance}
+	// Created long ago but still adding visits
+	busy := &models.User{CanvasUserID: "demo-busy", CanvasInstanceURL: demoInstance, CreatedAt: old, UpdatedAt: old}
+	shared := &models.User{CanvasUserID: demoCanvasID, CanvasInstanceURL: demoInstance, CreatedAt: old, UpdatedAt: old}
+	lmsUser := &models.User{CanvasUserID: "canvas-1", CanvasInstanceURL: "https://canvas.example.com", CreatedAt: old, UpdatedAt: old}
+	for _, u := range []*models.User{expired, fresh, busy, shared, lmsUser} {
+		db.Create(u)
+	}
+	db.Create(&models.Visit{UserID: expired.ID, CountryID: 1, VisitedAt: old, CreatedAt: old})
+	mediaURL := uploadOwnedMedia(t, db, s, expired.ID)
+	oldEntry := &models.ScrapbookEntry{UserID: expired.ID, CountryID: 1, Title: "Old", MediaURL: mediaURL}
+	db.Create(oldEntry)
+	db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", expired.ID).UpdateColumn("updated_at", old)
+	db.Create(&models.ScrapbookMedia{EntryID: oldEntry.ID, URL: mediaURL, Type: "image/jpeg"})
+	db.Create(&models.Comment{EntryID: oldEntry.ID, AuthorUserID: lmsUser.ID, Body: "Lovely"})
+	db.Create(&models.Feedback{CourseID: demoCourseID, LearnerUserID: expired.ID, InstructorUserID: lmsUser.ID})
+	db.Create(&models.LaunchEvent{Stage: "launch", UserID: &expired.ID, Success: true})
+	db.Create(&models.Visit{UserID: fresh.ID, CountryID: 1, VisitedAt: time.Now()})
+	db.Create(&models.Visit{UserID: busy.ID, CountryID: 1, VisitedAt: old})
+
+	purged, err := handler.PurgeExpiredDemoUsers(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 user purged, got %d", purged)
+	}
+
+	var userCount int64
+	db.Unscoped().Model(&models.User{}).Count(&userCount)
+	if userCount != 4 {
+		t.Errorf("expected 4 users remaining, got %d", userCount)
+	}
+
+	var visitCount, entryCount int64
+	db.Unscoped().Model(&models.Visit{}).Count(&visitCount)
+	db.Unscoped().Model(&models.ScrapbookEntry{}).Count(&entryCount)
+	if visitCount != 2 || entryCount != 0 {
+		t.Errorf("expected expired user's data purged, got %d visits and %d entries", visitCount, entryCount)
+	}
+
+	var mediaCount, commentCount, feedbackCount, launchCount, uploadCount int64
+	db.Model(&models.ScrapbookMedia{}).Count(&mediaCount)
+	db.Model(&models.Comment{}).Count(&commentCount)
+	db.Model(&models.Feedback{}).Count(&feedbackCount)
+	db.Model(&models.LaunchEvent{}).Count(&launchCount)
+	db.Unscoped().Model(&models.Upload{}).Count(&uploadCount)
+	if mediaCount != 0 || commentCount != 0 || feedbackCount != 0 || launchCount != 0 || uploadCount != 0 {
+		t.Errorf("expected no orphaned rows, got %d media, %d comments, %d feedback, %d launch events and %d uploads",
+			mediaCount, commentCount, feedbackCount, launchCount, uploadCount)
+	}
+	if s.Exists(path.Base(mediaURL)) {
+		t.Error("expected the purged user's uploaded file to be deleted")
+	}
+}
+
+func TestRouter_DemoSeed_DisabledOutsideDemoMode(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.DemoMode = false
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/seed", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 when demo mode disabled, got %d", w.Code)
+	}
+}
+
+func TestDemoHandler_DemoLogin_ReusesSessionUser(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router := createDemoTestRouter(db)
+
+	login := func(body string, cookies []*http.Cookie) (*httptest.ResponseRecorder, MeResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			User MeResponse `json:"user"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.User
+	}
+
+	first, firstUser := login(`{}`, nil)
+	cookies := first.Result().Cookies()
+
+	_, again := login(`{}`, cookies)
+	if again.ID != firstUser.ID {
+		t.Errorf("expected existing session to keep user %d, got %d", firstUser.ID, again.ID)
+	}
+
+	_, fresh := login(`{"fresh":true}`, cookies)
+	if fresh.ID == firstUser.ID {
+		t.Error("expected fresh login to create a new demo user")
+	}
+}
+
+// createLimitedDemoTestRouter returns a demo router whose handler uses
+// sessionTTL and loginLimit, and the session manager it signs tokens with
+func createLimitedDemoTestRouter(db *gorm.DB, sessionTTL time.Duration, loginLimit int) (*gin.Engine, *lti.SessionManager) {
+	router := gin.New()
+	sm := lti.NewSessionManager("test-secret", 86400)
+	handler := NewDemoHandler(db, sm)
+	handler.SetSessionTTL(sessionTTL)
+	handler.SetLoginLimit(loginLimit)
+
+	demo := router.Group("/api/v1/demo")
+	demo.Use(middleware.OptionalAuthMiddleware(sm))
+	demo.POST("/login", handler.DemoLogin)
+	return router, sm
+}
+
+func TestDemoHandler_DemoLogin_SessionTTL(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router, sm := createLimitedDemoTestRouter(db, 15*time.Minute, 0)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session" {
+			session = cookie
+		}
+	}
+	if session == nil {
+		t.Fatal("expected a session cookie")
+	}
+	if session.MaxAge != 900 {
+		t.Errorf("expected cookie max age 900, got %d", session.MaxAge)
+	}
+
+	// The token expires with the demo TTL, not the 24 hour session max age
+	claims, err := sm.ValidateToken(session.Value)
+	if err != nil {
+		t.Fatalf("failed to validate demo token: %v", err)
+	}
+	if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != 15*time.Minute {
+		t.Errorf("expected a 15 minute demo token, got %v", got)
+	}
+}
+
+func TestDemoHandler_DemoLogin_RateLimited(t *testing.T) {
+	db := setupDemoTestDB(t)
+	router, _ := createLimitedDemoTestRouter(db, time.Hour, 2)
+
+	login := func(ip, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/demo/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := login("203.0.113.7", `{}`, nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", first.Code)
+	}
+	if w := login("203.0.113.7", `{}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected second login to be allowed, got %d", w.Code)
+	}
+
+	w := login("203.0.113.7", `{}`, nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	// Resuming an existing demo session creates no user and is not limited
+	if w := login("203.0.113.7", `{}`, first.Result().Cookies()); w.Code != http.StatusOK {
+		t.Errorf("expected an existing session to log in again, got %d", w.Code)
+	}
+	if w := login("198.51.100.2", `{}`, nil); w.Code != http.StatusOK {
+		t.Errorf("expected another IP to be allowed, got %d", w.Code)
+	}
+
+	var demoUsers int64
+	db.Model(&models.User{}).Where("canvas_instance_url = ?", demoInstance).Count(&demoUsers)
+	if demoUsers != 3 {
+		t.Errorf("expected 3 demo users, got %d", demoUsers)
+	}
+}
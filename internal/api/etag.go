@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// countryCacheControl is the Cache-Control policy for mostly-static country data
+const countryCacheControl = "public, max-age=300"
+
+// countryVary lists the request headers country names are localized from
+const countryVary = "Accept-Language, Cookie, Authorization"
+
+// tableVersion summarizes the state of a query's rows for ETag computation
+type tableVersion struct {
+	Count       int64
+	LastUpdated sql.NullString
+}
+
+// queryVersion returns the row count and latest updated_at for a query
+func queryVersion(query *gorm.DB) (tableVersion, error) {
+	var version tableVersion
+	err := query.Select("COUNT(*) AS count, MAX(updated_at) AS last_updated").
+		Scan(&version).Error
+	return version, err
+}
+
+// weakETag builds a weak ETag from the given parts
+func weakETag(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagForVersion builds a weak ETag for the versions of the tables a scoped
+// response is built from
+func etagForVersion(scope string, versions ...tableVersion) string {
+	parts := []string{scope}
+	for _, v := range versions {
+		parts = append(parts, fmt.Sprintf("%d", v.Count), v.LastUpdated.String)
+	}
+	return weakETag(parts...)
+}
+
+// checkNotModified sets caching headers and writes a 304 response if the
+// client's If-None-Match matches etag. Returns true if the response was sent.
+func checkNotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", countryCacheControl)
+	c.Header("Vary", countryVary)
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches performs a weak comparison of an If-None-Match header against etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == target {
+			return true
+		}
+	}
+	return false
+}
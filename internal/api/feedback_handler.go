@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FeedbackHandler handles instructor grades and notes on learners' journals
+type FeedbackHandler struct {
+	db *gorm.DB
+}
+
+// NewFeedbackHandler creates a new feedback handler
+func NewFeedbackHandler(db *gorm.DB) *FeedbackHandler {
+	return &FeedbackHandler{db: db}
+}
+
+// FeedbackResponse represents feedback in API responses
+type FeedbackResponse struct {
+	CourseID       string   `json:"courseId"`
+	LearnerID      uint     `json:"learnerId"`
+	InstructorID   uint     `json:"instructorId"`
+	InstructorName string   `json:"instructorName,omitempty"`
+	Score          *float64 `json:"score,omitempty"`
+	Comment        string   `json:"comment,omitempty"`
+	UpdatedAt      string   `json:"updatedAt"`
+}
+
+// PutFeedbackRequest represents the request body for recording feedback.
+// Omitting score leaves the journal ungraded.
+type PutFeedbackRequest struct {
+	Score   *float64 `json:"score" binding:"omitempty,min=0,max=100"`
+	Comment string   `json:"comment" binding:"max=5000"`
+}
+
+func toFeedbackResponse(f *models.Feedback, loc *time.Location) FeedbackResponse {
+	return FeedbackResponse{
+		CourseID:       f.CourseID,
+		LearnerID:      f.LearnerUserID,
+		InstructorID:   f.InstructorUserID,
+		InstructorName: f.Instructor.DisplayName,
+		Score:          f.Score,
+		Comment:        f.Comment,
+		UpdatedAt:      formatTimestamp(f.UpdatedAt, loc),
+	}
+}
+
+// learnerInCourse reports whether a user has launched into a course or has
+// visits or entries in it
+func learnerInCourse(db *gorm.DB, userID uint, courseID string) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).
+		Where("id = ? AND (id IN (?) OR id IN (?))", userID, courseUserIDs(db, courseID), courseLaunchUserIDs(db, courseID)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// PutLearnerFeedback records the instructor's grade and note on a learner's
+// journal in the instructor's course, replacing any earlier feedback.
+// Learners outside the course are reported as not found.
+// PUT /api/v1/course/learners/:userId/feedback
+func (h *FeedbackHandler) PutLearnerFeedback(c *gin.Context) {
+	instructorID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	courseID, _ := middleware.GetCourseID(c) // Checked by RequireCourse
+
+	learnerID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req PutFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if req.Score == nil && comment == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "score or comment required"})
+		return
+	}
+
+	inCourse, err := learnerInCourse(h.db, uint(learnerID), courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save feedback"})
+		return
+	}
+	if !inCourse {
+		c.JSON(http.StatusNotFound, gin.H{"error": "learner not found in this course"})
+		return
+	}
+
+	feedback := models.Feedback{CourseID: courseID, LearnerUserID: uint(learnerID)}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&feedback).FirstOrInit(&feedback).Error; err != nil {
+			return err
+		}
+		feedback.InstructorUserID = instructorID
+		feedback.Score = req.Score
+		feedback.Comment = comment
+		return tx.Save(&feedback).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save feedback"})
+		return
+	}
+	h.db.First(&feedback.Instructor, instructorID)
+
+	c.JSON(http.StatusOK, toFeedbackResponse(&feedback, loc))
+}
+
+// GetMyFeedback returns the feedback instructors have left on the user's
+// journal, most recently updated first
+// GET /api/v1/me/feedback
+// Query params: courseId (optional) - only feedback from this course
+func (h *FeedbackHandler) GetMyFeedback(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Preload("Instructor").Where("learner_user_id = ?", userID)
+	if courseID := c.Query("courseId"); courseID != "" {
+		query = query.Where("course_id = ?", courseID)
+	}
+
+	var feedback []models.Feedback
+	if err := query.Order("updated_at DESC, id DESC").Find(&feedback).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch feedback"})
+		return
+	}
+
+	response := make([]FeedbackResponse, len(feedback))
+	for i := range feedback {
+		response[i] = toFeedbackResponse(&feedback[i], loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"feedback": response})
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// feedbackTestUsers are users seeded for the feedback tests
+type feedbackTestUsers struct {
+	instructor, learnerA, learnerB, newcomer models.User
+}
+
+// setupFeedbackTest seeds learnerA with data in course-a, learnerB with data
+// in course-b and a newcomer who has only launched into course-a
+func setupFeedbackTest(t *testing.T) (*gorm.DB, *gin.Engine, *lti.SessionManager, feedbackTestUsers) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	err = db.AutoMigrate(&models.User{}, &models.Visit{}, &models.ScrapbookEntry{}, &models.LaunchEvent{}, &models.Feedback{})
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var users feedbackTestUsers
+	for i, u := range []*models.User{&users.instructor, &users.learnerA, &users.learnerB, &users.newcomer} {
+		*u = models.User{
+			CanvasUserID:      fmt.Sprintf("canvas-%d", i),
+			CanvasInstanceURL: "https://canvas.example.com",
+			DisplayName:       []string{"Prof. Lee", "Ada", "Bea", "Cy"}[i],
+		}
+		db.Create(u)
+	}
+	db.Create(&models.Visit{UserID: users.learnerA.ID, CountryID: 1, CourseID: "course-a"})
+	db.Create(&models.ScrapbookEntry{UserID: users.learnerB.ID, CountryID: 1, CourseID: "course-b", Title: "B"})
+	db.Create(&models.LaunchEvent{Stage: models.LaunchStageLaunch, UserID: &users.newcomer.ID, CourseID: "course-a", Success: true})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	handler := NewFeedbackHandler(db)
+
+	router := gin.New()
+	auth := router.Group("/api/v1")
+	auth.Use(middleware.AuthMiddleware(sm))
+	{
+		auth.GET("/me/feedback", handler.GetMyFeedback)
+		auth.PUT("/course/learners/:userId/feedback", middleware.RequireInstructor(), middleware.RequireCourse(), handler.PutLearnerFeedback)
+	}
+	return db, router, sm, users
+}
+
+func putFeedback(router *gin.Engine, token string, learnerID uint, body string) *httptest.ResponseRecorder {
+	path := fmt.Sprintf("/api/v1/course/learners/%d/feedback", learnerID)
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func getMyFeedback(router *gin.Engine, token string) []FeedbackResponse {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/feedback", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Feedback []FeedbackResponse `json:"feedback"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Feedback
+}
+
+func TestFeedbackHandler_PutLearnerFeedback(t *testing.T) {
+	db, router, sm, users := setupFeedbackTest(t)
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	w := putFeedback(router, token, users.learnerA.ID, `{"score": 85, "comment": "Great journal"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response FeedbackResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Score == nil || *response.Score != 85 || response.Comment != "Great journal" ||
+		response.CourseID != "course-a" || response.InstructorName != "Prof. Lee" {
+		t.Errorf("unexpected feedback: %+v", response)
+	}
+
+	// A second PUT replaces the feedback rather than adding another
+	w = putFeedback(router, token, users.learnerA.ID, `{"comment": "Ungraded for now"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&models.Feedback{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 feedback row, got %d", count)
+	}
+
+	learner, _ := sm.CreateToken(users.learnerA.ID, "canvas-1", "course-a", "learner")
+	feedback := getMyFeedback(router, learner)
+	if len(feedback) != 1 || feedback[0].Score != nil || feedback[0].Comment != "Ungraded for now" {
+		t.Errorf("expected the learner to see the updated feedback, got %+v", feedback)
+	}
+
+	other, _ := sm.CreateToken(users.learnerB.ID, "canvas-2", "course-b", "learner")
+	if feedback := getMyFeedback(router, other); len(feedback) != 0 {
+		t.Errorf("expected no feedback for another learner, got %+v", feedback)
+	}
+}
+
+func TestFeedbackHandler_PutLearnerFeedback_LaunchedLearner(t *testing.T) {
+	_, router, sm, users := setupFeedbackTest(t)
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	// A learner who launched but has no visits or entries yet can be graded
+	if w := putFeedback(router, token, users.newcomer.ID, `{"score": 0}`); w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFeedbackHandler_PutLearnerFeedback_CrossCourse(t *testing.T) {
+	db, router, sm, users := setupFeedbackTest(t)
+
+	// An instructor of course-a cannot grade a learner who is only in course-b
+	tokenA, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+	if w := putFeedback(router, tokenA, users.learnerB.ID, `{"score": 50}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a learner in another course, got %d", w.Code)
+	}
+
+	// Nor can an instructor of course-b grade a course-a learner
+	tokenB, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-b", "instructor")
+	if w := putFeedback(router, tokenB, users.learnerA.ID, `{"score": 50}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a learner in another course, got %d", w.Code)
+	}
+
+	noCourse, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "", "instructor")
+	if w := putFeedback(router, noCourse, users.learnerA.ID, `{"score": 50}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without a course, got %d", w.Code)
+	}
+
+	learner, _ := sm.CreateToken(users.learnerA.ID, "canvas-1", "course-a", "learner")
+	if w := putFeedback(router, learner, users.learnerA.ID, `{"score": 100}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a learner, got %d", w.Code)
+	}
+
+	var count int64
+	db.Model(&models.Feedback{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no feedback recorded, got %d", count)
+	}
+}
+
+func TestFeedbackHandler_PutLearnerFeedback_Invalid(t *testing.T) {
+	_, router, sm, users := setupFeedbackTest(t)
+	token, _ := sm.CreateToken(users.instructor.ID, "canvas-0", "course-a", "instructor")
+
+	for _, body := range []string{`{}`, `{"comment": "  "}`, `{"score": -1}`, `{"score": 101}`} {
+		if w := putFeedback(router, token, users.learnerA.ID, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/course/learners/abc/feedback", bytes.NewBufferString(`{"score": 1}`))
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid ID, got %d", w.Code)
+	}
+}
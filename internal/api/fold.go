@@ -0,0 +1,44 @@
+package api
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldSpecial covers letters that have no Unicode decomposition to a base letter
+var foldSpecial = map[rune]rune{
+	'ø': 'o',
+	'ł': 'l',
+	'đ': 'd',
+	'ı': 'i',
+}
+
+// foldRune lowercases r and strips any diacritics, e.g. 'É' -> 'e'
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if base, ok := foldSpecial[r]; ok {
+		return base
+	}
+	if r < unicode.MaxASCII {
+		return r
+	}
+	// The first rune of the canonical decomposition is the base letter
+	for _, base := range norm.NFD.String(string(r)) {
+		return base
+	}
+	return r
+}
+
+// foldText normalizes s for search comparisons by folding every rune.
+// The result has exactly one rune per rune of s, so character offsets into
+// it are offsets into s.
+func foldText(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+// foldContains reports whether s contains sub, ignoring case and diacritics
+func foldContains(s, sub string) bool {
+	return strings.Contains(foldText(s), foldText(sub))
+}
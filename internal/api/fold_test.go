@@ -0,0 +1,35 @@
+package api
+
+import "testing"
+
+func TestFoldText(t *testing.T) {
+	tests := map[string]string{
+		"Perú":          "peru",
+		"CÔTE D'IVOIRE": "cote d'ivoire",
+		"Türkiye":       "turkiye",
+		"São Tomé":      "sao tome",
+		"Łódź":          "lodz",
+		"Færøerne":      "færoerne",
+		"plain":         "plain",
+	}
+	for input, want := range tests {
+		if got := foldText(input); got != want {
+			t.Errorf("foldText(%q) = %q, want %q", input, got, want)
+		}
+		if len([]rune(foldText(input))) != len([]rune(input)) {
+			t.Errorf("foldText(%q) changed the rune count", input)
+		}
+	}
+}
+
+func TestMatchOffsets_Accented(t *testing.T) {
+	start, end, ok := matchOffsets("Côte d'Ivoire", "COTE")
+	if !ok || start != 0 || end != 4 {
+		t.Errorf("expected [0 4], got [%d %d] ok=%v", start, end, ok)
+	}
+
+	start, end, ok = matchOffsets("São Tomé", "tome")
+	if !ok || start != 4 || end != 8 {
+		t.Errorf("expected [4 8], got [%d %d] ok=%v", start, end, ok)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"math"
+
+	"globe-expedition-journal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// maxCheckInDistanceKm bounds how far a check-in may be from the nearest
+// country centroid before the country is considered unknown, so points in
+// open ocean or in countries that are not seeded are not misattributed
+const maxCheckInDistanceKm = 2500.0
+
+// distanceKm returns the great-circle distance between two points in degrees
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// nearestCountry returns the country whose centroid is closest to the point,
+// or nil if none is within maxCheckInDistanceKm. Countries without a centroid
+// are never matched.
+func nearestCountry(db *gorm.DB, lat, lng float64) (*models.Country, error) {
+	var countries []models.Country
+	if err := db.Where("latitude IS NOT NULL AND longitude IS NOT NULL").Find(&countries).Error; err != nil {
+		return nil, err
+	}
+
+	var nearest *models.Country
+	best := maxCheckInDistanceKm
+	for i := range countries {
+		d := distanceKm(lat, lng, *countries[i].Latitude, *countries[i].Longitude)
+		if d <= best {
+			nearest, best = &countries[i], d
+		}
+	}
+	return nearest, nil
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"math"
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+)
+
+func TestDistanceKm(t *testing.T) {
+	// Paris to London is roughly 344 km
+	d := distanceKm(48.8566, 2.3522, 51.5074, -0.1278)
+	if math.Abs(d-344) > 5 {
+		t.Errorf("expected about 344 km, got %.1f", d)
+	}
+	if d := distanceKm(10, 20, 10, 20); d != 0 {
+		t.Errorf("expected 0 for the same point, got %f", d)
+	}
+}
+
+func TestNearestCountry(t *testing.T) {
+	db := setupVisitTestDB(t)
+	lat, lng := 46.2276, 2.2137
+	db.Create(&models.Country{Name: "France", ISOCode: "FR", Region: "Europe", Latitude: &lat, Longitude: &lng})
+	db.Create(&models.Country{Name: "Atlantis", ISOCode: "AX", Region: "Ocean"})
+
+	country, err := nearestCountry(db, 45.76, 4.84)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country == nil || country.ISOCode != "FR" {
+		t.Fatalf("expected FR near Lyon, got %+v", country)
+	}
+
+	country, err = nearestCountry(db, 0, -140)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country != nil {
+		t.Errorf("expected no country in the open Pacific, got %s", country.ISOCode)
+	}
+}
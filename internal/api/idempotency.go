@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header clients use to make creates retry-safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotencyWindow is how long a key maps to its original resource
+	idempotencyWindow = 24 * time.Hour
+
+	// Resource types recorded against idempotency keys
+	idempotencyResourceVisit          = "visit"
+	idempotencyResourceScrapbookEntry = "scrapbook_entry"
+)
+
+// getIdempotencyKey returns the Idempotency-Key header value, if any
+func getIdempotencyKey(c *gin.Context) string {
+	return c.GetHeader(IdempotencyKeyHeader)
+}
+
+// lookupIdempotencyKey returns the resource ID previously created by this user
+// with the given key, if the key was recorded within the idempotency window
+func lookupIdempotencyKey(db *gorm.DB, userID uint, key, resourceType string) (uint, bool) {
+	var record models.IdempotencyKey
+	err := db.Where("user_id = ? AND key = ? AND resource_type = ? AND created_at > ?",
+		userID, key, resourceType, time.Now().Add(-idempotencyWindow)).
+		First(&record).Error
+	if err != nil {
+		return 0, false
+	}
+	return record.ResourceID, true
+}
+
+// errIdempotencyKeyConflict is the error response when a key's original
+// request has not finished, so there is nothing to replay yet
+const errIdempotencyKeyConflict = "a request with this Idempotency-Key is already in progress"
+
+// errIdempotencyKeyInUse is returned when another request already claimed the key
+var errIdempotencyKeyInUse = errors.New("idempotency key already in use")
+
+// claimIdempotencyKey reserves a key before its resource is created,
+// replacing any expired record that still holds the same key. The unique
+// (user, key, type) index lets only one of several concurrent requests claim
+// a key; the others get errIdempotencyKeyInUse and should replay the
+// resource the winner created. Must run in the transaction that creates the
+// resource, followed by completeIdempotencyKey.
+func claimIdempotencyKey(tx *gorm.DB, userID uint, key, resourceType string) (*models.IdempotencyKey, error) {
+	if err := tx.Where("user_id = ? AND key = ? AND resource_type = ? AND created_at <= ?",
+		userID, key, resourceType, time.Now().Add(-idempotencyWindow)).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	record := &models.IdempotencyKey{
+		UserID:       userID,
+		Key:          key,
+		ResourceType: resourceType,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errIdempotencyKeyInUse
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// completeIdempotencyKey records the resource created under a claimed key
+func completeIdempotencyKey(tx *gorm.DB, record *models.IdempotencyKey, resourceID uint) error {
+	return tx.Model(record).Update("resource_id", resourceID).Error
+}
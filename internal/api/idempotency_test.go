@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIdempotencyTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+// saveTestIdempotencyKey claims a key and completes it with resourceID
+func saveTestIdempotencyKey(t *testing.T, db *gorm.DB, userID uint, key, resourceType string, resourceID uint) {
+	t.Helper()
+	claim, err := claimIdempotencyKey(db, userID, key, resourceType)
+	if err != nil {
+		t.Fatalf("failed to claim key: %v", err)
+	}
+	if err := completeIdempotencyKey(db, claim, resourceID); err != nil {
+		t.Fatalf("failed to complete key: %v", err)
+	}
+}
+
+func TestIdempotencyKey_SaveAndLookup(t *testing.T) {
+	db := setupIdempotencyTestDB(t)
+
+	saveTestIdempotencyKey(t, db, 1, "key-1", idempotencyResourceVisit, 42)
+
+	id, found := lookupIdempotencyKey(db, 1, "key-1", idempotencyResourceVisit)
+	if !found || id != 42 {
+		t.Errorf("expected resource 42, got %d (found=%v)", id, found)
+	}
+
+	if _, found := lookupIdempotencyKey(db, 1, "key-1", idempotencyResourceScrapbookEntry); found {
+		t.Error("expected key to be scoped to resource type")
+	}
+}
+
+func TestIdempotencyKey_ExpiredKeyIgnoredAndReplaced(t *testing.T) {
+	db := setupIdempotencyTestDB(t)
+
+	db.Create(&models.IdempotencyKey{
+		UserID:       1,
+		Key:          "old-key",
+		ResourceType: idempotencyResourceVisit,
+		ResourceID:   7,
+		CreatedAt:    time.Now().Add(-idempotencyWindow - time.Hour),
+	})
+
+	if _, found := lookupIdempotencyKey(db, 1, "old-key", idempotencyResourceVisit); found {
+		t.Error("expected expired key to be ignored")
+	}
+
+	saveTestIdempotencyKey(t, db, 1, "old-key", idempotencyResourceVisit, 8)
+
+	id, found := lookupIdempotencyKey(db, 1, "old-key", idempotencyResourceVisit)
+	if !found || id != 8 {
+		t.Errorf("expected resource 8, got %d (found=%v)", id, found)
+	}
+}
+
+func TestClaimIdempotencyKey_OnlyOneClaimWins(t *testing.T) {
+	db := setupIdempotencyTestDB(t)
+
+	saveTestIdempotencyKey(t, db, 1, "key-1", idempotencyResourceVisit, 42)
+
+	// A second request with a live key must not create another resource
+	if _, err := claimIdempotencyKey(db, 1, "key-1", idempotencyResourceVisit); !errors.Is(err, errIdempotencyKeyInUse) {
+		t.Errorf("expected errIdempotencyKeyInUse, got %v", err)
+	}
+
+	id, found := lookupIdempotencyKey(db, 1, "key-1", idempotencyResourceVisit)
+	if !found || id != 42 {
+		t.Errorf("expected the original resource 42 to be kept, got %d (found=%v)", id, found)
+	}
+
+	// The same key is free for another user or resource type
+	if _, err := claimIdempotencyKey(db, 2, "key-1", idempotencyResourceVisit); err != nil {
+		t.Errorf("expected another user to claim the key, got %v", err)
+	}
+	if _, err := claimIdempotencyKey(db, 1, "key-1", idempotencyResourceScrapbookEntry); err != nil {
+		t.Errorf("expected another resource type to claim the key, got %v", err)
+	}
+}
+
+func TestClaimIdempotencyKey_RolledBackWithResource(t *testing.T) {
+	db := setupIdempotencyTestDB(t)
+
+	errCreate := errors.New("create failed")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := claimIdempotencyKey(tx, 1, "key-1", idempotencyResourceVisit); err != nil {
+			return err
+		}
+		return errCreate
+	})
+	if !errors.Is(err, errCreate) {
+		t.Fatalf("expected the create error, got %v", err)
+	}
+
+	// A failed create must not leave the key claimed
+	if _, err := claimIdempotencyKey(db, 1, "key-1", idempotencyResourceVisit); err != nil {
+		t.Errorf("expected the key to be claimable after rollback, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"globe-expedition-journal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLocale resolves the locale to localize content in: the ?locale=
+// query parameter if present, else the session's default locale from the
+// launch, else the preferred Accept-Language tag. Returns "" if none is set.
+func requestLocale(c *gin.Context) string {
+	if locale := strings.TrimSpace(c.Query("locale")); locale != "" {
+		return locale
+	}
+
+	if claims, ok := middleware.GetSessionClaims(c); ok && claims.Locale != "" {
+		return claims.Locale
+	}
+
+	return preferredLanguage(c.GetHeader("Accept-Language"))
+}
+
+// preferredLanguage returns the highest-weighted tag in an Accept-Language
+// header, ignoring the "*" wildcard. Ties keep header order.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
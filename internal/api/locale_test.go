@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"fr-FR", "fr-FR"},
+		{"fr-FR, en;q=0.8", "fr-FR"},
+		{"en;q=0.5, de;q=0.9", "de"},
+		{"*, es;q=0.4", "es"},
+		{"en, fr", "en"},
+		{"fr;q=bad, de;q=0.1", "de"},
+		{"*", ""},
+	}
+
+	for _, tt := range tests {
+		if got := preferredLanguage(tt.header); got != tt.want {
+			t.Errorf("preferredLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLocale(t *testing.T) {
+	sm := lti.NewSessionManager("test-secret", 3600)
+	withLocale, _ := sm.CreateTokenWithLocale(1, "canvas-123", "course-1", "learner", "de-DE")
+	withoutLocale, _ := sm.CreateToken(1, "canvas-123", "course-1", "learner")
+
+	tests := []struct {
+		name           string
+		query          string
+		token          string
+		acceptLanguage string
+		want           string
+	}{
+		{"nothing set", "", "", "", ""},
+		{"query wins", "?locale=fr", withLocale, "es", "fr"},
+		{"session before header", "", withLocale, "es", "de-DE"},
+		{"header when session has none", "", withoutLocale, "es", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			router := gin.New()
+			router.Use(middleware.OptionalAuthMiddleware(sm))
+			router.GET("/", func(c *gin.Context) { got = requestLocale(c) })
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.token})
+			}
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIVersion is the version of this API reported in the spec
+const openAPIVersion = "1.0.0"
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse is the body of responses that only confirm an action
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// openAPIParam is a query parameter of an operation. Path parameters are
+// taken from the route itself.
+type openAPIParam struct {
+	name, typ, description string
+}
+
+// openAPIOperation describes one documented route
+type openAPIOperation struct {
+	method, path string // Gin route, e.g. "/api/v1/visits/:id"
+	tag, summary string
+	public       bool           // Reachable without a session
+	query        []openAPIParam // Query parameters
+	request      any            // JSON request body struct, nil if none
+	form         map[string]any // Form fields, name -> schema; multipart if one is a file
+	status       int            // Success status
+	response     any            // Success body: a struct, or a schema built with listOf/wrapped
+}
+
+// Query parameters shared by several operations
+var (
+	pageParams = []openAPIParam{
+		{"page", "integer", "Page number, starting at 1"},
+		{"pageSize", "integer", "Items per page"},
+		{"limit", "integer", "Items to return, with offset"},
+		{"offset", "integer", "Items to skip, with limit"},
+	}
+	localeParam    = openAPIParam{"locale", "string", "Locale for country names, e.g. fr-FR"}
+	courseParam    = openAPIParam{"courseId", "string", "Only items from this course"}
+	dateRangeParam = []openAPIParam{
+		{"from", "string", "Only items visited at or after this time (RFC3339 or YYYY-MM-DD)"},
+		{"to", "string", "Only items visited at or before this time (RFC3339 or YYYY-MM-DD)"},
+	}
+	tzParam = openAPIParam{"tz", "string", "IANA zone timestamps are rendered in"}
+)
+
+// listOf describes a list response: the items under key plus a total and
+// optional pagination, as written by listResponse
+type listOf struct {
+	key  string
+	item any
+}
+
+// wrapped describes an object holding a single array of items under key
+type wrapped struct {
+	key  string
+	item any
+}
+
+// openAPIOperations lists the documented routes. Schemas are derived from
+// the request and response structs, so only routes and parameters are
+// maintained here.
+var openAPIOperations = []openAPIOperation{
+	// Auth
+	{method: "GET", path: "/lti/login", tag: "auth", summary: "Start an LTI 1.3 launch (OIDC login initiation)", public: true,
+		query:  []openAPIParam{{"iss", "string", "Platform issuer"}, {"login_hint", "string", "Opaque user hint"}, {"target_link_uri", "string", "Where the launch should end up"}, {"lti_message_hint", "string", "Opaque launch hint"}},
+		status: http.StatusFound},
+	{method: "POST", path: "/lti/launch", tag: "auth", summary: "Complete an LTI 1.3 launch and start a session", public: true,
+		form: map[string]any{"id_token": map[string]any{"type": "string"}, "state": map[string]any{"type": "string"}}, status: http.StatusFound},
+	{method: "POST", path: "/api/v1/demo/login", tag: "auth", summary: "Start a demo session (demo mode only)", public: true,
+		request: DemoLoginRequest{}, status: http.StatusOK, response: demoLoginResponse{}},
+	{method: "POST", path: "/api/v1/logout", tag: "auth", summary: "End the session", status: http.StatusOK, response: MessageResponse{}},
+	{method: "GET", path: "/api/v1/me", tag: "auth", summary: "Get the current user", status: http.StatusOK, response: MeResponse{}},
+	{method: "PUT", path: "/api/v1/me", tag: "auth", summary: "Update the current user", request: UpdateMeRequest{}, status: http.StatusOK, response: MeResponse{}},
+
+	// Countries
+	{method: "GET", path: "/api/v1/countries", tag: "countries", summary: "List countries", public: true,
+		query:  append([]openAPIParam{{"region", "string", "Only countries in this region"}, localeParam}, pageParams...),
+		status: http.StatusOK, response: CountryListResponse{}},
+	{method: "GET", path: "/api/v1/countries/regions", tag: "countries", summary: "List regions", public: true,
+		status: http.StatusOK, response: wrapped{"regions", ""}},
+	{method: "GET", path: "/api/v1/countries/search", tag: "countries", summary: "Search countries by name, ISO code or alias", public: true,
+		query:  append([]openAPIParam{{"q", "string", "Search text"}, {"highlight", "boolean", "Report which field matched"}, localeParam}, pageParams...),
+		status: http.StatusOK, response: listOf{"countries", CountrySearchResult{}}},
+	{method: "GET", path: "/api/v1/countries/popular", tag: "countries", summary: "List the most visited countries", public: true,
+		query: []openAPIParam{{"limit", "integer", "1 to 100, default 10"}, localeParam}, status: http.StatusOK, response: wrapped{"countries", PopularCountryResponse{}}},
+	{method: "GET", path: "/api/v1/countries/code/:code", tag: "countries", summary: "Get a country by ISO 3166-1 alpha-2 code", public: true,
+		query: []openAPIParam{localeParam}, status: http.StatusOK, response: CountryResponse{}},
+	{method: "GET", path: "/api/v1/countries/code3/:code", tag: "countries", summary: "Get a country by ISO 3166-1 alpha-3 code", public: true,
+		query: []openAPIParam{localeParam}, status: http.StatusOK, response: CountryResponse{}},
+	{method: "GET", path: "/api/v1/countries/:id", tag: "countries", summary: "Get a country", public: true,
+		query: []openAPIParam{localeParam}, status: http.StatusOK, response: CountryResponse{}},
+	{method: "GET", path: "/api/v1/countries/unvisited", tag: "countries", summary: "List countries the user has not visited",
+		query: []openAPIParam{{"region", "string", "Only countries in this region"}, localeParam}, status: http.StatusOK, response: CountryListResponse{}},
+	{method: "GET", path: "/api/v1/countries/:id/overview", tag: "countries", summary: "Get a country with the user's activity in it",
+		query: []openAPIParam{tzParam}, status: http.StatusOK, response: CountryOverviewResponse{}},
+	{method: "GET", path: "/api/v1/me/suggestions", tag: "countries", summary: "Suggest countries to visit next",
+		query: []openAPIParam{{"limit", "integer", "1 to 50, default 10"}, localeParam}, status: http.StatusOK, response: wrapped{"suggestions", SuggestionResponse{}}},
+
+	// Visits
+	{method: "GET", path: "/api/v1/visits", tag: "visits", summary: "List the user's visits",
+		query:  append(append([]openAPIParam{{"region", "string", "Only visits to countries in this region"}, courseParam, {"cursor", "string", "Keyset paging cursor; empty for the first page"}, tzParam}, dateRangeParam...), pageParams...),
+		status: http.StatusOK, response: VisitListResponse{}},
+	{method: "POST", path: "/api/v1/visits", tag: "visits", summary: "Record a visit", request: CreateVisitRequest{}, status: http.StatusCreated, response: VisitResponse{}},
+	{method: "GET", path: "/api/v1/visits/timeline", tag: "visits", summary: "Count visits per month or year",
+		query:  append([]openAPIParam{{"granularity", "string", "month (default) or year"}, courseParam, tzParam}, dateRangeParam...),
+		status: http.StatusOK, response: VisitTimelineResponse{}},
+	{method: "GET", path: "/api/v1/visits/counts", tag: "visits", summary: "Count visits per country",
+		query: append([]openAPIParam{courseParam}, dateRangeParam...), status: http.StatusOK, response: wrapped{"counts", VisitCountResponse{}}},
+	{method: "GET", path: "/api/v1/visits/countries", tag: "visits", summary: "Summarize visits per visited country",
+		query: []openAPIParam{localeParam, tzParam}, status: http.StatusOK, response: wrapped{"countries", VisitedCountryResponse{}}},
+	{method: "GET", path: "/api/v1/visits/:id", tag: "visits", summary: "Get a visit", query: []openAPIParam{tzParam}, status: http.StatusOK, response: VisitResponse{}},
+	{method: "PUT", path: "/api/v1/visits/:id", tag: "visits", summary: "Update a visit", request: UpdateVisitRequest{}, status: http.StatusOK, response: VisitResponse{}},
+	{method: "DELETE", path: "/api/v1/visits/:id", tag: "visits", summary: "Delete a visit", status: http.StatusOK, response: MessageResponse{}},
+	{method: "GET", path: "/api/v1/visits/country/:countryId", tag: "visits", summary: "List the user's visits to a country",
+		query: append([]openAPIParam{tzParam}, pageParams...), status: http.StatusOK, response: listOf{"visits", VisitResponse{}}},
+
+	// Scrapbook
+	{method: "GET", path: "/api/v1/scrapbook/entries", tag: "scrapbook", summary: "List the user's scrapbook entries",
+		query:  append(append([]openAPIParam{{"tag", "string", "Only entries with this tag"}, courseParam, {"sort", "string", "pinned (default), newest, oldest or visited"}, tzParam}, dateRangeParam...), pageParams...),
+		status: http.StatusOK, response: ScrapbookEntryListResponse{}},
+	{method: "POST", path: "/api/v1/scrapbook/entries", tag: "scrapbook", summary: "Create a scrapbook entry", request: CreateScrapbookEntryRequest{}, status: http.StatusCreated, response: ScrapbookEntryResponse{}},
+	{method: "POST", path: "/api/v1/scrapbook/entries/bulk-delete", tag: "scrapbook", summary: "Delete several entries", request: BulkDeleteEntriesRequest{}, status: http.StatusOK, response: BulkDeleteEntriesResponse{}},
+	{method: "GET", path: "/api/v1/scrapbook/entries/:id", tag: "scrapbook", summary: "Get a scrapbook entry", query: []openAPIParam{tzParam}, status: http.StatusOK, response: ScrapbookEntryResponse{}},
+	{method: "PUT", path: "/api/v1/scrapbook/entries/:id", tag: "scrapbook", summary: "Update a scrapbook entry", request: UpdateScrapbookEntryRequest{}, status: http.StatusOK, response: ScrapbookEntryResponse{}},
+	{method: "DELETE", path: "/api/v1/scrapbook/entries/:id", tag: "scrapbook", summary: "Delete a scrapbook entry", status: http.StatusOK, response: MessageResponse{}},
+	{method: "PATCH", path: "/api/v1/scrapbook/entries/:id/pin", tag: "scrapbook", summary: "Pin or unpin an entry", request: PinScrapbookEntryRequest{}, status: http.StatusOK, response: ScrapbookEntryResponse{}},
+	{method: "PUT", path: "/api/v1/scrapbook/entries/:id/media/order", tag: "scrapbook", summary: "Reorder an entry's media", request: ReorderScrapbookMediaRequest{}, status: http.StatusOK, response: ScrapbookEntryResponse{}},
+	{method: "GET", path: "/api/v1/scrapbook/entries/:id/comments", tag: "scrapbook", summary: "List comments on an entry", status: http.StatusOK, response: wrapped{"comments", CommentResponse{}}},
+	{method: "POST", path: "/api/v1/scrapbook/entries/:id/comments", tag: "scrapbook", summary: "Comment on an entry", request: CreateCommentRequest{}, status: http.StatusCreated, response: CommentResponse{}},
+	{method: "GET", path: "/api/v1/scrapbook/countries/:countryId/entries", tag: "scrapbook", summary: "List the user's entries for a country",
+		query: append([]openAPIParam{tzParam}, pageParams...), status: http.StatusOK, response: listOf{"entries", ScrapbookEntryResponse{}}},
+	{method: "GET", path: "/api/v1/scrapbook/stats", tag: "scrapbook", summary: "Get scrapbook statistics", status: http.StatusOK, response: ScrapbookStatsResponse{}},
+	{method: "POST", path: "/api/v1/scrapbook/tags/rename", tag: "scrapbook", summary: "Rename or merge a tag", request: RenameTagRequest{}, status: http.StatusOK, response: renameTagResponse{}},
+
+	// Uploads
+	{method: "POST", path: "/api/v1/upload", tag: "uploads", summary: "Upload a media file",
+		form:   map[string]any{"file": map[string]any{"type": "string", "format": "binary"}, "entryId": map[string]any{"type": "integer"}},
+		status: http.StatusCreated, response: UploadResponse{}},
+	{method: "GET", path: "/api/v1/upload/:filename/info", tag: "uploads", summary: "Describe one of the user's uploads", status: http.StatusOK, response: UploadInfoResponse{}},
+	{method: "DELETE", path: "/api/v1/upload/:filename", tag: "uploads", summary: "Delete one of the user's uploads", status: http.StatusOK, response: MessageResponse{}},
+	{method: "GET", path: "/api/v1/me/storage", tag: "uploads", summary: "Get upload usage and limits", status: http.StatusOK, response: StorageUsageResponse{}},
+	{method: "GET", path: "/uploads/:filename", tag: "uploads", summary: "Download an uploaded file", public: true, status: http.StatusOK},
+}
+
+// demoLoginResponse is the body of a successful demo login
+type demoLoginResponse struct {
+	Message string     `json:"message"`
+	User    MeResponse `json:"user"`
+}
+
+// renameTagResponse is the body of a successful tag rename
+type renameTagResponse struct {
+	Updated int `json:"updated"` // Entries changed
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  gin.H
+)
+
+// OpenAPISpec serves an OpenAPI 3 description of the public API
+// GET /api/v1/openapi.json
+func OpenAPISpec(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = buildOpenAPISpec(openAPIOperations)
+	})
+	c.JSON(http.StatusOK, openAPIDoc)
+}
+
+// buildOpenAPISpec builds the OpenAPI document for ops
+func buildOpenAPISpec(ops []openAPIOperation) gin.H {
+	schemas := newSchemaRegistry()
+	errorRef := schemas.ref(reflect.TypeOf(ErrorResponse{}))
+
+	paths := gin.H{}
+	for _, op := range ops {
+		path, pathParams := openAPIPath(op.path)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+
+		params := []gin.H{}
+		for _, name := range pathParams {
+			typ := "string"
+			if name == "id" || strings.HasSuffix(name, "Id") {
+				typ = "integer"
+			}
+			params = append(params, gin.H{"name": name, "in": "path", "required": true, "schema": gin.H{"type": typ}})
+		}
+		for _, p := range op.query {
+			params = append(params, gin.H{"name": p.name, "in": "query", "description": p.description, "schema": gin.H{"type": p.typ}})
+		}
+
+		success := gin.H{"description": http.StatusText(op.status)}
+		if op.response != nil {
+			success["content"] = gin.H{"application/json": gin.H{"schema": schemas.responseSchema(op.response)}}
+		}
+		operation := gin.H{
+			"tags":       []string{op.tag},
+			"summary":    op.summary,
+			"parameters": params,
+			"responses": gin.H{
+				statusKey(op.status): success,
+				"default": gin.H{
+					"description": "Error",
+					"content":     gin.H{"application/json": gin.H{"schema": errorRef}},
+				},
+			},
+		}
+		if op.request != nil {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content":  gin.H{"application/json": gin.H{"schema": schemas.schemaOf(reflect.TypeOf(op.request))}},
+			}
+		}
+		if op.form != nil {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content":  gin.H{formContentType(op.form): gin.H{"schema": gin.H{"type": "object", "properties": op.form}}},
+			}
+		}
+		if op.public {
+			operation["security"] = []gin.H{}
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "Globe Expedition Journal API",
+			"version": openAPIVersion,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas.schemas,
+			"securitySchemes": gin.H{
+				"sessionCookie": gin.H{"type": "apiKey", "in": "cookie", "name": "session"},
+				"bearerToken":   gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []gin.H{{"sessionCookie": []string{}}, {"bearerToken": []string{}}},
+	}
+}
+
+// openAPIPath converts a gin route to an OpenAPI path, returning the names
+// of its path parameters
+func openAPIPath(route string) (string, []string) {
+	segments := strings.Split(route, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// formContentType is multipart for forms carrying a file, urlencoded otherwise
+func formContentType(form map[string]any) string {
+	for _, field := range form {
+		if schema, ok := field.(map[string]any); ok && schema["format"] == "binary" {
+			return "multipart/form-data"
+		}
+	}
+	return "application/x-www-form-urlencoded"
+}
+
+// statusKey is the responses key for status
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+// schemaRegistry collects the named schemas referenced from the spec
+type schemaRegistry struct {
+	schemas gin.H
+}
+
+// newSchemaRegistry creates an empty registry
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: gin.H{}}
+}
+
+// responseSchema returns the schema of a success body: a struct, a listOf
+// or a wrapped list
+func (r *schemaRegistry) responseSchema(body any) gin.H {
+	switch b := body.(type) {
+	case listOf:
+		return gin.H{
+			"type": "object",
+			"properties": gin.H{
+				b.key:        gin.H{"type": "array", "items": r.schemaOf(reflect.TypeOf(b.item))},
+				"total":      gin.H{"type": "integer"},
+				"pagination": r.ref(reflect.TypeOf(PageInfo{})),
+			},
+			"required": []string{b.key, "total"},
+		}
+	case wrapped:
+		return gin.H{
+			"type":       "object",
+			"properties": gin.H{b.key: gin.H{"type": "array", "items": r.schemaOf(reflect.TypeOf(b.item))}},
+			"required":   []string{b.key},
+		}
+	}
+	return r.schemaOf(reflect.TypeOf(body))
+}
+
+// timeType is rendered as a date-time string
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema for t, registering named structs as components
+func (r *schemaRegistry) schemaOf(t reflect.Type) gin.H {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		return r.ref(t)
+	case t.Kind() == reflect.Struct:
+		return r.objectSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return gin.H{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": r.schemaOf(t.Elem())}
+	}
+	return gin.H{}
+}
+
+// ref registers the named struct t and returns a reference to it
+func (r *schemaRegistry) ref(t reflect.Type) gin.H {
+	name := t.Name()
+	if _, ok := r.schemas[name]; !ok {
+		r.schemas[name] = gin.H{} // Placeholder while the struct's fields are visited
+		r.schemas[name] = r.objectSchema(t)
+	}
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+// objectSchema describes a struct by its JSON fields. Embedded structs are
+// flattened as encoding/json does, and fields with a required binding are
+// listed as required.
+func (r *schemaRegistry) objectSchema(t reflect.Type) gin.H {
+	properties := gin.H{}
+	var required []string
+	r.addFields(t, properties, &required)
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of struct t to properties
+func (r *schemaRegistry) addFields(t reflect.Type, properties gin.H, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.schemaOf(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				*required = append(*required, name)
+			}
+		}
+	}
+}
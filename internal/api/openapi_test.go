@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIDocument is the part of the spec the tests inspect
+type openAPIDocument struct {
+	OpenAPI    string                                       `json:"openapi"`
+	Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+			Required   []string                          `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func getOpenAPISpec(t *testing.T) openAPIDocument {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/v1/openapi.json", OpenAPISpec)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected the spec to be valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPISpec_ListsCorePaths(t *testing.T) {
+	doc := getOpenAPISpec(t)
+
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("expected OpenAPI 3.0.3, got %q", doc.OpenAPI)
+	}
+	for path, method := range map[string]string{
+		"/api/v1/countries":                  "get",
+		"/api/v1/countries/{id}":             "get",
+		"/api/v1/visits":                     "post",
+		"/api/v1/visits/{id}":                "put",
+		"/api/v1/scrapbook/entries":          "post",
+		"/api/v1/scrapbook/entries/{id}":     "delete",
+		"/api/v1/upload":                     "post",
+		"/api/v1/upload/{filename}/info":     "get",
+		"/api/v1/me":                         "get",
+		"/lti/launch":                        "post",
+		"/api/v1/scrapbook/tags/rename":      "post",
+		"/api/v1/visits/country/{countryId}": "get",
+	} {
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("expected %s %s in the spec", method, path)
+		}
+	}
+}
+
+func TestOpenAPISpec_SchemasFromStructs(t *testing.T) {
+	doc := getOpenAPISpec(t)
+
+	visit, ok := doc.Components.Schemas["VisitResponse"]
+	if !ok {
+		t.Fatal("expected a VisitResponse schema")
+	}
+	for _, field := range []string{"id", "countryId", "visitedAt", "notes", "country"} {
+		if _, ok := visit.Properties[field]; !ok {
+			t.Errorf("expected VisitResponse.%s in the schema", field)
+		}
+	}
+	if ref := visit.Properties["country"]["$ref"]; ref != "#/components/schemas/CountryResponse" {
+		t.Errorf("expected country to reference CountryResponse, got %v", ref)
+	}
+
+	// Required bindings become required properties
+	create := doc.Components.Schemas["CreateScrapbookEntryRequest"]
+	if !reflect.DeepEqual(create.Required, []string{"countryId", "title"}) {
+		t.Errorf("expected countryId and title to be required, got %v", create.Required)
+	}
+
+	// Embedded structs are flattened like encoding/json does
+	search := doc.Components.Schemas["CountrySearchResult"]
+	for _, field := range []string{"isoCode", "name", "highlight"} {
+		if _, ok := search.Properties[field]; !ok {
+			t.Errorf("expected CountrySearchResult.%s in the schema", field)
+		}
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	path, params := openAPIPath("/api/v1/scrapbook/entries/:id/media/order")
+	if path != "/api/v1/scrapbook/entries/{id}/media/order" || !reflect.DeepEqual(params, []string{"id"}) {
+		t.Errorf("unexpected conversion: %s %v", path, params)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultMaxPageSize is the default for the largest page a client may request
+	defaultMaxPageSize = 200
+	// defaultPageSize is the page size used when only page is given
+	defaultPageSize = 50
+)
+
+// pageSizeCap returns n as a page size cap, or the default if n is not positive
+func pageSizeCap(n int) int {
+	if n > 0 {
+		return n
+	}
+	return defaultMaxPageSize
+}
+
+// Pagination is the page of rows a list request asks for.
+// The zero value means the client did not ask for paging and gets every row.
+type Pagination struct {
+	limit  int
+	offset int
+}
+
+// NewPagination builds a page from 1-based page and pageSize, clamping out
+// of range values: page below 1 becomes 1, pageSize below 1 becomes the
+// default and pageSize above maxSize becomes maxSize
+func NewPagination(page, pageSize, maxSize int) Pagination {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if maxSize > 0 && pageSize > maxSize {
+		pageSize = maxSize
+	}
+	return Pagination{limit: pageSize, offset: (page - 1) * pageSize}
+}
+
+// Limit returns the maximum number of rows to return, or 0 for all rows
+func (p Pagination) Limit() int {
+	return p.limit
+}
+
+// Offset returns the number of rows to skip
+func (p Pagination) Offset() int {
+	return p.offset
+}
+
+// PageInfo describes the page returned by a paginated list endpoint
+type PageInfo struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"totalPages"`
+}
+
+// Info returns the pagination metadata for a list of total rows,
+// or nil if the client did not ask for paging
+func (p Pagination) Info(total int64) *PageInfo {
+	if p.limit == 0 {
+		return nil
+	}
+	size := int64(p.limit)
+	return &PageInfo{
+		Page:       p.offset/p.limit + 1,
+		PageSize:   p.limit,
+		Total:      total,
+		TotalPages: (total + size - 1) / size,
+	}
+}
+
+// apply restricts a query to the requested page
+func (p Pagination) apply(query *gorm.DB) *gorm.DB {
+	if p.limit > 0 {
+		query = query.Limit(p.limit)
+	}
+	if p.offset > 0 {
+		query = query.Offset(p.offset)
+	}
+	return query
+}
+
+// parsePagination reads the optional paging query params: either page and
+// pageSize, or limit and offset. Both forms share the size cap maxSize.
+// Unlike NewPagination, values a client sent are validated rather than
+// clamped. Writes a 400 response and returns false if any is invalid.
+func parsePagination(c *gin.Context, maxSize int) (Pagination, bool) {
+	pageStr, sizeStr := c.Query("page"), c.Query("pageSize")
+	if pageStr == "" && sizeStr == "" {
+		return parseLimitOffset(c, maxSize)
+	}
+
+	if c.Query("limit") != "" || c.Query("offset") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "use either page and pageSize or limit and offset"})
+		return Pagination{}, false
+	}
+
+	var size int
+	if sizeStr != "" {
+		parsed, err := strconv.Atoi(sizeStr)
+		if err != nil || parsed < 1 || parsed > maxSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pageSize must be between 1 and " + strconv.Itoa(maxSize)})
+			return Pagination{}, false
+		}
+		size = parsed
+	}
+
+	page := 1
+	if pageStr != "" {
+		parsed, err := strconv.Atoi(pageStr)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return Pagination{}, false
+		}
+		page = parsed
+	}
+
+	return NewPagination(page, size, maxSize), true
+}
+
+// parseLimitOffset reads the optional limit and offset query params
+func parseLimitOffset(c *gin.Context, maxSize int) (Pagination, bool) {
+	var p Pagination
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxSize)})
+			return p, false
+		}
+		p.limit = limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return p, false
+		}
+		p.offset = offset
+	}
+
+	return p, true
+}
+
+// listResponse builds a list response body with the total and, when paging
+// was requested, its metadata
+func listResponse(key string, items any, total int64, p Pagination) gin.H {
+	body := gin.H{key: items, "total": total}
+	if info := p.Info(total); info != nil {
+		body["pagination"] = info
+	}
+	return body
+}
+
+// pageOf returns the requested page of rows already loaded in memory
+func pageOf[T any](p Pagination, rows []T) []T {
+	if p.offset >= len(rows) {
+		return nil
+	}
+	rows = rows[p.offset:]
+	if p.limit > 0 && p.limit < len(rows) {
+		rows = rows[:p.limit]
+	}
+	return rows
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   Pagination
+		wantOK bool
+	}{
+		{"", Pagination{}, true},
+		{"?limit=10", Pagination{limit: 10}, true},
+		{"?limit=10&offset=20", Pagination{limit: 10, offset: 20}, true},
+		{"?offset=5", Pagination{offset: 5}, true},
+		{"?limit=200", Pagination{limit: 200}, true},
+		{"?limit=201", Pagination{}, false},
+		{"?limit=0", Pagination{}, false},
+		{"?limit=ten", Pagination{}, false},
+		{"?offset=-1", Pagination{}, false},
+		{"?page=1", Pagination{limit: defaultPageSize}, true},
+		{"?page=3&pageSize=10", Pagination{limit: 10, offset: 20}, true},
+		{"?pageSize=200", Pagination{limit: 200}, true},
+		{"?pageSize=201", Pagination{}, false},
+		{"?pageSize=0", Pagination{}, false},
+		{"?page=0", Pagination{}, false},
+		{"?page=two", Pagination{}, false},
+		{"?page=2&limit=10", Pagination{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+
+			got, ok := parsePagination(c, defaultMaxPageSize)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("expected status 400, got %d", w.Code)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePagination_MaxSize(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?page=1", nil)
+
+	got, ok := parsePagination(c, 20)
+	if !ok || got.Limit() != 20 {
+		t.Errorf("expected default page size capped at 20, got %+v (ok=%v)", got, ok)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?pageSize=21", nil)
+	if _, ok := parsePagination(c, 20); ok {
+		t.Error("expected pageSize above the configured max to be rejected")
+	}
+}
+
+func TestPageSizeCap(t *testing.T) {
+	if got := pageSizeCap(20); got != 20 {
+		t.Errorf("expected 20, got %d", got)
+	}
+	if got := pageSizeCap(0); got != defaultMaxPageSize {
+		t.Errorf("expected the default for 0, got %d", got)
+	}
+}
+
+func TestPaginationInfo(t *testing.T) {
+	if info := (Pagination{}).Info(10); info != nil {
+		t.Errorf("expected no metadata without paging, got %+v", info)
+	}
+
+	info := Pagination{limit: 4, offset: 8}.Info(10)
+	want := PageInfo{Page: 3, PageSize: 4, Total: 10, TotalPages: 3}
+	if info == nil || *info != want {
+		t.Errorf("expected %+v, got %+v", want, info)
+	}
+}
+
+func TestNewPagination(t *testing.T) {
+	tests := []struct {
+		name           string
+		page, pageSize int
+		wantLimit      int
+		wantOffset     int
+	}{
+		{"defaults", 0, 0, defaultPageSize, 0},
+		{"explicit", 3, 10, 10, 20},
+		{"negative page", -2, 10, 10, 0},
+		{"negative page size", 2, -5, defaultPageSize, defaultPageSize},
+		{"over max", 2, 500, 200, 200},
+		{"at max", 1, 200, 200, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPagination(tt.page, tt.pageSize, 200)
+			if p.Limit() != tt.wantLimit || p.Offset() != tt.wantOffset {
+				t.Errorf("expected limit %d offset %d, got limit %d offset %d",
+					tt.wantLimit, tt.wantOffset, p.Limit(), p.Offset())
+			}
+		})
+	}
+}
+
+func TestNewPagination_DefaultAboveMax(t *testing.T) {
+	p := NewPagination(1, 0, 20)
+	if p.Limit() != 20 {
+		t.Errorf("expected the default page size clamped to 20, got %d", p.Limit())
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errStaleUpdate is returned when a resource changed after the client last read it
+const errStaleUpdate = "entry was modified since it was last read"
+
+// errModifiedSinceRead is returned by saveUnmodified when the row changed
+// after it was read
+var errModifiedSinceRead = errors.New(errStaleUpdate)
+
+// setLastModified sets the Last-Modified header so clients can send it back
+// as If-Unmodified-Since on their next update
+func setLastModified(c *gin.Context, updatedAt time.Time) {
+	c.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+}
+
+// checkUnmodified enforces optimistic concurrency for an update. The client's
+// last-seen version comes from the If-Unmodified-Since header or, failing
+// that, the version value from the request body (an RFC 3339 updatedAt as
+// returned in responses). Both have one-second precision, so stored is
+// compared at that granularity. With neither set the update is unconditional.
+// conditional reports whether the client sent a precondition, in which case
+// the write must also go through saveUnmodified. Writes a 400 or 412 response
+// and returns ok false if the update must not proceed.
+func checkUnmodified(c *gin.Context, stored time.Time, version string) (conditional, ok bool) {
+	var seen time.Time
+	if header := strings.TrimSpace(c.GetHeader("If-Unmodified-Since")); header != "" {
+		parsed, err := http.ParseTime(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Unmodified-Since header"})
+			return true, false
+		}
+		seen = parsed
+	} else if version = strings.TrimSpace(version); version != "" {
+		parsed, err := time.Parse(time.RFC3339, version)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid updatedAt, expected RFC 3339"})
+			return true, false
+		}
+		seen = parsed
+	} else {
+		return false, true
+	}
+
+	if stored.Truncate(time.Second).After(seen) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": errStaleUpdate})
+		return true, false
+	}
+	return true, true
+}
+
+// saveUnmodified saves every field of value, a model read with updated_at
+// readAt, only if the row still has that updated_at. Another update committed
+// between the read and this write makes it return errModifiedSinceRead
+// instead of being overwritten.
+func saveUnmodified(tx *gorm.DB, value any, readAt time.Time) error {
+	result := tx.Select("*").Where("updated_at = ?", readAt).Save(value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errModifiedSinceRead
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckUnmodified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stored := time.Date(2024, 6, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name        string
+		header      string
+		version     string
+		conditional bool
+		wantOK      bool
+		want        int
+	}{
+		{"unconditional", "", "", false, true, 0},
+		{"header current", stored.Format(http.TimeFormat), "", true, true, 0},
+		{"header stale", stored.Add(-time.Minute).Format(http.TimeFormat), "", true, false, http.StatusPreconditionFailed},
+		{"header invalid", "yesterday", "", true, false, http.StatusBadRequest},
+		{"version current", "", "2024-06-01T14:00:00+02:00", true, true, 0},
+		{"version stale", "", "2024-06-01T11:59:59Z", true, false, http.StatusPreconditionFailed},
+		{"version invalid", "", "2024-06-01", true, false, http.StatusBadRequest},
+		{"header wins over version", stored.Format(http.TimeFormat), "2024-01-01T00:00:00Z", true, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Unmodified-Since", tt.header)
+			}
+
+			conditional, ok := checkUnmodified(c, stored, tt.version)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if conditional != tt.conditional {
+				t.Errorf("expected conditional=%v, got %v", tt.conditional, conditional)
+			}
+			if !ok && w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestSaveUnmodified(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Original"}
+	db.Create(entry)
+
+	// Two clients read the same version
+	var first, second models.ScrapbookEntry
+	db.First(&first, entry.ID)
+	db.First(&second, entry.ID)
+
+	first.Title = "First"
+	if err := saveUnmodified(db, &first, second.UpdatedAt); err != nil {
+		t.Fatalf("expected the first write to succeed, got %v", err)
+	}
+
+	// The second write was checked against a version that is now gone
+	readAt := second.UpdatedAt
+	second.Title = "Second"
+	if err := saveUnmodified(db, &second, readAt); !errors.Is(err, errModifiedSinceRead) {
+		t.Fatalf("expected errModifiedSinceRead, got %v", err)
+	}
+
+	var stored models.ScrapbookEntry
+	db.First(&stored, entry.ID)
+	if stored.Title != "First" {
+		t.Errorf("expected the first write to be kept, got %q", stored.Title)
+	}
+}
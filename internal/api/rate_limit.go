@@ -0,0 +1,60 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows up to max events per key in each fixed window. It is
+// safe for concurrent use and only keeps state in memory, so limits are per
+// process and reset on restart.
+type rateLimiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow counts one key's events since start
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter creates a limiter allowing max events per key per window
+func newRateLimiter(max int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		max:     max,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the
+// limit. When it is not, the second result is how long until the key's
+// window resets.
+func (l *rateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for k, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, k)
+		}
+	}
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.max {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, time.Hour)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("10.0.0.1"); !ok {
+			t.Fatalf("event %d: expected to be allowed", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("10.0.0.1")
+	if ok {
+		t.Fatal("expected third event to be limited")
+	}
+	if retryAfter != time.Hour {
+		t.Errorf("expected retry after 1h, got %v", retryAfter)
+	}
+
+	// Keys are limited independently
+	if ok, _ := limiter.Allow("10.0.0.2"); !ok {
+		t.Error("expected another key to be allowed")
+	}
+
+	now = now.Add(time.Hour)
+	if ok, _ := limiter.Allow("10.0.0.1"); !ok {
+		t.Error("expected events to be allowed once the window resets")
+	}
+}
+
+func TestRateLimiter_Concurrent(t *testing.T) {
+	limiter := newRateLimiter(10, time.Hour)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := limiter.Allow("10.0.0.1"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 10 {
+		t.Errorf("expected exactly 10 events allowed, got %d", got)
+	}
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
-	"log"
+	"io/fs"
+	"log/slog"
+	"time"
 
 	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/mail"
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/storage"
+	"globe-expedition-journal/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,12 +17,14 @@ import (
 
 // NewRouter creates and configures the Gin router
 func NewRouter() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.RequestLogger(slog.Default()), gin.Recovery())
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/health", HealthCheck)
+		v1.GET("/openapi.json", OpenAPISpec)
 	}
 
 	return router
@@ -28,8 +34,67 @@ func NewRouter() *gin.Engine {
 type RouterConfig struct {
 	SessionSecret string
 	SessionMaxAge int
-	DemoMode      bool   // Enable demo login without LTI
-	UploadsDir    string // Directory for file uploads
+	SessionLeeway time.Duration // Clock skew tolerated on session expiry
+	DemoMode      bool          // Enable demo login without LTI
+	DemoUserTTL   int           // Seconds of inactivity before per-session demo users are purged
+	UploadsDir    string        // Directory for file uploads
+
+	// PreviousSessionSecrets are retired secrets whose sessions are still
+	// accepted after a rotation
+	PreviousSessionSecrets []string
+
+	DemoSessionTTL int // Seconds demo sessions last, capped at SessionMaxAge
+	DemoLoginLimit int // New demo users each client IP may create per hour; 0 for no limit
+
+	DefaultTimezone string // IANA zone used to render timestamps without ?tz=
+
+	RejectAnimatedUploads bool // Reject multi-frame GIF/WebP uploads
+	PreserveUploadNames   bool // Prefix stored filenames with the sanitized original name
+	MaxUploadsPerDay      int  // Uploads a user may make per UTC day; 0 for no limit
+	MaxMediaPerEntry      int  // Media items one scrapbook entry may have, however uploaded; 0 for no limit
+
+	UploadAllowedExtensions []string // Extensions uploaded filenames may have, e.g. ".jpg"; any if empty
+
+	UploadCacheMaxAge time.Duration // How long clients may cache served uploads; defaults to a year
+
+	LTIStateStore string // "memory" or "database" for multi-instance deployments
+
+	PublicBaseURL         string   // Canonical external URL used for the LTI launch URL
+	TrustedProxies        []string // Proxy IPs/CIDRs whose X-Forwarded-* headers are honored
+	TrustForwardedHeaders bool     // Honor X-Forwarded-* from any client (development only)
+	RedirectOrigins       []string // Origins besides this server that LTI launches may redirect to
+
+	Logger *slog.Logger // Request and startup logging; slog.Default() if nil
+
+	// Frontend is a built single-page app, such as an embed.FS or os.DirFS,
+	// served for paths outside the API with index.html as the fallback.
+	// Disabled if nil.
+	Frontend fs.FS
+
+	MaxPageSize int // Largest page size list endpoints accept
+
+	// SessionManager signs and validates sessions; built from the session
+	// settings above if nil. Leeway and previous secrets are applied to it.
+	SessionManager *lti.SessionManager
+
+	MaxNotesLength int  // Longest visit or entry notes accepted, in characters; defaults to 20000
+	StripHTML      bool // Remove HTML tags from titles and notes before saving
+
+	MaxVisitsPerUser  int // Visits each user may record; 0 for no limit
+	MaxEntriesPerUser int // Scrapbook entries each user may create; 0 for no limit
+
+	ReadOnly bool // Reject writes with 503 during maintenance; reads still work
+
+	WebhookURL    string // Receives visit and scrapbook creation events; disabled if empty
+	WebhookSecret string // Shared secret used to sign webhook payloads
+
+	OwnerComments bool // Let entry owners comment on their own entries as well as instructors
+
+	SMTPHost     string // SMTP server for notification email; disabled if empty
+	SMTPPort     int    // SMTP port; 587 if unset
+	SMTPUsername string // Optional SMTP credentials
+	SMTPPassword string
+	SMTPFrom     string // Sender address of notification email
 }
 
 // DefaultRouterConfig returns the default router configuration
@@ -37,8 +102,23 @@ func DefaultRouterConfig() RouterConfig {
 	return RouterConfig{
 		SessionSecret: "change-me-in-production",
 		SessionMaxAge: 86400,
+		SessionLeeway: lti.DefaultSessionLeeway,
 		DemoMode:      true,        // Enable by default for dev
+		DemoUserTTL:   86400,       // Purge per-session demo users after 24 hours
 		UploadsDir:    "./uploads", // Default uploads directory
+
+		DemoSessionTTL: 3600,
+		DemoLoginLimit: 10,
+
+		DefaultTimezone: "UTC",
+		LTIStateStore:   "memory",
+
+		TrustForwardedHeaders: true, // Dev servers commonly sit behind an untracked proxy
+
+		MaxPageSize: defaultMaxPageSize,
+
+		MaxUploadsPerDay: 100,
+		MaxMediaPerEntry: 10,
 	}
 }
 
@@ -49,55 +129,163 @@ func NewRouterWithDB(db *gorm.DB) *gin.Engine {
 
 // NewRouterWithConfig creates a router with custom configuration
 func NewRouterWithConfig(db *gorm.DB, cfg RouterConfig) *gin.Engine {
-	router := gin.Default()
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	router := gin.New()
+	if len(cfg.TrustedProxies) > 0 {
+		if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+			logger.Warn("invalid trusted proxies", "error", err)
+		}
+	}
+	router.Use(middleware.RequestID(), middleware.RequestLogger(logger), gin.Recovery())
 
 	// CORS middleware for development
 	if cfg.DemoMode {
 		router.Use(corsMiddleware())
 	}
 
+	// Timestamps are stored in UTC and rendered in the default zone unless ?tz= is given
+	defaultLoc, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		logger.Warn("invalid default timezone, using UTC", "timezone", cfg.DefaultTimezone)
+		defaultLoc = time.UTC
+	}
+	router.Use(TimezoneMiddleware(defaultLoc))
+
+	// Maintenance mode: reads keep working and users can still log out, but
+	// every other write, including LTI launches and demo logins, is refused
+	if cfg.ReadOnly {
+		router.Use(middleware.ReadOnly("/api/v1/logout"))
+		logger.Warn("read-only mode enabled - writes are rejected with 503")
+	}
+
 	// Create session manager for auth middleware
-	sessionManager := lti.NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	sessionManager := cfg.SessionManager
+	if sessionManager == nil {
+		sessionManager = lti.NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	}
+	sessionManager.SetLeeway(cfg.SessionLeeway)
+	sessionManager.SetPreviousSecrets(cfg.PreviousSessionSecrets)
 
 	// API v1 routes - public
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/health", HealthCheck)
+		v1.GET("/openapi.json", OpenAPISpec)
+	}
+
+	// File storage for uploads
+	storageConfig := storage.DefaultConfig()
+	storageConfig.UploadsDir = cfg.UploadsDir
+	storageConfig.RejectAnimated = cfg.RejectAnimatedUploads
+	storageConfig.PreserveOriginalNames = cfg.PreserveUploadNames
+	storageConfig.AllowedExtensions = cfg.UploadAllowedExtensions
+	var mediaStorage storage.Storage
+	localStorage, err := storage.NewLocalStorage(storageConfig)
+	if err != nil {
+		logger.Warn("failed to initialize storage", "error", err)
+		localStorage = nil
+	} else {
+		mediaStorage = localStorage
 	}
 
 	// Demo routes (dev mode only)
 	if cfg.DemoMode {
 		demoHandler := NewDemoHandler(db, sessionManager)
+		demoHandler.SetSessionTTL(time.Duration(cfg.DemoSessionTTL) * time.Second)
+		demoHandler.SetLoginLimit(cfg.DemoLoginLimit)
+		demoHandler.SetStorage(mediaStorage)
+		demoHandler.StartCleanup(time.Duration(cfg.DemoUserTTL) * time.Second)
 		demo := router.Group("/api/v1/demo")
+		demo.Use(middleware.OptionalAuthMiddleware(sessionManager))
 		{
 			demo.POST("/login", demoHandler.DemoLogin)
+			demo.POST("/seed", demoHandler.DemoSeed)
 		}
-		log.Println("Demo mode enabled: POST /api/v1/demo/login")
+		logger.Warn("demo mode enabled - anyone can log in without credentials. Do not expose this server publicly.",
+			"routes", "POST /api/v1/demo/login, POST /api/v1/demo/seed")
 	}
 
 	// Country routes (public, read-only)
 	countryHandler := NewCountryHandler(db)
+	countryHandler.SetMaxPageSize(cfg.MaxPageSize)
 	countries := router.Group("/api/v1/countries")
+	countries.Use(middleware.OptionalAuthMiddleware(sessionManager)) // Session locale localizes names
 	{
 		countries.GET("", countryHandler.ListCountries)
 		countries.GET("/regions", countryHandler.ListRegions)
 		countries.GET("/search", countryHandler.SearchCountries)
+		countries.GET("/popular", countryHandler.ListPopularCountries)
 		countries.GET("/code/:code", countryHandler.GetCountryByCode)
+		countries.GET("/code3/:code", countryHandler.GetCountryByCode3)
 		countries.GET("/:id", countryHandler.GetCountry)
 	}
 
 	// API v1 routes - authenticated
-	userHandler := NewUserHandler(db)
+	userHandler := NewUserHandler(db, sessionManager, mediaStorage)
 	visitHandler := NewVisitHandler(db)
-	scrapbookHandler := NewScrapbookHandler(db)
+	scrapbookHandler := NewScrapbookHandler(db, mediaStorage)
+
+	// Outbound webhooks on creation, delivered in the background
+	webhookCfg := webhook.DefaultConfig(cfg.WebhookURL, cfg.WebhookSecret)
+	webhookCfg.Logger = logger
+	notifier := webhook.NewNotifier(webhookCfg)
+	visitHandler.SetNotifier(notifier)
+	scrapbookHandler.SetNotifier(notifier)
+
+	textPolicy := TextPolicy{MaxNotesLength: cfg.MaxNotesLength, StripHTML: cfg.StripHTML}
+	visitHandler.SetTextPolicy(textPolicy)
+	scrapbookHandler.SetTextPolicy(textPolicy)
+	visitHandler.SetMaxVisits(cfg.MaxVisitsPerUser)
+	scrapbookHandler.SetMaxEntries(cfg.MaxEntriesPerUser)
+	scrapbookHandler.SetMaxMediaPerEntry(cfg.MaxMediaPerEntry)
+	visitHandler.SetMaxPageSize(cfg.MaxPageSize)
+	scrapbookHandler.SetMaxPageSize(cfg.MaxPageSize)
+
+	// Email to learners when an instructor comments; a no-op without SMTP_HOST
+	mailCfg := mail.DefaultConfig(cfg.SMTPHost)
+	if cfg.SMTPPort != 0 {
+		mailCfg.Port = cfg.SMTPPort
+	}
+	mailCfg.Username = cfg.SMTPUsername
+	mailCfg.Password = cfg.SMTPPassword
+	mailCfg.From = cfg.SMTPFrom
+	commentHandler := NewCommentHandler(db)
+	commentHandler.SetMailer(mail.NewSender(mailCfg))
+	commentHandler.SetOwnerComments(cfg.OwnerComments)
+	feedbackHandler := NewFeedbackHandler(db)
+	courseReportHandler := NewCourseReportHandler(db)
+
 	v1Auth := router.Group("/api/v1")
-	v1Auth.Use(middleware.AuthMiddleware(sessionManager))
+	v1Auth.Use(middleware.AuthMiddleware(sessionManager), middleware.RequireActiveUser(db))
 	{
 		v1Auth.GET("/me", userHandler.GetMe)
+		v1Auth.PUT("/me", userHandler.UpdateMe)
+		v1Auth.GET("/me/export", userHandler.ExportMe)
+		v1Auth.GET("/me/passport", userHandler.GetPassport)
+		v1Auth.DELETE("/me", userHandler.DeleteMe)
 		v1Auth.POST("/logout", userHandler.Logout)
+		v1Auth.GET("/me/feedback", feedbackHandler.GetMyFeedback)
+
+		// Instructor feedback on learners in the session's course
+		course := v1Auth.Group("/course", middleware.RequireInstructor(), middleware.RequireCourse())
+		course.PUT("/learners/:userId/feedback", feedbackHandler.PutLearnerFeedback)
+		course.GET("/report.csv", courseReportHandler.ExportReport)
+
+		// Country routes scoped to the user
+		v1Auth.GET("/countries/unvisited", countryHandler.ListUnvisitedCountries)
+		v1Auth.GET("/me/suggestions", countryHandler.ListSuggestions)
+		v1Auth.GET("/countries/:id/overview", countryHandler.GetCountryOverview)
+		v1Auth.GET("/countries/:id/class-stats", middleware.RequireInstructor(), middleware.RequireCourse(), countryHandler.GetCountryClassStats)
 
 		// Visit routes
 		v1Auth.GET("/visits", visitHandler.ListVisits)
+		v1Auth.GET("/visits/timeline", visitHandler.GetTimeline)
+		v1Auth.GET("/visits/counts", visitHandler.GetVisitCounts)
+		v1Auth.GET("/visits/countries", visitHandler.GetVisitedCountries)
 		v1Auth.POST("/visits", visitHandler.CreateVisit)
 		v1Auth.GET("/visits/:id", visitHandler.GetVisit)
 		v1Auth.PUT("/visits/:id", visitHandler.UpdateVisit)
@@ -107,44 +295,60 @@ func NewRouterWithConfig(db *gorm.DB, cfg RouterConfig) *gin.Engine {
 		// Scrapbook routes
 		v1Auth.GET("/scrapbook/entries", scrapbookHandler.ListEntries)
 		v1Auth.POST("/scrapbook/entries", scrapbookHandler.CreateEntry)
+		v1Auth.POST("/scrapbook/entries/bulk-delete", scrapbookHandler.BulkDeleteEntries)
 		v1Auth.GET("/scrapbook/entries/:id", scrapbookHandler.GetEntry)
 		v1Auth.PUT("/scrapbook/entries/:id", scrapbookHandler.UpdateEntry)
 		v1Auth.DELETE("/scrapbook/entries/:id", scrapbookHandler.DeleteEntry)
+		v1Auth.PATCH("/scrapbook/entries/:id/pin", scrapbookHandler.PinEntry)
+		v1Auth.PUT("/scrapbook/entries/:id/media/order", scrapbookHandler.ReorderMedia)
+		v1Auth.GET("/scrapbook/entries/:id/comments", commentHandler.ListComments)
+		v1Auth.POST("/scrapbook/entries/:id/comments", commentHandler.CreateComment)
 		v1Auth.GET("/scrapbook/countries/:countryId/entries", scrapbookHandler.GetEntriesByCountry)
 		v1Auth.GET("/scrapbook/stats", scrapbookHandler.GetStats)
+		v1Auth.POST("/scrapbook/tags/rename", scrapbookHandler.RenameTag)
 	}
 
 	// File upload handling
-	storageConfig := storage.DefaultConfig()
-	storageConfig.UploadsDir = cfg.UploadsDir
-	localStorage, err := storage.NewLocalStorage(storageConfig)
-	if err != nil {
-		log.Printf("Warning: failed to initialize storage: %v", err)
-	} else {
-		uploadHandler := NewUploadHandler(localStorage)
+	if localStorage != nil {
+		uploadHandler := NewUploadHandler(db, localStorage)
+		uploadHandler.SetLimits(cfg.MaxUploadsPerDay, cfg.MaxMediaPerEntry)
+		uploadHandler.SetCacheMaxAge(cfg.UploadCacheMaxAge)
 		v1Auth := router.Group("/api/v1")
-		v1Auth.Use(middleware.AuthMiddleware(sessionManager))
+		v1Auth.Use(middleware.AuthMiddleware(sessionManager), middleware.RequireActiveUser(db))
 		{
+			v1Auth.GET("/me/storage", uploadHandler.GetStorageUsage)
 			v1Auth.POST("/upload", uploadHandler.Upload)
 			v1Auth.DELETE("/upload/:filename", uploadHandler.Delete)
+			v1Auth.GET("/upload/:filename/info", uploadHandler.Info)
 		}
 
-		// Static file serving for uploads
-		router.Static("/uploads", cfg.UploadsDir)
-		log.Printf("Serving uploads from: %s", cfg.UploadsDir)
+		// Uploaded files, served with cache and content type headers
+		router.GET("/uploads/:filename", uploadHandler.Serve)
+		router.HEAD("/uploads/:filename", uploadHandler.Serve)
+		logger.Info("serving uploads", "dir", cfg.UploadsDir)
 	}
 
 	// Initialize key manager for JWKS
 	keyManager, err := lti.NewKeyManager()
 	if err != nil {
-		log.Printf("Warning: failed to initialize key manager: %v", err)
+		logger.Warn("failed to initialize key manager", "error", err)
 	}
 
 	// LTI routes
 	ltiHandler := lti.NewHandlerWithConfig(db, lti.HandlerConfig{
 		SessionSecret: cfg.SessionSecret,
 		SessionMaxAge: cfg.SessionMaxAge,
+		SessionLeeway: cfg.SessionLeeway,
 		FrontendURL:   "/",
+		StateStore:    cfg.LTIStateStore,
+
+		PreviousSessionSecrets: cfg.PreviousSessionSecrets,
+		SessionManager:         sessionManager,
+
+		PublicBaseURL:         cfg.PublicBaseURL,
+		TrustedProxies:        cfg.TrustedProxies,
+		TrustForwardedHeaders: cfg.TrustForwardedHeaders,
+		RedirectOrigins:       cfg.RedirectOrigins,
 	})
 	ltiGroup := router.Group("/lti")
 	{
@@ -153,6 +357,24 @@ func NewRouterWithConfig(db *gorm.DB, cfg RouterConfig) *gin.Engine {
 		ltiGroup.POST("/launch", ltiHandler.Launch)
 	}
 
+	// Admin routes - instructors only
+	adminCountryHandler := NewAdminCountryHandler(db)
+	adminCourseHandler := NewAdminCourseHandler(db, mediaStorage)
+	adminCourseHandler.SetMaxPageSize(cfg.MaxPageSize)
+	admin := router.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(sessionManager), middleware.RequireInstructor(), middleware.RequireActiveUser(db))
+	{
+		admin.POST("/platforms/:id/test", ltiHandler.TestPlatform)
+		admin.GET("/launches", ltiHandler.ListLaunches)
+		admin.POST("/countries", adminCountryHandler.CreateCountry)
+		admin.POST("/countries/reseed", adminCountryHandler.ReseedCountries)
+		admin.PUT("/countries/:id", adminCountryHandler.UpdateCountry)
+		admin.DELETE("/countries/:id", adminCountryHandler.DeleteCountry)
+		adminCourse := admin.Group("/course", middleware.RequireCourse())
+		adminCourse.GET("/users", adminCourseHandler.ListCourseUsers)
+		adminCourse.DELETE("/users/:id/data", adminCourseHandler.PurgeCourseUserData)
+	}
+
 	// JWKS endpoint (well-known)
 	if keyManager != nil {
 		jwksHandler := lti.NewJWKSHandler(keyManager)
@@ -162,6 +384,14 @@ func NewRouterWithConfig(db *gorm.DB, cfg RouterConfig) *gin.Engine {
 		}
 	}
 
+	// Built frontend, for anything no route above matched
+	if cfg.Frontend != nil {
+		if _, err := fs.Stat(cfg.Frontend, spaIndex); err != nil {
+			logger.Warn("frontend has no "+spaIndex, "error", err)
+		}
+		router.NoRoute(spaHandler(cfg.Frontend))
+	}
+
 	return router
 }
 
@@ -185,8 +415,9 @@ func corsMiddleware() gin.HandlerFunc {
 
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+IdempotencyKeyHeader+", If-Unmodified-Since, "+middleware.RequestIDHeader)
+		c.Header("Access-Control-Expose-Headers", "Last-Modified, "+middleware.RequestIDHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
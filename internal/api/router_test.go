@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -66,3 +71,224 @@ func TestUnknownRoute_Returns404(t *testing.T) {
 		t.Errorf("expected status 404, got %d", w.Code)
 	}
 }
+
+func TestRouter_AdminRoutes_RequireInstructor(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.DemoMode = false
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	sm := lti.NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	learner, _ := sm.CreateToken(1, "canvas-1", "course-1", "learner")
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"unauthenticated", "", http.StatusUnauthorized},
+		{"learner", learner, http.StatusForbidden},
+	}
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/admin/platforms/1/test"},
+		{http.MethodGet, "/api/v1/admin/launches"},
+		{http.MethodPost, "/api/v1/admin/countries"},
+		{http.MethodPost, "/api/v1/admin/countries/reseed"},
+		{http.MethodPut, "/api/v1/admin/countries/1"},
+		{http.MethodDelete, "/api/v1/admin/countries/1"},
+		{http.MethodGet, "/api/v1/admin/course/users"},
+		{http.MethodDelete, "/api/v1/admin/course/users/1/data"},
+	}
+
+	for _, route := range routes {
+		for _, tt := range tests {
+			t.Run(route.method+" "+route.path+" "+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(route.method, route.path, nil)
+				if tt.token != "" {
+					req.AddCookie(&http.Cookie{Name: "session", Value: tt.token})
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if w.Code != tt.want {
+					t.Errorf("expected status %d, got %d", tt.want, w.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestRouter_CourseRoutes_RequireCourse(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.DemoMode = false
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	sm := lti.NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	instructor := &models.User{CanvasUserID: "canvas-1", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(instructor)
+	noCourse, _ := sm.CreateToken(instructor.ID, "canvas-1", "", "instructor")
+	inCourse, _ := sm.CreateToken(instructor.ID, "canvas-1", "course-1", "instructor")
+
+	routes := []struct {
+		method, path, token string
+	}{
+		{http.MethodGet, "/api/v1/course/report.csv", noCourse},
+		{http.MethodPut, "/api/v1/course/learners/2/feedback", noCourse},
+		{http.MethodGet, "/api/v1/countries/1/class-stats", noCourse},
+		{http.MethodGet, "/api/v1/admin/course/users", noCourse},
+		{http.MethodDelete, "/api/v1/admin/course/users/2/data", noCourse},
+		{http.MethodGet, "/api/v1/admin/course/users?courseId=course-2", inCourse},
+		{http.MethodGet, "/api/v1/countries/1/class-stats?courseId=course-2", inCourse},
+	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: route.token})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected status 403, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_MaxPageSizePerRouter(t *testing.T) {
+	db := setupDemoTestDB(t)
+	small := DefaultRouterConfig()
+	small.UploadsDir = t.TempDir()
+	small.MaxPageSize = 10
+	smallRouter := NewRouterWithConfig(db, small)
+
+	// A second router must not change the first one's limit
+	large := DefaultRouterConfig()
+	large.UploadsDir = t.TempDir()
+	large.MaxPageSize = 500
+	largeRouter := NewRouterWithConfig(db, large)
+
+	for _, tt := range []struct {
+		name   string
+		router *gin.Engine
+		want   int
+	}{
+		{"small", smallRouter, http.StatusBadRequest},
+		{"large", largeRouter, http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/countries?pageSize=20", nil)
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_ServesOpenAPISpec(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var doc openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected the spec to be valid JSON: %v", err)
+	}
+	if doc.OpenAPI == "" {
+		t.Error("expected the served document to be the OpenAPI spec")
+	}
+}
+
+func TestRouter_CORSPreflightAllowsPatch(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/scrapbook/entries/1/pin", nil)
+	req.Header.Set("Origin", "http://localhost:8081")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PATCH") {
+		t.Errorf("expected PATCH in allowed methods, got %q", methods)
+	}
+}
+
+func TestRouter_ResponsesCarryRequestID(t *testing.T) {
+	db := setupDemoTestDB(t)
+	cfg := DefaultRouterConfig()
+	cfg.UploadsDir = t.TempDir()
+	router := NewRouterWithConfig(db, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get(middleware.RequestIDHeader) == "" {
+		t.Error("expected a generated request ID in the response")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set(middleware.RequestIDHeader, "client-id-1")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(middleware.RequestIDHeader); got != "client-id-1" {
+		t.Errorf("expected the client's request ID to be echoed, got %q", got)
+	}
+}
+
+func TestRouter_ReadOnly(t *testing.T) {
+	db := setupDemoTestDB(t)
+	user := models.User{CanvasUserID: "canvas-1", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(&user)
+
+	cfg := DefaultRouterConfig()
+	cfg.UploadsDir = t.TempDir()
+	cfg.ReadOnly = true
+	router := NewRouterWithConfig(db, cfg)
+
+	sm := lti.NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	token, _ := sm.CreateToken(user.ID, "canvas-1", "course-1", "learner")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(http.MethodPost, "/api/v1/visits", `{"countryId": 1}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected POST to be rejected with 503, got %d", w.Code)
+	}
+	var visits int64
+	db.Model(&models.Visit{}).Count(&visits)
+	if visits != 0 {
+		t.Errorf("expected no visit to be created, got %d", visits)
+	}
+
+	if w := send(http.MethodGet, "/api/v1/visits", ""); w.Code != http.StatusOK {
+		t.Errorf("expected GET to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/v1/logout", ""); w.Code != http.StatusOK {
+		t.Errorf("expected logout to be allowed, got %d", w.Code)
+	}
+}
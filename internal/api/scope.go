@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"globe-expedition-journal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotAuthenticated is returned by ScopedDB for requests without a user
+var ErrNotAuthenticated = errors.New("not authenticated")
+
+// OwnedBy is a GORM scope restricting a query to rows whose user_id is
+// userID. The column is qualified with the query's table, so the scope also
+// holds when other tables are joined in.
+func OwnedBy(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: "user_id"},
+			Value:  userID,
+		})
+	}
+}
+
+// ScopedDB returns db restricted to rows owned by the request's authenticated
+// user, so handlers state ownership once rather than in every query. The
+// result is safe to reuse for several queries. Only use it for models with a
+// user_id column, and not to start a transaction, where every statement
+// would be scoped; apply OwnedBy inside the transaction instead.
+func ScopedDB(c *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		return nil, ErrNotAuthenticated
+	}
+	return db.Scopes(OwnedBy(userID)).Session(&gorm.Session{}), nil
+}
+
+// userDB returns ScopedDB for the request along with the user's ID. Writes a
+// 401 response and returns false if the request is not authenticated.
+func userDB(c *gin.Context, db *gorm.DB) (*gorm.DB, uint, bool) {
+	scoped, err := ScopedDB(c, db)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, 0, false
+	}
+	userID, _ := middleware.GetUserID(c)
+	return scoped, userID, true
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopeTestContext returns a context authenticated as userID
+func scopeTestContext(userID uint) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(middleware.ContextKeyUserID, userID)
+	return c, w
+}
+
+func TestScopedDB_OnlyReturnsOwnRows(t *testing.T) {
+	db := setupTestDB(t)
+	db.Create(&models.Country{Name: "France", ISOCode: "FR"})
+	db.Create(&models.Country{Name: "Japan", ISOCode: "JP"})
+
+	mine := models.Visit{UserID: 1, CountryID: 1}
+	theirs := models.Visit{UserID: 2, CountryID: 1}
+	db.Create(&mine)
+	db.Create(&theirs)
+	db.Create(&models.Visit{UserID: 1, CountryID: 2})
+	db.Create(&models.ScrapbookEntry{UserID: 2, CountryID: 1, Title: "Theirs"})
+
+	c, _ := scopeTestContext(1)
+	scoped, err := ScopedDB(c, db)
+	if err != nil {
+		t.Fatalf("expected a scoped DB, got %v", err)
+	}
+
+	var visits []models.Visit
+	scoped.Find(&visits)
+	if len(visits) != 2 {
+		t.Fatalf("expected only the user's 2 visits, got %d", len(visits))
+	}
+	for _, v := range visits {
+		if v.UserID != 1 {
+			t.Errorf("scoped query returned a visit of user %d", v.UserID)
+		}
+	}
+
+	// Looking another user's row up by ID finds nothing
+	var visit models.Visit
+	if err := scoped.First(&visit, theirs.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected another user's visit to be not found, got %v", err)
+	}
+	if err := scoped.Where("user_id = ?", 2).First(&visit).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected an explicit user filter not to widen the scope, got %v", err)
+	}
+
+	// Conditions added for one query do not leak into the next
+	var french int64
+	scoped.Model(&models.Visit{}).Where("country_id = ?", 1).Count(&french)
+	var all int64
+	scoped.Model(&models.Visit{}).Count(&all)
+	if french != 1 || all != 2 {
+		t.Errorf("expected 1 French visit of 2, got %d of %d", french, all)
+	}
+
+	// The user column is qualified, so joins with other owned tables work
+	var joined int64
+	err = scoped.Model(&models.Visit{}).
+		Joins("JOIN scrapbook_entries ON scrapbook_entries.country_id = visits.country_id").
+		Count(&joined).Error
+	if err != nil || joined != 1 {
+		t.Errorf("expected 1 joined row, got %d (%v)", joined, err)
+	}
+
+	// Deletes are scoped too
+	if result := scoped.Delete(&models.Visit{}, theirs.ID); result.RowsAffected != 0 {
+		t.Errorf("expected another user's visit not to be deleted, got %d rows", result.RowsAffected)
+	}
+}
+
+func TestScopedDB_Unauthenticated(t *testing.T) {
+	db := setupTestDB(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	if _, err := ScopedDB(c, db); !errors.Is(err, ErrNotAuthenticated) {
+		t.Errorf("expected ErrNotAuthenticated, got %v", err)
+	}
+
+	if _, _, ok := userDB(c, db); ok {
+		t.Fatal("expected userDB to fail without a user")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
@@ -1,67 +1,236 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
+	"globe-expedition-journal/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// errDuplicateEntryTitle is returned when the optional unique title index,
+// see models.MigrateUniqueEntryTitles, rejects an entry
+const errDuplicateEntryTitle = "an entry with this title already exists for this country"
+
 // ScrapbookHandler handles scrapbook entry API endpoints
 type ScrapbookHandler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	storage    storage.Storage
+	webhooks   *webhook.Notifier
+	text       TextPolicy
+	maxEntries int // Entries each user may have; 0 for no limit
+	maxMedia   int // Media items each entry may have; 0 for no limit
+
+	maxPageSize int // Largest page size list requests may ask for
+}
+
+// NewScrapbookHandler creates a new scrapbook handler. The storage is used to
+// remove uploaded media when an entry is deleted and may be nil.
+func NewScrapbookHandler(db *gorm.DB, s storage.Storage) *ScrapbookHandler {
+	return &ScrapbookHandler{db: db, storage: s, text: DefaultTextPolicy(), maxPageSize: defaultMaxPageSize}
+}
+
+// SetMaxPageSize sets the largest page size list requests may ask for;
+// non-positive values keep the default
+func (h *ScrapbookHandler) SetMaxPageSize(n int) {
+	h.maxPageSize = pageSizeCap(n)
+}
+
+// SetNotifier sends a webhook event for each created entry; nil disables it
+func (h *ScrapbookHandler) SetNotifier(n *webhook.Notifier) {
+	h.webhooks = n
+}
+
+// SetTextPolicy sets the notes limit and HTML handling for entry titles and
+// notes; a non-positive notes limit keeps the default
+func (h *ScrapbookHandler) SetTextPolicy(p TextPolicy) {
+	h.text = p.withDefaults()
+}
+
+// SetMaxEntries caps how many scrapbook entries each user may create.
+// Creating one more returns 409 Conflict. Zero or less removes the cap.
+func (h *ScrapbookHandler) SetMaxEntries(n int) {
+	h.maxEntries = n
 }
 
-// NewScrapbookHandler creates a new scrapbook handler
-func NewScrapbookHandler(db *gorm.DB) *ScrapbookHandler {
-	return &ScrapbookHandler{db: db}
+// SetMaxMediaPerEntry caps how many media items an entry may have, however
+// the files were uploaded. Attaching more returns 400 Bad Request. Zero or
+// less removes the cap.
+func (h *ScrapbookHandler) SetMaxMediaPerEntry(n int) {
+	h.maxMedia = n
+}
+
+// checkMediaCount writes a 400 response and returns false if n media items
+// exceed the per-entry limit
+func (h *ScrapbookHandler) checkMediaCount(c *gin.Context, n int) bool {
+	if h.maxMedia > 0 && n > h.maxMedia {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "entry media limit reached",
+			"code":  errCodeEntryMediaLimit,
+			"limit": h.maxMedia,
+		})
+		return false
+	}
+	return true
 }
 
 // ScrapbookEntryResponse represents a scrapbook entry in API responses
 type ScrapbookEntryResponse struct {
-	ID        uint             `json:"id"`
-	CountryID uint             `json:"countryId"`
-	Title     string           `json:"title"`
-	Notes     string           `json:"notes,omitempty"`
-	MediaURL  string           `json:"mediaUrl,omitempty"`
-	MediaType string           `json:"mediaType,omitempty"`
-	Tags      string           `json:"tags,omitempty"`
-	VisitedAt string           `json:"visitedAt,omitempty"`
-	CreatedAt string           `json:"createdAt"`
-	UpdatedAt string           `json:"updatedAt"`
-	Country   *CountryResponse `json:"country,omitempty"`
+	ID        uint                 `json:"id"`
+	CountryID uint                 `json:"countryId"`
+	CourseID  string               `json:"courseId,omitempty"`
+	Title     string               `json:"title"`
+	Notes     string               `json:"notes,omitempty"`
+	MediaURL  string               `json:"mediaUrl,omitempty"`
+	MediaType string               `json:"mediaType,omitempty"`
+	Media     []ScrapbookMediaItem `json:"media,omitempty"` // In display order; includes the legacy mediaUrl
+	Tags      string               `json:"tags,omitempty"`
+	VisitedAt string               `json:"visitedAt,omitempty"`
+	Pinned    bool                 `json:"pinned"`
+	SortOrder *int                 `json:"sortOrder,omitempty"`
+	CreatedAt string               `json:"createdAt"`
+	UpdatedAt string               `json:"updatedAt"`
+	Country   *CountryResponse     `json:"country,omitempty"`
+}
+
+// ScrapbookMediaItem represents one media item of an entry
+type ScrapbookMediaItem struct {
+	ID   uint   `json:"id,omitempty"` // Set in responses, ignored in requests
+	URL  string `json:"url" binding:"required,max=512"`
+	Type string `json:"type,omitempty" binding:"max=50"`
 }
 
 // ScrapbookEntryListResponse represents the response for listing entries
 type ScrapbookEntryListResponse struct {
-	Entries []ScrapbookEntryResponse `json:"entries"`
-	Total   int64                    `json:"total"`
+	Entries    []ScrapbookEntryResponse `json:"entries"`
+	Total      int64                    `json:"total"`
+	Pagination *PageInfo                `json:"pagination,omitempty"` // Set when paging is requested
 }
 
-// CreateScrapbookEntryRequest represents the request body for creating an entry
+// CreateScrapbookEntryRequest represents the request body for creating an
+// entry. Media lists items in display order, up to the per-entry media limit;
+// mediaUrl and mediaType still attach a single item for older clients.
 type CreateScrapbookEntryRequest struct {
-	CountryID uint   `json:"countryId" binding:"required"`
-	Title     string `json:"title" binding:"required"`
-	Notes     string `json:"notes"`
-	MediaURL  string `json:"mediaUrl"`
-	MediaType string `json:"mediaType"`
-	Tags      string `json:"tags"`
-	VisitedAt string `json:"visitedAt"`
+	CountryID uint                 `json:"countryId" binding:"required"`
+	Title     string               `json:"title" binding:"required,max=255"`
+	Notes     string               `json:"notes"`
+	MediaURL  string               `json:"mediaUrl"`
+	MediaType string               `json:"mediaType"`
+	Media     []ScrapbookMediaItem `json:"media" binding:"omitempty,dive"`
+	Tags      string               `json:"tags"`
+	VisitedAt string               `json:"visitedAt"`
 }
 
-// UpdateScrapbookEntryRequest represents the request body for updating an entry
+// UpdateScrapbookEntryRequest represents the request body for updating an
+// entry. A media array, even an empty one, replaces all of the entry's media
+// items; omitting it leaves them as they are.
 type UpdateScrapbookEntryRequest struct {
-	Title     string `json:"title"`
-	Notes     string `json:"notes"`
-	MediaURL  string `json:"mediaUrl"`
-	MediaType string `json:"mediaType"`
-	Tags      string `json:"tags"`
-	VisitedAt string `json:"visitedAt"`
+	Title     string               `json:"title" binding:"max=255"`
+	Notes     string               `json:"notes"`
+	MediaURL  string               `json:"mediaUrl"`
+	MediaType string               `json:"mediaType"`
+	Media     []ScrapbookMediaItem `json:"media" binding:"omitempty,dive"`
+	Tags      string               `json:"tags"`
+	VisitedAt string               `json:"visitedAt"`
+	UpdatedAt string               `json:"updatedAt"` // Version last read by the client, see checkUnmodified
+}
+
+// PinScrapbookEntryRequest represents the request body for pinning an entry.
+// Omitting pinned toggles the current state.
+type PinScrapbookEntryRequest struct {
+	Pinned    *bool `json:"pinned"`
+	SortOrder *int  `json:"sortOrder"`
+}
+
+// entryListOrder puts pinned entries first, then entries with a manual sort
+// order (lowest first), then the most recently created
+const entryListOrder = "pinned DESC, sort_order IS NULL, sort_order ASC, created_at DESC"
+
+// entrySortOrders maps the ?sort= values accepted by entry lists to their
+// ordering; the default keeps pinned entries first
+var entrySortOrders = map[string]string{
+	"pinned":  entryListOrder,
+	"newest":  "created_at DESC, id DESC",
+	"oldest":  "created_at ASC, id ASC",
+	"visited": "visited_at DESC, id DESC",
+}
+
+// entryFilter holds the filters shared by the entry list endpoints
+type entryFilter struct {
+	Tag      string
+	CourseID string
+	Dates    dateRange
+	Order    string
+}
+
+// parseEntryFilter reads the tag, courseId, from/to and sort query parameters.
+// Writes a 400 response and returns false if any is invalid.
+func parseEntryFilter(c *gin.Context) (entryFilter, bool) {
+	f := entryFilter{
+		Tag:      c.Query("tag"),
+		CourseID: c.Query("courseId"),
+		Order:    entryListOrder,
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		order, ok := entrySortOrders[sort]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort parameter, use pinned, newest, oldest or visited"})
+			return f, false
+		}
+		f.Order = order
+	}
+
+	var ok bool
+	if f.Dates, ok = parseDateRange(c); !ok {
+		return f, false
+	}
+	return f, true
+}
+
+// apply adds the filters, but not the ordering, to a scrapbook entry query
+func (f entryFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Tag != "" {
+		query = query.Where("tags LIKE ?", "%"+f.Tag+"%")
+	}
+	if f.CourseID != "" {
+		query = filterByCourse(query, f.CourseID)
+	}
+	return f.Dates.apply(query)
+}
+
+// RenameTagRequest represents the request body for renaming a tag
+type RenameTagRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// BulkDeleteEntriesRequest represents the request body for deleting several entries
+type BulkDeleteEntriesRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=100"` // 1 to 100 entry IDs
+}
+
+// BulkDeleteEntriesResponse reports the outcome of a bulk delete
+type BulkDeleteEntriesResponse struct {
+	Deleted []uint `json:"deleted"`
+	Skipped []uint `json:"skipped"` // Not found or not owned by the user
+}
+
+// ReorderScrapbookMediaRequest represents the request body for reordering an
+// entry's media. It must list every media item of the entry exactly once.
+type ReorderScrapbookMediaRequest struct {
+	MediaIDs []uint `json:"mediaIds" binding:"required"` // In the new display order
 }
 
 // ScrapbookStatsResponse represents user statistics
@@ -71,22 +240,112 @@ type ScrapbookStatsResponse struct {
 	PhotosUploaded      int64 `json:"photosUploaded"`
 }
 
-// toScrapbookEntryResponse converts a model to a response
-func toScrapbookEntryResponse(e *models.ScrapbookEntry, includeCountry bool) ScrapbookEntryResponse {
+// preloadMedia loads the media items of queried entries in display order
+func preloadMedia(query *gorm.DB) *gorm.DB {
+	return query.Preload("Media", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	})
+}
+
+// loadMedia loads an entry's media items in display order
+func loadMedia(db *gorm.DB, entry *models.ScrapbookEntry) error {
+	return db.Where("entry_id = ?", entry.ID).Order("position ASC, id ASC").Find(&entry.Media).Error
+}
+
+// toMediaModels positions request media items in the order given
+func toMediaModels(items []ScrapbookMediaItem) []models.ScrapbookMedia {
+	media := make([]models.ScrapbookMedia, len(items))
+	for i, item := range items {
+		media[i] = models.ScrapbookMedia{URL: item.URL, Type: item.Type, Position: i}
+	}
+	return media
+}
+
+// setPrimaryMedia mirrors the first media item into the legacy single media
+// fields so older clients keep showing a photo
+func setPrimaryMedia(e *models.ScrapbookEntry, media []models.ScrapbookMedia) {
+	e.MediaURL, e.MediaType = "", ""
+	if len(media) > 0 {
+		e.MediaURL, e.MediaType = media[0].URL, media[0].Type
+	}
+}
+
+// replacePrimaryMedia swaps the first of an entry's media items for the given
+// single photo, keeping the rest in order
+func replacePrimaryMedia(existing []models.ScrapbookMedia, url, mediaType string) []models.ScrapbookMedia {
+	items := []ScrapbookMediaItem{{URL: url, Type: mediaType}}
+	for _, m := range existing[1:] {
+		items = append(items, ScrapbookMediaItem{URL: m.URL, Type: m.Type})
+	}
+	return toMediaModels(items)
+}
+
+// entryMediaItems returns an entry's media items in display order. Entries
+// from before multiple media were supported list their single legacy item.
+func entryMediaItems(e *models.ScrapbookEntry) []ScrapbookMediaItem {
+	if len(e.Media) == 0 {
+		if e.MediaURL == "" {
+			return nil
+		}
+		return []ScrapbookMediaItem{{URL: e.MediaURL, Type: e.MediaType}}
+	}
+	items := make([]ScrapbookMediaItem, len(e.Media))
+	for i, m := range e.Media {
+		items[i] = ScrapbookMediaItem{ID: m.ID, URL: m.URL, Type: m.Type}
+	}
+	return items
+}
+
+// deleteEntryMedia removes the media items of the given entries and returns
+// their URLs so the files can be removed once the transaction commits
+func deleteEntryMedia(tx *gorm.DB, entryIDs []uint) ([]string, error) {
+	if len(entryIDs) == 0 {
+		return nil, nil
+	}
+	var urls []string
+	if err := tx.Model(&models.ScrapbookMedia{}).Where("entry_id IN ?", entryIDs).Pluck("url", &urls).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("entry_id IN ?", entryIDs).Delete(&models.ScrapbookMedia{}).Error; err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// entryMediaURLs returns the set of media URLs an entry currently has,
+// including the legacy single media URL
+func entryMediaURLs(db *gorm.DB, entry *models.ScrapbookEntry) (map[string]bool, error) {
+	var urls []string
+	if err := db.Model(&models.ScrapbookMedia{}).Where("entry_id = ?", entry.ID).Pluck("url", &urls).Error; err != nil {
+		return nil, err
+	}
+	set := map[string]bool{entry.MediaURL: true}
+	for _, url := range urls {
+		set[url] = true
+	}
+	return set, nil
+}
+
+// toScrapbookEntryResponse converts a model to a response, rendering times in loc
+func toScrapbookEntryResponse(e *models.ScrapbookEntry, includeCountry bool, loc *time.Location) ScrapbookEntryResponse {
 	resp := ScrapbookEntryResponse{
 		ID:        e.ID,
 		CountryID: e.CountryID,
+		CourseID:  e.CourseID,
 		Title:     e.Title,
 		Notes:     e.Notes,
 		MediaURL:  e.MediaURL,
 		MediaType: e.MediaType,
+		Media:     entryMediaItems(e),
 		Tags:      e.Tags,
-		CreatedAt: e.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: e.UpdatedAt.Format(time.RFC3339),
+		Pinned:    e.Pinned,
+		SortOrder: e.SortOrder,
+		CreatedAt: formatTimestamp(e.CreatedAt, loc),
+		UpdatedAt: formatTimestamp(e.UpdatedAt, loc),
 	}
 
 	if !e.VisitedAt.IsZero() {
-		resp.VisitedAt = e.VisitedAt.Format(time.RFC3339)
+		resp.VisitedAt = formatTimestamp(e.VisitedAt, loc)
 	}
 
 	if includeCountry && e.Country.ID != 0 {
@@ -99,44 +358,53 @@ func toScrapbookEntryResponse(e *models.ScrapbookEntry, includeCountry bool) Scr
 
 // ListEntries returns all scrapbook entries for the authenticated user
 // GET /api/v1/scrapbook/entries
-// Query params: tag (optional) - filter by tag using LIKE match
+// Query params: tag (optional) - filter by tag using LIKE match,
+// courseId (optional) - filter by course; untagged entries always match,
+// from, to (optional) - RFC3339 bounds on visitedAt, both inclusive,
+// sort (optional) - pinned (default), newest, oldest or visited,
+// page, pageSize or limit, offset (optional) - page through entries; total is always the full count
 func (h *ScrapbookHandler) ListEntries(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
-	var entries []models.ScrapbookEntry
-	query := h.db.Where("user_id = ?", userID).Preload("Country")
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
 
-	// Filter by tag if provided
-	tagFilter := c.Query("tag")
-	if tagFilter != "" {
-		query = query.Where("tags LIKE ?", "%"+tagFilter+"%")
+	filter, ok := parseEntryFilter(c)
+	if !ok {
+		return
 	}
 
-	// Get total count (with tag filter if applied)
-	var total int64
-	countQuery := h.db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", userID)
-	if tagFilter != "" {
-		countQuery = countQuery.Where("tags LIKE ?", "%"+tagFilter+"%")
+	page, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
 	}
-	countQuery.Count(&total)
 
-	// Get entries (ordered by creation date, most recent first)
-	if err := query.Order("created_at DESC").Find(&entries).Error; err != nil {
+	var entries []models.ScrapbookEntry
+	query := filter.apply(preloadMedia(db.Preload("Country")))
+
+	// Get total count (with filters if applied)
+	var total int64
+	filter.apply(db.Model(&models.ScrapbookEntry{})).Count(&total)
+
+	// Get entries (pinned first, then by sort order and creation date, unless ?sort= says otherwise)
+	if err := page.apply(query.Order(filter.Order)).Find(&entries).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entries"})
 		return
 	}
 
 	response := ScrapbookEntryListResponse{
-		Entries: make([]ScrapbookEntryResponse, len(entries)),
-		Total:   total,
+		Entries:    make([]ScrapbookEntryResponse, len(entries)),
+		Total:      total,
+		Pagination: page.Info(total),
 	}
 
 	for i, entry := range entries {
-		response.Entries[i] = toScrapbookEntryResponse(&entry, true)
+		response.Entries[i] = toScrapbookEntryResponse(&entry, true, loc)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -145,9 +413,13 @@ func (h *ScrapbookHandler) ListEntries(c *gin.Context) {
 // GetEntry returns a specific scrapbook entry
 // GET /api/v1/scrapbook/entries/:id
 func (h *ScrapbookHandler) GetEntry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -159,7 +431,7 @@ func (h *ScrapbookHandler) GetEntry(c *gin.Context) {
 	}
 
 	var entry models.ScrapbookEntry
-	if err := h.db.Preload("Country").Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+	if err := preloadMedia(db.Preload("Country")).First(&entry, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
 			return
@@ -168,21 +440,67 @@ func (h *ScrapbookHandler) GetEntry(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true))
+	setLastModified(c, entry.UpdatedAt)
+	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true, loc))
+}
+
+// respondEmptyTitle writes the 400 response for a title that is empty once
+// cleaned, in the same form as a missing title
+func respondEmptyTitle(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+		Error:  "invalid request body",
+		Errors: []FieldError{{Field: "title", Rule: "required"}},
+	})
 }
 
 // CreateEntry creates a new scrapbook entry
 // POST /api/v1/scrapbook/entries
 func (h *ScrapbookHandler) CreateEntry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, userID, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
 	var req CreateScrapbookEntryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBindError(c, &req, err)
+		return
+	}
+	if !h.text.checkLengths(c, req.Title, req.Notes) {
+		return
+	}
+	req.Title, req.Notes = h.text.cleanTitle(req.Title), h.text.cleanNotes(req.Notes)
+	if req.Title == "" {
+		respondEmptyTitle(c)
+		return
+	}
+	if !h.checkMediaCount(c, len(req.Media)) {
+		return
+	}
+	if !checkMediaOwner(c, h.db, h.storage, userID, requestMediaURLs(req.MediaURL, req.Media)) {
+		return
+	}
+
+	// Replay the original entry if this request was already processed
+	idempotencyKey := getIdempotencyKey(c)
+	replay := func() bool {
+		entryID, found := lookupIdempotencyKey(h.db, userID, idempotencyKey, idempotencyResourceScrapbookEntry)
+		if !found {
+			return false
+		}
+		var existing models.ScrapbookEntry
+		if err := preloadMedia(db.Preload("Country")).First(&existing, entryID).Error; err != nil {
+			return false
+		}
+		c.JSON(http.StatusOK, toScrapbookEntryResponse(&existing, true, loc))
+		return true
+	}
+	if idempotencyKey != "" && replay() {
 		return
 	}
 
@@ -197,43 +515,98 @@ func (h *ScrapbookHandler) CreateEntry(c *gin.Context) {
 		return
 	}
 
+	courseID, _ := middleware.GetCourseID(c)
+
 	entry := models.ScrapbookEntry{
 		UserID:    userID,
 		CountryID: req.CountryID,
+		CourseID:  courseID,
 		Title:     req.Title,
 		Notes:     req.Notes,
 		MediaURL:  req.MediaURL,
 		MediaType: req.MediaType,
 		Tags:      req.Tags,
 	}
+	if len(req.Media) > 0 {
+		entry.Media = toMediaModels(req.Media)
+		setPrimaryMedia(&entry, entry.Media)
+	}
 
 	// Parse visit date if provided
 	if req.VisitedAt != "" {
-		parsed, err := time.Parse(time.RFC3339, req.VisitedAt)
+		parsed, err := parseVisitedAt(req.VisitedAt)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visitedAt format, use RFC3339"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidVisitedAt})
 			return
 		}
 		entry.VisitedAt = parsed
 	}
 
-	if err := h.db.Create(&entry).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var claim *models.IdempotencyKey
+		if idempotencyKey != "" {
+			var err error
+			if claim, err = claimIdempotencyKey(tx, userID, idempotencyKey, idempotencyResourceScrapbookEntry); err != nil {
+				return err
+			}
+		}
+		if err := checkUserLimit(tx, &models.ScrapbookEntry{}, userID, h.maxEntries); err != nil {
+			return err
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if claim != nil {
+			return completeIdempotencyKey(tx, claim, entry.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, errIdempotencyKeyInUse) {
+		// A concurrent request with the same key won the claim
+		if !replay() {
+			c.JSON(http.StatusConflict, gin.H{"error": errIdempotencyKeyConflict})
+		}
+		return
+	}
+	if errors.Is(err, errUserLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("entry limit reached: each user may create at most %d scrapbook entries", h.maxEntries)})
+		return
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": errDuplicateEntryTitle})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create entry"})
 		return
 	}
 
+	h.webhooks.Notify(webhook.Event{
+		Type:       webhook.EventScrapbookEntryCreated,
+		UserID:     userID,
+		ResourceID: entry.ID,
+		CountryID:  entry.CountryID,
+		Timestamp:  entry.CreatedAt.UTC(),
+	})
+
 	// Load country for response
 	entry.Country = country
 
-	c.JSON(http.StatusCreated, toScrapbookEntryResponse(&entry, true))
+	c.JSON(http.StatusCreated, toScrapbookEntryResponse(&entry, true, loc))
 }
 
-// UpdateEntry updates an existing scrapbook entry
+// UpdateEntry updates an existing scrapbook entry. If the client sends
+// If-Unmodified-Since or updatedAt and the entry has changed since, the
+// update is rejected with 412 Precondition Failed.
 // PUT /api/v1/scrapbook/entries/:id
 func (h *ScrapbookHandler) UpdateEntry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, userID, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -246,13 +619,26 @@ func (h *ScrapbookHandler) UpdateEntry(c *gin.Context) {
 
 	var req UpdateScrapbookEntryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBindError(c, &req, err)
+		return
+	}
+	if !h.text.checkLengths(c, req.Title, req.Notes) {
+		return
+	}
+	if req.Title != "" {
+		if req.Title = h.text.cleanTitle(req.Title); req.Title == "" {
+			respondEmptyTitle(c)
+			return
+		}
+	}
+	req.Notes = h.text.cleanNotes(req.Notes)
+	if !h.checkMediaCount(c, len(req.Media)) {
 		return
 	}
 
 	// Find existing entry
 	var entry models.ScrapbookEntry
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+	if err := db.First(&entry, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
 			return
@@ -261,6 +647,50 @@ func (h *ScrapbookHandler) UpdateEntry(c *gin.Context) {
 		return
 	}
 
+	conditional, ok := checkUnmodified(c, entry.UpdatedAt, req.UpdatedAt)
+	if !ok {
+		return
+	}
+	readAt := entry.UpdatedAt
+
+	// Media the entry already has may stay; anything new must be the user's own
+	current, err := entryMediaURLs(h.db, &entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entry"})
+		return
+	}
+	var added []string
+	for _, url := range requestMediaURLs(req.MediaURL, req.Media) {
+		if !current[url] {
+			added = append(added, url)
+		}
+	}
+	if !checkMediaOwner(c, h.db, h.storage, userID, added) {
+		return
+	}
+
+	// The media rows are replaced when media is sent, or when an older client
+	// changes the single photo, which stands for the first item. Without either
+	// field the items are left alone.
+	var media []models.ScrapbookMedia
+	replaceMedia := req.Media != nil
+	if replaceMedia {
+		media = toMediaModels(req.Media)
+	} else if req.MediaURL != entry.MediaURL {
+		var existing []models.ScrapbookMedia
+		if err := h.db.Where("entry_id = ?", entry.ID).Order("position ASC, id ASC").Find(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entry"})
+			return
+		}
+		if len(existing) > 0 && req.MediaURL == "" {
+			req.MediaURL, req.MediaType = entry.MediaURL, entry.MediaType
+		} else if len(existing) > 0 {
+			replaceMedia = true
+			media = replacePrimaryMedia(existing, req.MediaURL, req.MediaType)
+		}
+	}
+	mediaChanged := replaceMedia || req.MediaURL != entry.MediaURL
+
 	// Update fields if provided
 	if req.Title != "" {
 		entry.Title = req.Title
@@ -269,33 +699,228 @@ func (h *ScrapbookHandler) UpdateEntry(c *gin.Context) {
 	entry.MediaURL = req.MediaURL
 	entry.MediaType = req.MediaType
 	entry.Tags = req.Tags
+	if replaceMedia {
+		setPrimaryMedia(&entry, media)
+	}
+
+	// Uploads the entry no longer uses are released and their files deleted
+	var dropped []string
+	if mediaChanged {
+		kept := map[string]bool{entry.MediaURL: true}
+		for _, m := range media {
+			kept[m.URL] = true
+		}
+		for url := range current {
+			if !kept[url] {
+				dropped = append(dropped, url)
+			}
+		}
+	}
 
 	if req.VisitedAt != "" {
-		parsed, err := time.Parse(time.RFC3339, req.VisitedAt)
+		parsed, err := parseVisitedAt(req.VisitedAt)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visitedAt format, use RFC3339"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidVisitedAt})
 			return
 		}
 		entry.VisitedAt = parsed
 	}
 
-	if err := h.db.Save(&entry).Error; err != nil {
+	var released []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// A conditional update is checked again as it is written, so one
+		// committed since the check above still fails the precondition
+		if conditional {
+			if err := saveUnmodified(tx, &entry, readAt); err != nil {
+				return err
+			}
+		} else if err := tx.Save(&entry).Error; err != nil {
+			return err
+		}
+		var err error
+		if released, err = releaseOwnedUploads(tx, h.storage, userID, dropped); err != nil {
+			return err
+		}
+		if !replaceMedia {
+			return nil
+		}
+		if _, err := deleteEntryMedia(tx, []uint{entry.ID}); err != nil {
+			return err
+		}
+		if len(media) == 0 {
+			return nil
+		}
+		for i := range media {
+			media[i].EntryID = entry.ID
+		}
+		return tx.Create(&media).Error
+	})
+	if errors.Is(err, errModifiedSinceRead) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": errStaleUpdate})
+		return
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": errDuplicateEntryTitle})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update entry"})
 		return
 	}
+	deleteStoredFiles(h.storage, released)
 
-	// Load country for response
+	// Load country and media for response
 	h.db.First(&entry.Country, entry.CountryID)
+	loadMedia(h.db, &entry)
 
-	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true))
+	setLastModified(c, entry.UpdatedAt)
+	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true, loc))
 }
 
-// DeleteEntry deletes a scrapbook entry
+// PinEntry pins or unpins a scrapbook entry so it is listed first
+// PATCH /api/v1/scrapbook/entries/:id/pin
+// Body (optional): pinned - explicit state, toggled if omitted; sortOrder - manual position
+func (h *ScrapbookHandler) PinEntry(c *gin.Context) {
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	var req PinScrapbookEntryRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, &req, err)
+			return
+		}
+	}
+
+	var entry models.ScrapbookEntry
+	if err := db.First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entry"})
+		return
+	}
+
+	if req.Pinned != nil {
+		entry.Pinned = *req.Pinned
+	} else {
+		entry.Pinned = !entry.Pinned
+	}
+	if req.SortOrder != nil {
+		entry.SortOrder = req.SortOrder
+	}
+
+	if err := db.Save(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update entry"})
+		return
+	}
+
+	// Load country and media for response
+	h.db.First(&entry.Country, entry.CountryID)
+	loadMedia(h.db, &entry)
+
+	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true, loc))
+}
+
+// ReorderMedia sets the display order of an entry's media items. The first
+// item becomes the entry's primary media.
+// PUT /api/v1/scrapbook/entries/:id/media/order
+// Body: mediaIds - every media item ID of the entry, in the new order
+func (h *ScrapbookHandler) ReorderMedia(c *gin.Context) {
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	var req ReorderScrapbookMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	var entry models.ScrapbookEntry
+	if err := preloadMedia(db).First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entry"})
+		return
+	}
+
+	// The IDs must be a permutation of the entry's media
+	byID := make(map[uint]models.ScrapbookMedia, len(entry.Media))
+	for _, m := range entry.Media {
+		byID[m.ID] = m
+	}
+	ordered := make([]models.ScrapbookMedia, 0, len(req.MediaIDs))
+	for _, mediaID := range req.MediaIDs {
+		m, ok := byID[mediaID]
+		if !ok {
+			break
+		}
+		delete(byID, mediaID)
+		ordered = append(ordered, m)
+	}
+	if len(ordered) != len(req.MediaIDs) || len(byID) != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mediaIds must list each of the entry's media items exactly once"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for i := range ordered {
+			ordered[i].Position = i
+			if err := tx.Model(&ordered[i]).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		setPrimaryMedia(&entry, ordered)
+		return tx.Model(&entry).Updates(map[string]interface{}{"media_url": entry.MediaURL, "media_type": entry.MediaType}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reorder media"})
+		return
+	}
+
+	entry.Media = ordered
+	h.db.First(&entry.Country, entry.CountryID)
+
+	c.JSON(http.StatusOK, toScrapbookEntryResponse(&entry, true, loc))
+}
+
+// DeleteEntry deletes a scrapbook entry along with the user's own uploads
+// attached to it
 // DELETE /api/v1/scrapbook/entries/:id
 func (h *ScrapbookHandler) DeleteEntry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, userID, ok := userDB(c, h.db)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -308,7 +933,7 @@ func (h *ScrapbookHandler) DeleteEntry(c *gin.Context) {
 
 	// Verify entry exists and belongs to user
 	var entry models.ScrapbookEntry
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+	if err := db.First(&entry, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
 			return
@@ -317,20 +942,214 @@ func (h *ScrapbookHandler) DeleteEntry(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&entry).Error; err != nil {
+	var filenames []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		urls, err := deleteEntryMedia(tx, []uint{entry.ID})
+		if err != nil {
+			return err
+		}
+		if filenames, err = releaseOwnedUploads(tx, h.storage, userID, append(urls, entry.MediaURL)); err != nil {
+			return err
+		}
+		return tx.Delete(&entry).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete entry"})
 		return
 	}
 
+	// Files cannot be rolled back, so they are only removed once the entry is gone
+	deleteStoredFiles(h.storage, filenames)
+
 	c.JSON(http.StatusOK, gin.H{"message": "entry deleted"})
 }
 
+// BulkDeleteEntries soft-deletes several of the user's entries in one
+// transaction. IDs that do not exist or belong to another user are skipped
+// rather than failing the request.
+// POST /api/v1/scrapbook/entries/bulk-delete
+func (h *ScrapbookHandler) BulkDeleteEntries(c *gin.Context) {
+	_, userID, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	var req BulkDeleteEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	// Duplicates are reported once, in the order first given
+	ids := make([]uint, 0, len(req.IDs))
+	seen := make(map[uint]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var entries []models.ScrapbookEntry
+	var filenames []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(OwnedBy(userID)).Where("id IN ?", ids).Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		entryIDs := make([]uint, len(entries))
+		for i, entry := range entries {
+			entryIDs[i] = entry.ID
+		}
+		mediaURLs, err := deleteEntryMedia(tx, entryIDs)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			mediaURLs = append(mediaURLs, entry.MediaURL)
+		}
+		if filenames, err = releaseOwnedUploads(tx, h.storage, userID, mediaURLs); err != nil {
+			return err
+		}
+		return tx.Delete(&entries).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete entries"})
+		return
+	}
+
+	// Files cannot be rolled back, so they are only removed once the entries are gone
+	deleteStoredFiles(h.storage, filenames)
+
+	owned := make(map[uint]bool, len(entries))
+	for _, entry := range entries {
+		owned[entry.ID] = true
+	}
+
+	response := BulkDeleteEntriesResponse{Deleted: []uint{}, Skipped: []uint{}}
+	for _, id := range ids {
+		if owned[id] {
+			response.Deleted = append(response.Deleted, id)
+		} else {
+			response.Skipped = append(response.Skipped, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// uploadFilename returns the stored filename behind a media URL when it
+// points at our own uploads
+func uploadFilename(s storage.Storage, mediaURL string) (string, bool) {
+	if s == nil || mediaURL == "" {
+		return "", false
+	}
+	filename := path.Base(mediaURL)
+	if s.GetURL(filename) != mediaURL {
+		return "", false // Not one of our uploads
+	}
+	return filename, true
+}
+
+// uploadFilenames returns the distinct stored filenames behind media URLs,
+// skipping external URLs
+func uploadFilenames(s storage.Storage, mediaURLs []string) []string {
+	var filenames []string
+	seen := make(map[string]bool, len(mediaURLs))
+	for _, url := range mediaURLs {
+		if filename, ok := uploadFilename(s, url); ok && !seen[filename] {
+			seen[filename] = true
+			filenames = append(filenames, filename)
+		}
+	}
+	return filenames
+}
+
+// releaseOwnedUploads soft-deletes userID's upload records behind the given
+// media URLs and returns their filenames, so the files can be removed once
+// the transaction commits. Uploads of other users and external URLs are left
+// alone, whatever entry they were attached to.
+func releaseOwnedUploads(tx *gorm.DB, s storage.Storage, userID uint, mediaURLs []string) ([]string, error) {
+	filenames := uploadFilenames(s, mediaURLs)
+	if len(filenames) == 0 {
+		return nil, nil
+	}
+	var owned []models.Upload
+	if err := tx.Where("filename IN ? AND user_id = ?", filenames, userID).Find(&owned).Error; err != nil {
+		return nil, err
+	}
+	if len(owned) == 0 {
+		return nil, nil
+	}
+	if err := tx.Delete(&owned).Error; err != nil {
+		return nil, err
+	}
+	released := make([]string, len(owned))
+	for i, u := range owned {
+		released[i] = u.Filename
+	}
+	return released, nil
+}
+
+// deleteStoredFiles removes files from storage. Files that are already gone
+// are ignored and other failures are logged, since the data referencing the
+// files has been deleted either way.
+func deleteStoredFiles(s storage.Storage, filenames []string) {
+	if s == nil {
+		return
+	}
+	for _, filename := range filenames {
+		if err := s.Delete(filename); err != nil && err != storage.ErrFileNotFound {
+			slog.Warn("failed to delete media", "filename", filename, "error", err)
+		}
+	}
+}
+
+// checkMediaOwner verifies that every media URL pointing at our uploads names
+// an upload of userID, so one user cannot attach, and later delete, another
+// user's file. External URLs are allowed. Writes a 403 response and returns
+// false otherwise.
+func checkMediaOwner(c *gin.Context, db *gorm.DB, s storage.Storage, userID uint, mediaURLs []string) bool {
+	filenames := uploadFilenames(s, mediaURLs)
+	if len(filenames) == 0 {
+		return true
+	}
+	var owned int64
+	if err := db.Model(&models.Upload{}).Where("filename IN ? AND user_id = ?", filenames, userID).
+		Count(&owned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify media"})
+		return false
+	}
+	if owned != int64(len(filenames)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "media must be your own uploads or external URLs"})
+		return false
+	}
+	return true
+}
+
+// requestMediaURLs returns the media URLs an entry request attaches
+func requestMediaURLs(mediaURL string, media []ScrapbookMediaItem) []string {
+	urls := []string{mediaURL}
+	for _, item := range media {
+		urls = append(urls, item.URL)
+	}
+	return urls
+}
+
 // GetEntriesByCountry returns all scrapbook entries for a specific country
 // GET /api/v1/scrapbook/countries/:countryId/entries
+// Query params: tag, courseId, from, to, sort (optional) - as for ListEntries,
+// page, pageSize or limit, offset (optional) - page through entries; total is always the full count
 func (h *ScrapbookHandler) GetEntriesByCountry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -341,10 +1160,28 @@ func (h *ScrapbookHandler) GetEntriesByCountry(c *gin.Context) {
 		return
 	}
 
+	filter, ok := parseEntryFilter(c)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := filter.apply(db.Model(&models.ScrapbookEntry{}).
+		Where("country_id = ?", countryID)).
+		Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entries"})
+		return
+	}
+
 	var entries []models.ScrapbookEntry
-	if err := h.db.Where("user_id = ? AND country_id = ?", userID, countryID).
+	if err := page.apply(filter.apply(preloadMedia(db).Where("country_id = ?", countryID))).
 		Preload("Country").
-		Order("created_at DESC").
+		Order(filter.Order).
 		Find(&entries).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch entries"})
 		return
@@ -352,36 +1189,96 @@ func (h *ScrapbookHandler) GetEntriesByCountry(c *gin.Context) {
 
 	response := make([]ScrapbookEntryResponse, len(entries))
 	for i, entry := range entries {
-		response[i] = toScrapbookEntryResponse(&entry, true)
+		response[i] = toScrapbookEntryResponse(&entry, true, loc)
+	}
+
+	c.JSON(http.StatusOK, listResponse("entries", response, total, page))
+}
+
+// RenameTag renames or merges a tag across all of the user's entries.
+// Entries that already have the target tag keep a single copy of it.
+// POST /api/v1/scrapbook/tags/rename
+func (h *ScrapbookHandler) RenameTag(c *gin.Context) {
+	_, userID, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	from := strings.TrimSpace(req.From)
+	to := strings.TrimSpace(req.To)
+	if from == "" || to == "" || strings.Contains(from, ",") || strings.Contains(to, ",") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be single, non-empty tags"})
+		return
+	}
+	if from == to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must differ"})
+		return
+	}
+
+	updated := 0
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// LIKE narrows the candidates; renameTag does the exact per-tag match
+		var entries []models.ScrapbookEntry
+		if err := tx.Scopes(OwnedBy(userID)).Where("LOWER(tags) LIKE ?", "%"+strings.ToLower(from)+"%").
+			Find(&entries).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			tags, found := renameTag(entry.Tags, from, to)
+			if !found || tags == entry.Tags {
+				continue
+			}
+			if err := tx.Model(&entry).Update("tags", tags).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rename tag"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"entries": response})
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
 }
 
 // GetStats returns scrapbook statistics for the authenticated user
 // GET /api/v1/scrapbook/stats
 func (h *ScrapbookHandler) GetStats(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
 	var stats ScrapbookStatsResponse
 
 	// Total entries
-	h.db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", userID).Count(&stats.TotalEntries)
+	db.Model(&models.ScrapbookEntry{}).Count(&stats.TotalEntries)
 
 	// Countries documented (distinct countries with entries)
-	h.db.Model(&models.ScrapbookEntry{}).
-		Where("user_id = ?", userID).
+	db.Model(&models.ScrapbookEntry{}).
 		Distinct("country_id").
 		Count(&stats.CountriesDocumented)
 
-	// Photos uploaded (entries with media_url)
-	h.db.Model(&models.ScrapbookEntry{}).
-		Where("user_id = ? AND media_url != ''", userID).
-		Count(&stats.PhotosUploaded)
+	// Photos uploaded: every media item, plus the single photo of entries
+	// from before multiple media were supported
+	var mediaItems, legacyPhotos int64
+	h.db.Model(&models.ScrapbookMedia{}).
+		Where("entry_id IN (?)", db.Model(&models.ScrapbookEntry{}).Select("id")).
+		Count(&mediaItems)
+	db.Model(&models.ScrapbookEntry{}).
+		Where("media_url != ''").
+		Where("id NOT IN (?)", h.db.Model(&models.ScrapbookMedia{}).Select("entry_id")).
+		Count(&legacyPhotos)
+	stats.PhotosUploaded = mediaItems + legacyPhotos
 
 	c.JSON(http.StatusOK, stats)
 }
@@ -3,13 +3,20 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
+	"globe-expedition-journal/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
@@ -22,7 +29,7 @@ func setupScrapbookTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.ScrapbookEntry{})
+	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.ScrapbookEntry{}, &models.ScrapbookMedia{}, &models.IdempotencyKey{}, &models.Upload{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -53,19 +60,27 @@ func seedScrapbookTestData(t *testing.T, db *gorm.DB) (*models.User, *models.Cou
 }
 
 func createScrapbookTestRouter(db *gorm.DB, sm *lti.SessionManager) *gin.Engine {
+	return createScrapbookTestRouterWithStorage(db, sm, nil)
+}
+
+func createScrapbookTestRouterWithStorage(db *gorm.DB, sm *lti.SessionManager, s storage.Storage) *gin.Engine {
 	router := gin.New()
-	handler := NewScrapbookHandler(db)
+	handler := NewScrapbookHandler(db, s)
 
 	auth := router.Group("/api/v1/scrapbook")
 	auth.Use(middleware.AuthMiddleware(sm))
 	{
 		auth.GET("/entries", handler.ListEntries)
 		auth.POST("/entries", handler.CreateEntry)
+		auth.POST("/entries/bulk-delete", handler.BulkDeleteEntries)
 		auth.GET("/entries/:id", handler.GetEntry)
 		auth.PUT("/entries/:id", handler.UpdateEntry)
 		auth.DELETE("/entries/:id", handler.DeleteEntry)
+		auth.PATCH("/entries/:id/pin", handler.PinEntry)
+		auth.PUT("/entries/:id/media/order", handler.ReorderMedia)
 		auth.GET("/countries/:countryId/entries", handler.GetEntriesByCountry)
 		auth.GET("/stats", handler.GetStats)
+		auth.POST("/tags/rename", handler.RenameTag)
 	}
 
 	return router
@@ -178,6 +193,455 @@ func TestScrapbookHandler_CreateEntry_WithMedia(t *testing.T) {
 	}
 }
 
+func TestScrapbookHandler_CreateEntry_WithMediaItems(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	body := CreateScrapbookEntryRequest{
+		CountryID: country.ID,
+		Title:     "Lyon",
+		Media: []ScrapbookMediaItem{
+			{URL: "https://storage.example.com/photos/1.jpg", Type: "image/jpeg"},
+			{URL: "https://storage.example.com/photos/2.png", Type: "image/png"},
+			{URL: "https://storage.example.com/clips/3.mp4", Type: "video/mp4"},
+		},
+	}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Media) != 3 {
+		t.Fatalf("expected 3 media items, got %+v", response.Media)
+	}
+	for i, item := range body.Media {
+		if response.Media[i].URL != item.URL || response.Media[i].Type != item.Type || response.Media[i].ID == 0 {
+			t.Errorf("expected media item %d to be %+v, got %+v", i, item, response.Media[i])
+		}
+	}
+	// The first item stays available through the single-media fields
+	if response.MediaURL != body.Media[0].URL || response.MediaType != "image/jpeg" {
+		t.Errorf("expected primary media to mirror the first item, got %s %s", response.MediaURL, response.MediaType)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/scrapbook/entries/%d", response.ID), nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var fetched ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	if len(fetched.Media) != 3 || fetched.Media[2].URL != body.Media[2].URL {
+		t.Errorf("expected media items in order on fetch, got %+v", fetched.Media)
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_LegacyMediaListed(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Old", MediaURL: "https://cdn.example.com/a.jpg", MediaType: "image/jpeg"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/entries/1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	want := ScrapbookMediaItem{URL: "https://cdn.example.com/a.jpg", Type: "image/jpeg"}
+	if len(response.Media) != 1 || response.Media[0] != want {
+		t.Errorf("expected single-media entry to list one item, got %+v", response.Media)
+	}
+}
+
+func TestScrapbookHandler_MediaLimit(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Trip"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewScrapbookHandler(db, nil)
+	handler.SetMaxMediaPerEntry(2)
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.POST("/api/v1/scrapbook/entries", handler.CreateEntry)
+	router.PUT("/api/v1/scrapbook/entries/:id", handler.UpdateEntry)
+
+	send := func(method, path, media string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"countryId":%d,"title":"Trip","media":[%s]}`, country.ID, media)
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	two := `{"url":"https://cdn.example.com/1.jpg"},{"url":"https://cdn.example.com/2.jpg"}`
+	three := two + `,{"url":"https://cdn.example.com/3.jpg"}`
+	entryPath := fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID)
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/scrapbook/entries"},
+		{http.MethodPut, entryPath},
+	} {
+		w := send(tc.method, tc.path, three)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400 over the limit, got %d: %s", tc.method, w.Code, w.Body.String())
+		}
+		var response map[string]any
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response["code"] != errCodeEntryMediaLimit {
+			t.Errorf("%s: expected code %s, got %v", tc.method, errCodeEntryMediaLimit, response["code"])
+		}
+	}
+
+	if w := send(http.MethodPut, entryPath, two); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 at the limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_ReplacesMedia(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Trip",
+		MediaURL:  "https://cdn.example.com/old.jpg",
+		Media: []models.ScrapbookMedia{
+			{URL: "https://cdn.example.com/old.jpg", Position: 0},
+			{URL: "https://cdn.example.com/older.jpg", Position: 1},
+		},
+	}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	update := func(body string) ScrapbookEntryResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/scrapbook/entries/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response ScrapbookEntryResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	// Updates without a media field leave the items alone
+	response := update(`{"title":"Trip"}`)
+	if len(response.Media) != 2 {
+		t.Errorf("expected media untouched, got %+v", response.Media)
+	}
+
+	response = update(`{"title":"Trip","media":[{"url":"https://cdn.example.com/new.png","type":"image/png"}]}`)
+	if len(response.Media) != 1 || response.Media[0].URL != "https://cdn.example.com/new.png" {
+		t.Errorf("expected media replaced, got %+v", response.Media)
+	}
+	if response.MediaURL != "https://cdn.example.com/new.png" {
+		t.Errorf("expected primary media updated, got %s", response.MediaURL)
+	}
+
+	response = update(`{"title":"Trip","media":[]}`)
+	if len(response.Media) != 0 || response.MediaURL != "" {
+		t.Errorf("expected media cleared, got %+v %s", response.Media, response.MediaURL)
+	}
+
+	var rows int64
+	db.Model(&models.ScrapbookMedia{}).Count(&rows)
+	if rows != 0 {
+		t.Errorf("expected no media rows after clearing, got %d", rows)
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_LegacyMediaURLSyncsMedia(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Trip",
+		MediaURL:  "https://cdn.example.com/old.jpg",
+		Media: []models.ScrapbookMedia{
+			{URL: "https://cdn.example.com/old.jpg", Position: 0},
+			{URL: "https://cdn.example.com/second.jpg", Position: 1},
+		},
+	}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createScrapbookTestRouter(db, sm)
+
+	// An older client only knows the single photo
+	body := `{"title":"Trip","mediaUrl":"https://cdn.example.com/new.jpg","mediaType":"image/jpeg"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/scrapbook/entries/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.MediaURL != "https://cdn.example.com/new.jpg" {
+		t.Errorf("expected the single photo updated, got %s", response.MediaURL)
+	}
+	if len(response.Media) != 2 || response.Media[0].URL != "https://cdn.example.com/new.jpg" ||
+		response.Media[1].URL != "https://cdn.example.com/second.jpg" {
+		t.Errorf("expected the first media item replaced and the rest kept, got %+v", response.Media)
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_DeletesReplacedUploads(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	replacedURL := uploadOwnedMedia(t, db, s, user.ID)
+	keptURL := uploadOwnedMedia(t, db, s, user.ID)
+
+	entry := &models.ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Trip",
+		MediaURL:  replacedURL,
+		Media: []models.ScrapbookMedia{
+			{URL: replacedURL, Position: 0},
+			{URL: keptURL, Position: 1},
+		},
+	}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
+
+	body := fmt.Sprintf(`{"title":"Trip","media":[{"url":%q},{"url":"https://cdn.example.com/new.jpg"}]}`, keptURL)
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if s.Exists(path.Base(replacedURL)) {
+		t.Error("expected the replaced upload's file to be deleted")
+	}
+	if !s.Exists(path.Base(keptURL)) {
+		t.Error("expected the kept upload's file to remain")
+	}
+	var uploads int64
+	db.Model(&models.Upload{}).Count(&uploads)
+	if uploads != 1 {
+		t.Errorf("expected only the kept upload record to remain, got %d", uploads)
+	}
+}
+
+func TestScrapbookHandler_ReorderMedia(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Trip",
+		MediaURL:  "https://cdn.example.com/first.jpg",
+		Media: []models.ScrapbookMedia{
+			{URL: "https://cdn.example.com/first.jpg", Position: 0},
+			{URL: "https://cdn.example.com/second.jpg", Position: 1},
+		},
+	}
+	db.Create(entry)
+	firstID, secondID := entry.Media[0].ID, entry.Media[1].ID
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	body := fmt.Sprintf(`{"mediaIds":[%d,%d]}`, secondID, firstID)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/scrapbook/entries/1/media/order", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/entries/1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Media) != 2 || response.Media[0].ID != secondID || response.Media[1].ID != firstID {
+		t.Fatalf("expected media in the new order, got %+v", response.Media)
+	}
+	if response.MediaURL != "https://cdn.example.com/second.jpg" {
+		t.Errorf("expected primary media to follow the new order, got %s", response.MediaURL)
+	}
+}
+
+func TestScrapbookHandler_ReorderMedia_Invalid(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-456", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	entry := &models.ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Trip",
+		Media:     []models.ScrapbookMedia{{URL: "https://cdn.example.com/a.jpg"}, {URL: "https://cdn.example.com/b.jpg", Position: 1}},
+	}
+	db.Create(entry)
+	otherEntry := &models.ScrapbookEntry{
+		UserID:    other.ID,
+		CountryID: country.ID,
+		Title:     "Theirs",
+		Media:     []models.ScrapbookMedia{{URL: "https://cdn.example.com/c.jpg"}},
+	}
+	db.Create(otherEntry)
+	a, b, c := entry.Media[0].ID, entry.Media[1].ID, otherEntry.Media[0].ID
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"missing item", "/api/v1/scrapbook/entries/1/media/order", fmt.Sprintf(`{"mediaIds":[%d]}`, a), http.StatusBadRequest},
+		{"duplicate item", "/api/v1/scrapbook/entries/1/media/order", fmt.Sprintf(`{"mediaIds":[%d,%d]}`, a, a), http.StatusBadRequest},
+		{"another entry's item", "/api/v1/scrapbook/entries/1/media/order", fmt.Sprintf(`{"mediaIds":[%d,%d,%d]}`, a, b, c), http.StatusBadRequest},
+		{"missing body field", "/api/v1/scrapbook/entries/1/media/order", `{}`, http.StatusBadRequest},
+		{"another user's entry", "/api/v1/scrapbook/entries/2/media/order", fmt.Sprintf(`{"mediaIds":[%d]}`, c), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var positions []int
+	db.Model(&models.ScrapbookMedia{}).Where("entry_id = ?", entry.ID).Order("id ASC").Pluck("position", &positions)
+	if len(positions) != 2 || positions[0] != 0 || positions[1] != 1 {
+		t.Errorf("expected rejected reorders to leave positions alone, got %v", positions)
+	}
+}
+
+func TestScrapbookHandler_DuplicateTitle_Conflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Country{}, &models.ScrapbookEntry{}, &models.ScrapbookMedia{}, &models.IdempotencyKey{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if created, err := models.MigrateUniqueEntryTitles(db); err != nil || !created {
+		t.Fatalf("failed to add unique title index: %v, %v", created, err)
+	}
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	paris := fmt.Sprintf(`{"countryId":%d,"title":"Paris"}`, country.ID)
+	if w := send(http.MethodPost, "/api/v1/scrapbook/entries", paris); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w := send(http.MethodPost, "/api/v1/scrapbook/entries", paris)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a duplicate title, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Renaming another entry onto a taken title conflicts too
+	lyon := fmt.Sprintf(`{"countryId":%d,"title":"Lyon"}`, country.ID)
+	if w := send(http.MethodPost, "/api/v1/scrapbook/entries", lyon); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPut, "/api/v1/scrapbook/entries/2", `{"title":"Paris"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 renaming to a duplicate title, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	db.Model(&models.ScrapbookEntry{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 entries, got %d", count)
+	}
+}
+
 func TestScrapbookHandler_CreateEntry_WithDate(t *testing.T) {
 	db := setupScrapbookTestDB(t)
 	user, country := seedScrapbookTestData(t, db)
@@ -414,47 +878,316 @@ func TestScrapbookHandler_DeleteEntry(t *testing.T) {
 	}
 }
 
-func TestScrapbookHandler_GetEntriesByCountry(t *testing.T) {
+func TestScrapbookHandler_DeleteEntry_RemovesMedia(t *testing.T) {
 	db := setupScrapbookTestDB(t)
 	user, country := seedScrapbookTestData(t, db)
 
-	// Create another country
-	country2 := &models.Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
-	db.Create(country2)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
 
-	// Create entries
-	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris 1"})
-	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris 2"})
-	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country2.ID, Title: "Berlin"})
+	mediaURL := uploadOwnedMedia(t, db, s, user.ID)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Ours", MediaURL: mediaURL})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "External", MediaURL: "https://cdn.example.com/uploads/photo.jpg"})
 
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
 
-	router := createScrapbookTestRouter(db, sm)
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/countries/1/entries", nil)
-	req.AddCookie(&http.Cookie{Name: "session", Value: token})
-	w := httptest.NewRecorder()
+	for _, id := range []string{"1", "2"} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/scrapbook/entries/"+id, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
 
-	router.ServeHTTP(w, req)
+		router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 deleting entry %s, got %d", id, w.Code)
+		}
 	}
 
-	var response struct {
-		Entries []ScrapbookEntryResponse `json:"entries"`
+	if s.Exists(mediaURL) {
+		t.Error("expected uploaded media to be removed with its entry")
 	}
-	json.Unmarshal(w.Body.Bytes(), &response)
-
-	if len(response.Entries) != 2 {
-		t.Errorf("expected 2 entries for country 1, got %d", len(response.Entries))
+	var live int64
+	db.Model(&models.Upload{}).Where("url = ?", mediaURL).Count(&live)
+	if live != 0 {
+		t.Error("expected the upload record to be deleted with its file")
 	}
 }
 
-func TestScrapbookHandler_GetStats(t *testing.T) {
+func TestScrapbookHandler_DeleteEntry_KeepsOtherUsersMedia(t *testing.T) {
 	db := setupScrapbookTestDB(t)
-	user, country := seedScrapbookTestData(t, db)
+	owner, country := seedScrapbookTestData(t, db)
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	// An entry linking to someone else's upload, e.g. written before ownership was checked
+	mediaURL := uploadOwnedMedia(t, db, s, owner.ID)
+	entry := &models.ScrapbookEntry{UserID: other.ID, CountryID: country.ID, Title: "Borrowed", MediaURL: mediaURL,
+		Media: []models.ScrapbookMedia{{URL: mediaURL}}}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(other.ID, "canvas-other", "course-1", "learner")
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID), nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !s.Exists(mediaURL) {
+		t.Error("expected the owner's file to survive another user's delete")
+	}
+	var live int64
+	db.Model(&models.Upload{}).Where("url = ?", mediaURL).Count(&live)
+	if live != 1 {
+		t.Error("expected the owner's upload record to remain")
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_RejectsOtherUsersMedia(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	owner, country := seedScrapbookTestData(t, db)
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	ownerURL := uploadOwnedMedia(t, db, s, owner.ID)
+	otherURL := uploadOwnedMedia(t, db, s, other.ID)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(other.ID, "canvas-other", "course-1", "learner")
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"foreign mediaUrl", fmt.Sprintf(`{"countryId":%d,"title":"A","mediaUrl":%q}`, country.ID, ownerURL), http.StatusForbidden},
+		{"foreign media item", fmt.Sprintf(`{"countryId":%d,"title":"B","media":[{"url":%q},{"url":%q}]}`, country.ID, otherURL, ownerURL), http.StatusForbidden},
+		{"unknown upload", fmt.Sprintf(`{"countryId":%d,"title":"C","mediaUrl":"/uploads/00000000-0000-0000-0000-000000000000.jpg"}`, country.ID), http.StatusForbidden},
+		{"own upload", fmt.Sprintf(`{"countryId":%d,"title":"D","mediaUrl":%q}`, country.ID, otherURL), http.StatusCreated},
+		{"external URL", fmt.Sprintf(`{"countryId":%d,"title":"E","mediaUrl":"https://example.com/photo.jpg"}`, country.ID), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_RejectsOtherUsersMedia(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	owner, country := seedScrapbookTestData(t, db)
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	ownerURL := uploadOwnedMedia(t, db, s, owner.ID)
+
+	// Media already on an entry may be kept even without an upload record
+	legacyURL := "/uploads/11111111-1111-1111-1111-111111111111.jpg"
+	entry := &models.ScrapbookEntry{UserID: other.ID, CountryID: country.ID, Title: "Mine", MediaURL: legacyURL}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(other.ID, "canvas-other", "course-1", "learner")
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
+
+	path := fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID)
+	for body, status := range map[string]int{
+		fmt.Sprintf(`{"title":"Mine","mediaUrl":%q}`, ownerURL):  http.StatusForbidden,
+		fmt.Sprintf(`{"title":"Mine","mediaUrl":%q}`, legacyURL): http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", body, status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestScrapbookHandler_BulkDeleteEntries(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	mediaURL := uploadOwnedMedia(t, db, s, user.ID)
+
+	mine1 := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Mine 1", MediaURL: mediaURL}
+	mine2 := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Mine 2"}
+	kept := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Kept"}
+	theirs := &models.ScrapbookEntry{UserID: other.ID, CountryID: country.ID, Title: "Theirs"}
+	for _, e := range []*models.ScrapbookEntry{mine1, mine2, kept, theirs} {
+		db.Create(e)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouterWithStorage(db, sm, s)
+
+	body := fmt.Sprintf(`{"ids":[%d,%d,%d,999,%d]}`, mine1.ID, theirs.ID, mine2.ID, mine1.ID)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries/bulk-delete", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response BulkDeleteEntriesResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if fmt.Sprint(response.Deleted) != fmt.Sprint([]uint{mine1.ID, mine2.ID}) {
+		t.Errorf("expected deleted %v, got %v", []uint{mine1.ID, mine2.ID}, response.Deleted)
+	}
+	if fmt.Sprint(response.Skipped) != fmt.Sprint([]uint{theirs.ID, 999}) {
+		t.Errorf("expected skipped %v, got %v", []uint{theirs.ID, 999}, response.Skipped)
+	}
+
+	var remaining []uint
+	db.Model(&models.ScrapbookEntry{}).Order("id").Pluck("id", &remaining)
+	if fmt.Sprint(remaining) != fmt.Sprint([]uint{kept.ID, theirs.ID}) {
+		t.Errorf("expected entries %v to remain, got %v", []uint{kept.ID, theirs.ID}, remaining)
+	}
+
+	var softDeleted int64
+	db.Unscoped().Model(&models.ScrapbookEntry{}).Where("deleted_at IS NOT NULL").Count(&softDeleted)
+	if softDeleted != 2 {
+		t.Errorf("expected 2 soft-deleted entries, got %d", softDeleted)
+	}
+	if s.Exists(mediaURL) {
+		t.Error("expected uploaded media to be removed with its entry")
+	}
+}
+
+func TestScrapbookHandler_BulkDeleteEntries_InvalidBody(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, _ := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, body := range []string{`{}`, `{"ids":[]}`, `{"ids":["one"]}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries/bulk-delete", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestReleaseOwnedUploads(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	owner, _ := seedScrapbookTestData(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	mine := uploadOwnedMedia(t, db, s, owner.ID)
+	theirs := uploadOwnedMedia(t, db, s, owner.ID+1)
+
+	filenames, err := releaseOwnedUploads(db, s, owner.ID, []string{mine, theirs, mine, "https://example.com/uploads/photo.jpg", ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filenames) != 1 || filenames[0] != path.Base(mine) {
+		t.Fatalf("expected only the owned upload released, got %v", filenames)
+	}
+
+	var live, deleted int64
+	db.Model(&models.Upload{}).Count(&live)
+	db.Unscoped().Model(&models.Upload{}).Where("deleted_at IS NOT NULL").Count(&deleted)
+	if live != 1 || deleted != 1 {
+		t.Errorf("expected the owned record soft-deleted, got %d live and %d deleted", live, deleted)
+	}
+
+	deleteStoredFiles(s, filenames)
+	deleteStoredFiles(s, filenames) // Already gone
+	deleteStoredFiles(nil, filenames)
+	if s.Exists(mine) || !s.Exists(theirs) {
+		t.Error("expected only the released file to be removed")
+	}
+}
+
+func TestScrapbookHandler_GetEntriesByCountry(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	// Create another country
+	country2 := &models.Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
+	db.Create(country2)
+
+	// Create entries
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris 1"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris 2"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country2.ID, Title: "Berlin"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/countries/1/entries", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Entries []ScrapbookEntryResponse `json:"entries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Entries) != 2 {
+		t.Errorf("expected 2 entries for country 1, got %d", len(response.Entries))
+	}
+}
+
+func TestScrapbookHandler_GetStats(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
 
 	// Create another country
 	country2 := &models.Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
@@ -464,6 +1197,9 @@ func TestScrapbookHandler_GetStats(t *testing.T) {
 	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Entry 1"})
 	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Entry 2", MediaURL: "http://photo.jpg"})
 	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country2.ID, Title: "Entry 3", MediaURL: "http://photo2.jpg"})
+	// Every item of a multi-photo entry counts
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country2.ID, Title: "Entry 4", MediaURL: "http://a.jpg",
+		Media: []models.ScrapbookMedia{{URL: "http://a.jpg", Position: 0}, {URL: "http://b.jpg", Position: 1}}})
 
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
@@ -483,14 +1219,14 @@ func TestScrapbookHandler_GetStats(t *testing.T) {
 	var response ScrapbookStatsResponse
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	if response.TotalEntries != 3 {
-		t.Errorf("expected 3 total entries, got %d", response.TotalEntries)
+	if response.TotalEntries != 4 {
+		t.Errorf("expected 4 total entries, got %d", response.TotalEntries)
 	}
 	if response.CountriesDocumented != 2 {
 		t.Errorf("expected 2 countries documented, got %d", response.CountriesDocumented)
 	}
-	if response.PhotosUploaded != 2 {
-		t.Errorf("expected 2 photos uploaded, got %d", response.PhotosUploaded)
+	if response.PhotosUploaded != 4 {
+		t.Errorf("expected 4 photos uploaded, got %d", response.PhotosUploaded)
 	}
 }
 
@@ -700,3 +1436,978 @@ func TestScrapbookHandler_ListEntries_FilterByTag_NoMatch(t *testing.T) {
 		t.Errorf("expected 0 entries, got %d", response.Total)
 	}
 }
+
+func TestScrapbookHandler_CreateEntry_TagsCourse(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	body := CreateScrapbookEntryRequest{CountryID: country.ID, Title: "Paris"}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.CourseID != "course-1" {
+		t.Errorf("expected course ID 'course-1', got '%s'", response.CourseID)
+	}
+}
+
+func TestScrapbookHandler_ListEntries_FilterByCourse(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, CourseID: "course-1", Title: "Course 1"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, CourseID: "course-2", Title: "Course 2"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Untagged"})
+	legacy := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Legacy"}
+	db.Create(legacy)
+	db.Exec("UPDATE scrapbook_entries SET course_id = NULL WHERE id = ?", legacy.ID)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/entries?courseId=course-2", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response ScrapbookEntryListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 3 {
+		t.Errorf("expected total 3 (course-2 + untagged + legacy), got %d", response.Total)
+	}
+	for _, e := range response.Entries {
+		if e.CourseID == "course-1" {
+			t.Error("expected course-1 entry to be filtered out")
+		}
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_IdempotencyKeyReplay(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateScrapbookEntryRequest{CountryID: country.ID, Title: "Retry me"})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "entry-key-1")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send()
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", first.Code, first.Body.String())
+	}
+	var original ScrapbookEntryResponse
+	json.Unmarshal(first.Body.Bytes(), &original)
+
+	replay := send()
+	if replay.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on replay, got %d: %s", replay.Code, replay.Body.String())
+	}
+	var replayed ScrapbookEntryResponse
+	json.Unmarshal(replay.Body.Bytes(), &replayed)
+
+	if replayed.ID != original.ID {
+		t.Errorf("expected replay to return entry %d, got %d", original.ID, replayed.ID)
+	}
+
+	var count int64
+	db.Model(&models.ScrapbookEntry{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 entry after replay, got %d", count)
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_ValidationDetails(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, _ := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader([]byte(`{"notes":"Nothing else"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var response ValidationErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	fields := make(map[string]string)
+	for _, fe := range response.Errors {
+		fields[fe.Field] = fe.Rule
+	}
+	if fields["countryId"] != "required" {
+		t.Errorf("expected countryId to be reported as required, got %v", response.Errors)
+	}
+	if fields["title"] != "required" {
+		t.Errorf("expected title to be reported as required, got %v", response.Errors)
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_DateOnly(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateScrapbookEntryRequest{CountryID: country.ID, Title: "Day trip", VisitedAt: "2024-06-15"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.VisitedAt != "2024-06-15T00:00:00Z" {
+		t.Errorf("expected visitedAt '2024-06-15T00:00:00Z', got '%s'", response.VisitedAt)
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_MalformedDate(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Entry"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(UpdateScrapbookEntryRequest{VisitedAt: "2024/06/15"})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/scrapbook/entries/1", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_FieldErrors(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Entry"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/scrapbook/entries/1", bytes.NewReader([]byte(`{"title":["not","a","string"]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var response ValidationErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Errors) != 1 || response.Errors[0].Field != "title" || response.Errors[0].Rule != "type" {
+		t.Errorf("expected title/type error, got %s", w.Body.String())
+	}
+}
+
+func TestScrapbookHandler_EntryLengthLimits(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Entry"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createScrapbookTestRouter(db, sm)
+
+	send := func(method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	longTitle := strings.Repeat("a", maxEntryTitleLength+1)
+	longNotes := strings.Repeat("n", defaultMaxNotesLength+1)
+	tests := []struct {
+		name, method, path string
+		body               map[string]interface{}
+		titleError         bool // Rejected by binding rather than the notes limit
+	}{
+		{"create title", http.MethodPost, "/api/v1/scrapbook/entries",
+			map[string]interface{}{"countryId": country.ID, "title": longTitle}, true},
+		{"create notes", http.MethodPost, "/api/v1/scrapbook/entries",
+			map[string]interface{}{"countryId": country.ID, "title": "Ok", "notes": longNotes}, false},
+		{"update title", http.MethodPut, "/api/v1/scrapbook/entries/1",
+			map[string]interface{}{"title": longTitle}, true},
+		{"update notes", http.MethodPut, "/api/v1/scrapbook/entries/1",
+			map[string]interface{}{"notes": longNotes}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.method, tt.path, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+			var response ValidationErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if tt.titleError {
+				if len(response.Errors) != 1 || response.Errors[0] != (FieldError{Field: "title", Rule: "max", Param: "255"}) {
+					t.Errorf("expected title/max/255 error, got %+v", response.Errors)
+				}
+			} else if response.Error != "notes must be at most 20000 characters" {
+				t.Errorf("expected the notes limit in the error, got %q", response.Error)
+			}
+		})
+	}
+
+	var entry models.ScrapbookEntry
+	db.First(&entry, 1)
+	if entry.Title != "Entry" {
+		t.Errorf("expected entry to be unchanged, got title of length %d", len(entry.Title))
+	}
+
+	// The limit counts characters, not bytes
+	w := send(http.MethodPost, "/api/v1/scrapbook/entries",
+		map[string]interface{}{"countryId": country.ID, "title": strings.Repeat("é", maxEntryTitleLength)})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected a %d-character title to be accepted, got %d: %s", maxEntryTitleLength, w.Code, w.Body.String())
+	}
+}
+
+func TestScrapbookHandler_GetEntriesByCountry_Paginated(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	for i := 0; i < 4; i++ {
+		db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: fmt.Sprintf("Entry %d", i)})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/countries/1/entries?limit=3", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Entries []ScrapbookEntryResponse `json:"entries"`
+		Total   int64                    `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 4 {
+		t.Errorf("expected total 4, got %d", response.Total)
+	}
+	if len(response.Entries) != 3 {
+		t.Errorf("expected a page of 3 entries, got %d", len(response.Entries))
+	}
+}
+
+func TestScrapbookHandler_GetEntriesByCountry_Filters(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	country2 := &models.Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
+	db.Create(country2)
+
+	june := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Louvre", Tags: "museum,art", VisitedAt: june})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Orsay", Tags: "museum", VisitedAt: june.AddDate(1, 0, 0)})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Beach", Tags: "nature", VisitedAt: june})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: country2.ID, Title: "Pergamon", Tags: "museum", VisitedAt: june})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"tag scoped to country", "?tag=museum&sort=oldest", []string{"Louvre", "Orsay"}},
+		{"tag and date range", "?tag=museum&to=2024-12-31T00:00:00Z", []string{"Louvre"}},
+		{"sort by visit date", "?sort=visited", []string{"Orsay", "Beach", "Louvre"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/countries/1/entries"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Entries []ScrapbookEntryResponse `json:"entries"`
+				Total   int64                    `json:"total"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			var titles []string
+			for _, e := range response.Entries {
+				titles = append(titles, e.Title)
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, titles)
+			}
+			if response.Total != int64(len(tt.want)) {
+				t.Errorf("expected total %d, got %d", len(tt.want), response.Total)
+			}
+		})
+	}
+}
+
+func TestScrapbookHandler_ListEntries_InvalidSort(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, _ := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, path := range []string{"/api/v1/scrapbook/entries?sort=title", "/api/v1/scrapbook/countries/1/entries?from=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_DateTimeWithoutZone(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateScrapbookEntryRequest{CountryID: country.ID, Title: "Museum", VisitedAt: "2024-06-15T14:30"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.VisitedAt != "2024-06-15T14:30:00Z" {
+		t.Errorf("expected visitedAt '2024-06-15T14:30:00Z', got '%s'", response.VisitedAt)
+	}
+}
+
+func TestScrapbookHandler_PinEntry_Toggle(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Eiffel Tower"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, want := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/scrapbook/entries/%d/pin", entry.ID), nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response ScrapbookEntryResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Pinned != want {
+			t.Errorf("expected pinned=%v, got %v", want, response.Pinned)
+		}
+	}
+}
+
+func TestScrapbookHandler_PinEntry_ExplicitStateAndSortOrder(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Louvre"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/scrapbook/entries/%d/pin", entry.ID),
+		strings.NewReader(`{"pinned":true,"sortOrder":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.Pinned {
+		t.Error("expected entry to be pinned")
+	}
+	if response.SortOrder == nil || *response.SortOrder != 2 {
+		t.Errorf("expected sortOrder 2, got %v", response.SortOrder)
+	}
+}
+
+func TestScrapbookHandler_PinEntry_OtherUsersEntry(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+	entry := &models.ScrapbookEntry{UserID: other.ID, CountryID: country.ID, Title: "Not mine"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/scrapbook/entries/%d/pin", entry.ID), nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestScrapbookHandler_PinnedEntriesSortFirst(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	now := time.Now()
+	second, first := 2, 1
+	entries := []*models.ScrapbookEntry{
+		{UserID: user.ID, CountryID: country.ID, Title: "Old pinned", Pinned: true, CreatedAt: now.Add(-72 * time.Hour)},
+		{UserID: user.ID, CountryID: country.ID, Title: "Newest", CreatedAt: now},
+		{UserID: user.ID, CountryID: country.ID, Title: "Ordered second", Pinned: true, SortOrder: &second, CreatedAt: now.Add(-48 * time.Hour)},
+		{UserID: user.ID, CountryID: country.ID, Title: "Ordered first", Pinned: true, SortOrder: &first, CreatedAt: now.Add(-96 * time.Hour)},
+		{UserID: user.ID, CountryID: country.ID, Title: "Older", CreatedAt: now.Add(-24 * time.Hour)},
+	}
+	for _, e := range entries {
+		db.Create(e)
+	}
+	want := []string{"Ordered first", "Ordered second", "Old pinned", "Newest", "Older"}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, path := range []string{
+		"/api/v1/scrapbook/entries",
+		fmt.Sprintf("/api/v1/scrapbook/countries/%d/entries", country.ID),
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+		}
+
+		var response ScrapbookEntryListResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		if len(response.Entries) != len(want) {
+			t.Fatalf("%s: expected %d entries, got %d", path, len(want), len(response.Entries))
+		}
+		for i, title := range want {
+			if response.Entries[i].Title != title {
+				t.Errorf("%s: position %d: expected %q, got %q", path, i, title, response.Entries[i].Title)
+			}
+		}
+	}
+}
+
+func TestScrapbookHandler_RenameTag(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	renamed := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Louvre", Tags: "art,museums"}
+	merged := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Orsay", Tags: "museum,Museums,food"}
+	untouched := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Shop", Tags: "museumshop,food"}
+	notMine := &models.ScrapbookEntry{UserID: other.ID, CountryID: country.ID, Title: "Theirs", Tags: "museums"}
+	for _, e := range []*models.ScrapbookEntry{renamed, merged, untouched, notMine} {
+		db.Create(e)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	body := bytes.NewBufferString(`{"from":"museums","to":"museum"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/tags/rename", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]int
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["updated"] != 2 {
+		t.Errorf("expected 2 entries updated, got %d", response["updated"])
+	}
+
+	want := map[uint]string{
+		renamed.ID:   "art,museum",
+		merged.ID:    "museum,food",
+		untouched.ID: "museumshop,food",
+		notMine.ID:   "museums",
+	}
+	for id, tags := range want {
+		var entry models.ScrapbookEntry
+		db.First(&entry, id)
+		if entry.Tags != tags {
+			t.Errorf("entry %q: expected tags %q, got %q", entry.Title, tags, entry.Tags)
+		}
+	}
+}
+
+func TestScrapbookHandler_RenameTag_Invalid(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, _ := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, body := range []string{
+		`{"from":"museums"}`,
+		`{"from":"museums","to":"art,museum"}`,
+		`{"from":"museum","to":"museum"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/tags/rename", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_RejectsStaleUpdate(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Original"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+	path := fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID)
+
+	// Both tabs read the entry before either saves
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("expected Last-Modified header")
+	}
+
+	// Simulate the first tab's save landing later than the read
+	db.Model(entry).Update("updated_at", time.Now().Add(2*time.Second))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Unmodified-Since", lastModified)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = put(`{"title":"Stale"}`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status 412, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.ScrapbookEntry
+	db.First(&stored, entry.ID)
+	if stored.Title != "Original" {
+		t.Errorf("expected stale update to be discarded, got title %q", stored.Title)
+	}
+
+	// After re-reading, the second tab's update succeeds
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	lastModified = w.Header().Get("Last-Modified")
+
+	w = put(`{"title":"Fresh"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	db.First(&stored, entry.ID)
+	if stored.Title != "Fresh" {
+		t.Errorf("expected title 'Fresh', got %q", stored.Title)
+	}
+}
+
+func TestScrapbookHandler_UpdateEntry_VersionField(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	entry := &models.ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Original"}
+	db.Create(entry)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+	path := fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID)
+	stale := entry.UpdatedAt.Add(-time.Hour).Format(time.RFC3339)
+	fresh := entry.UpdatedAt.Format(time.RFC3339)
+
+	for _, tt := range []struct {
+		version string
+		want    int
+	}{
+		{stale, http.StatusPreconditionFailed},
+		{fresh, http.StatusOK},
+	} {
+		body := fmt.Sprintf(`{"title":"Edited","updatedAt":%q}`, tt.version)
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("updatedAt %s: expected status %d, got %d", tt.version, tt.want, w.Code)
+		}
+	}
+}
+
+func TestScrapbookHandler_ListEntries_Paginated(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		db.Create(&models.ScrapbookEntry{
+			UserID:    user.ID,
+			CountryID: country.ID,
+			Title:     fmt.Sprintf("Entry %d", i),
+			CreatedAt: now.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createScrapbookTestRouter(db, sm)
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"?page=2&pageSize=2", http.StatusOK},
+		{"?pageSize=-1", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/scrapbook/entries"+tt.query, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Fatalf("%s: expected status %d, got %d", tt.query, tt.want, w.Code)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var response ScrapbookEntryListResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Entries) != 1 || response.Entries[0].Title != "Entry 0" {
+			t.Errorf("expected only the oldest entry on page 2, got %+v", response.Entries)
+		}
+		if response.Pagination == nil || response.Pagination.TotalPages != 2 || response.Total != 3 {
+			t.Errorf("expected 2 pages of 3 entries, got %+v (total %d)", response.Pagination, response.Total)
+		}
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_WebhookFailureNotSurfaced(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	var deliveries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	cfg := webhook.DefaultConfig(server.URL, "hook-secret")
+	cfg.MaxAttempts = 2
+	cfg.Backoff = time.Millisecond
+	notifier := webhook.NewNotifier(cfg)
+	handler := NewScrapbookHandler(db, nil)
+	handler.SetNotifier(notifier)
+
+	router := gin.New()
+	router.POST("/api/v1/scrapbook/entries", middleware.AuthMiddleware(sm), handler.CreateEntry)
+
+	bodyBytes, _ := json.Marshal(CreateScrapbookEntryRequest{CountryID: country.ID, Title: "Market day"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+	notifier.Wait()
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 despite webhook failure, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := deliveries.Load(); got != 2 {
+		t.Errorf("expected 2 delivery attempts, got %d", got)
+	}
+}
+
+func TestScrapbookHandler_SanitizesTitleAndNotes(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	send := func(router *gin.Engine, method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Control characters are always stripped; HTML is kept unless configured
+	router := createScrapbookTestRouter(db, sm)
+	w := send(router, http.MethodPost, "/api/v1/scrapbook/entries", map[string]interface{}{
+		"countryId": country.ID, "title": " Rome\x00 ", "notes": "<em>Colosseum</em>\x1b",
+	})
+	var entry ScrapbookEntryResponse
+	json.Unmarshal(w.Body.Bytes(), &entry)
+	if w.Code != http.StatusCreated || entry.Title != "Rome" || entry.Notes != "<em>Colosseum</em>" {
+		t.Errorf("unexpected default sanitizing: %d %q %q", w.Code, entry.Title, entry.Notes)
+	}
+
+	handler := NewScrapbookHandler(db, nil)
+	handler.SetTextPolicy(TextPolicy{StripHTML: true})
+	router = gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.POST("/api/v1/scrapbook/entries", handler.CreateEntry)
+	router.PUT("/api/v1/scrapbook/entries/:id", handler.UpdateEntry)
+
+	w = send(router, http.MethodPost, "/api/v1/scrapbook/entries", map[string]interface{}{
+		"countryId": country.ID,
+		"title":     "<h1>Kyoto</h1>",
+		"notes":     `<p onclick="x()">Temples</p><script>alert(1)</script>`,
+	})
+	json.Unmarshal(w.Body.Bytes(), &entry)
+	if w.Code != http.StatusCreated || entry.Title != "Kyoto" || entry.Notes != "Temples" {
+		t.Errorf("expected HTML to be stripped, got %d %q %q", w.Code, entry.Title, entry.Notes)
+	}
+
+	path := fmt.Sprintf("/api/v1/scrapbook/entries/%d", entry.ID)
+	w = send(router, http.MethodPut, path, map[string]interface{}{"notes": "<b>Shrines</b>"})
+	json.Unmarshal(w.Body.Bytes(), &entry)
+	if w.Code != http.StatusOK || entry.Title != "Kyoto" || entry.Notes != "Shrines" {
+		t.Errorf("expected HTML to be stripped on update, got %d %q %q", w.Code, entry.Title, entry.Notes)
+	}
+
+	// A title that is nothing but markup is treated as missing
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/scrapbook/entries"},
+		{http.MethodPut, path},
+	} {
+		w = send(router, tc.method, tc.path, map[string]interface{}{"countryId": country.ID, "title": "<br>"})
+		var response ValidationErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusBadRequest || len(response.Errors) != 1 || response.Errors[0].Rule != "required" {
+			t.Errorf("%s: expected title/required error, got %d: %s", tc.method, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestScrapbookHandler_CreateEntry_MaxEntries(t *testing.T) {
+	db := setupScrapbookTestDB(t)
+	user, country := seedScrapbookTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewScrapbookHandler(db, nil)
+	handler.SetMaxEntries(2)
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.POST("/api/v1/scrapbook/entries", handler.CreateEntry)
+
+	create := func(title string) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(CreateScrapbookEntryRequest{CountryID: country.ID, Title: title})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scrapbook/entries", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := create("First"); w.Code != http.StatusCreated {
+		t.Fatalf("expected the 1st entry to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create("Second"); w.Code != http.StatusCreated {
+		t.Fatalf("expected the 2nd entry to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	w := create("Third")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for the 3rd entry, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !strings.Contains(response["error"], "at most 2 scrapbook entries") {
+		t.Errorf("expected the limit in the error, got %q", response["error"])
+	}
+
+	// Deleted entries free up room
+	db.Where("title = ?", "First").Delete(&models.ScrapbookEntry{})
+	if w := create("Third"); w.Code != http.StatusCreated {
+		t.Errorf("expected an entry to be created after a deletion, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// spaIndex is the page served for client-side routes
+const spaIndex = "index.html"
+
+// spaReservedPrefixes are server routes that never fall back to the SPA, so
+// unknown API paths still get a JSON 404
+var spaReservedPrefixes = []string{"/api/", "/lti/", "/.well-known/", "/uploads/"}
+
+// spaHandler serves a built single-page app from files for requests no
+// route matched. Files that exist are served as they are; any other GET or
+// HEAD gets index.html so client-side routes survive a reload.
+func spaHandler(files fs.FS) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+		if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || isSPAReserved(urlPath) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(urlPath), "/")
+		if name != "" && name != spaIndex && serveFSFile(c, files, name) {
+			return
+		}
+
+		// The index names the current asset bundles, so it must not be cached
+		c.Header("Cache-Control", "no-cache")
+		if !serveFSFile(c, files, spaIndex) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		}
+	}
+}
+
+// isSPAReserved reports whether urlPath belongs to a server route
+func isSPAReserved(urlPath string) bool {
+	for _, prefix := range spaReservedPrefixes {
+		if urlPath == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(urlPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveFSFile writes the regular file name from files, returning false if
+// there is no such file
+func serveFSFile(c *gin.Context, files fs.FS, name string) bool {
+	if !fs.ValidPath(name) {
+		return false
+	}
+	f, err := files.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), content)
+	return true
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testFrontend is a minimal built SPA
+var testFrontend = fstest.MapFS{
+	"index.html":    {Data: []byte("<!doctype html><div id=root></div>")},
+	"assets/app.js": {Data: []byte("console.log('app')")},
+}
+
+func TestRouter_ServesFrontend(t *testing.T) {
+	cfg := DefaultRouterConfig()
+	cfg.UploadsDir = t.TempDir()
+	cfg.Frontend = testFrontend
+	router := NewRouterWithConfig(setupDemoTestDB(t), cfg)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// Client-side routes get the index
+	for _, path := range []string{"/", "/countries/42", "/scrapbook/new"} {
+		w := get(path)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "id=root") {
+			t.Errorf("expected the SPA index for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("expected the index not to be cached for %s, got %q", path, w.Header().Get("Cache-Control"))
+		}
+	}
+
+	// Built assets are served as they are
+	if w := get("/assets/app.js"); w.Code != http.StatusOK || w.Body.String() != "console.log('app')" {
+		t.Errorf("expected the asset, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// API routes still work and unknown API paths are not swallowed
+	if w := get("/api/v1/health"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "healthy") {
+		t.Errorf("expected the health check, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/api/v1/unknown", "/lti/unknown", "/.well-known/unknown", "/api"} {
+		w := get(path)
+		if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "id=root") {
+			t.Errorf("expected 404 without the SPA for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSPAHandler_OnlyGetAndHead(t *testing.T) {
+	router := gin.New()
+	router.NoRoute(spaHandler(testFrontend))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/countries", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for POST, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/countries", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for HEAD, got %d", w.Code)
+	}
+}
+
+func TestSPAHandler_MissingIndex(t *testing.T) {
+	router := gin.New()
+	router.NoRoute(spaHandler(fstest.MapFS{}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/countries", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an index, got %d", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import "strings"
+
+// splitTags parses a comma-separated tag list, trimming whitespace and
+// dropping empty tags
+func splitTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// joinTags formats tags as the comma-separated list stored on entries
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// renameTag replaces from with to in a comma-separated tag list, matching
+// case-insensitively and dropping duplicates of to. Reports whether the tag
+// was present.
+func renameTag(tags, from, to string) (string, bool) {
+	found := false
+	seenTo := false
+	var result []string
+	for _, tag := range splitTags(tags) {
+		if strings.EqualFold(tag, from) {
+			found = true
+			tag = to
+		}
+		if strings.EqualFold(tag, to) {
+			if seenTo {
+				continue
+			}
+			seenTo = true
+			tag = to
+		}
+		result = append(result, tag)
+	}
+	if !found {
+		return tags, false
+	}
+	return joinTags(result), true
+}
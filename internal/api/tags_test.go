@@ -0,0 +1,48 @@
+package api
+
+import "testing"
+
+func TestSplitTags(t *testing.T) {
+	got := splitTags(" food, culture ,, museums ")
+	want := []string{"food", "culture", "museums"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+	if splitTags("") != nil {
+		t.Error("expected no tags for an empty string")
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      string
+		want      string
+		wantFound bool
+	}{
+		{"simple rename", "food,museums", "food,museum", true},
+		{"case-insensitive match", "Museums, art", "museum,art", true},
+		{"dedup when target present", "museum,food,museums", "museum,food", true},
+		{"dedup keeps first position", "museums,food,museum", "museum,food", true},
+		{"not present", "food,art", "food,art", false},
+		{"substring is not a match", "museumshop", "museumshop", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := renameTag(tt.tags, "museums", "museum")
+			if found != tt.wantFound {
+				t.Fatalf("expected found=%v, got %v", tt.wantFound, found)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
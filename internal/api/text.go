@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxEntryTitleLength is the longest title accepted, in characters. It
+	// matches the column size, which SQLite does not enforce.
+	maxEntryTitleLength = 255
+	// defaultMaxNotesLength is the longest visit or entry notes accepted, in
+	// characters, unless configured otherwise
+	defaultMaxNotesLength = 20000
+)
+
+var (
+	// htmlBlockPattern matches script and style elements with their contents
+	htmlBlockPattern = regexp.MustCompile(`(?is)<(?:script|style)\b[^>]*>.*?</(?:script|style)\s*>`)
+	// htmlCommentPattern matches HTML comments
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// htmlTagPattern matches opening and closing tags but not a bare "<", so
+	// text like "a < b" or "<3" survives
+	htmlTagPattern = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// TextPolicy controls the checks and cleanup applied to the titles and notes
+// users write on visits and scrapbook entries
+type TextPolicy struct {
+	MaxNotesLength int  // Longest notes accepted, in characters
+	StripHTML      bool // Remove HTML tags, and script and style contents, before saving
+}
+
+// DefaultTextPolicy returns the policy used unless configured otherwise
+func DefaultTextPolicy() TextPolicy {
+	return TextPolicy{MaxNotesLength: defaultMaxNotesLength}
+}
+
+// withDefaults returns p with a non-positive notes limit replaced by the default
+func (p TextPolicy) withDefaults() TextPolicy {
+	if p.MaxNotesLength <= 0 {
+		p.MaxNotesLength = defaultMaxNotesLength
+	}
+	return p
+}
+
+// checkLengths enforces the title and notes limits independently of any
+// binding tags. Writes a 400 response naming the limit and returns false if
+// either is too long.
+func (p TextPolicy) checkLengths(c *gin.Context, title, notes string) bool {
+	if utf8.RuneCountInString(title) > maxEntryTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("title must be at most %d characters", maxEntryTitleLength),
+		})
+		return false
+	}
+	if utf8.RuneCountInString(notes) > p.MaxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("notes must be at most %d characters", p.MaxNotesLength),
+		})
+		return false
+	}
+	return true
+}
+
+// cleanTitle strips control characters, including line breaks, and HTML if
+// the policy asks for it, and trims surrounding space
+func (p TextPolicy) cleanTitle(s string) string {
+	return strings.TrimSpace(sanitizeText(s, false, p.StripHTML))
+}
+
+// cleanNotes strips control characters other than line breaks and tabs, and
+// HTML if the policy asks for it. Line endings are normalized to "\n".
+func (p TextPolicy) cleanNotes(s string) string {
+	return sanitizeText(s, true, p.StripHTML)
+}
+
+// sanitizeText removes control characters from s, keeping "\n" and "\t" when
+// multiline is set, and optionally removes HTML markup
+func sanitizeText(s string, multiline, stripHTML bool) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.Map(func(r rune) rune {
+		if multiline && (r == '\n' || r == '\t') {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	if stripHTML {
+		s = htmlBlockPattern.ReplaceAllString(s, "")
+		s = htmlCommentPattern.ReplaceAllString(s, "")
+		s = htmlTagPattern.ReplaceAllString(s, "")
+	}
+	return s
+}
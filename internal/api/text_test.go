@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		multiline bool
+		stripHTML bool
+		want      string
+	}{
+		{"plain", "Paris in spring", true, false, "Paris in spring"},
+		{"control characters", "Bell\a and null\x00 and escape\x1b[31m", true, false, "Bell and null and escape[31m"},
+		{"line endings normalized", "one\r\ntwo\rthree\n\tfour", true, false, "one\ntwo\nthree\n\tfour"},
+		{"single line drops breaks", "one\ntwo\tthree", false, false, "onetwothree"},
+		{"HTML kept by default", "<b>bold</b>", true, false, "<b>bold</b>"},
+		{"tags stripped", `<p>Loved <a href="x">the museum</a></p>`, true, true, "Loved the museum"},
+		{"script contents stripped", "Hi<script>alert('x')</script> there", true, true, "Hi there"},
+		{"style and comments stripped", "<style>p{}</style>A<!-- hidden -->B", true, true, "AB"},
+		{"bare angle brackets kept", "a < b and <3", true, true, "a < b and <3"},
+		{"unicode kept", "Café ☕ 東京", true, true, "Café ☕ 東京"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeText(tt.in, tt.multiline, tt.stripHTML); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTextPolicy_CleanTitle(t *testing.T) {
+	p := TextPolicy{StripHTML: true}
+	if got := p.cleanTitle("  <i>Kyoto</i>\nin spring "); got != "Kyotoin spring" {
+		t.Errorf("unexpected cleaned title %q", got)
+	}
+}
+
+func TestTextPolicy_CheckLengths(t *testing.T) {
+	p := TextPolicy{MaxNotesLength: 10}
+	tests := []struct {
+		name, title, notes string
+		ok                 bool
+		message            string
+	}{
+		{"within limits", strings.Repeat("t", maxEntryTitleLength), strings.Repeat("é", 10), true, ""},
+		{"long title", strings.Repeat("t", maxEntryTitleLength+1), "", false, "title must be at most 255 characters"},
+		{"long notes", "Title", strings.Repeat("n", 11), false, "notes must be at most 10 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if got := p.checkLengths(c, tt.title, tt.notes); got != tt.ok {
+				t.Fatalf("expected %v, got %v", tt.ok, got)
+			}
+			if tt.ok {
+				return
+			}
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != http.StatusBadRequest || response["error"] != tt.message {
+				t.Errorf("expected 400 %q, got %d %q", tt.message, w.Code, response["error"])
+			}
+		})
+	}
+}
+
+func TestTextPolicy_WithDefaults(t *testing.T) {
+	if got := (TextPolicy{}).withDefaults(); got.MaxNotesLength != defaultMaxNotesLength {
+		t.Errorf("expected default notes limit, got %d", got.MaxNotesLength)
+	}
+	if got := (TextPolicy{MaxNotesLength: 50}).withDefaults(); got.MaxNotesLength != 50 {
+		t.Errorf("expected configured notes limit to be kept, got %d", got.MaxNotesLength)
+	}
+}
@@ -1,34 +1,96 @@
 package api
 
 import (
+	"fmt"
+	"log/slog"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"globe-expedition-journal/internal/middleware"
+	"globe-expedition-journal/internal/models"
 	"globe-expedition-journal/internal/storage"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UploadHandler handles file upload API endpoints
 type UploadHandler struct {
-	storage *storage.LocalStorage
+	db          *gorm.DB
+	storage     *storage.LocalStorage
+	maxPerDay   int // Uploads per user per UTC day; 0 for no limit
+	maxPerEntry int // Uploads per scrapbook entry; 0 for no limit
+	cacheMaxAge time.Duration
 }
 
-// NewUploadHandler creates a new upload handler
-func NewUploadHandler(s *storage.LocalStorage) *UploadHandler {
-	return &UploadHandler{storage: s}
+// NewUploadHandler creates a new upload handler with no upload limits
+func NewUploadHandler(db *gorm.DB, s *storage.LocalStorage) *UploadHandler {
+	return &UploadHandler{db: db, storage: s, cacheMaxAge: defaultUploadCacheMaxAge}
+}
+
+// SetLimits caps uploads per user per UTC day and per scrapbook entry.
+// Zero disables a limit.
+func (h *UploadHandler) SetLimits(perDay, perEntry int) {
+	h.maxPerDay = perDay
+	h.maxPerEntry = perEntry
+}
+
+// SetCacheMaxAge sets how long clients may cache served uploads, in whole
+// seconds; durations under a second are ignored
+func (h *UploadHandler) SetCacheMaxAge(d time.Duration) {
+	if d >= time.Second {
+		h.cacheMaxAge = d
+	}
+}
+
+// Upload limit error codes returned in the "code" field
+const (
+	errCodeDailyUploadLimit = "daily_upload_limit"
+	errCodeEntryMediaLimit  = "entry_media_limit"
+)
+
+// startOfUTCDay returns midnight UTC of the day containing t
+func startOfUTCDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// uploadsToday counts the user's uploads since midnight UTC, including
+// deleted ones so deleting files does not free up quota
+func uploadsToday(db *gorm.DB, userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := db.Unscoped().Model(&models.Upload{}).
+		Where("user_id = ? AND created_at >= ?", userID, startOfUTCDay(now)).
+		Count(&count).Error
+	return count, err
 }
 
 // UploadResponse represents the response after a successful upload
 type UploadResponse struct {
-	URL      string `json:"url"`
-	Filename string `json:"filename"`
+	URL            string `json:"url"`
+	Filename       string `json:"filename"`       // Original name of the uploaded file
+	StoredFilename string `json:"storedFilename"` // Name the file is stored and deleted under
 }
 
-// Upload handles file uploads
+// UploadInfoResponse describes a stored upload without its content
+type UploadInfoResponse struct {
+	Filename   string `json:"filename"` // Name the file is stored under
+	Size       int64  `json:"size"`
+	MimeType   string `json:"mimeType"`
+	URL        string `json:"url"`
+	UploadedAt string `json:"uploadedAt"`
+}
+
+// Upload handles file uploads. Uploads are limited per user per UTC day and,
+// when entryId names the scrapbook entry the file is for, per entry.
 // POST /api/v1/upload
+// Form fields: file (required), entryId (optional) - entry the file belongs to
 func (h *UploadHandler) Upload(c *gin.Context) {
-	_, ok := middleware.GetUserID(c)
+	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
@@ -59,6 +121,15 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	// The original extension is only checked when the name has one
+	if ext := path.Ext(storage.SanitizeFilename(header.Filename)); ext != "" && !config.IsAllowedExtension(ext) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid file extension",
+			"allowedExtensions": config.AllowedExtensions,
+		})
+		return
+	}
+
 	// Validate file size
 	if header.Size > config.MaxFileSize {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -68,8 +139,42 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	var entryID *uint
+	if entryStr := c.PostForm("entryId"); entryStr != "" {
+		id, err := strconv.ParseUint(entryStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+			return
+		}
+		var count int64
+		if err := h.db.Model(&models.ScrapbookEntry{}).
+			Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify entry"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		entry := uint(id)
+		entryID = &entry
+	}
+
+	// Checked here to avoid storing a file that would be rejected, and again
+	// when the upload is recorded
+	now := time.Now()
+	code, err := h.reachedLimit(h.db, userID, entryID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check upload limit"})
+		return
+	}
+	if code != "" {
+		h.respondLimitReached(c, code, now)
+		return
+	}
+
 	// Upload file
-	url, err := h.storage.UploadWithMimeType(file, header.Size, contentType)
+	url, err := h.storage.UploadWithOriginalName(file, header.Size, contentType, header.Filename)
 	if err != nil {
 		if err == storage.ErrFileTooLarge {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "file too large"})
@@ -83,26 +188,205 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, UploadResponse{
+	record := models.Upload{
+		UserID:   userID,
+		EntryID:  entryID,
+		Filename: path.Base(url),
 		URL:      url,
-		Filename: header.Filename,
+		MimeType: contentType,
+		Size:     header.Size,
+	}
+	code, err = h.recordUpload(&record, now)
+	if err != nil || code != "" {
+		// An untracked file would escape the limits, so remove it
+		if err := h.storage.Delete(record.Filename); err != nil {
+			slog.Warn("failed to remove untracked upload", "filename", record.Filename, "error", err)
+		}
+		if code != "" {
+			h.respondLimitReached(c, code, now)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload file"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, UploadResponse{
+		URL:            url,
+		Filename:       header.Filename,
+		StoredFilename: record.Filename,
+	})
+}
+
+// reachedLimit returns the code of the daily or per-entry upload limit the
+// user has reached, or "" if another upload is allowed
+func (h *UploadHandler) reachedLimit(db *gorm.DB, userID uint, entryID *uint, now time.Time) (string, error) {
+	if h.maxPerDay > 0 {
+		count, err := uploadsToday(db, userID, now)
+		if err != nil {
+			return "", err
+		}
+		if count >= int64(h.maxPerDay) {
+			return errCodeDailyUploadLimit, nil
+		}
+	}
+
+	if entryID != nil && h.maxPerEntry > 0 {
+		var count int64
+		if err := db.Model(&models.Upload{}).Where("entry_id = ?", *entryID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count >= int64(h.maxPerEntry) {
+			return errCodeEntryMediaLimit, nil
+		}
+	}
+	return "", nil
+}
+
+// recordUpload saves the record for a stored file unless that would exceed
+// an upload limit, in which case the limit's code is returned. The user's row
+// is locked while counting, so concurrent uploads cannot all pass the check.
+func (h *UploadHandler) recordUpload(record *models.Upload, now time.Time) (string, error) {
+	var code string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Find(&models.User{}, record.UserID).Error; err != nil {
+			return err
+		}
+		var err error
+		if code, err = h.reachedLimit(tx, record.UserID, record.EntryID, now); err != nil || code != "" {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+	return code, err
+}
+
+// respondLimitReached writes the 429 (daily) or 400 (per entry) response for
+// the upload limit with the given code
+func (h *UploadHandler) respondLimitReached(c *gin.Context, code string, now time.Time) {
+	if code == errCodeDailyUploadLimit {
+		resetsAt := startOfUTCDay(now).Add(24 * time.Hour)
+		c.Header("Retry-After", strconv.Itoa(int(resetsAt.Sub(now).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":    "daily upload limit reached",
+			"code":     errCodeDailyUploadLimit,
+			"limit":    h.maxPerDay,
+			"resetsAt": resetsAt.Format(time.RFC3339),
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "entry media limit reached",
+		"code":  errCodeEntryMediaLimit,
+		"limit": h.maxPerEntry,
+	})
+}
+
+// StorageUsageResponse reports a user's uploads against their limits
+type StorageUsageResponse struct {
+	UploadsToday     int64  `json:"uploadsToday"`
+	DailyLimit       int    `json:"dailyLimit"` // 0 means unlimited
+	ResetsAt         string `json:"resetsAt"`   // When uploadsToday returns to zero
+	MaxMediaPerEntry int    `json:"maxMediaPerEntry"`
+	TotalUploads     int64  `json:"totalUploads"` // Files currently stored
+	TotalBytes       int64  `json:"totalBytes"`
+}
+
+// GetStorageUsage returns the authenticated user's upload counts and limits
+// GET /api/v1/me/storage
+func (h *UploadHandler) GetStorageUsage(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	today, err := uploadsToday(h.db, userID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch storage usage"})
+		return
+	}
+
+	var totals struct {
+		Count int64
+		Bytes int64
+	}
+	if err := h.db.Model(&models.Upload{}).Where("user_id = ?", userID).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS bytes").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, StorageUsageResponse{
+		UploadsToday:     today,
+		DailyLimit:       h.maxPerDay,
+		ResetsAt:         formatTimestamp(startOfUTCDay(now).Add(24*time.Hour), loc),
+		MaxMediaPerEntry: h.maxPerEntry,
+		TotalUploads:     totals.Count,
+		TotalBytes:       totals.Bytes,
 	})
 }
 
-// Delete handles file deletion
+// defaultUploadCacheMaxAge lets clients cache served uploads for a year.
+// Stored filenames are unique and files are never rewritten in place, so
+// responses are also marked immutable.
+const defaultUploadCacheMaxAge = 365 * 24 * time.Hour
+
+// Serve serves a stored upload with a content type taken from its extension.
+// Images are served inline; anything else is served as a download. Range
+// requests get partial content, so videos can seek.
+// GET /uploads/:filename
+// Headers: Range (optional) - byte range, e.g. bytes=0-1023
+func (h *UploadHandler) Serve(c *gin.Context) {
+	filename := c.Param("filename")
+	// Only plain stored names are served, never paths or hidden files
+	if filename == "" || filename != storage.SanitizeFilename(filename) || strings.HasPrefix(filename, ".") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
+	if _, err := h.storage.FileInfo(filename); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
+	disposition := "inline"
+	contentType := storage.GetMimeTypeForExtension(path.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+		disposition = "attachment"
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int64(h.cacheMaxAge/time.Second)))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(h.storage.GetFilePath(filename)) // http.ServeFile answers Range and conditional requests
+}
+
+// Delete handles file deletion. Names that are not shaped like a stored
+// upload are rejected with 400; well-formed names with no file get 404.
+// Users can only delete files they uploaded themselves.
 // DELETE /api/v1/upload/:filename
 func (h *UploadHandler) Delete(c *gin.Context) {
-	_, ok := middleware.GetUserID(c)
+	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "filename required"})
+	record, ok := h.findOwnedUpload(c, userID)
+	if !ok {
 		return
 	}
+	filename := record.Filename
 
 	err := h.storage.Delete(filename)
 	if err != nil {
@@ -114,5 +398,84 @@ func (h *UploadHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if err := h.db.Delete(record).Error; err != nil {
+		slog.Warn("failed to remove upload record", "filename", filename, "error", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "file deleted"})
 }
+
+// Info returns a stored upload's size, type and upload time without its
+// content. Users can only see files they uploaded themselves.
+// GET /api/v1/upload/:filename/info
+// Query params: tz (optional) - zone to render uploadedAt in
+func (h *UploadHandler) Info(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	record, ok := h.findOwnedUpload(c, userID)
+	if !ok {
+		return
+	}
+
+	size, err := h.storage.FileInfo(record.Filename)
+	if err != nil {
+		if err == storage.ErrFileNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch file info"})
+		return
+	}
+
+	mimeType := record.MimeType
+	if mimeType == "" {
+		mimeType = storage.GetMimeTypeForExtension(path.Ext(record.Filename))
+	}
+
+	c.JSON(http.StatusOK, UploadInfoResponse{
+		Filename:   record.Filename,
+		Size:       size,
+		MimeType:   mimeType,
+		URL:        record.URL,
+		UploadedAt: formatTimestamp(record.CreatedAt, loc),
+	})
+}
+
+// findOwnedUpload loads the upload record named by the :filename param.
+// Writes a 400 response for malformed names, 404 if there is no such
+// upload and 403 if it belongs to another user, and returns false.
+func (h *UploadHandler) findOwnedUpload(c *gin.Context, userID uint) (*models.Upload, bool) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filename required"})
+		return nil, false
+	}
+	if !storage.IsStoredFilename(filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filename"})
+		return nil, false
+	}
+
+	var record models.Upload
+	if err := h.db.Where("filename = ?", filename).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch file"})
+		return nil, false
+	}
+	if record.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed to access this file"})
+		return nil, false
+	}
+	return &record, true
+}
@@ -3,13 +3,16 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/middleware"
@@ -27,7 +30,7 @@ func setupUploadTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.ScrapbookEntry{}, &models.Upload{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -67,6 +70,21 @@ func setupUploadTestStorage(t *testing.T) (*storage.LocalStorage, func()) {
 	return s, cleanup
 }
 
+// uploadOwnedMedia stores a photo with an upload record for userID, as the
+// upload endpoint does, and returns its URL
+func uploadOwnedMedia(t *testing.T, db *gorm.DB, s *storage.LocalStorage, userID uint) string {
+	content := []byte("photo")
+	mediaURL, err := s.UploadWithMimeType(bytes.NewReader(content), int64(len(content)), "image/jpeg")
+	if err != nil {
+		t.Fatalf("failed to upload media: %v", err)
+	}
+	record := models.Upload{UserID: userID, Filename: filepath.Base(mediaURL), URL: mediaURL, MimeType: "image/jpeg", Size: int64(len(content))}
+	if err := db.Create(&record).Error; err != nil {
+		t.Fatalf("failed to record upload: %v", err)
+	}
+	return mediaURL
+}
+
 func seedUploadTestUser(t *testing.T, db *gorm.DB) *models.User {
 	user := &models.User{
 		CanvasUserID:      "canvas-123",
@@ -79,16 +97,19 @@ func seedUploadTestUser(t *testing.T, db *gorm.DB) *models.User {
 	return user
 }
 
-func createUploadTestRouter(s *storage.LocalStorage, sm *lti.SessionManager) *gin.Engine {
+func createUploadTestRouter(db *gorm.DB, s *storage.LocalStorage, sm *lti.SessionManager) *gin.Engine {
 	router := gin.New()
-	handler := NewUploadHandler(s)
+	handler := NewUploadHandler(db, s)
 
 	auth := router.Group("/api/v1")
 	auth.Use(middleware.AuthMiddleware(sm))
 	{
 		auth.POST("/upload", handler.Upload)
 		auth.DELETE("/upload/:filename", handler.Delete)
+		auth.GET("/upload/:filename/info", handler.Info)
 	}
+	router.GET("/uploads/:filename", handler.Serve)
+	router.HEAD("/uploads/:filename", handler.Serve)
 
 	return router
 }
@@ -128,7 +149,7 @@ func TestUploadHandler_Upload_Success(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
 
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(db, s, sm)
 
 	// Create multipart form
 	body := &bytes.Buffer{}
@@ -161,6 +182,62 @@ func TestUploadHandler_Upload_Success(t *testing.T) {
 	}
 }
 
+func TestUploadHandler_Upload_PreservesOriginalName(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+
+	tempDir := t.TempDir()
+	config := storage.DefaultConfig()
+	config.UploadsDir = tempDir
+	config.PreserveOriginalNames = true
+	s, err := storage.NewLocalStorage(config)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createUploadTestRouter(db, s, sm)
+
+	// Go's multipart reader strips "/" paths, but not Windows-style ones
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreatePart(map[string][]string{
+		"Content-Disposition": {`form-data; name="file"; filename="..\\..\\Paris Eiffel.jpg"`},
+		"Content-Type":        {"image/jpeg"},
+	})
+	part.Write([]byte("fake jpeg content"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Filename != `..\..\Paris Eiffel.jpg` {
+		t.Errorf("expected the original filename, got %q", response.Filename)
+	}
+	if !strings.HasPrefix(response.StoredFilename, "paris-eiffel-") || strings.ContainsAny(response.StoredFilename, `/\`) {
+		t.Errorf("expected a sanitized stored filename, got %q", response.StoredFilename)
+	}
+	if response.URL != "/uploads/"+response.StoredFilename {
+		t.Errorf("expected URL to name the stored file, got %s", response.URL)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, response.StoredFilename)); err != nil {
+		t.Errorf("expected file in the uploads directory: %v", err)
+	}
+}
+
 func TestUploadHandler_Upload_NoFile(t *testing.T) {
 	db := setupUploadTestDB(t)
 	user := seedUploadTestUser(t, db)
@@ -170,7 +247,7 @@ func TestUploadHandler_Upload_NoFile(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
 
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(db, s, sm)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", nil)
 	req.AddCookie(&http.Cookie{Name: "session", Value: token})
@@ -192,7 +269,7 @@ func TestUploadHandler_Upload_InvalidFileType(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
 
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(db, s, sm)
 
 	// Create multipart form with PDF
 	body := &bytes.Buffer{}
@@ -217,12 +294,62 @@ func TestUploadHandler_Upload_InvalidFileType(t *testing.T) {
 	}
 }
 
+func TestUploadHandler_Upload_DisallowedExtension(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+
+	config := storage.DefaultConfig()
+	config.UploadsDir = t.TempDir()
+	config.AllowedExtensions = []string{".jpg", ".png"}
+	s, err := storage.NewLocalStorage(config)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createUploadTestRouter(db, s, sm)
+
+	// An allowed MIME type does not make a .svg name acceptable
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreatePart(map[string][]string{
+		"Content-Disposition": {`form-data; name="file"; filename="logo.svg"`},
+		"Content-Type":        {"image/png"},
+	})
+	part.Write([]byte("<svg/>"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"] != "invalid file extension" {
+		t.Errorf("unexpected response: %v", response)
+	}
+
+	var count int64
+	db.Model(&models.Upload{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no upload recorded, got %d", count)
+	}
+}
+
 func TestUploadHandler_Upload_Unauthenticated(t *testing.T) {
 	s, cleanup := setupUploadTestStorage(t)
 	defer cleanup()
 
 	sm := lti.NewSessionManager("test-secret", 3600)
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(nil, s, sm)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", nil)
 	w := httptest.NewRecorder()
@@ -246,8 +373,9 @@ func TestUploadHandler_Delete_Success(t *testing.T) {
 	// Upload a file first
 	url, _ := s.UploadWithMimeType(bytes.NewReader([]byte("test")), 4, "image/jpeg")
 	filename := filepath.Base(url)
+	db.Create(&models.Upload{UserID: user.ID, Filename: filename, URL: url, Size: 4})
 
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(db, s, sm)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/"+filename, nil)
 	req.AddCookie(&http.Cookie{Name: "session", Value: token})
@@ -274,9 +402,10 @@ func TestUploadHandler_Delete_NotFound(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
 
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(db, s, sm)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/nonexistent.jpg", nil)
+	// Well-formed but never stored
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/3f2c1b4a-9d8e-4f7a-b6c5-1a2b3c4d5e6f.jpg", nil)
 	req.AddCookie(&http.Cookie{Name: "session", Value: token})
 	w := httptest.NewRecorder()
 
@@ -287,12 +416,92 @@ func TestUploadHandler_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestUploadHandler_Delete_OtherUsersUpload(t *testing.T) {
+	db := setupUploadTestDB(t)
+	owner := seedUploadTestUser(t, db)
+	other := &models.User{CanvasUserID: "canvas-456", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	ownerToken, _ := sm.CreateToken(owner.ID, "canvas-123", "course-1", "learner")
+	otherToken, _ := sm.CreateToken(other.ID, "canvas-456", "course-1", "learner")
+
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, ownerToken, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var uploaded UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &uploaded)
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/"+uploaded.StoredFilename, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(otherToken); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for another user, got %d: %s", w.Code, w.Body.String())
+	}
+	if !s.Exists(uploaded.StoredFilename) {
+		t.Fatal("another user's request should not delete the file")
+	}
+
+	if w := send(ownerToken); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for the owner, got %d: %s", w.Code, w.Body.String())
+	}
+	if s.Exists(uploaded.StoredFilename) {
+		t.Error("file should have been deleted")
+	}
+}
+
+func TestUploadHandler_Delete_InvalidFilename(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createUploadTestRouter(db, s, sm)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"traversal", "/api/v1/upload/..%5C..%5Cconfig.go"},
+		{"dot dot", "/api/v1/upload/%2E%2E"},
+		{"malformed name", "/api/v1/upload/nonexistent.jpg"},
+		{"unknown extension", "/api/v1/upload/3f2c1b4a-9d8e-4f7a-b6c5-1a2b3c4d5e6f.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestUploadHandler_Delete_Unauthenticated(t *testing.T) {
 	s, cleanup := setupUploadTestStorage(t)
 	defer cleanup()
 
 	sm := lti.NewSessionManager("test-secret", 3600)
-	router := createUploadTestRouter(s, sm)
+	router := createUploadTestRouter(nil, s, sm)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/test.jpg", nil)
 	w := httptest.NewRecorder()
@@ -303,3 +512,458 @@ func TestUploadHandler_Delete_Unauthenticated(t *testing.T) {
 		t.Errorf("expected status 401, got %d", w.Code)
 	}
 }
+
+// postTestUpload uploads a small JPEG, optionally for a scrapbook entry
+func TestUploadHandler_Serve(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, token, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var upload UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &upload)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, upload.URL, nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("expected Content-Type image/jpeg, got %q", got)
+	}
+	if got, want := w.Header().Get("Cache-Control"), "public, max-age=31536000, immutable"; got != want {
+		t.Errorf("expected Cache-Control %q, got %q", want, got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "inline") {
+		t.Errorf("expected inline Content-Disposition, got %q", got)
+	}
+	if w.Body.String() != "fake jpeg content" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestUploadHandler_Serve_Range(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, token, "")
+	var upload UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &upload)
+
+	req := httptest.NewRequest(http.MethodGet, upload.URL, nil)
+	req.Header.Set("Range", "bytes=5-8")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "jpeg" {
+		t.Errorf("expected bytes 5-8, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 5-8/17" {
+		t.Errorf("expected Content-Range bytes 5-8/17, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("expected Content-Type image/jpeg on partial content, got %q", got)
+	}
+
+	// Ranges past the end of the file are not satisfiable
+	req = httptest.NewRequest(http.MethodGet, upload.URL, nil)
+	req.Header.Set("Range", "bytes=100-200")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected status 416, got %d", w.Code)
+	}
+}
+
+func TestUploadHandler_SetCacheMaxAge(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewUploadHandler(db, s)
+	handler.SetCacheMaxAge(time.Hour)
+	handler.SetCacheMaxAge(0) // Ignored
+	router := gin.New()
+	router.POST("/api/v1/upload", middleware.AuthMiddleware(sm), handler.Upload)
+	router.GET("/uploads/:filename", handler.Serve)
+
+	w := postTestUpload(router, token, "")
+	var upload UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &upload)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, upload.URL, nil))
+	if got, want := w.Header().Get("Cache-Control"), "public, max-age=3600, immutable"; got != want {
+		t.Errorf("expected Cache-Control %q, got %q", want, got)
+	}
+}
+
+func TestUploadHandler_Serve_Head(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, token, "")
+	var upload UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &upload)
+
+	req := httptest.NewRequest(http.MethodHead, upload.URL, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Length"); got != "17" {
+		t.Errorf("expected Content-Length 17, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("expected Content-Type image/jpeg, got %q", got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no body, got %d bytes", w.Body.Len())
+	}
+
+	req = httptest.NewRequest(http.MethodHead, "/uploads/3f2c1b4a-9d8e-4f7a-b6c5-1a2b3c4d5e6f.jpg", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing file, got %d", w.Code)
+	}
+}
+
+func TestUploadHandler_Info(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	other := &models.User{CanvasUserID: "canvas-456", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	otherToken, _ := sm.CreateToken(other.ID, "canvas-456", "course-1", "learner")
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, token, "")
+	var upload UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &upload)
+
+	send := func(filename, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/upload/"+filename+"/info", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = send(upload.StoredFilename, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var info UploadInfoResponse
+	json.Unmarshal(w.Body.Bytes(), &info)
+	if info.Filename != upload.StoredFilename || info.Size != 17 || info.MimeType != "image/jpeg" || info.URL != upload.URL || info.UploadedAt == "" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	if w := send(upload.StoredFilename, otherToken); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for another user, got %d", w.Code)
+	}
+	if w := send("3f2c1b4a-9d8e-4f7a-b6c5-1a2b3c4d5e6f.jpg", token); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown file, got %d", w.Code)
+	}
+	if w := send("notes.txt", token); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a malformed name, got %d", w.Code)
+	}
+
+	// A record whose file has gone missing is reported as not found
+	os.Remove(s.GetFilePath(upload.StoredFilename))
+	if w := send(upload.StoredFilename, token); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing file, got %d", w.Code)
+	}
+}
+
+func TestUploadHandler_Serve_RejectsTraversal(t *testing.T) {
+	db := setupUploadTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	// A file beside the uploads directory must not be reachable
+	secret := filepath.Join(filepath.Dir(s.GetConfig().UploadsDir), "secret.jpg")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	defer os.Remove(secret)
+
+	router := createUploadTestRouter(db, s, lti.NewSessionManager("test-secret", 3600))
+
+	for _, target := range []string{
+		"/uploads/..%2Fsecret.jpg",
+		"/uploads/..%5Csecret.jpg",
+		"/uploads/..",
+		"/uploads/../config.go",
+		"/uploads/.hidden",
+		"/uploads/missing.jpg",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", target, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: served a file outside the uploads directory", target)
+		}
+	}
+}
+
+func postTestUpload(router *gin.Engine, token, entryID string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if entryID != "" {
+		writer.WriteField("entryId", entryID)
+	}
+	h := make(map[string][]string)
+	h["Content-Disposition"] = []string{`form-data; name="file"; filename="test.jpg"`}
+	h["Content-Type"] = []string{"image/jpeg"}
+	part, _ := writer.CreatePart(h)
+	part.Write([]byte("fake jpeg content"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createLimitedUploadTestRouter(db *gorm.DB, s *storage.LocalStorage, sm *lti.SessionManager, perDay, perEntry int) *gin.Engine {
+	handler := NewUploadHandler(db, s)
+	handler.SetLimits(perDay, perEntry)
+
+	router := gin.New()
+	auth := router.Group("/api/v1")
+	auth.Use(middleware.AuthMiddleware(sm))
+	{
+		auth.GET("/me/storage", handler.GetStorageUsage)
+		auth.POST("/upload", handler.Upload)
+		auth.DELETE("/upload/:filename", handler.Delete)
+	}
+	return router
+}
+
+func TestUploadHandler_Upload_RecordsUpload(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createUploadTestRouter(db, s, sm)
+
+	w := postTestUpload(router, token, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	var record models.Upload
+	if err := db.Where("url = ?", response.URL).First(&record).Error; err != nil {
+		t.Fatalf("expected upload record: %v", err)
+	}
+	if record.UserID != user.ID || record.MimeType != "image/jpeg" || record.Size == 0 {
+		t.Errorf("unexpected upload record: %+v", record)
+	}
+}
+
+func TestUploadHandler_Upload_DailyLimit(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createLimitedUploadTestRouter(db, s, sm, 2, 0)
+
+	// Yesterday's uploads do not count
+	db.Create(&models.Upload{UserID: user.ID, Filename: "old.jpg", URL: "/uploads/old.jpg", Size: 1,
+		CreatedAt: time.Now().UTC().Add(-48 * time.Hour)})
+
+	var first UploadResponse
+	for i := 0; i < 2; i++ {
+		w := postTestUpload(router, token, "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload %d: expected status 201, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+		if i == 0 {
+			json.Unmarshal(w.Body.Bytes(), &first)
+		}
+	}
+
+	// Deleting a file does not free up quota
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/upload/"+filepath.Base(first.URL), nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := postTestUpload(router, token, "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	var response map[string]any
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["code"] != errCodeDailyUploadLimit {
+		t.Errorf("expected code %s, got %v", errCodeDailyUploadLimit, response["code"])
+	}
+
+	var stored int64
+	db.Model(&models.Upload{}).Where("user_id = ?", user.ID).Count(&stored)
+	if stored != 2 {
+		t.Errorf("expected 2 stored uploads (old plus one remaining), got %d", stored)
+	}
+}
+
+func TestUploadHandler_Upload_EntryLimit(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	entry := models.ScrapbookEntry{UserID: user.ID, CountryID: 1, Title: "Photos"}
+	db.Create(&entry)
+	other := models.ScrapbookEntry{UserID: user.ID + 1, CountryID: 1, Title: "Theirs"}
+	db.Create(&other)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createLimitedUploadTestRouter(db, s, sm, 0, 1)
+
+	entryID := fmt.Sprint(entry.ID)
+	if w := postTestUpload(router, token, entryID); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := postTestUpload(router, token, entryID)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]any
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["code"] != errCodeEntryMediaLimit {
+		t.Errorf("expected code %s, got %v", errCodeEntryMediaLimit, response["code"])
+	}
+
+	// Uploads without an entry are unaffected
+	if w := postTestUpload(router, token, ""); w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 without entry, got %d", w.Code)
+	}
+
+	// Another user's entry is not found
+	if w := postTestUpload(router, token, fmt.Sprint(other.ID)); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for another user's entry, got %d", w.Code)
+	}
+	if w := postTestUpload(router, token, "abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid entry ID, got %d", w.Code)
+	}
+}
+
+func TestUploadHandler_RecordUpload_RechecksLimits(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	handler := NewUploadHandler(db, s)
+	handler.SetLimits(1, 0)
+	now := time.Now()
+
+	first := models.Upload{UserID: user.ID, Filename: "first.jpg", URL: "/uploads/first.jpg", Size: 1}
+	if code, err := handler.recordUpload(&first, now); err != nil || code != "" {
+		t.Fatalf("expected the first upload to be recorded, got %q, %v", code, err)
+	}
+
+	// A concurrent upload that passed the early check is still rejected
+	second := models.Upload{UserID: user.ID, Filename: "second.jpg", URL: "/uploads/second.jpg", Size: 1}
+	code, err := handler.recordUpload(&second, now)
+	if err != nil || code != errCodeDailyUploadLimit {
+		t.Fatalf("expected code %s, got %q, %v", errCodeDailyUploadLimit, code, err)
+	}
+
+	var stored int64
+	db.Model(&models.Upload{}).Where("user_id = ?", user.ID).Count(&stored)
+	if stored != 1 {
+		t.Errorf("expected only the first upload to be recorded, got %d", stored)
+	}
+}
+
+func TestUploadHandler_GetStorageUsage(t *testing.T) {
+	db := setupUploadTestDB(t)
+	user := seedUploadTestUser(t, db)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+
+	db.Create(&models.Upload{UserID: user.ID, Filename: "old.jpg", URL: "/uploads/old.jpg", Size: 100,
+		CreatedAt: time.Now().UTC().Add(-48 * time.Hour)})
+	db.Create(&models.Upload{UserID: user.ID + 1, Filename: "theirs.jpg", URL: "/uploads/theirs.jpg", Size: 500})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createLimitedUploadTestRouter(db, s, sm, 5, 3)
+
+	postTestUpload(router, token, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/storage", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var usage StorageUsageResponse
+	json.Unmarshal(w.Body.Bytes(), &usage)
+
+	if usage.UploadsToday != 1 || usage.DailyLimit != 5 || usage.MaxMediaPerEntry != 3 {
+		t.Errorf("unexpected counts or limits: %+v", usage)
+	}
+	if usage.TotalUploads != 2 || usage.TotalBytes != 100+int64(len("fake jpeg content")) {
+		t.Errorf("unexpected totals: %+v", usage)
+	}
+	if usage.ResetsAt == "" {
+		t.Error("expected resetsAt to be set")
+	}
+}
@@ -1,10 +1,20 @@
 package api
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
+	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,12 +22,20 @@ import (
 
 // UserHandler handles user-related API endpoints
 type UserHandler struct {
-	db *gorm.DB
+	db             *gorm.DB
+	sessionManager *lti.SessionManager
+	storage        storage.Storage
 }
 
-// NewUserHandler creates a new user handler
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+// NewUserHandler creates a new user handler. The session manager is used to
+// revoke sessions and the storage to remove media when an account is deleted;
+// either may be nil.
+func NewUserHandler(db *gorm.DB, sessionManager *lti.SessionManager, s storage.Storage) *UserHandler {
+	return &UserHandler{
+		db:             db,
+		sessionManager: sessionManager,
+		storage:        s,
+	}
 }
 
 // MeResponse represents the response for the /me endpoint
@@ -28,6 +46,11 @@ type MeResponse struct {
 	Role        string `json:"role"`
 	DisplayName string `json:"displayName,omitempty"`
 	Email       string `json:"email,omitempty"`
+	Locale      string `json:"locale"`
+	PictureURL  string `json:"pictureUrl,omitempty"`
+
+	CountriesVisited int64 `json:"countriesVisited"` // Distinct countries with a visit
+	TotalEntries     int64 `json:"totalEntries"`     // Scrapbook entries
 }
 
 // GetMe returns the current authenticated user's information
@@ -39,10 +62,6 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 		return
 	}
 
-	canvasID, _ := middleware.GetCanvasID(c)
-	courseID, _ := middleware.GetCourseID(c)
-	role, _ := middleware.GetRole(c)
-
 	// Get full user info from database
 	var user models.User
 	if err := h.db.First(&user, userID).Error; err != nil {
@@ -50,22 +69,119 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 		return
 	}
 
+	h.respondMe(c, &user)
+}
+
+// UpdateMeRequest represents the request body for updating the current user.
+// Omitted fields are left unchanged; an empty string clears the field.
+// Email is checked by UpdateMe once surrounding whitespace is trimmed.
+type UpdateMeRequest struct {
+	DisplayName *string `json:"displayName" binding:"omitempty,max=255"`
+	Email       *string `json:"email" binding:"omitempty,max=255"`
+}
+
+// UpdateMe updates the current user's display name and email. The Canvas
+// identity fields cannot be changed, and a later LTI launch that supplies a
+// name or email overwrites the values set here.
+// PUT /api/v1/me
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.DisplayName != nil {
+		updates["display_name"] = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if email != "" && !validEmail(email) {
+			c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+				Error:  "invalid request body",
+				Errors: []FieldError{{Field: "email", Rule: "email"}},
+			})
+			return
+		}
+		updates["email"] = email
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+			return
+		}
+	}
+
+	h.respondMe(c, &user)
+}
+
+// respondMe writes the MeResponse for user with the session's Canvas
+// identity, course and role
+func (h *UserHandler) respondMe(c *gin.Context, user *models.User) {
+	canvasID, _ := middleware.GetCanvasID(c)
+	courseID, _ := middleware.GetCourseID(c)
+	role, _ := middleware.GetRole(c)
+
+	countriesVisited, totalEntries, err := countUserStats(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user stats"})
+		return
+	}
+
 	response := MeResponse{
-		ID:          user.ID,
-		CanvasID:    canvasID,
-		CourseID:    courseID,
-		Role:        role,
-		DisplayName: user.DisplayName,
-		Email:       user.Email,
+		ID:               user.ID,
+		CanvasID:         canvasID,
+		CourseID:         courseID,
+		Role:             role,
+		DisplayName:      user.DisplayName,
+		Email:            user.Email,
+		Locale:           user.Locale,
+		PictureURL:       user.PictureURL,
+		CountriesVisited: countriesVisited,
+		TotalEntries:     totalEntries,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// countUserStats returns the number of distinct countries a user has visited
+// and the number of scrapbook entries they have written
+func countUserStats(db *gorm.DB, userID uint) (countriesVisited, totalEntries int64, err error) {
+	if err = db.Model(&models.Visit{}).Where("user_id = ?", userID).
+		Distinct("country_id").Count(&countriesVisited).Error; err != nil {
+		return 0, 0, err
+	}
+	if err = db.Model(&models.ScrapbookEntry{}).Where("user_id = ?", userID).
+		Count(&totalEntries).Error; err != nil {
+		return 0, 0, err
+	}
+	return countriesVisited, totalEntries, nil
+}
+
 // Logout clears the session cookie
 // POST /api/v1/logout
 func (h *UserHandler) Logout(c *gin.Context) {
-	// Clear the session cookie
+	clearSessionCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// clearSessionCookie expires the session cookie
+func clearSessionCookie(c *gin.Context) {
 	c.SetCookie(
 		"session",
 		"",
@@ -75,6 +191,568 @@ func (h *UserHandler) Logout(c *gin.Context) {
 		c.Request.TLS != nil,
 		true,
 	)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+// DeleteAccountRequest represents the request body for deleting an account
+type DeleteAccountRequest struct {
+	Confirm bool `json:"confirm" binding:"required"` // Must be true
+}
+
+// DeleteMe deletes the authenticated user's account. The user's personal
+// fields are scrubbed before the row is soft-deleted, and their visits, scrapbook entries, upload records, idempotency keys,
+// comments written by or about them, feedback they received and their launch
+// events are permanently removed in one transaction; the files the user uploaded are then
+// removed from storage and every session for the user is revoked. Files of
+// other users that the user's entries link to are left alone.
+// With anonymize=true the visits and entries are kept for aggregate reporting
+// but stripped of personal content.
+// Feedback the user gave as an instructor belongs to its learners and is kept.
+// DELETE /api/v1/me
+// Query params: anonymize (optional) - "true" to anonymize instead of delete
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	anonymize := c.Query("anonymize") == "true"
+
+	var filenames []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// The soft-deleted row must not keep the user's identity
+		if err := anonymizeUser(tx, userID); err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.User{}, userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Media items go in both cases, since anonymized entries keep no media
+		var entryIDs []uint
+		if err := tx.Unscoped().Model(&models.ScrapbookEntry{}).Where("user_id = ?", userID).
+			Pluck("id", &entryIDs).Error; err != nil {
+			return err
+		}
+		if _, err := deleteEntryMedia(tx, entryIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("entry_id IN ? OR author_user_id = ?", entryIDs, userID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("learner_user_id = ?", userID).Delete(&models.Feedback{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.LaunchEvent{}).Error; err != nil {
+			return err
+		}
+		// Every file the user uploaded goes, whether or not an entry links to it
+		if err := tx.Model(&models.Upload{}).Where("user_id = ?", userID).
+			Pluck("filename", &filenames).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Upload{}).Error; err != nil {
+			return err
+		}
+		if anonymize {
+			if err := anonymizeUserContent(tx, userID); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Visit{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.ScrapbookEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.IdempotencyKey{}).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+
+	// Files cannot be rolled back, so they are only removed once the data is gone
+	deleteStoredFiles(h.storage, filenames)
+
+	if h.sessionManager != nil {
+		h.sessionManager.RevokeUser(userID)
+	}
+	clearSessionCookie(c)
+
+	if anonymize {
+		c.JSON(http.StatusOK, gin.H{"message": "account anonymized"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// anonymizeUser clears a user's personal fields. The Canvas user ID is
+// replaced so a later launch cannot be linked back to the anonymized row.
+func anonymizeUser(tx *gorm.DB, userID uint) error {
+	return tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"canvas_user_id": fmt.Sprintf("anonymized-%d", userID),
+		"display_name":   "",
+		"email":          "",
+		"locale":         "",
+		"picture_url":    "",
+	}).Error
+}
+
+// anonymizeUserContent strips free text and media from a user's visits and
+// scrapbook entries, keeping the country, course and dates for aggregates
+func anonymizeUserContent(tx *gorm.DB, userID uint) error {
+	if err := tx.Unscoped().Model(&models.Visit{}).Where("user_id = ?", userID).
+		Update("notes", "").Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.ScrapbookEntry{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"title":      "",
+			"notes":      "",
+			"media_url":  "",
+			"media_type": "",
+			"tags":       "",
+		}).Error
+}
+
+// PassportRegion represents exploration progress within one region
+type PassportRegion struct {
+	Region  string  `json:"region"`
+	Total   int64   `json:"total"`
+	Visited int64   `json:"visited"`
+	Percent float64 `json:"percent"`
+}
+
+// PassportResponse represents the user's exploration progress by region
+type PassportResponse struct {
+	Regions []PassportRegion `json:"regions"`
+	Total   int64            `json:"total"`
+	Visited int64            `json:"visited"`
+	Percent float64          `json:"percent"`
+}
+
+// GetPassport returns, per region, how many countries exist and how many the
+// authenticated user has visited, plus overall totals
+// GET /api/v1/me/passport
+func (h *UserHandler) GetPassport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	// Distinct visited countries so repeat visits count once
+	visited := h.db.Model(&models.Visit{}).
+		Select("DISTINCT country_id").
+		Where("user_id = ?", userID)
+
+	var rows []PassportRegion
+	if err := h.db.Model(&models.Country{}).
+		Select("countries.region AS region, COUNT(*) AS total, COUNT(visited.country_id) AS visited").
+		Joins("LEFT JOIN (?) AS visited ON visited.country_id = countries.id", visited).
+		Group("countries.region").
+		Order("countries.region ASC").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch passport"})
+		return
+	}
+
+	response := PassportResponse{Regions: make([]PassportRegion, len(rows))}
+	for i, row := range rows {
+		row.Percent = percentOf(row.Visited, row.Total)
+		response.Regions[i] = row
+		response.Total += row.Total
+		response.Visited += row.Visited
+	}
+	response.Percent = percentOf(response.Visited, response.Total)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// percentOf returns part as a percentage of total, rounded to one decimal
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
+// exportBatchSize is how many records are loaded per query while streaming an export
+const exportBatchSize = 100
+
+// ExportProfile represents the user profile section of a data export
+type ExportProfile struct {
+	ID                uint   `json:"id"`
+	CanvasID          string `json:"canvasId"`
+	CanvasInstanceURL string `json:"canvasInstanceUrl"`
+	DisplayName       string `json:"displayName,omitempty"`
+	Email             string `json:"email,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+	PictureURL        string `json:"pictureUrl,omitempty"`
+	CreatedAt         string `json:"createdAt"`
+}
+
+// ExportMediaFile lists one of the user's uploaded files in a data export's
+// manifest
+type ExportMediaFile struct {
+	EntryID   uint   `json:"entryId,omitempty"` // First entry using the file, if any
+	MediaURL  string `json:"mediaUrl"`
+	MediaType string `json:"mediaType,omitempty"`
+	File      string `json:"file,omitempty"` // Path of the file within a zip export, if included
+}
+
+// ExportLaunchEvent represents one of the user's LTI launches in a data export
+type ExportLaunchEvent struct {
+	Stage       string `json:"stage"`
+	CourseID    string `json:"courseId,omitempty"`
+	MessageType string `json:"messageType,omitempty"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+	IPAddress   string `json:"ipAddress,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// exportMediaDir is the directory media files are stored under in a zip export
+const exportMediaDir = "media/"
+
+// ExportMe streams everything stored about the authenticated user as a single
+// JSON document: profile, visits, scrapbook entries, a manifest of uploaded
+// media, comments written by or about the user, feedback they received and
+// their LTI launches. With format=zip the document is sent as export.json inside
+// a zip archive together with the media files themselves.
+// GET /api/v1/me/export
+// Query params: tz (optional) - zone to render timestamps in,
+// format (optional) - "json" (default) or "zip"
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `format must be "json" or "zip"`})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Once streaming has begun the status can no longer change, so failures are
+	// logged and the download is left truncated (and therefore invalid).
+	if format == "zip" {
+		if err := h.writeExportZip(c, &user, loc); err != nil {
+			slog.Warn("account export aborted", "user_id", userID, "error", err)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="globe-expedition-export-%d.json"`, user.ID))
+	c.Status(http.StatusOK)
+
+	if _, err := h.writeExport(c.Writer, c.Writer.Flush, &user, loc, false); err != nil {
+		slog.Warn("account export aborted", "user_id", userID, "error", err)
+	}
+}
+
+// writeExportZip streams a zip archive holding export.json and the user's
+// uploaded media files
+func (h *UserHandler) writeExportZip(c *gin.Context, user *models.User, loc *time.Location) error {
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="globe-expedition-export-%d.zip"`, user.ID))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	doc, err := zw.Create("export.json")
+	if err != nil {
+		return err
+	}
+	flush := func() {
+		zw.Flush()
+		c.Writer.Flush()
+	}
+
+	filenames, err := h.writeExport(doc, flush, user, loc, true)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		if err := h.copyMediaToZip(zw, filename); err != nil {
+			return err
+		}
+		flush()
+	}
+	return zw.Close()
+}
+
+// copyMediaToZip adds one stored file to the archive. Images are already
+// compressed, so they are stored as-is. A file missing from storage is
+// skipped; the manifest already reflects which files were found.
+func (h *UserHandler) copyMediaToZip(zw *zip.Writer, filename string) error {
+	rc, err := h.storage.Open(filename)
+	if err != nil {
+		slog.Warn("failed to read media for export", "filename", filename, "error", err)
+		return nil
+	}
+	defer rc.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     exportMediaDir + filename,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// writeExport writes the export document to w, calling flush after each
+// batch of records. With includeMedia, manifest entries for files found in
+// storage point into the zip's media directory and their filenames are
+// returned so the caller can add them.
+func (h *UserHandler) writeExport(w io.Writer, flush func(), user *models.User, loc *time.Location, includeMedia bool) ([]string, error) {
+	// Records are written in batches so large accounts never sit in memory at once
+	out := &jsonStreamWriter{w: w}
+	out.field("exportedAt", formatTimestamp(time.Now(), loc))
+	out.field("profile", ExportProfile{
+		ID:                user.ID,
+		CanvasID:          user.CanvasUserID,
+		CanvasInstanceURL: user.CanvasInstanceURL,
+		DisplayName:       user.DisplayName,
+		Email:             user.Email,
+		Locale:            user.Locale,
+		PictureURL:        user.PictureURL,
+		CreatedAt:         formatTimestamp(user.CreatedAt, loc),
+	})
+
+	out.beginArray("visits")
+	var visits []models.Visit
+	err := h.db.Preload("Country").Where("user_id = ?", user.ID).
+		FindInBatches(&visits, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range visits {
+				out.item(toVisitResponse(&visits[i], true, loc))
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	linkedFrom := map[string]uint{} // Media URL to the first entry using it
+	out.beginArray("scrapbookEntries")
+	var entries []models.ScrapbookEntry
+	err = preloadMedia(h.db.Preload("Country")).Where("user_id = ?", user.ID).
+		FindInBatches(&entries, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range entries {
+				out.item(toScrapbookEntryResponse(&entries[i], true, loc))
+				for _, item := range entryMediaItems(&entries[i]) {
+					if _, seen := linkedFrom[item.URL]; !seen {
+						linkedFrom[item.URL] = entries[i].ID
+					}
+				}
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	// The manifest comes from the user's own upload records, so files they
+	// never attached are listed and other users' files never are
+	var filenames []string
+	out.beginArray("media")
+	var uploads []models.Upload
+	err = h.db.Where("user_id = ?", user.ID).
+		FindInBatches(&uploads, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, upload := range uploads {
+				file := ExportMediaFile{
+					EntryID:   linkedFrom[upload.URL],
+					MediaURL:  upload.URL,
+					MediaType: upload.MimeType,
+				}
+				if file.EntryID == 0 && upload.EntryID != nil {
+					file.EntryID = *upload.EntryID
+				}
+				if includeMedia && h.storage != nil && h.storage.Exists(upload.Filename) {
+					file.File = exportMediaDir + upload.Filename
+					filenames = append(filenames, upload.Filename)
+				}
+				out.item(file)
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	out.beginArray("comments")
+	var comments []models.Comment
+	userEntries := h.db.Unscoped().Model(&models.ScrapbookEntry{}).Select("id").Where("user_id = ?", user.ID)
+	err = h.db.Preload("Author").Where("entry_id IN (?) OR author_user_id = ?", userEntries, user.ID).
+		FindInBatches(&comments, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range comments {
+				out.item(toCommentResponse(&comments[i], loc))
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	out.beginArray("feedback")
+	var feedback []models.Feedback
+	err = h.db.Preload("Instructor").Where("learner_user_id = ?", user.ID).
+		FindInBatches(&feedback, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range feedback {
+				out.item(toFeedbackResponse(&feedback[i], loc))
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	out.beginArray("launchEvents")
+	var launches []models.LaunchEvent
+	err = h.db.Where("user_id = ?", user.ID).
+		FindInBatches(&launches, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, event := range launches {
+				out.item(ExportLaunchEvent{
+					Stage:       event.Stage,
+					CourseID:    event.CourseID,
+					MessageType: event.MessageType,
+					Success:     event.Success,
+					Error:       event.Error,
+					IPAddress:   event.IPAddress,
+					CreatedAt:   formatTimestamp(event.CreatedAt, loc),
+				})
+			}
+			flush()
+			return out.err
+		}).Error
+	out.endArray()
+	if err != nil {
+		return nil, err
+	}
+
+	out.close()
+	flush()
+	return filenames, out.err
+}
+
+// jsonStreamWriter writes a JSON object incrementally. The first write error
+// is kept in err and makes every later call a no-op.
+type jsonStreamWriter struct {
+	w       io.Writer
+	err     error
+	started bool // object opened by the first field
+	inArray bool // at least one item written to the current array
+}
+
+func (s *jsonStreamWriter) write(parts ...[]byte) {
+	for _, p := range parts {
+		if s.err != nil {
+			return
+		}
+		_, s.err = s.w.Write(p)
+	}
+}
+
+func (s *jsonStreamWriter) key(name string) {
+	sep := []byte(",")
+	if !s.started {
+		sep = []byte("{")
+		s.started = true
+	}
+	encoded, _ := json.Marshal(name)
+	s.write(sep, encoded, []byte(":"))
+}
+
+func (s *jsonStreamWriter) value(v any) []byte {
+	if s.err != nil {
+		return nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		s.err = err
+	}
+	return encoded
+}
+
+// field writes a complete "name": value pair
+func (s *jsonStreamWriter) field(name string, v any) {
+	s.key(name)
+	s.write(s.value(v))
+}
+
+// beginArray opens a "name": [ array
+func (s *jsonStreamWriter) beginArray(name string) {
+	s.key(name)
+	s.write([]byte("["))
+	s.inArray = false
+}
+
+// item appends a value to the open array
+func (s *jsonStreamWriter) item(v any) {
+	encoded := s.value(v)
+	if s.inArray {
+		s.write([]byte(","))
+	}
+	s.write(encoded)
+	s.inArray = true
+}
+
+// endArray closes the open array
+func (s *jsonStreamWriter) endArray() {
+	s.write([]byte("]"))
+}
+
+// close terminates the object
+func (s *jsonStreamWriter) close() {
+	if !s.started {
+		s.write([]byte("{"))
+	}
+	s.write([]byte("}"))
 }
@@ -1,14 +1,20 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"strings"
 	"testing"
 
 	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
@@ -25,7 +31,8 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.Visit{}, &models.ScrapbookEntry{}, &models.ScrapbookMedia{}, &models.IdempotencyKey{}, &models.Upload{},
+		&models.Comment{}, &models.Feedback{}, &models.LaunchEvent{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -53,7 +60,7 @@ func TestUserHandler_GetMe_Authenticated(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
 
-	handler := NewUserHandler(db)
+	handler := NewUserHandler(db, nil, nil)
 
 	router := gin.New()
 	router.Use(middleware.AuthMiddleware(sm))
@@ -91,9 +98,40 @@ func TestUserHandler_GetMe_Authenticated(t *testing.T) {
 	}
 }
 
+func TestUserHandler_GetMe_LocaleAndPicture(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	db.Model(user).Updates(map[string]interface{}{"locale": "fr-FR", "picture_url": "https://canvas.example.com/avatars/1.png"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	handler := NewUserHandler(db, nil, nil)
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me", handler.GetMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	var response MeResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Locale != "fr-FR" {
+		t.Errorf("expected locale 'fr-FR', got '%s'", response.Locale)
+	}
+	if response.PictureURL != "https://canvas.example.com/avatars/1.png" {
+		t.Errorf("expected picture URL, got '%s'", response.PictureURL)
+	}
+}
+
 func TestUserHandler_GetMe_Unauthenticated(t *testing.T) {
 	db := setupTestDB(t)
-	handler := NewUserHandler(db)
+	handler := NewUserHandler(db, nil, nil)
 
 	router := gin.New()
 	router.GET("/api/v1/me", handler.GetMe)
@@ -115,7 +153,7 @@ func TestUserHandler_GetMe_UserNotFound(t *testing.T) {
 	// Token for non-existent user
 	token, _ := sm.CreateToken(999, "canvas-999", "course-456", "learner")
 
-	handler := NewUserHandler(db)
+	handler := NewUserHandler(db, nil, nil)
 
 	router := gin.New()
 	router.Use(middleware.AuthMiddleware(sm))
@@ -139,7 +177,7 @@ func TestUserHandler_Logout(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
 
-	handler := NewUserHandler(db)
+	handler := NewUserHandler(db, nil, nil)
 
 	router := gin.New()
 	router.Use(middleware.AuthMiddleware(sm))
@@ -178,7 +216,7 @@ func TestUserHandler_GetMe_InstructorRole(t *testing.T) {
 	sm := lti.NewSessionManager("test-secret", 3600)
 	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-789", "instructor")
 
-	handler := NewUserHandler(db)
+	handler := NewUserHandler(db, nil, nil)
 
 	router := gin.New()
 	router.Use(middleware.AuthMiddleware(sm))
@@ -203,3 +241,753 @@ func TestUserHandler_GetMe_InstructorRole(t *testing.T) {
 		t.Errorf("expected Role 'instructor', got '%s'", response.Role)
 	}
 }
+
+func TestUserHandler_ExportMe(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	other := &models.User{CanvasUserID: "canvas-999", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	france := &models.Country{Name: "France", ISOCode: "FR", Region: "Europe"}
+	db.Create(france)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: france.ID, Notes: "Mine"})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: france.ID, Notes: "Mine again"})
+	mine := &models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "My entry"}
+	db.Create(mine)
+	db.Create(&models.Visit{UserID: other.ID, CountryID: france.ID, Notes: "Theirs"})
+	theirs := &models.ScrapbookEntry{UserID: other.ID, CountryID: france.ID, Title: "Their entry"}
+	db.Create(theirs)
+
+	db.Create(&models.Comment{EntryID: mine.ID, AuthorUserID: other.ID, Body: "About me"})
+	db.Create(&models.Comment{EntryID: theirs.ID, AuthorUserID: user.ID, Body: "By me"})
+	db.Create(&models.Comment{EntryID: theirs.ID, AuthorUserID: other.ID, Body: "Not mine"})
+	db.Create(&models.Feedback{CourseID: "course-456", LearnerUserID: user.ID, InstructorUserID: other.ID, Comment: "For me"})
+	db.Create(&models.Feedback{CourseID: "course-456", LearnerUserID: other.ID, InstructorUserID: user.ID, Comment: "For them"})
+	db.Create(&models.LaunchEvent{Stage: "launch", UserID: &user.ID, CourseID: "course-456", Success: true})
+	db.Create(&models.LaunchEvent{Stage: "launch", UserID: &other.ID, Success: true})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	handler := NewUserHandler(db, nil, nil)
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/export", handler.ExportMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") {
+		t.Errorf("expected attachment Content-Disposition, got '%s'", disposition)
+	}
+
+	var export struct {
+		ExportedAt       string                   `json:"exportedAt"`
+		Profile          ExportProfile            `json:"profile"`
+		Visits           []VisitResponse          `json:"visits"`
+		ScrapbookEntries []ScrapbookEntryResponse `json:"scrapbookEntries"`
+		Comments         []CommentResponse        `json:"comments"`
+		Feedback         []FeedbackResponse       `json:"feedback"`
+		LaunchEvents     []ExportLaunchEvent      `json:"launchEvents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("failed to parse export: %v\n%s", err, w.Body.String())
+	}
+
+	if export.ExportedAt == "" {
+		t.Error("expected exportedAt to be set")
+	}
+	if export.Profile.ID != user.ID || export.Profile.Email != "test@example.com" {
+		t.Errorf("expected profile of user %d, got %+v", user.ID, export.Profile)
+	}
+	if len(export.Visits) != 2 {
+		t.Errorf("expected 2 visits, got %d", len(export.Visits))
+	}
+	for _, v := range export.Visits {
+		if v.Notes == "Theirs" {
+			t.Error("export contains another user's visit")
+		}
+	}
+	if len(export.ScrapbookEntries) != 1 || export.ScrapbookEntries[0].Title != "My entry" {
+		t.Errorf("expected only the user's entry, got %+v", export.ScrapbookEntries)
+	}
+	if len(export.Comments) != 2 {
+		t.Errorf("expected the comments on and by the user, got %+v", export.Comments)
+	}
+	for _, comment := range export.Comments {
+		if comment.Body == "Not mine" {
+			t.Error("export contains a comment neither by nor about the user")
+		}
+	}
+	if len(export.Feedback) != 1 || export.Feedback[0].Comment != "For me" {
+		t.Errorf("expected only the feedback the user received, got %+v", export.Feedback)
+	}
+	if len(export.LaunchEvents) != 1 || export.LaunchEvents[0].CourseID != "course-456" {
+		t.Errorf("expected only the user's launch events, got %+v", export.LaunchEvents)
+	}
+}
+
+func TestUserHandler_ExportMe_Empty(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/export", NewUserHandler(db, nil, nil).ExportMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	var export map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("failed to parse export: %v\n%s", err, w.Body.String())
+	}
+	for _, section := range []string{"visits", "scrapbookEntries", "media", "comments", "feedback", "launchEvents"} {
+		if string(export[section]) != "[]" {
+			t.Errorf("expected empty %s, got %s", section, export[section])
+		}
+	}
+}
+
+// createExportMediaTestData creates a user with two entries using the same
+// uploaded photo, an upload not attached to any entry, an entry linking to
+// external media, an entry linking to another user's upload and one without
+// media. Returns the user, the photo's URL and the unattached upload's URL.
+func createExportMediaTestData(t *testing.T, db *gorm.DB, s *storage.LocalStorage) (*models.User, string, string) {
+	user := createTestUser(t, db)
+	other := &models.User{CanvasUserID: "canvas-999", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+	france := &models.Country{Name: "France", ISOCode: "FR", Region: "Europe"}
+	db.Create(france)
+
+	mediaURL := uploadOwnedMedia(t, db, s, user.ID)
+	loose := uploadOwnedMedia(t, db, s, user.ID)
+	theirs := uploadOwnedMedia(t, db, s, other.ID)
+
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "Photo", MediaURL: mediaURL, MediaType: "image/jpeg"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "Photo again", MediaURL: mediaURL, MediaType: "image/jpeg"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "Linked", MediaURL: "https://example.com/eiffel.jpg"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "Theirs", MediaURL: theirs, MediaType: "image/jpeg"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: france.ID, Title: "Text only"})
+	return user, mediaURL, loose
+}
+
+func TestUserHandler_ExportMe_MediaManifest(t *testing.T) {
+	db := setupTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	user, mediaURL, loose := createExportMediaTestData(t, db, s)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/export", NewUserHandler(db, sm, s).ExportMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	var export struct {
+		ScrapbookEntries []ScrapbookEntryResponse `json:"scrapbookEntries"`
+		Media            []ExportMediaFile        `json:"media"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("failed to parse export: %v\n%s", err, w.Body.String())
+	}
+
+	if len(export.ScrapbookEntries) != 5 {
+		t.Errorf("expected 5 entries, got %d", len(export.ScrapbookEntries))
+	}
+	// Only the user's own uploads are listed, once each
+	if len(export.Media) != 2 {
+		t.Fatalf("expected 2 media files in manifest, got %+v", export.Media)
+	}
+	if export.Media[0].MediaURL != mediaURL || export.Media[0].MediaType != "image/jpeg" || export.Media[0].EntryID == 0 {
+		t.Errorf("unexpected manifest entry: %+v", export.Media[0])
+	}
+	if export.Media[1].MediaURL != loose || export.Media[1].EntryID != 0 {
+		t.Errorf("expected the unattached upload in the manifest, got %+v", export.Media[1])
+	}
+	for _, file := range export.Media {
+		if file.File != "" {
+			t.Errorf("expected no archive paths in a JSON export, got %q", file.File)
+		}
+	}
+}
+
+func TestUserHandler_ExportMe_Zip(t *testing.T) {
+	db := setupTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	user, mediaURL, loose := createExportMediaTestData(t, db, s)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/export", NewUserHandler(db, sm, s).ExportMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export?format=zip", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, ".zip") {
+		t.Errorf("expected zip attachment, got %q", disposition)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var export struct {
+		Profile ExportProfile     `json:"profile"`
+		Media   []ExportMediaFile `json:"media"`
+	}
+	if err := json.Unmarshal(files["export.json"], &export); err != nil {
+		t.Fatalf("failed to parse export.json: %v", err)
+	}
+	if export.Profile.ID != user.ID {
+		t.Errorf("expected profile of user %d, got %d", user.ID, export.Profile.ID)
+	}
+
+	mediaPath := "media/" + path.Base(mediaURL)
+	loosePath := "media/" + path.Base(loose)
+	if len(export.Media) != 2 || export.Media[0].File != mediaPath || export.Media[1].File != loosePath {
+		t.Errorf("expected the user's uploads to be included, got %+v", export.Media)
+	}
+	if string(files[mediaPath]) != "photo" {
+		t.Errorf("expected archived media content, got %q", files[mediaPath])
+	}
+	// The shared photo is archived once and the other user's file not at all
+	if len(archive.File) != 3 {
+		t.Errorf("expected export.json and two media files, got %d files", len(archive.File))
+	}
+}
+
+func TestUserHandler_ExportMe_InvalidFormat(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/export", NewUserHandler(db, nil, nil).ExportMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export?format=tar", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func createDeleteMeTestRouter(db *gorm.DB, sm *lti.SessionManager, s storage.Storage) *gin.Engine {
+	handler := NewUserHandler(db, sm, s)
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me", handler.GetMe)
+	router.DELETE("/api/v1/me", handler.DeleteMe)
+	return router
+}
+
+func sendUpdateMeRequest(router *gin.Engine, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/me", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createUpdateMeTestRouter(db *gorm.DB, sm *lti.SessionManager) *gin.Engine {
+	handler := NewUserHandler(db, sm, nil)
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.PUT("/api/v1/me", handler.UpdateMe)
+	return router
+}
+
+func TestUserHandler_UpdateMe(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+	router := createUpdateMeTestRouter(db, sm)
+
+	w := sendUpdateMeRequest(router, token,
+		`{"displayName": "  Ada Lovelace ", "email": "ada@example.edu", "canvasId": "hijack"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response MeResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.DisplayName != "Ada Lovelace" || response.Email != "ada@example.edu" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if response.CanvasID != "canvas-123" {
+		t.Errorf("expected Canvas ID to come from the session, got %q", response.CanvasID)
+	}
+
+	var stored models.User
+	db.First(&stored, user.ID)
+	if stored.DisplayName != "Ada Lovelace" || stored.Email != "ada@example.edu" {
+		t.Errorf("expected user to be updated, got %q / %q", stored.DisplayName, stored.Email)
+	}
+	if stored.CanvasUserID != user.CanvasUserID || stored.CanvasInstanceURL != user.CanvasInstanceURL {
+		t.Error("expected Canvas identity to be unchanged")
+	}
+
+	// Omitted fields are left alone
+	w = sendUpdateMeRequest(router, token, `{"displayName": "Ada"}`)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.DisplayName != "Ada" || response.Email != "ada@example.edu" {
+		t.Errorf("expected only the display name to change, got %+v", response)
+	}
+}
+
+func TestUserHandler_UpdateMe_InvalidEmail(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+	router := createUpdateMeTestRouter(db, sm)
+
+	w := sendUpdateMeRequest(router, token, `{"displayName": "Ada", "email": "not-an-email"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ValidationErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Errors) != 1 || response.Errors[0].Field != "email" || response.Errors[0].Rule != "email" {
+		t.Errorf("expected an email field error, got %+v", response.Errors)
+	}
+
+	var stored models.User
+	db.First(&stored, user.ID)
+	if stored.DisplayName != user.DisplayName {
+		t.Errorf("expected user to be unchanged, got %q", stored.DisplayName)
+	}
+}
+
+func TestUserHandler_UpdateMe_EmailTrimmedAndCleared(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+	router := createUpdateMeTestRouter(db, sm)
+
+	// Padding is trimmed before the address is checked
+	w := sendUpdateMeRequest(router, token, `{"email": "  ada@example.edu "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for a padded address, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.User
+	db.First(&stored, user.ID)
+	if stored.Email != "ada@example.edu" {
+		t.Errorf("expected the trimmed address to be stored, got %q", stored.Email)
+	}
+
+	// An empty string clears the address
+	w = sendUpdateMeRequest(router, token, `{"email": ""}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 when clearing the email, got %d: %s", w.Code, w.Body.String())
+	}
+	db.First(&stored, user.ID)
+	if stored.Email != "" {
+		t.Errorf("expected the email to be cleared, got %q", stored.Email)
+	}
+}
+
+func TestUserHandler_UpdateMe_Unauthenticated(t *testing.T) {
+	db := setupTestDB(t)
+	router := createUpdateMeTestRouter(db, lti.NewSessionManager("test-secret", 3600))
+
+	if w := sendUpdateMeRequest(router, "", `{"displayName": "Ada"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
+
+func TestUserHandler_DeleteMe(t *testing.T) {
+	db := setupTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	sm := lti.NewSessionManager("test-secret", 3600)
+	router := createDeleteMeTestRouter(db, sm, s)
+
+	user := createTestUser(t, db)
+	db.Model(user).Updates(map[string]interface{}{"picture_url": "https://example.com/me.png", "locale": "fr"})
+	other := &models.User{CanvasUserID: "canvas-999", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	mediaURL := uploadOwnedMedia(t, db, s, user.ID)
+	theirs := uploadOwnedMedia(t, db, s, other.ID)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: 1})
+	mine := &models.ScrapbookEntry{UserID: user.ID, CountryID: 1, Title: "Mine", MediaURL: mediaURL}
+	db.Create(mine)
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: 1, Title: "Borrowed", MediaURL: theirs})
+	db.Create(&models.IdempotencyKey{UserID: user.ID, Key: "k1", ResourceType: idempotencyResourceVisit, ResourceID: 1})
+	db.Create(&models.Visit{UserID: other.ID, CountryID: 1})
+	otherEntry := &models.ScrapbookEntry{UserID: other.ID, CountryID: 1, Title: "Theirs"}
+	db.Create(otherEntry)
+
+	db.Create(&models.Comment{EntryID: mine.ID, AuthorUserID: other.ID, Body: "About me"})
+	db.Create(&models.Comment{EntryID: otherEntry.ID, AuthorUserID: user.ID, Body: "By me"})
+	db.Create(&models.Comment{EntryID: otherEntry.ID, AuthorUserID: other.ID, Body: "Not mine"})
+	db.Create(&models.Feedback{CourseID: "course-456", LearnerUserID: user.ID, InstructorUserID: other.ID})
+	db.Create(&models.LaunchEvent{Stage: "launch", UserID: &user.ID, Success: true})
+
+	// An upload never attached to an entry
+	loose := uploadOwnedMedia(t, db, s, user.ID)
+
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/me", strings.NewReader(`{"confirm":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Session cookie cleared
+	var cleared bool
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session" && cookie.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("expected session cookie to be cleared")
+	}
+
+	// User soft-deleted, data removed
+	var userCount, deletedCount int64
+	db.Model(&models.User{}).Where("id = ?", user.ID).Count(&userCount)
+	db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&deletedCount)
+	if userCount != 0 || deletedCount != 1 {
+		t.Errorf("expected user to be soft-deleted, got %d active and %d total", userCount, deletedCount)
+	}
+
+	var visitCount, entryCount, keyCount int64
+	db.Unscoped().Model(&models.Visit{}).Where("user_id = ?", user.ID).Count(&visitCount)
+	db.Unscoped().Model(&models.ScrapbookEntry{}).Where("user_id = ?", user.ID).Count(&entryCount)
+	db.Model(&models.IdempotencyKey{}).Where("user_id = ?", user.ID).Count(&keyCount)
+	if visitCount != 0 || entryCount != 0 || keyCount != 0 {
+		t.Errorf("expected user data removed, got %d visits, %d entries, %d keys", visitCount, entryCount, keyCount)
+	}
+
+	var otherVisits int64
+	db.Model(&models.Visit{}).Where("user_id = ?", other.ID).Count(&otherVisits)
+	if otherVisits != 1 {
+		t.Errorf("expected other user's visit to remain, got %d", otherVisits)
+	}
+
+	var comments []models.Comment
+	var feedbackCount, launchCount int64
+	db.Find(&comments)
+	db.Model(&models.Feedback{}).Count(&feedbackCount)
+	db.Model(&models.LaunchEvent{}).Count(&launchCount)
+	if len(comments) != 1 || comments[0].Body != "Not mine" {
+		t.Errorf("expected only the comment neither by nor about the user to remain, got %+v", comments)
+	}
+	if feedbackCount != 0 || launchCount != 0 {
+		t.Errorf("expected the user's feedback and launch events removed, got %d feedback and %d launch events", feedbackCount, launchCount)
+	}
+
+	if s.Exists(mediaURL) || s.Exists(loose) {
+		t.Error("expected uploaded media to be removed")
+	}
+	if !s.Exists(theirs) {
+		t.Error("expected another user's upload linked from an entry to remain")
+	}
+
+	var uploadCount int64
+	db.Unscoped().Model(&models.Upload{}).Where("user_id = ?", user.ID).Count(&uploadCount)
+	if uploadCount != 0 {
+		t.Errorf("expected upload records removed, got %d", uploadCount)
+	}
+
+	// The soft-deleted row keeps no personal data
+	var deleted models.User
+	db.Unscoped().First(&deleted, user.ID)
+	if deleted.DisplayName != "" || deleted.Email != "" || deleted.PictureURL != "" || deleted.Locale != "" ||
+		deleted.CanvasUserID == user.CanvasUserID {
+		t.Errorf("expected personal fields scrubbed, got %+v", deleted)
+	}
+
+	// The old token no longer authenticates
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 with revoked session, got %d", w.Code)
+	}
+}
+
+func TestUserHandler_DeleteMe_Anonymize(t *testing.T) {
+	db := setupTestDB(t)
+	s, cleanup := setupUploadTestStorage(t)
+	defer cleanup()
+	sm := lti.NewSessionManager("test-secret", 3600)
+	router := createDeleteMeTestRouter(db, sm, s)
+
+	user := createTestUser(t, db)
+	db.Model(user).Updates(map[string]interface{}{"display_name": "Ada Learner", "email": "ada@example.edu"})
+
+	mediaURL := uploadOwnedMedia(t, db, s, user.ID)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: 1, Notes: "Met my cousin"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: 2, Title: "Family", Notes: "Private", Tags: "family", MediaURL: mediaURL})
+	db.Create(&models.IdempotencyKey{UserID: user.ID, Key: "k1", ResourceType: idempotencyResourceVisit, ResourceID: 1})
+
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/me?anonymize=true", strings.NewReader(`{"confirm":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.User
+	db.Unscoped().First(&stored, user.ID)
+	if !stored.DeletedAt.Valid {
+		t.Error("expected user to be soft-deleted")
+	}
+	if stored.DisplayName != "" || stored.Email != "" || stored.CanvasUserID == "canvas-123" {
+		t.Errorf("expected personal fields cleared, got %+v", stored)
+	}
+
+	// Aggregate rows remain without personal content
+	var visits []models.Visit
+	db.Where("user_id = ?", user.ID).Find(&visits)
+	if len(visits) != 1 || visits[0].CountryID != 1 || visits[0].Notes != "" {
+		t.Errorf("expected one anonymized visit, got %+v", visits)
+	}
+
+	var entries []models.ScrapbookEntry
+	db.Where("user_id = ?", user.ID).Find(&entries)
+	if len(entries) != 1 || entries[0].CountryID != 2 {
+		t.Fatalf("expected one anonymized entry, got %+v", entries)
+	}
+	if entries[0].Title != "" || entries[0].Notes != "" || entries[0].Tags != "" || entries[0].MediaURL != "" {
+		t.Errorf("expected entry content stripped, got %+v", entries[0])
+	}
+
+	var keyCount int64
+	db.Model(&models.IdempotencyKey{}).Where("user_id = ?", user.ID).Count(&keyCount)
+	if keyCount != 0 {
+		t.Errorf("expected idempotency keys removed, got %d", keyCount)
+	}
+
+	if s.Exists(mediaURL) {
+		t.Error("expected uploaded media to be removed")
+	}
+}
+
+func TestUserHandler_DeleteMe_RequiresConfirmation(t *testing.T) {
+	db := setupTestDB(t)
+	sm := lti.NewSessionManager("test-secret", 3600)
+	router := createDeleteMeTestRouter(db, sm, nil)
+
+	user := createTestUser(t, db)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	for _, body := range []string{`{}`, `{"confirm":false}`} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/me", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for body %s, got %d", body, w.Code)
+		}
+	}
+
+	var userCount int64
+	db.Model(&models.User{}).Count(&userCount)
+	if userCount != 1 {
+		t.Error("expected user to remain without confirmation")
+	}
+}
+
+func TestUserHandler_GetMe_Stats(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+	other := &models.User{CanvasUserID: "canvas-999", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	// Two visits to country 1 count once
+	db.Create(&models.Visit{UserID: user.ID, CountryID: 1})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: 1})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: 2})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: 1, Title: "One"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: 2, Title: "Two"})
+	db.Create(&models.ScrapbookEntry{UserID: user.ID, CountryID: 2, Title: "Three"})
+	db.Create(&models.Visit{UserID: other.ID, CountryID: 3})
+	db.Create(&models.ScrapbookEntry{UserID: other.ID, CountryID: 3, Title: "Theirs"})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me", NewUserHandler(db, nil, nil).GetMe)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response MeResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.CountriesVisited != 2 {
+		t.Errorf("expected 2 countries visited, got %d", response.CountriesVisited)
+	}
+	if response.TotalEntries != 3 {
+		t.Errorf("expected 3 entries, got %d", response.TotalEntries)
+	}
+}
+
+func TestUserHandler_GetPassport(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db)
+
+	countries := []models.Country{
+		{Name: "France", ISOCode: "FR", Region: "Europe"},
+		{Name: "Germany", ISOCode: "DE", Region: "Europe"},
+		{Name: "Italy", ISOCode: "IT", Region: "Europe"},
+		{Name: "Japan", ISOCode: "JP", Region: "Asia"},
+		{Name: "Kenya", ISOCode: "KE", Region: "Africa"},
+	}
+	for i := range countries {
+		db.Create(&countries[i])
+	}
+
+	// France twice, Japan once; another user's Kenya doesn't count
+	db.Create(&models.Visit{UserID: user.ID, CountryID: countries[0].ID})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: countries[0].ID})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: countries[3].ID})
+	db.Create(&models.Visit{UserID: user.ID + 1, CountryID: countries[4].ID})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-456", "learner")
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.GET("/api/v1/me/passport", NewUserHandler(db, nil, nil).GetPassport)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/passport", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response PassportResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	want := []PassportRegion{
+		{Region: "Africa", Total: 1, Visited: 0, Percent: 0},
+		{Region: "Asia", Total: 1, Visited: 1, Percent: 100},
+		{Region: "Europe", Total: 3, Visited: 1, Percent: 33.3},
+	}
+	if len(response.Regions) != len(want) {
+		t.Fatalf("expected %d regions, got %+v", len(want), response.Regions)
+	}
+	for i, region := range want {
+		if response.Regions[i] != region {
+			t.Errorf("expected region %+v, got %+v", region, response.Regions[i])
+		}
+	}
+
+	if response.Total != 5 || response.Visited != 2 || response.Percent != 40 {
+		t.Errorf("expected 2 of 5 visited (40%%), got %d of %d (%v%%)", response.Visited, response.Total, response.Percent)
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		part, total int64
+		want        float64
+	}{
+		{0, 0, 0},
+		{0, 10, 0},
+		{1, 3, 33.3},
+		{2, 3, 66.7},
+		{5, 5, 100},
+	}
+
+	for _, tt := range tests {
+		if got := percentOf(tt.part, tt.total); got != tt.want {
+			t.Errorf("percentOf(%d, %d) = %v, want %v", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
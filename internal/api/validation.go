@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single request field that failed validation
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"` // Rule parameter, e.g. the limit for max
+}
+
+// ValidationErrorResponse represents a 400 response for an invalid request body
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// respondBindError writes a 400 response listing the fields of req that
+// failed binding, using their JSON names
+func respondBindError(c *gin.Context, req interface{}, err error) {
+	c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+		Error:  "invalid request body",
+		Errors: fieldErrors(req, err),
+	})
+}
+
+// fieldErrors extracts per-field failures from a binding error
+func fieldErrors(req interface{}, err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		result := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			result = append(result, FieldError{
+				Field: jsonFieldName(req, fe.StructField()),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+			})
+		}
+		return result
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Rule: "type"}}
+	}
+
+	return nil
+}
+
+// validEmail reports whether s passes the same "email" rule as request
+// bodies, for fields that must be normalized before they are checked
+func validEmail(s string) bool {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	return ok && v.Var(s, "email") == nil
+}
+
+// jsonFieldName returns the JSON tag name for a struct field, falling back to
+// the Go field name when no tag is present
+func jsonFieldName(req interface{}, structField string) string {
+	t := reflect.TypeOf(req)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return structField
+	}
+
+	field, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFieldErrors_TypeMismatch(t *testing.T) {
+	var req CreateVisitRequest
+	err := json.Unmarshal([]byte(`{"countryId":"france"}`), &req)
+	if err == nil {
+		t.Fatal("expected unmarshal error")
+	}
+
+	errs := fieldErrors(&req, err)
+	if len(errs) != 1 || errs[0].Field != "countryId" || errs[0].Rule != "type" {
+		t.Errorf("expected countryId/type error, got %v", errs)
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	if !validEmail("ada@example.edu") {
+		t.Error("expected a plain address to be valid")
+	}
+	for _, s := range []string{"", "not-an-email", " ada@example.edu"} {
+		if validEmail(s) {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestJSONFieldName(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         interface{}
+		structField string
+		want        string
+	}{
+		{"tagged field", &CreateVisitRequest{}, "CountryID", "countryId"},
+		{"non-pointer", CreateScrapbookEntryRequest{}, "Title", "title"},
+		{"unknown field", &CreateVisitRequest{}, "Missing", "Missing"},
+		{"non-struct", "not a struct", "Field", "Field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonFieldName(tt.req, tt.structField); got != tt.want {
+				t.Errorf("jsonFieldName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,49 +17,110 @@ import (
 
 // VisitHandler handles visit-related API endpoints
 type VisitHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	webhooks  *webhook.Notifier
+	text      TextPolicy
+	maxVisits int // Visits each user may have; 0 for no limit
+
+	maxPageSize int // Largest page size list requests may ask for
+}
+
+// errUserLimitReached is returned inside a create transaction when the user
+// already has as many resources as allowed
+var errUserLimitReached = errors.New("user limit reached")
+
+// checkUserLimit returns errUserLimitReached if the user already has limit
+// or more rows of model. A non-positive limit never rejects.
+func checkUserLimit(tx *gorm.DB, model interface{}, userID uint, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return errUserLimitReached
+	}
+	return nil
 }
 
 // NewVisitHandler creates a new visit handler
 func NewVisitHandler(db *gorm.DB) *VisitHandler {
-	return &VisitHandler{db: db}
+	return &VisitHandler{db: db, text: DefaultTextPolicy(), maxPageSize: defaultMaxPageSize}
+}
+
+// SetMaxPageSize sets the largest page size list requests may ask for;
+// non-positive values keep the default
+func (h *VisitHandler) SetMaxPageSize(n int) {
+	h.maxPageSize = pageSizeCap(n)
+}
+
+// SetNotifier sends a webhook event for each created visit; nil disables it
+func (h *VisitHandler) SetNotifier(n *webhook.Notifier) {
+	h.webhooks = n
+}
+
+// SetTextPolicy sets the notes limit and HTML handling for visit notes; a
+// non-positive notes limit keeps the default
+func (h *VisitHandler) SetTextPolicy(p TextPolicy) {
+	h.text = p.withDefaults()
+}
+
+// SetMaxVisits caps how many visits each user may record. Creating one more
+// returns 409 Conflict. Zero or less removes the cap.
+func (h *VisitHandler) SetMaxVisits(n int) {
+	h.maxVisits = n
 }
 
 // VisitResponse represents a visit in API responses
 type VisitResponse struct {
 	ID        uint             `json:"id"`
 	CountryID uint             `json:"countryId"`
+	CourseID  string           `json:"courseId,omitempty"`
 	VisitedAt string           `json:"visitedAt"`
 	Notes     string           `json:"notes,omitempty"`
+	Latitude  *float64         `json:"latitude,omitempty"`
+	Longitude *float64         `json:"longitude,omitempty"`
 	Country   *CountryResponse `json:"country,omitempty"`
 }
 
 // VisitListResponse represents the response for listing visits
 type VisitListResponse struct {
-	Visits []VisitResponse `json:"visits"`
-	Total  int64           `json:"total"`
+	Visits     []VisitResponse `json:"visits"`
+	Total      int64           `json:"total"`
+	Pagination *PageInfo       `json:"pagination,omitempty"` // Set when paging is requested
+	NextCursor string          `json:"nextCursor,omitempty"` // Set in cursor mode while more visits follow
 }
 
-// CreateVisitRequest represents the request body for creating a visit
+// CreateVisitRequest represents the request body for creating a visit.
+// Either countryId or both latitude and longitude are required; the country
+// of a check-in without countryId is inferred from its coordinates.
 type CreateVisitRequest struct {
-	CountryID uint   `json:"countryId" binding:"required"`
-	VisitedAt string `json:"visitedAt"` // Optional, defaults to now
-	Notes     string `json:"notes"`
+	CountryID uint     `json:"countryId"`
+	VisitedAt string   `json:"visitedAt"` // Optional, defaults to now
+	Notes     string   `json:"notes"`
+	Latitude  *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
 }
 
-// UpdateVisitRequest represents the request body for updating a visit
+// UpdateVisitRequest represents the request body for updating a visit.
+// Omitted fields are left unchanged; an empty notes string clears the notes.
 type UpdateVisitRequest struct {
-	VisitedAt string `json:"visitedAt"`
-	Notes     string `json:"notes"`
+	VisitedAt *string `json:"visitedAt"`
+	Notes     *string `json:"notes"`
 }
 
-// toVisitResponse converts a model to a response
-func toVisitResponse(v *models.Visit, includeCountry bool) VisitResponse {
+// toVisitResponse converts a model to a response, rendering times in loc
+func toVisitResponse(v *models.Visit, includeCountry bool, loc *time.Location) VisitResponse {
 	resp := VisitResponse{
 		ID:        v.ID,
 		CountryID: v.CountryID,
-		VisitedAt: v.VisitedAt.Format(time.RFC3339),
+		CourseID:  v.CourseID,
+		VisitedAt: formatTimestamp(v.VisitedAt, loc),
 		Notes:     v.Notes,
+		Latitude:  v.Latitude,
+		Longitude: v.Longitude,
 	}
 
 	if includeCountry && v.Country.ID != 0 {
@@ -69,33 +133,85 @@ func toVisitResponse(v *models.Visit, includeCountry bool) VisitResponse {
 
 // ListVisits returns all visits for the authenticated user
 // GET /api/v1/visits
+// Query params: courseId (optional) - filter by course; untagged visits always match,
+// region (optional) - filter by the visited country's region,
+// from, to (optional) - RFC3339 bounds on visitedAt, both inclusive,
+// page, pageSize or limit, offset (optional) - page through visits; total is always the full count,
+// cursor (optional) - page by cursor instead, sized by limit or pageSize; empty for the first page,
+// then the previous response's nextCursor
 func (h *VisitHandler) ListVisits(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	dates, ok := parseDateRange(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
+	cursor, cursorMode, ok := parseCursorPage(c, h.maxPageSize)
+	if !ok {
+		return
+	}
+	var page Pagination
+	if !cursorMode {
+		if page, ok = parsePagination(c, h.maxPageSize); !ok {
+			return
+		}
+	}
+
 	var visits []models.Visit
-	query := h.db.Where("user_id = ?", userID).Preload("Country")
+	query := dates.apply(db.Preload("Country"))
+	countQuery := dates.apply(db.Model(&models.Visit{}))
+
+	// Filter by course if provided
+	if courseFilter := c.Query("courseId"); courseFilter != "" {
+		query = filterByCourse(query, courseFilter)
+		countQuery = filterByCourse(countQuery, courseFilter)
+	}
+
+	if region := c.Query("region"); region != "" {
+		query = filterByRegion(h.db, query, region)
+		countQuery = filterByRegion(h.db, countQuery, region)
+	}
 
 	// Get total count
 	var total int64
-	h.db.Model(&models.Visit{}).Where("user_id = ?", userID).Count(&total)
+	countQuery.Count(&total)
 
 	// Get visits (ordered by visit date, most recent first)
-	if err := query.Order("visited_at DESC").Find(&visits).Error; err != nil {
+	if cursorMode {
+		query = cursor.apply(query, "visited_at")
+	} else {
+		query = page.apply(query.Order("visited_at DESC").Order("id DESC"))
+	}
+	if err := query.Find(&visits).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visits"})
 		return
 	}
 
+	var nextCursor string
+	if cursorMode {
+		visits, nextCursor = cursorPageOf(cursor, visits, func(v *models.Visit) pageCursor {
+			return pageCursor{at: v.VisitedAt, id: v.ID}
+		})
+	}
+
 	response := VisitListResponse{
-		Visits: make([]VisitResponse, len(visits)),
-		Total:  total,
+		Visits:     make([]VisitResponse, len(visits)),
+		Total:      total,
+		Pagination: page.Info(total),
+		NextCursor: nextCursor,
 	}
 
 	for i, visit := range visits {
-		response.Visits[i] = toVisitResponse(&visit, true)
+		response.Visits[i] = toVisitResponse(&visit, true, loc)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -104,9 +220,13 @@ func (h *VisitHandler) ListVisits(c *gin.Context) {
 // GetVisit returns a specific visit
 // GET /api/v1/visits/:id
 func (h *VisitHandler) GetVisit(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -118,7 +238,7 @@ func (h *VisitHandler) GetVisit(c *gin.Context) {
 	}
 
 	var visit models.Visit
-	if err := h.db.Preload("Country").Where("id = ? AND user_id = ?", id, userID).First(&visit).Error; err != nil {
+	if err := db.Preload("Country").First(&visit, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "visit not found"})
 			return
@@ -127,70 +247,165 @@ func (h *VisitHandler) GetVisit(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, toVisitResponse(&visit, true))
+	c.JSON(http.StatusOK, toVisitResponse(&visit, true, loc))
 }
 
-// CreateVisit creates a new visit
+// CreateVisit creates a new visit. A check-in may send latitude and longitude
+// instead of countryId, and the visit is recorded in the nearest country.
 // POST /api/v1/visits
 func (h *VisitHandler) CreateVisit(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, userID, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
 	var req CreateVisitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBindError(c, &req, err)
+		return
+	}
+	if req.CountryID == 0 && req.Latitude == nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:  "invalid request body",
+			Errors: []FieldError{{Field: "countryId", Rule: "required"}},
+		})
+		return
+	}
+	if !h.text.checkLengths(c, "", req.Notes) {
+		return
+	}
+	req.Notes = h.text.cleanNotes(req.Notes)
+
+	// Replay the original visit if this request was already processed
+	idempotencyKey := getIdempotencyKey(c)
+	replay := func() bool {
+		visitID, found := lookupIdempotencyKey(h.db, userID, idempotencyKey, idempotencyResourceVisit)
+		if !found {
+			return false
+		}
+		var existing models.Visit
+		if err := db.Preload("Country").First(&existing, visitID).Error; err != nil {
+			return false
+		}
+		c.JSON(http.StatusOK, toVisitResponse(&existing, true, loc))
+		return true
+	}
+	if idempotencyKey != "" && replay() {
 		return
 	}
 
-	// Verify country exists
+	// Infer the country of a check-in, or verify the given country exists
 	var country models.Country
-	if err := h.db.First(&country, req.CountryID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "country not found"})
+	if req.CountryID == 0 {
+		nearest, err := nearestCountry(h.db, *req.Latitude, *req.Longitude)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve country"})
+			return
+		}
+		if nearest == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no country found near these coordinates"})
+			return
+		}
+		country = *nearest
+		req.CountryID = country.ID
+	} else {
+		if err := h.db.First(&country, req.CountryID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "country not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify country"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify country"})
-		return
 	}
 
 	// Parse visit date or use current time
 	visitedAt := time.Now()
 	if req.VisitedAt != "" {
-		parsed, err := time.Parse(time.RFC3339, req.VisitedAt)
+		parsed, err := parseVisitedAt(req.VisitedAt)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visitedAt format, use RFC3339"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidVisitedAt})
 			return
 		}
 		visitedAt = parsed
 	}
 
+	courseID, _ := middleware.GetCourseID(c)
+
 	visit := models.Visit{
 		UserID:    userID,
 		CountryID: req.CountryID,
+		CourseID:  courseID,
 		VisitedAt: visitedAt,
 		Notes:     req.Notes,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
 	}
 
-	if err := h.db.Create(&visit).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var claim *models.IdempotencyKey
+		if idempotencyKey != "" {
+			var err error
+			if claim, err = claimIdempotencyKey(tx, userID, idempotencyKey, idempotencyResourceVisit); err != nil {
+				return err
+			}
+		}
+		if err := checkUserLimit(tx, &models.Visit{}, userID, h.maxVisits); err != nil {
+			return err
+		}
+		if err := tx.Create(&visit).Error; err != nil {
+			return err
+		}
+		if claim != nil {
+			return completeIdempotencyKey(tx, claim, visit.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, errIdempotencyKeyInUse) {
+		// A concurrent request with the same key won the claim
+		if !replay() {
+			c.JSON(http.StatusConflict, gin.H{"error": errIdempotencyKeyConflict})
+		}
+		return
+	}
+	if errors.Is(err, errUserLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("visit limit reached: each user may record at most %d visits", h.maxVisits)})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create visit"})
 		return
 	}
 
+	h.webhooks.Notify(webhook.Event{
+		Type:       webhook.EventVisitCreated,
+		UserID:     userID,
+		ResourceID: visit.ID,
+		CountryID:  visit.CountryID,
+		Timestamp:  visit.CreatedAt.UTC(),
+	})
+
 	// Load country for response
 	visit.Country = country
 
-	c.JSON(http.StatusCreated, toVisitResponse(&visit, true))
+	c.JSON(http.StatusCreated, toVisitResponse(&visit, true, loc))
 }
 
 // UpdateVisit updates an existing visit
 // PUT /api/v1/visits/:id
 func (h *VisitHandler) UpdateVisit(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -203,13 +418,20 @@ func (h *VisitHandler) UpdateVisit(c *gin.Context) {
 
 	var req UpdateVisitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBindError(c, &req, err)
 		return
 	}
+	if req.Notes != nil {
+		if !h.text.checkLengths(c, "", *req.Notes) {
+			return
+		}
+		notes := h.text.cleanNotes(*req.Notes)
+		req.Notes = &notes
+	}
 
 	// Find existing visit
 	var visit models.Visit
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&visit).Error; err != nil {
+	if err := db.First(&visit, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "visit not found"})
 			return
@@ -218,18 +440,21 @@ func (h *VisitHandler) UpdateVisit(c *gin.Context) {
 		return
 	}
 
-	// Update fields
-	if req.VisitedAt != "" {
-		parsed, err := time.Parse(time.RFC3339, req.VisitedAt)
+	// Update only the fields that were sent
+	if req.VisitedAt != nil {
+		// A visit always has a date, so an empty value is rejected rather than cleared
+		parsed, err := parseVisitedAt(*req.VisitedAt)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visitedAt format, use RFC3339"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidVisitedAt})
 			return
 		}
 		visit.VisitedAt = parsed
 	}
-	visit.Notes = req.Notes
+	if req.Notes != nil {
+		visit.Notes = *req.Notes
+	}
 
-	if err := h.db.Save(&visit).Error; err != nil {
+	if err := db.Save(&visit).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update visit"})
 		return
 	}
@@ -237,15 +462,14 @@ func (h *VisitHandler) UpdateVisit(c *gin.Context) {
 	// Load country for response
 	h.db.First(&visit.Country, visit.CountryID)
 
-	c.JSON(http.StatusOK, toVisitResponse(&visit, true))
+	c.JSON(http.StatusOK, toVisitResponse(&visit, true, loc))
 }
 
 // DeleteVisit deletes a visit
 // DELETE /api/v1/visits/:id
 func (h *VisitHandler) DeleteVisit(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -258,7 +482,7 @@ func (h *VisitHandler) DeleteVisit(c *gin.Context) {
 
 	// Verify visit exists and belongs to user
 	var visit models.Visit
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&visit).Error; err != nil {
+	if err := db.First(&visit, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "visit not found"})
 			return
@@ -267,7 +491,7 @@ func (h *VisitHandler) DeleteVisit(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&visit).Error; err != nil {
+	if err := db.Delete(&visit).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete visit"})
 		return
 	}
@@ -277,10 +501,15 @@ func (h *VisitHandler) DeleteVisit(c *gin.Context) {
 
 // GetVisitsByCountry returns all visits for a specific country
 // GET /api/v1/visits/country/:countryId
+// Query params: page, pageSize or limit, offset (optional) - page through visits; total is always the full count
 func (h *VisitHandler) GetVisitsByCountry(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
@@ -291,8 +520,21 @@ func (h *VisitHandler) GetVisitsByCountry(c *gin.Context) {
 		return
 	}
 
+	page, ok := parsePagination(c, h.maxPageSize)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := db.Model(&models.Visit{}).
+		Where("country_id = ?", countryID).
+		Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visits"})
+		return
+	}
+
 	var visits []models.Visit
-	if err := h.db.Where("user_id = ? AND country_id = ?", userID, countryID).
+	if err := page.apply(db.Where("country_id = ?", countryID)).
 		Preload("Country").
 		Order("visited_at DESC").
 		Find(&visits).Error; err != nil {
@@ -302,8 +544,219 @@ func (h *VisitHandler) GetVisitsByCountry(c *gin.Context) {
 
 	response := make([]VisitResponse, len(visits))
 	for i, visit := range visits {
-		response[i] = toVisitResponse(&visit, true)
+		response[i] = toVisitResponse(&visit, true, loc)
+	}
+
+	c.JSON(http.StatusOK, listResponse("visits", response, total, page))
+}
+
+// timelineLayouts maps a timeline granularity to the layout of its period labels
+var timelineLayouts = map[string]string{
+	"month": "2006-01",
+	"year":  "2006",
+}
+
+// VisitTimelinePoint is the number of visits in one period
+type VisitTimelinePoint struct {
+	Period string `json:"period"` // "2024-06" for months, "2024" for years
+	Count  int    `json:"count"`
+}
+
+// VisitTimelineResponse represents visits bucketed by period
+type VisitTimelineResponse struct {
+	Granularity string               `json:"granularity"`
+	Timeline    []VisitTimelinePoint `json:"timeline"`
+}
+
+// GetTimeline returns the number of visits per month or year, oldest first.
+// Periods without visits are omitted.
+// GET /api/v1/visits/timeline
+// Query params: granularity (optional) - "month" (default) or "year",
+// courseId, from, to (optional) - filters as for ListVisits,
+// tz (optional) - zone whose calendar the visits are bucketed in
+func (h *VisitHandler) GetTimeline(c *gin.Context) {
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "month")
+	layout, ok := timelineLayouts[granularity]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be month or year"})
+		return
+	}
+
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	query := dates.apply(db.Model(&models.Visit{}))
+	if courseFilter := c.Query("courseId"); courseFilter != "" {
+		query = filterByCourse(query, courseFilter)
+	}
+
+	// Bucketing happens in Go since date truncation differs between SQLite and Postgres
+	var visitedAt []time.Time
+	if err := query.Order("visited_at ASC").Pluck("visited_at", &visitedAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visits"})
+		return
+	}
+
+	response := VisitTimelineResponse{
+		Granularity: granularity,
+		Timeline:    []VisitTimelinePoint{},
+	}
+	for _, t := range visitedAt {
+		period := t.In(loc).Format(layout)
+		if n := len(response.Timeline); n > 0 && response.Timeline[n-1].Period == period {
+			response.Timeline[n-1].Count++
+			continue
+		}
+		response.Timeline = append(response.Timeline, VisitTimelinePoint{Period: period, Count: 1})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VisitCountResponse represents how many times the user visited a country
+type VisitCountResponse struct {
+	CountryID uint   `json:"countryId"`
+	ISOCode   string `json:"isoCode"`
+	Count     int64  `json:"count"`
+}
+
+// GetVisitCounts returns the number of visits to each visited country, most
+// visited first
+// GET /api/v1/visits/counts
+// Query params: courseId, from, to (optional) - filters as for ListVisits
+func (h *VisitHandler) GetVisitCounts(c *gin.Context) {
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"visits": response})
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	query := dates.apply(db.Model(&models.Visit{}))
+	if courseFilter := c.Query("courseId"); courseFilter != "" {
+		query = filterByCourse(query, courseFilter)
+	}
+
+	counts := []VisitCountResponse{}
+	err := query.
+		Select("visits.country_id, countries.iso_code, COUNT(*) AS count").
+		Joins("JOIN countries ON countries.id = visits.country_id").
+		Group("visits.country_id, countries.iso_code").
+		Order("count DESC, visits.country_id ASC").
+		Scan(&counts).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visit counts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counts": counts})
+}
+
+// VisitedCountryResponse summarizes the user's visits to one country
+type VisitedCountryResponse struct {
+	Country    CountryResponse `json:"country"`
+	FirstVisit string          `json:"firstVisit"`
+	LastVisit  string          `json:"lastVisit"`
+	Count      int64           `json:"count"`
+}
+
+// GetVisitedCountries returns each country the user has visited once, with
+// the dates of the first and last visits and the number of visits, ordered
+// by country name
+// GET /api/v1/visits/countries
+// Query params: courseId, from, to (optional) - filters as for ListVisits,
+// tz (optional) - zone to render visit dates in
+func (h *VisitHandler) GetVisitedCountries(c *gin.Context) {
+	db, _, ok := userDB(c, h.db)
+	if !ok {
+		return
+	}
+
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
+	}
+
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	query := dates.apply(db.Model(&models.Visit{}))
+	if courseFilter := c.Query("courseId"); courseFilter != "" {
+		query = filterByCourse(query, courseFilter)
+	}
+
+	// First and last visits are found in Go, since MIN and MAX over
+	// timestamps come back as text from SQLite
+	var visits []models.Visit
+	if err := query.Select("country_id, visited_at").Order("visited_at ASC").Find(&visits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch visits"})
+		return
+	}
+
+	type visitSpan struct {
+		first, last time.Time
+		count       int64
+	}
+	spans := make(map[uint]*visitSpan)
+	countryIDs := []uint{}
+	for _, v := range visits {
+		span, seen := spans[v.CountryID]
+		if !seen {
+			span = &visitSpan{first: v.VisitedAt}
+			spans[v.CountryID] = span
+			countryIDs = append(countryIDs, v.CountryID)
+		}
+		span.last = v.VisitedAt
+		span.count++
+	}
+
+	response := []VisitedCountryResponse{}
+	if len(countryIDs) > 0 {
+		var countries []models.Country
+		if err := h.db.Where("id IN ?", countryIDs).Order("name ASC").Find(&countries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch countries"})
+			return
+		}
+		for i := range countries {
+			span := spans[countries[i].ID]
+			response = append(response, VisitedCountryResponse{
+				Country:    toCountryResponse(&countries[i]),
+				FirstVisit: formatTimestamp(span.first, loc),
+				LastVisit:  formatTimestamp(span.last, loc),
+				Count:      span.count,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"countries": response})
+}
+
+// filterByCourse restricts a query to rows created in the given course.
+// Rows with an empty or NULL course ID predate course tagging and match any
+// course; the column was added without a default, so legacy rows hold NULL.
+func filterByCourse(query *gorm.DB, courseID string) *gorm.DB {
+	return query.Where("(course_id = ? OR course_id = '' OR course_id IS NULL)", courseID)
+}
+
+// filterByRegion restricts a visits query to countries in a region. A
+// subquery keeps the visits columns unambiguous for ordering and cursors.
+func filterByRegion(db, query *gorm.DB, region string) *gorm.DB {
+	return query.Where("country_id IN (?)", db.Model(&models.Country{}).Select("id").Where("region = ?", region))
 }
@@ -3,14 +3,17 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"globe-expedition-journal/internal/lti"
 	"globe-expedition-journal/internal/middleware"
 	"globe-expedition-journal/internal/models"
+	"globe-expedition-journal/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
@@ -23,7 +26,7 @@ func setupVisitTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.Visit{})
+	err = db.AutoMigrate(&models.User{}, &models.Country{}, &models.Visit{}, &models.IdempotencyKey{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -61,6 +64,9 @@ func createVisitTestRouter(db *gorm.DB, sm *lti.SessionManager) *gin.Engine {
 	auth.Use(middleware.AuthMiddleware(sm))
 	{
 		auth.GET("/visits", handler.ListVisits)
+		auth.GET("/visits/timeline", handler.GetTimeline)
+		auth.GET("/visits/counts", handler.GetVisitCounts)
+		auth.GET("/visits/countries", handler.GetVisitedCountries)
 		auth.POST("/visits", handler.CreateVisit)
 		auth.GET("/visits/:id", handler.GetVisit)
 		auth.PUT("/visits/:id", handler.UpdateVisit)
@@ -283,10 +289,7 @@ func TestVisitHandler_UpdateVisit(t *testing.T) {
 
 	router := createVisitTestRouter(db, sm)
 
-	body := UpdateVisitRequest{
-		Notes: "Updated notes",
-	}
-	bodyBytes, _ := json.Marshal(body)
+	bodyBytes, _ := json.Marshal(map[string]string{"notes": "Updated notes"})
 
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/visits/1", bytes.NewReader(bodyBytes))
 	req.Header.Set("Content-Type", "application/json")
@@ -424,3 +427,1211 @@ func TestVisitHandler_Unauthenticated(t *testing.T) {
 		t.Errorf("expected status 401, got %d", w.Code)
 	}
 }
+
+func TestVisitHandler_CreateVisit_TagsCourse(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	body := CreateVisitRequest{CountryID: country.ID}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.CourseID != "course-1" {
+		t.Errorf("expected course ID 'course-1', got '%s'", response.CourseID)
+	}
+
+	var visit models.Visit
+	db.First(&visit, response.ID)
+	if visit.CourseID != "course-1" {
+		t.Errorf("expected stored course ID 'course-1', got '%s'", visit.CourseID)
+	}
+}
+
+func TestVisitHandler_ListVisits_FilterByCourse(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, CourseID: "course-1", VisitedAt: time.Now()})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, CourseID: "course-2", VisitedAt: time.Now()})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Now()}) // untagged
+	// Rows from before the column existed hold NULL rather than ''
+	legacy := &models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Now()}
+	db.Create(legacy)
+	db.Exec("UPDATE visits SET course_id = NULL WHERE id = ?", legacy.ID)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits?courseId=course-1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response VisitListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 3 {
+		t.Errorf("expected total 3 (course-1 + untagged + legacy), got %d", response.Total)
+	}
+	if len(response.Visits) != 3 {
+		t.Errorf("expected 3 visits, got %d", len(response.Visits))
+	}
+	for _, v := range response.Visits {
+		if v.CourseID == "course-2" {
+			t.Error("expected course-2 visit to be filtered out")
+		}
+	}
+}
+
+func TestVisitHandler_ListVisits_FilterByRegion(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, france := seedVisitTestData(t, db)
+	japan := &models.Country{Name: "Japan", ISOCode: "JP", Region: "Asia"}
+	db.Create(japan)
+
+	db.Create(&models.Visit{UserID: user.ID, CountryID: france.ID, VisitedAt: time.Now()})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: japan.ID, VisitedAt: time.Now()})
+	// Another user's visit in the region is never included
+	db.Create(&models.Visit{UserID: user.ID + 1, CountryID: france.ID, VisitedAt: time.Now()})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	for _, path := range []string{"/api/v1/visits?region=Europe", "/api/v1/visits?region=Europe&cursor="} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+
+		var response VisitListResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		if response.Total != 1 || len(response.Visits) != 1 {
+			t.Fatalf("%s: expected 1 European visit, got total %d and %d visits", path, response.Total, len(response.Visits))
+		}
+		if response.Visits[0].CountryID != france.ID {
+			t.Errorf("%s: expected the visit to France, got country %d", path, response.Visits[0].CountryID)
+		}
+	}
+}
+
+func TestVisitHandler_CreateVisit_IdempotencyKeyReplay(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID, Notes: "Retry me"})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "visit-key-1")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send()
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", first.Code, first.Body.String())
+	}
+	var original VisitResponse
+	json.Unmarshal(first.Body.Bytes(), &original)
+
+	replay := send()
+	if replay.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on replay, got %d: %s", replay.Code, replay.Body.String())
+	}
+	var replayed VisitResponse
+	json.Unmarshal(replay.Body.Bytes(), &replayed)
+
+	if replayed.ID != original.ID {
+		t.Errorf("expected replay to return visit %d, got %d", original.ID, replayed.ID)
+	}
+
+	var count int64
+	db.Model(&models.Visit{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 visit after replay, got %d", count)
+	}
+}
+
+func TestVisitHandler_CreateVisit_IdempotencyKeyPerUser(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-456", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID})
+
+	for _, u := range []*models.User{user, other} {
+		token, _ := sm.CreateToken(u.ID, u.CanvasUserID, "course-1", "learner")
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status 201 for user %d, got %d", u.ID, w.Code)
+		}
+	}
+
+	var count int64
+	db.Model(&models.Visit{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 visits for distinct users, got %d", count)
+	}
+}
+
+func TestVisitHandler_CreateVisit_MissingCountryID(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader([]byte(`{"notes":"No country"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var response ValidationErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Errors) != 1 {
+		t.Fatalf("expected 1 field error, got %d: %s", len(response.Errors), w.Body.String())
+	}
+	if response.Errors[0].Field != "countryId" || response.Errors[0].Rule != "required" {
+		t.Errorf("expected countryId/required, got %s/%s", response.Errors[0].Field, response.Errors[0].Rule)
+	}
+}
+
+// postCheckIn sends a create visit request with the given JSON body
+func postCheckIn(router *gin.Engine, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// setCentroid gives a test country centroid coordinates
+func setCentroid(db *gorm.DB, country *models.Country, lat, lng float64) {
+	db.Model(country).Updates(map[string]interface{}{"latitude": lat, "longitude": lng})
+}
+
+func TestVisitHandler_CreateVisit_CheckInWithCountry(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	// An explicit country is used as given, even far from its centroid
+	body := fmt.Sprintf(`{"countryId":%d,"latitude":-33.87,"longitude":151.21}`, country.ID)
+	w := postCheckIn(router, token, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.CountryID != country.ID {
+		t.Errorf("expected country ID %d, got %d", country.ID, response.CountryID)
+	}
+	if response.Latitude == nil || *response.Latitude != -33.87 || response.Longitude == nil || *response.Longitude != 151.21 {
+		t.Errorf("expected coordinates in the response, got %v, %v", response.Latitude, response.Longitude)
+	}
+
+	var visit models.Visit
+	db.First(&visit, response.ID)
+	if visit.Latitude == nil || *visit.Latitude != -33.87 {
+		t.Errorf("expected latitude to be stored, got %v", visit.Latitude)
+	}
+}
+
+func TestVisitHandler_CreateVisit_CheckInInfersCountry(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, france := seedVisitTestData(t, db)
+	setCentroid(db, france, 46.2276, 2.2137)
+	swiss := &models.Country{Name: "Switzerland", ISOCode: "CH", Region: "Europe"}
+	db.Create(swiss)
+	setCentroid(db, swiss, 46.8182, 8.2275)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	// Lyon is closer to the centre of France than of Switzerland
+	w := postCheckIn(router, token, `{"latitude":45.76,"longitude":4.84,"notes":"Lyon"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.CountryID != france.ID {
+		t.Errorf("expected inferred country %d, got %d", france.ID, response.CountryID)
+	}
+	if response.Country == nil || response.Country.ISOCode != "FR" {
+		t.Errorf("expected inferred country to be included, got %+v", response.Country)
+	}
+	if response.Latitude == nil || *response.Latitude != 45.76 {
+		t.Errorf("expected latitude 45.76, got %v", response.Latitude)
+	}
+}
+
+func TestVisitHandler_CreateVisit_CheckInUnresolvable(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, france := seedVisitTestData(t, db)
+	setCentroid(db, france, 46.2276, 2.2137)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	w := postCheckIn(router, token, `{"latitude":0,"longitude":-140}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for the open Pacific, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var visits int64
+	db.Model(&models.Visit{}).Count(&visits)
+	if visits != 0 {
+		t.Errorf("expected no visit to be created, got %d", visits)
+	}
+}
+
+func TestVisitHandler_CreateVisit_CheckInInvalidCoordinates(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	tests := []struct {
+		body, field, rule string
+	}{
+		{`{"latitude":45.76}`, "longitude", "required_with"},
+		{`{"latitude":91,"longitude":0}`, "latitude", "max"},
+		{`{"latitude":0,"longitude":-181}`, "longitude", "min"},
+	}
+	for _, tt := range tests {
+		w := postCheckIn(router, token, tt.body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.body, w.Code)
+			continue
+		}
+		var response ValidationErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Errors) != 1 || response.Errors[0].Field != tt.field || response.Errors[0].Rule != tt.rule {
+			t.Errorf("%s: expected %s/%s, got %+v", tt.body, tt.field, tt.rule, response.Errors)
+		}
+	}
+}
+
+func TestVisitHandler_CreateVisit_DateOnly(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID, VisitedAt: "2024-06-15"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.VisitedAt != "2024-06-15T00:00:00Z" {
+		t.Errorf("expected visitedAt '2024-06-15T00:00:00Z', got '%s'", response.VisitedAt)
+	}
+}
+
+func TestVisitHandler_CreateVisit_MalformedDate(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID, VisitedAt: "June 15th"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"] != errInvalidVisitedAt {
+		t.Errorf("expected error listing accepted formats, got '%s'", response["error"])
+	}
+}
+
+func TestVisitHandler_UpdateVisit_DateOnly(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	visit := &models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Now()}
+	db.Create(visit)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(map[string]string{"visitedAt": "2023-01-02"})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/visits/1", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.VisitedAt != "2023-01-02T00:00:00Z" {
+		t.Errorf("expected visitedAt '2023-01-02T00:00:00Z', got '%s'", response.VisitedAt)
+	}
+}
+
+func TestVisitHandler_UpdateVisit_FieldErrors(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+	db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Now()})
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/visits/1", bytes.NewReader([]byte(`{"notes":42}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var response ValidationErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Errors) != 1 || response.Errors[0].Field != "notes" || response.Errors[0].Rule != "type" {
+		t.Errorf("expected notes/type error, got %s", w.Body.String())
+	}
+}
+
+func TestVisitHandler_UpdateVisit_PartialUpdates(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantNotes string
+		wantDate  string
+	}{
+		{"date only keeps notes", `{"visitedAt":"2023-01-02"}`, http.StatusOK, "Original notes", "2023-01-02T00:00:00Z"},
+		{"notes only keeps date", `{"notes":"New notes"}`, http.StatusOK, "New notes", "2022-05-01T00:00:00Z"},
+		{"empty notes clears them", `{"notes":""}`, http.StatusOK, "", "2022-05-01T00:00:00Z"},
+		{"empty body changes nothing", `{}`, http.StatusOK, "Original notes", "2022-05-01T00:00:00Z"},
+		{"empty date is rejected", `{"visitedAt":""}`, http.StatusBadRequest, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupVisitTestDB(t)
+			user, country := seedVisitTestData(t, db)
+
+			visit := &models.Visit{
+				UserID:    user.ID,
+				CountryID: country.ID,
+				VisitedAt: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+				Notes:     "Original notes",
+			}
+			db.Create(visit)
+
+			sm := lti.NewSessionManager("test-secret", 3600)
+			token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+			router := createVisitTestRouter(db, sm)
+
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/visits/%d", visit.ID), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var stored models.Visit
+			db.First(&stored, visit.ID)
+			if stored.Notes != tt.wantNotes {
+				t.Errorf("expected notes %q, got %q", tt.wantNotes, stored.Notes)
+			}
+
+			var response VisitResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.VisitedAt != tt.wantDate {
+				t.Errorf("expected visitedAt %s, got %s", tt.wantDate, response.VisitedAt)
+			}
+		})
+	}
+}
+
+func TestVisitHandler_CreateVisit_NormalizesToUTC(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID, VisitedAt: "2024-06-15T09:30:00+09:00"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.VisitedAt != "2024-06-15T00:30:00Z" {
+		t.Errorf("expected visitedAt rendered in UTC, got '%s'", response.VisitedAt)
+	}
+
+	var stored models.Visit
+	db.First(&stored, response.ID)
+	if !stored.VisitedAt.Equal(time.Date(2024, 6, 15, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected stored visit at 00:30 UTC, got %v", stored.VisitedAt)
+	}
+
+	// Rendered in the requested zone
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/visits/%d?tz=Asia/Tokyo", response.ID), nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.VisitedAt != "2024-06-15T09:30:00+09:00" {
+		t.Errorf("expected visitedAt rendered in Asia/Tokyo, got '%s'", response.VisitedAt)
+	}
+}
+
+func TestVisitHandler_ListVisits_InvalidTimezone(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits?tz=Not/AZone", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestVisitHandler_GetVisitsByCountry_Paginated(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: base.AddDate(0, 0, i)})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/country/1?limit=2&offset=1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Visits []VisitResponse `json:"visits"`
+		Total  int64           `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 5 {
+		t.Errorf("expected total 5, got %d", response.Total)
+	}
+	if len(response.Visits) != 2 {
+		t.Fatalf("expected a page of 2 visits, got %d", len(response.Visits))
+	}
+	// Most recent first, skipping the newest
+	if response.Visits[0].VisitedAt != "2024-01-04T00:00:00Z" {
+		t.Errorf("expected page to start at 2024-01-04, got %s", response.Visits[0].VisitedAt)
+	}
+}
+
+func TestVisitHandler_GetVisitsByCountry_InvalidLimit(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=1000", "offset=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/country/1?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestVisitHandler_ListVisits_DateRange(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	for _, visitedAt := range []time.Time{
+		time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 14, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: visitedAt})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantTotal int64
+	}{
+		{"whole year", "?from=2024-01-01T00:00:00Z&to=2024-12-31T23:59:59Z", http.StatusOK, 2},
+		{"from only", "?from=2024-07-01T00:00:00Z", http.StatusOK, 2},
+		{"to only", "?to=2024-01-01T00:00:00Z", http.StatusOK, 2},
+		{"malformed", "?from=2024", http.StatusBadRequest, 0},
+		{"inverted", "?from=2025-01-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/visits"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response VisitListResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if response.Total != tt.wantTotal {
+				t.Errorf("expected total %d, got %d", tt.wantTotal, response.Total)
+			}
+			if int64(len(response.Visits)) != tt.wantTotal {
+				t.Errorf("expected %d visits, got %d", tt.wantTotal, len(response.Visits))
+			}
+		})
+	}
+}
+
+func TestVisitHandler_GetTimeline(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	for _, v := range []models.Visit{
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 6, 30, 23, 30, 0, 0, time.UTC)},
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC)},
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)},
+		{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{UserID: other.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		db.Create(&v)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []VisitTimelinePoint
+	}{
+		{"default month", "", []VisitTimelinePoint{
+			{"2022-03", 1}, {"2023-12", 1}, {"2024-01", 1}, {"2024-06", 3},
+		}},
+		{"year", "?granularity=year", []VisitTimelinePoint{
+			{"2022", 1}, {"2023", 1}, {"2024", 4},
+		}},
+		{"month in user's zone", "?tz=Asia/Tokyo", []VisitTimelinePoint{
+			{"2022-03", 1}, {"2023-12", 1}, {"2024-01", 1}, {"2024-06", 2}, {"2024-07", 1},
+		}},
+		{"date range", "?from=2024-01-01T00:00:00Z&to=2024-06-05T00:00:00Z", []VisitTimelinePoint{
+			{"2024-01", 1}, {"2024-06", 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/timeline"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var response VisitTimelineResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if len(response.Timeline) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, response.Timeline)
+			}
+			for i, point := range tt.want {
+				if response.Timeline[i] != point {
+					t.Errorf("period %d: expected %v, got %v", i, point, response.Timeline[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVisitHandler_GetVisitCounts(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, france := seedVisitTestData(t, db)
+
+	japan := &models.Country{Name: "Japan", ISOCode: "JP", Region: "Asia"}
+	peru := &models.Country{Name: "Peru", ISOCode: "PE", Region: "South America"}
+	db.Create(japan)
+	db.Create(peru)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	for _, v := range []models.Visit{
+		{UserID: user.ID, CountryID: japan.ID},
+		{UserID: user.ID, CountryID: france.ID},
+		{UserID: user.ID, CountryID: japan.ID},
+		{UserID: user.ID, CountryID: japan.ID},
+		{UserID: user.ID, CountryID: france.ID},
+		{UserID: user.ID, CountryID: peru.ID},
+		{UserID: other.ID, CountryID: peru.ID},
+		{UserID: other.ID, CountryID: peru.ID},
+	} {
+		db.Create(&v)
+	}
+	// Deleted visits are not counted
+	deleted := models.Visit{UserID: user.ID, CountryID: peru.ID}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/counts", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Counts []VisitCountResponse `json:"counts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	want := []VisitCountResponse{
+		{CountryID: japan.ID, ISOCode: "JP", Count: 3},
+		{CountryID: france.ID, ISOCode: "FR", Count: 2},
+		{CountryID: peru.ID, ISOCode: "PE", Count: 1},
+	}
+	if len(response.Counts) != len(want) {
+		t.Fatalf("expected %v, got %v", want, response.Counts)
+	}
+	for i, count := range want {
+		if response.Counts[i] != count {
+			t.Errorf("position %d: expected %v, got %v", i, count, response.Counts[i])
+		}
+	}
+}
+
+func TestVisitHandler_GetVisitedCountries(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, france := seedVisitTestData(t, db)
+
+	japan := &models.Country{Name: "Japan", ISOCode: "JP", Region: "Asia"}
+	db.Create(japan)
+
+	other := &models.User{CanvasUserID: "canvas-other", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(other)
+
+	day := func(d int) time.Time { return time.Date(2025, time.March, d, 12, 0, 0, 0, time.UTC) }
+	for _, v := range []models.Visit{
+		{UserID: user.ID, CountryID: japan.ID, VisitedAt: day(10)},
+		{UserID: user.ID, CountryID: japan.ID, VisitedAt: day(2)},
+		{UserID: user.ID, CountryID: japan.ID, VisitedAt: day(20)},
+		{UserID: user.ID, CountryID: france.ID, VisitedAt: day(5)},
+		{UserID: other.ID, CountryID: france.ID, VisitedAt: day(1)},
+	} {
+		db.Create(&v)
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/countries", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Countries []VisitedCountryResponse `json:"countries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Countries) != 2 {
+		t.Fatalf("expected 2 countries, got %+v", response.Countries)
+	}
+	fr, jp := response.Countries[0], response.Countries[1]
+	if fr.Country.ID != france.ID || fr.Count != 1 || fr.FirstVisit != formatTimestamp(day(5), time.UTC) || fr.LastVisit != fr.FirstVisit {
+		t.Errorf("unexpected France summary: %+v", fr)
+	}
+	if jp.Country.ID != japan.ID || jp.Count != 3 || jp.FirstVisit != formatTimestamp(day(2), time.UTC) || jp.LastVisit != formatTimestamp(day(20), time.UTC) {
+		t.Errorf("unexpected Japan summary: %+v", jp)
+	}
+}
+
+func TestVisitHandler_GetTimeline_InvalidGranularity(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits/timeline?granularity=week", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestVisitHandler_ListVisits_Paginated(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	for day := 1; day <= 5; day++ {
+		db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC)})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/visits?page=2&pageSize=2", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response VisitListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Most recent first: Mar 5, 4 | 3, 2 | 1
+	if len(response.Visits) != 2 || !strings.HasPrefix(response.Visits[0].VisitedAt, "2024-03-03") {
+		t.Errorf("expected visits from Mar 3 and 2, got %+v", response.Visits)
+	}
+	want := PageInfo{Page: 2, PageSize: 2, Total: 5, TotalPages: 3}
+	if response.Total != 5 || response.Pagination == nil || *response.Pagination != want {
+		t.Errorf("expected total 5 and pagination %+v, got %d and %+v", want, response.Total, response.Pagination)
+	}
+}
+
+func TestVisitHandler_ListVisits_Cursor(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	// Seven visits, with a tie on Mar 3 that the ID breaks
+	for _, day := range []int{1, 2, 3, 3, 4, 5, 6} {
+		db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC)})
+	}
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	var ids []uint
+	cursor := ""
+	for batch := 1; ; batch++ {
+		if batch > 5 {
+			t.Fatal("cursor paging did not finish")
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/visits?limit=3&cursor="+cursor, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response VisitListResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if batch == 1 && response.Total != 7 {
+			t.Errorf("expected total 7, got %d", response.Total)
+		}
+		if response.Pagination != nil {
+			t.Errorf("expected no page metadata in cursor mode, got %+v", response.Pagination)
+		}
+		for _, v := range response.Visits {
+			ids = append(ids, v.ID)
+		}
+
+		// A visit added mid-way is newer than the cursor and does not shift later pages
+		if batch == 1 {
+			db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID, VisitedAt: time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)})
+		}
+
+		if response.NextCursor == "" {
+			if len(response.Visits) != 1 {
+				t.Errorf("expected one visit on the last page, got %d", len(response.Visits))
+			}
+			break
+		}
+		cursor = response.NextCursor
+	}
+
+	// Most recent first, the later of the tied visits first
+	want := []uint{7, 6, 5, 4, 3, 2, 1}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("expected visits %v across pages, got %v", want, ids)
+	}
+}
+
+func TestVisitHandler_ListVisits_InvalidCursor(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, _ := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	router := createVisitTestRouter(db, sm)
+
+	for _, query := range []string{"?cursor=garbage", "?cursor=&page=2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/visits"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestVisitHandler_CreateVisit_SendsWebhook(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	events := make(chan webhook.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer server.Close()
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	notifier := webhook.NewNotifier(webhook.DefaultConfig(server.URL, "hook-secret"))
+	handler := NewVisitHandler(db)
+	handler.SetNotifier(notifier)
+
+	router := gin.New()
+	router.POST("/api/v1/visits", middleware.AuthMiddleware(sm), handler.CreateVisit)
+
+	bodyBytes, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+	notifier.Wait()
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	select {
+	case event := <-events:
+		if event.Type != webhook.EventVisitCreated {
+			t.Errorf("expected type %s, got %s", webhook.EventVisitCreated, event.Type)
+		}
+		if event.UserID != user.ID || event.ResourceID != response.ID || event.CountryID != country.ID {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	default:
+		t.Fatal("expected a webhook event")
+	}
+}
+
+func TestVisitHandler_NotesPolicy(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewVisitHandler(db)
+	handler.SetTextPolicy(TextPolicy{MaxNotesLength: 60, StripHTML: true})
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.POST("/api/v1/visits", handler.CreateVisit)
+	router.PUT("/api/v1/visits/:id", handler.UpdateVisit)
+
+	send := func(method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/v1/visits", map[string]interface{}{
+		"countryId": country.ID,
+		"notes":     "<b>Great</b> trip<script>steal()</script>\x07",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Notes != "Great trip" {
+		t.Errorf("expected sanitized notes, got %q", created.Notes)
+	}
+	var stored models.Visit
+	db.First(&stored, created.ID)
+	if stored.Notes != "Great trip" {
+		t.Errorf("expected sanitized notes to be stored, got %q", stored.Notes)
+	}
+
+	tooLong := strings.Repeat("n", 61)
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/visits"},
+		{http.MethodPut, fmt.Sprintf("/api/v1/visits/%d", created.ID)},
+	} {
+		w = send(tc.method, tc.path, map[string]interface{}{"countryId": country.ID, "notes": tooLong})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "at most 60 characters") {
+			t.Errorf("%s %s: expected 400 naming the limit, got %d: %s", tc.method, tc.path, w.Code, w.Body.String())
+		}
+	}
+
+	w = send(http.MethodPut, fmt.Sprintf("/api/v1/visits/%d", created.ID), map[string]interface{}{"notes": "<i>Updated</i>"})
+	var updated VisitResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Notes != "Updated" {
+		t.Errorf("expected sanitized notes on update, got %d %q", w.Code, updated.Notes)
+	}
+}
+
+func TestVisitHandler_CreateVisit_MaxVisits(t *testing.T) {
+	db := setupVisitTestDB(t)
+	user, country := seedVisitTestData(t, db)
+
+	sm := lti.NewSessionManager("test-secret", 3600)
+	token, _ := sm.CreateToken(user.ID, "canvas-123", "course-1", "learner")
+
+	handler := NewVisitHandler(db)
+	handler.SetMaxVisits(2)
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sm))
+	router.POST("/api/v1/visits", handler.CreateVisit)
+
+	create := func() *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(CreateVisitRequest{CountryID: country.ID})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/visits", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Another user's visits do not count towards the limit
+	db.Create(&models.Visit{UserID: user.ID + 1, CountryID: country.ID})
+	db.Create(&models.Visit{UserID: user.ID, CountryID: country.ID})
+
+	if w := create(); w.Code != http.StatusCreated {
+		t.Fatalf("expected the 2nd visit to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	w := create()
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for the 3rd visit, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !strings.Contains(response["error"], "at most 2 visits") {
+		t.Errorf("expected the limit in the error, got %q", response["error"])
+	}
+
+	var count int64
+	db.Model(&models.Visit{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 visits stored, got %d", count)
+	}
+}
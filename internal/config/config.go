@@ -1,15 +1,27 @@
 package config
 
 import (
+	"errors"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"globe-expedition-journal/internal/logging"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	// Server settings
-	Port string
-	Host string
+	Port           string
+	Host           string
+	PublicBaseURL  string   // Canonical external URL, e.g. https://journal.example.edu
+	TrustedProxies []string // Proxy IPs/CIDRs whose X-Forwarded-* headers are honored
+
+	RedirectOrigins []string // Origins besides this server that LTI launches may redirect to
+
+	ShutdownTimeout int // Seconds to wait for in-flight requests, such as uploads, on shutdown
 
 	// Database settings
 	DBDriver    string // "sqlite" or "postgres"
@@ -22,29 +34,94 @@ type Config struct {
 	LTIJWKSEndpoint  string
 	LTIAuthEndpoint  string
 	LTITokenEndpoint string
+	LTIStateStore    string // "memory" or "database" (required for multiple instances)
 
 	// Session settings
 	SessionSecret string
 	SessionMaxAge int
+	SessionLeeway int // Seconds past expiry a session token is still accepted
+
+	// PreviousSessionSecrets are retired secrets still accepted when
+	// validating sessions, so rotating SessionSecret does not log everyone out
+	PreviousSessionSecrets []string
+
+	SessionSecretMinLength int // Shortest session secret accepted in production, in bytes
+
+	// Time settings
+	DefaultTimezone string // IANA zone used to render timestamps; storage is always UTC
+
+	// API settings
+	MaxPageSize int // Largest page size list endpoints accept
+
+	MaxNotesLength int  // Longest visit or entry notes accepted, in characters
+	StripHTML      bool // Remove HTML tags from titles and notes before saving
+
+	MaxVisitsPerUser  int // Visits each user may record; 0 for no limit
+	MaxEntriesPerUser int // Scrapbook entries each user may create; 0 for no limit
+
+	UniqueEntryTitles bool // Enforce one scrapbook entry title per country per user in the database
+
+	ReadOnly bool // Reject writes with 503 during maintenance
+
+	OwnerComments bool // Let entry owners comment on their own entries as well as instructors
 
 	// Development settings
-	DemoMode bool // Enable demo login without LTI
+	DemoMode    bool // Enable demo login without LTI
+	DemoUserTTL int  // Seconds of inactivity before per-session demo users are purged
+
+	DemoSessionTTL int // Seconds demo sessions last, capped at SessionMaxAge
+	DemoLoginLimit int // New demo users each client IP may create per hour; 0 for no limit
 
 	// Storage settings
 	StorageType string // "local" or "s3"
 	UploadsDir  string // Local directory for uploads
 	MaxFileSize int64  // Maximum file size in bytes
+
+	FrontendDir string // Built single-page app served for paths outside the API; disabled if empty
+
+	RejectAnimatedUploads bool // Reject multi-frame GIF/WebP uploads
+	PreserveUploadNames   bool // Prefix stored filenames with the sanitized original name
+
+	UploadAllowedExtensions []string // Extensions uploaded filenames may have; any if empty
+
+	MaxUploadsPerDay int // Uploads a user may make per UTC day; 0 for no limit
+	MaxMediaPerEntry int // Media items one scrapbook entry may have, however uploaded; 0 for no limit
+
+	UploadCacheMaxAge int // Seconds clients may cache served uploads
+
+	// Logging settings
+	LogFormat string // "text" (key=value) or "json"
+	LogLevel  string // Lowest level logged: "debug", "info", "warn" or "error"
+
+	// Webhook settings
+	WebhookURL    string // Receives visit and scrapbook creation events; disabled if empty
+	WebhookSecret string // Shared secret used to sign webhook payloads
+
+	// Email settings
+	SMTPHost     string // SMTP server for notification email; disabled if empty
+	SMTPPort     int
+	SMTPUsername string // Optional; PLAIN auth is used when set
+	SMTPPassword string
+	SMTPFrom     string // Sender address of notification email
 }
 
 // Load reads configuration from environment variables with sensible defaults
 func Load() *Config {
+	dbDriver := getEnv("DB_DRIVER", "sqlite")
+
 	return &Config{
 		// Server
-		Port: getEnv("PORT", "8080"),
-		Host: getEnv("HOST", "0.0.0.0"),
+		Port:           getEnv("PORT", "8080"),
+		Host:           getEnv("HOST", "0.0.0.0"),
+		PublicBaseURL:  getEnv("PUBLIC_BASE_URL", ""),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		RedirectOrigins: getEnvList("REDIRECT_ORIGINS"),
+
+		ShutdownTimeout: getEnvInt("SHUTDOWN_TIMEOUT", 30),
 
 		// Database
-		DBDriver:    getEnv("DB_DRIVER", "sqlite"),
+		DBDriver:    dbDriver,
 		DatabaseURL: getEnv("DATABASE_URL", "globe_expedition.db"),
 
 		// LTI 1.3
@@ -54,18 +131,73 @@ func Load() *Config {
 		LTIJWKSEndpoint:  getEnv("LTI_JWKS_ENDPOINT", ""),
 		LTIAuthEndpoint:  getEnv("LTI_AUTH_ENDPOINT", ""),
 		LTITokenEndpoint: getEnv("LTI_TOKEN_ENDPOINT", ""),
+		LTIStateStore:    getEnv("LTI_STATE_STORE", "memory"),
 
 		// Session
 		SessionSecret: getEnv("SESSION_SECRET", "change-me-in-production"),
 		SessionMaxAge: getEnvInt("SESSION_MAX_AGE", 86400), // 24 hours
+		SessionLeeway: getEnvInt("SESSION_LEEWAY", 30),
+
+		PreviousSessionSecrets: getEnvList("SESSION_SECRETS"),
+
+		SessionSecretMinLength: getEnvInt("SESSION_SECRET_MIN_LENGTH", 32), // HS256 key size
+
+		// Time
+		DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "UTC"),
+
+		// API
+		MaxPageSize: getEnvInt("MAX_PAGE_SIZE", 200),
+
+		MaxNotesLength: getEnvInt("MAX_NOTES_LENGTH", 20000),
+		StripHTML:      getEnvBool("STRIP_HTML", false),
+
+		MaxVisitsPerUser:  getEnvInt("MAX_VISITS_PER_USER", 10000),
+		MaxEntriesPerUser: getEnvInt("MAX_ENTRIES_PER_USER", 10000),
+
+		UniqueEntryTitles: getEnvBool("UNIQUE_ENTRY_TITLES", false),
+
+		ReadOnly: getEnvBool("READ_ONLY", false),
 
-		// Development - demo mode enabled by default for SQLite
-		DemoMode: getEnvBool("DEMO_MODE", true),
+		OwnerComments: getEnvBool("OWNER_COMMENTS", false),
+
+		// Development - demo mode enabled by default for SQLite only
+		DemoMode:    getEnvBool("DEMO_MODE", dbDriver == "sqlite"),
+		DemoUserTTL: getEnvInt("DEMO_USER_TTL", 86400), // 24 hours
+
+		DemoSessionTTL: getEnvInt("DEMO_SESSION_TTL", 3600), // 1 hour
+		DemoLoginLimit: getEnvInt("DEMO_LOGIN_LIMIT", 10),
 
 		// Storage
 		StorageType: getEnv("STORAGE_TYPE", "local"),
 		UploadsDir:  getEnv("UPLOADS_DIR", "./uploads"),
 		MaxFileSize: getEnvInt64("MAX_FILE_SIZE", 10*1024*1024), // 10MB default
+
+		FrontendDir: getEnv("FRONTEND_DIR", ""),
+
+		RejectAnimatedUploads: getEnvBool("REJECT_ANIMATED_UPLOADS", false),
+		PreserveUploadNames:   getEnvBool("PRESERVE_UPLOAD_NAMES", false),
+
+		UploadAllowedExtensions: getEnvList("UPLOAD_ALLOWED_EXTENSIONS"),
+
+		MaxUploadsPerDay: getEnvInt("MAX_UPLOADS_PER_DAY", 100),
+		MaxMediaPerEntry: getEnvInt("MAX_MEDIA_PER_ENTRY", 10),
+
+		UploadCacheMaxAge: getEnvInt("UPLOAD_CACHE_MAX_AGE", 365*24*60*60), // 1 year
+
+		// Logging
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+
+		// Webhooks
+		WebhookURL:    getEnv("WEBHOOK_URL", ""),
+		WebhookSecret: getEnv("WEBHOOK_SECRET", ""),
+
+		// Email
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
 	}
 }
 
@@ -77,6 +209,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList retrieves a comma-separated environment variable as a list,
+// dropping empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getEnvInt retrieves an environment variable as int or returns a default value
 func getEnvInt(key string, defaultValue int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -117,15 +261,69 @@ func (c *Config) IsProduction() bool {
 	return c.DBDriver == "postgres"
 }
 
-// Validate checks that required configuration is present
+// ValidateProduction checks the settings the server refuses to start with in
+// production. They are checked apart from Validate, so an unrelated mistake
+// reported first can never hide one of them, and every failure is returned.
+func (c *Config) ValidateProduction() error {
+	if !c.IsProduction() {
+		return nil
+	}
+	var errs []error
+	// Demo login grants sessions without credentials
+	if c.DemoMode {
+		errs = append(errs, ErrDemoModeInProduction)
+	}
+	// A published or short secret lets anyone forge session tokens
+	if c.SessionSecret == "change-me-in-production" {
+		errs = append(errs, ErrInsecureSessionSecret)
+	}
+	if len(c.SessionSecret) < c.SessionSecretMinLength {
+		errs = append(errs, ErrShortSessionSecret)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that required configuration is present. See
+// ValidateProduction for the settings that must stop the server.
 func (c *Config) Validate() error {
+	if _, err := time.LoadLocation(c.DefaultTimezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	if c.LTIStateStore != "memory" && c.LTIStateStore != "database" {
+		return ErrInvalidLTIStateStore
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return ErrInvalidLogFormat
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return ErrInvalidLogLevel
+	}
+	if c.PublicBaseURL != "" {
+		u, err := url.Parse(c.PublicBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidPublicBaseURL
+		}
+	}
+	if c.WebhookURL != "" {
+		u, err := url.Parse(c.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidWebhookURL
+		}
+		if c.WebhookSecret == "" {
+			return ErrMissingWebhookSecret
+		}
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		return ErrMissingSMTPFrom
+	}
+
 	// In production, require LTI configuration
 	if c.IsProduction() {
 		if c.LTIClientID == "" {
 			return ErrMissingLTIConfig
 		}
-		if c.SessionSecret == "change-me-in-production" {
-			return ErrInsecureSessionSecret
+		if c.PublicBaseURL == "" {
+			return ErrMissingPublicBaseURL
 		}
 	}
 	return nil
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"testing"
 )
@@ -26,6 +27,9 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.SessionMaxAge != 86400 {
 		t.Errorf("expected default session max age 86400, got %d", cfg.SessionMaxAge)
 	}
+	if cfg.DemoUserTTL != 86400 {
+		t.Errorf("expected default demo user TTL 86400, got %d", cfg.DemoUserTTL)
+	}
 }
 
 func TestLoad_FromEnv(t *testing.T) {
@@ -34,6 +38,7 @@ func TestLoad_FromEnv(t *testing.T) {
 	os.Setenv("DB_DRIVER", "postgres")
 	os.Setenv("DATABASE_URL", "postgres://localhost/test")
 	os.Setenv("SESSION_MAX_AGE", "3600")
+	os.Setenv("DEMO_USER_TTL", "600")
 	defer os.Clearenv()
 
 	cfg := Load()
@@ -50,6 +55,23 @@ func TestLoad_FromEnv(t *testing.T) {
 	if cfg.SessionMaxAge != 3600 {
 		t.Errorf("expected session max age 3600, got %d", cfg.SessionMaxAge)
 	}
+	if cfg.DemoUserTTL != 600 {
+		t.Errorf("expected demo user TTL 600, got %d", cfg.DemoUserTTL)
+	}
+}
+
+func TestLoad_DemoSession(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.DemoSessionTTL != 3600 || cfg.DemoLoginLimit != 10 {
+		t.Errorf("expected 1 hour demo sessions and 10 logins per hour, got %d and %d", cfg.DemoSessionTTL, cfg.DemoLoginLimit)
+	}
+
+	os.Setenv("DEMO_SESSION_TTL", "900")
+	os.Setenv("DEMO_LOGIN_LIMIT", "0")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.DemoSessionTTL != 900 || cfg.DemoLoginLimit != 0 {
+		t.Errorf("expected 900 second demo sessions without a login limit, got %d and %d", cfg.DemoSessionTTL, cfg.DemoLoginLimit)
+	}
 }
 
 func TestLoad_InvalidInt(t *testing.T) {
@@ -113,7 +135,7 @@ func TestValidate_Production_MissingLTI(t *testing.T) {
 	}
 }
 
-func TestValidate_Production_InsecureSecret(t *testing.T) {
+func TestValidateProduction_InsecureSecret(t *testing.T) {
 	os.Setenv("DB_DRIVER", "postgres")
 	os.Setenv("LTI_CLIENT_ID", "test-client")
 	// SESSION_SECRET not set, uses default
@@ -121,16 +143,58 @@ func TestValidate_Production_InsecureSecret(t *testing.T) {
 
 	cfg := Load()
 
-	err := cfg.Validate()
-	if err != ErrInsecureSessionSecret {
+	err := cfg.ValidateProduction()
+	if !errors.Is(err, ErrInsecureSessionSecret) {
 		t.Errorf("expected ErrInsecureSessionSecret, got %v", err)
 	}
 }
 
+func TestValidateProduction_ShortSessionSecret(t *testing.T) {
+	os.Setenv("DB_DRIVER", "postgres")
+	os.Setenv("LTI_CLIENT_ID", "test-client")
+	os.Setenv("SESSION_SECRET", "only-twenty-one-bytes")
+	os.Setenv("PUBLIC_BASE_URL", "https://journal.example.edu")
+	defer os.Clearenv()
+
+	if err := Load().ValidateProduction(); !errors.Is(err, ErrShortSessionSecret) {
+		t.Errorf("expected ErrShortSessionSecret, got %v", err)
+	}
+
+	// The minimum is configurable
+	os.Setenv("SESSION_SECRET_MIN_LENGTH", "16")
+	if err := Load().ValidateProduction(); err != nil {
+		t.Errorf("expected a 21-byte secret to pass a 16-byte minimum, got %v", err)
+	}
+
+	// Development keeps accepting short secrets
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Unsetenv("SESSION_SECRET_MIN_LENGTH")
+	if err := Load().ValidateProduction(); err != nil {
+		t.Errorf("expected short secrets to be allowed in development, got %v", err)
+	}
+}
+
+func TestValidateProduction_ShortSecretWithMissingLTI(t *testing.T) {
+	os.Setenv("DB_DRIVER", "postgres")
+	os.Setenv("SESSION_SECRET", "only-twenty-one-bytes")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	// The missing client ID is reported by Validate without hiding the secret
+	if err := cfg.Validate(); err != ErrMissingLTIConfig {
+		t.Errorf("expected ErrMissingLTIConfig from Validate, got %v", err)
+	}
+	if err := cfg.ValidateProduction(); !errors.Is(err, ErrShortSessionSecret) {
+		t.Errorf("expected ErrShortSessionSecret from ValidateProduction, got %v", err)
+	}
+}
+
 func TestValidate_Production_Valid(t *testing.T) {
 	os.Setenv("DB_DRIVER", "postgres")
 	os.Setenv("LTI_CLIENT_ID", "test-client")
-	os.Setenv("SESSION_SECRET", "secure-production-secret")
+	os.Setenv("SESSION_SECRET", "secure-production-secret-0123456789")
+	os.Setenv("PUBLIC_BASE_URL", "https://journal.example.edu")
 	defer os.Clearenv()
 
 	cfg := Load()
@@ -140,3 +204,451 @@ func TestValidate_Production_Valid(t *testing.T) {
 		t.Errorf("expected no error with valid production config, got %v", err)
 	}
 }
+
+func TestLoad_DemoModeDefaults(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); !cfg.DemoMode {
+		t.Error("expected demo mode enabled by default with sqlite")
+	}
+
+	os.Setenv("DB_DRIVER", "postgres")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.DemoMode {
+		t.Error("expected demo mode disabled by default with postgres")
+	}
+}
+
+func TestValidateProduction_DemoMode(t *testing.T) {
+	os.Setenv("DB_DRIVER", "postgres")
+	os.Setenv("DEMO_MODE", "true")
+	os.Setenv("LTI_CLIENT_ID", "test-client")
+	os.Setenv("SESSION_SECRET", "secure-production-secret-0123456789")
+	os.Setenv("PUBLIC_BASE_URL", "https://journal.example.edu")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.ValidateProduction(); !errors.Is(err, ErrDemoModeInProduction) {
+		t.Errorf("expected ErrDemoModeInProduction, got %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected demo mode to be left to ValidateProduction, got %v", err)
+	}
+}
+
+func TestValidateProduction_DemoModeWithOtherInvalidSettings(t *testing.T) {
+	os.Setenv("DB_DRIVER", "postgres")
+	os.Setenv("DEMO_MODE", "true")
+	os.Setenv("LOG_FORMAT", "pretty")
+	// LTI and session secret also missing
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	// An earlier, warning-level mistake must not mask the demo mode refusal
+	if err := cfg.Validate(); err != ErrInvalidLogFormat {
+		t.Errorf("expected ErrInvalidLogFormat from Validate, got %v", err)
+	}
+	if err := cfg.ValidateProduction(); !errors.Is(err, ErrDemoModeInProduction) {
+		t.Errorf("expected ErrDemoModeInProduction, got %v", err)
+	}
+}
+
+func TestValidateProduction_Development_DemoMode(t *testing.T) {
+	os.Setenv("DEMO_MODE", "true")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.ValidateProduction(); err != nil {
+		t.Errorf("expected demo mode to be allowed in development, got %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected demo mode to be allowed in development, got %v", err)
+	}
+}
+
+func TestLoad_DefaultTimezone(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.DefaultTimezone != "UTC" {
+		t.Errorf("expected default timezone UTC, got %s", cfg.DefaultTimezone)
+	}
+
+	os.Setenv("DEFAULT_TIMEZONE", "Europe/Paris")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.DefaultTimezone != "Europe/Paris" {
+		t.Errorf("expected timezone Europe/Paris, got %s", cfg.DefaultTimezone)
+	}
+}
+
+func TestValidate_InvalidTimezone(t *testing.T) {
+	os.Setenv("DEFAULT_TIMEZONE", "Mars/Olympus")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.Validate(); err != ErrInvalidTimezone {
+		t.Errorf("expected ErrInvalidTimezone, got %v", err)
+	}
+}
+
+func TestLoad_LTIStateStore(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.LTIStateStore != "memory" {
+		t.Errorf("expected default LTI state store memory, got %s", cfg.LTIStateStore)
+	}
+
+	os.Setenv("LTI_STATE_STORE", "database")
+	defer os.Clearenv()
+	cfg := Load()
+	if cfg.LTIStateStore != "database" {
+		t.Errorf("expected LTI state store database, got %s", cfg.LTIStateStore)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected database state store to be valid, got %v", err)
+	}
+}
+
+func TestValidate_InvalidLTIStateStore(t *testing.T) {
+	os.Setenv("LTI_STATE_STORE", "redis")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.Validate(); err != ErrInvalidLTIStateStore {
+		t.Errorf("expected ErrInvalidLTIStateStore, got %v", err)
+	}
+}
+
+func TestLoad_LogFormat(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.LogFormat != "text" {
+		t.Errorf("expected default log format text, got %s", cfg.LogFormat)
+	}
+
+	os.Setenv("LOG_FORMAT", "json")
+	defer os.Clearenv()
+	cfg := Load()
+	if cfg.LogFormat != "json" {
+		t.Errorf("expected log format json, got %s", cfg.LogFormat)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected json log format to be valid, got %v", err)
+	}
+}
+
+func TestValidate_InvalidLogFormat(t *testing.T) {
+	os.Setenv("LOG_FORMAT", "xml")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.Validate(); err != ErrInvalidLogFormat {
+		t.Errorf("expected ErrInvalidLogFormat, got %v", err)
+	}
+}
+
+func TestLoad_FrontendDir(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.FrontendDir != "" {
+		t.Errorf("expected no frontend by default, got %q", cfg.FrontendDir)
+	}
+
+	os.Setenv("FRONTEND_DIR", "./frontend/dist")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.FrontendDir != "./frontend/dist" {
+		t.Errorf("expected frontend dir ./frontend/dist, got %q", cfg.FrontendDir)
+	}
+}
+
+func TestLoad_LogLevel(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.LogLevel != "info" {
+		t.Errorf("expected default log level info, got %s", cfg.LogLevel)
+	}
+
+	os.Setenv("LOG_LEVEL", "debug")
+	defer os.Clearenv()
+	cfg := Load()
+	if cfg.LogLevel != "debug" {
+		t.Errorf("expected log level debug, got %s", cfg.LogLevel)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected debug log level to be valid, got %v", err)
+	}
+
+	os.Setenv("LOG_LEVEL", "chatty")
+	if err := Load().Validate(); err != ErrInvalidLogLevel {
+		t.Errorf("expected ErrInvalidLogLevel, got %v", err)
+	}
+}
+
+func TestLoad_PublicBaseURLAndTrustedProxies(t *testing.T) {
+	os.Setenv("PUBLIC_BASE_URL", "https://journal.example.edu")
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5,,")
+	defer os.Clearenv()
+
+	cfg := Load()
+	if cfg.PublicBaseURL != "https://journal.example.edu" {
+		t.Errorf("expected public base URL, got %q", cfg.PublicBaseURL)
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[0] != "10.0.0.0/8" || cfg.TrustedProxies[1] != "192.168.1.5" {
+		t.Errorf("expected two trusted proxies, got %v", cfg.TrustedProxies)
+	}
+}
+
+func TestValidate_InvalidPublicBaseURL(t *testing.T) {
+	for _, value := range []string{"journal.example.edu", "ftp://journal.example.edu", "https://"} {
+		os.Setenv("PUBLIC_BASE_URL", value)
+		cfg := Load()
+		if err := cfg.Validate(); err != ErrInvalidPublicBaseURL {
+			t.Errorf("%q: expected ErrInvalidPublicBaseURL, got %v", value, err)
+		}
+	}
+	os.Clearenv()
+}
+
+func TestValidate_Production_MissingPublicBaseURL(t *testing.T) {
+	os.Setenv("DB_DRIVER", "postgres")
+	os.Setenv("LTI_CLIENT_ID", "test-client")
+	os.Setenv("SESSION_SECRET", "secure-production-secret-0123456789")
+	defer os.Clearenv()
+
+	cfg := Load()
+
+	if err := cfg.Validate(); err != ErrMissingPublicBaseURL {
+		t.Errorf("expected ErrMissingPublicBaseURL, got %v", err)
+	}
+}
+
+func TestLoad_MaxPageSize(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.MaxPageSize != 200 {
+		t.Errorf("expected default max page size 200, got %d", cfg.MaxPageSize)
+	}
+
+	os.Setenv("MAX_PAGE_SIZE", "50")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.MaxPageSize != 50 {
+		t.Errorf("expected max page size 50, got %d", cfg.MaxPageSize)
+	}
+}
+
+func TestLoad_UploadAllowedExtensions(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); len(cfg.UploadAllowedExtensions) != 0 {
+		t.Errorf("expected no extension allowlist by default, got %v", cfg.UploadAllowedExtensions)
+	}
+
+	os.Setenv("UPLOAD_ALLOWED_EXTENSIONS", ".jpg, .png,")
+	defer os.Clearenv()
+	cfg := Load()
+	if len(cfg.UploadAllowedExtensions) != 2 || cfg.UploadAllowedExtensions[0] != ".jpg" || cfg.UploadAllowedExtensions[1] != ".png" {
+		t.Errorf("expected .jpg and .png, got %v", cfg.UploadAllowedExtensions)
+	}
+}
+
+func TestLoad_UploadCacheMaxAge(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.UploadCacheMaxAge != 31536000 {
+		t.Errorf("expected default upload cache max age of one year, got %d", cfg.UploadCacheMaxAge)
+	}
+
+	os.Setenv("UPLOAD_CACHE_MAX_AGE", "600")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.UploadCacheMaxAge != 600 {
+		t.Errorf("expected upload cache max age 600, got %d", cfg.UploadCacheMaxAge)
+	}
+}
+
+func TestLoad_TextPolicy(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.MaxNotesLength != 20000 || cfg.StripHTML {
+		t.Errorf("expected 20000-character notes without HTML stripping, got %d / %v", cfg.MaxNotesLength, cfg.StripHTML)
+	}
+
+	os.Setenv("MAX_NOTES_LENGTH", "500")
+	os.Setenv("STRIP_HTML", "true")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.MaxNotesLength != 500 || !cfg.StripHTML {
+		t.Errorf("expected 500-character notes with HTML stripping, got %d / %v", cfg.MaxNotesLength, cfg.StripHTML)
+	}
+}
+
+func TestLoad_UserLimits(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.MaxVisitsPerUser != 10000 || cfg.MaxEntriesPerUser != 10000 {
+		t.Errorf("expected limits of 10000, got %d visits / %d entries", cfg.MaxVisitsPerUser, cfg.MaxEntriesPerUser)
+	}
+
+	os.Setenv("MAX_VISITS_PER_USER", "50")
+	os.Setenv("MAX_ENTRIES_PER_USER", "0")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.MaxVisitsPerUser != 50 || cfg.MaxEntriesPerUser != 0 {
+		t.Errorf("expected 50 visits and unlimited entries, got %d / %d", cfg.MaxVisitsPerUser, cfg.MaxEntriesPerUser)
+	}
+}
+
+func TestLoad_OwnerComments(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.OwnerComments {
+		t.Error("expected owner comments to be disabled by default")
+	}
+
+	os.Setenv("OWNER_COMMENTS", "true")
+	defer os.Clearenv()
+	if cfg := Load(); !cfg.OwnerComments {
+		t.Error("expected owner comments to be enabled")
+	}
+}
+
+func TestLoad_ReadOnly(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.ReadOnly {
+		t.Error("expected read-only mode to be disabled by default")
+	}
+
+	os.Setenv("READ_ONLY", "true")
+	defer os.Clearenv()
+	if cfg := Load(); !cfg.ReadOnly {
+		t.Error("expected read-only mode to be enabled")
+	}
+}
+
+func TestLoad_UniqueEntryTitles(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.UniqueEntryTitles {
+		t.Error("expected unique entry titles to be disabled by default")
+	}
+
+	os.Setenv("UNIQUE_ENTRY_TITLES", "true")
+	defer os.Clearenv()
+	if cfg := Load(); !cfg.UniqueEntryTitles {
+		t.Error("expected unique entry titles to be enabled")
+	}
+}
+
+func TestLoad_Webhook(t *testing.T) {
+	os.Setenv("WEBHOOK_URL", "https://hooks.example.edu/journal")
+	os.Setenv("WEBHOOK_SECRET", "shared-secret")
+	defer os.Clearenv()
+
+	cfg := Load()
+	if cfg.WebhookURL != "https://hooks.example.edu/journal" || cfg.WebhookSecret != "shared-secret" {
+		t.Errorf("expected webhook settings from env, got %q / %q", cfg.WebhookURL, cfg.WebhookSecret)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected webhook config to be valid, got %v", err)
+	}
+}
+
+func TestValidate_InvalidWebhook(t *testing.T) {
+	os.Setenv("WEBHOOK_SECRET", "shared-secret")
+	for _, value := range []string{"hooks.example.edu", "ftp://hooks.example.edu", "https://"} {
+		os.Setenv("WEBHOOK_URL", value)
+		if err := Load().Validate(); err != ErrInvalidWebhookURL {
+			t.Errorf("%q: expected ErrInvalidWebhookURL, got %v", value, err)
+		}
+	}
+
+	os.Setenv("WEBHOOK_URL", "https://hooks.example.edu/journal")
+	os.Unsetenv("WEBHOOK_SECRET")
+	if err := Load().Validate(); err != ErrMissingWebhookSecret {
+		t.Errorf("expected ErrMissingWebhookSecret, got %v", err)
+	}
+	os.Clearenv()
+}
+
+func TestLoad_SMTP(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+
+	if cfg := Load(); cfg.SMTPHost != "" || cfg.SMTPPort != 587 {
+		t.Errorf("expected mail disabled on port 587 by default, got %q:%d", cfg.SMTPHost, cfg.SMTPPort)
+	}
+
+	os.Setenv("SMTP_HOST", "smtp.example.edu")
+	os.Setenv("SMTP_PORT", "2525")
+	os.Setenv("SMTP_USERNAME", "journal")
+	os.Setenv("SMTP_PASSWORD", "secret")
+	if err := Load().Validate(); err != ErrMissingSMTPFrom {
+		t.Errorf("expected ErrMissingSMTPFrom, got %v", err)
+	}
+
+	os.Setenv("SMTP_FROM", "journal@example.edu")
+	cfg := Load()
+	if cfg.SMTPHost != "smtp.example.edu" || cfg.SMTPPort != 2525 || cfg.SMTPUsername != "journal" ||
+		cfg.SMTPPassword != "secret" || cfg.SMTPFrom != "journal@example.edu" {
+		t.Errorf("expected SMTP settings from env, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected SMTP config to be valid, got %v", err)
+	}
+}
+
+func TestLoad_UploadLimits(t *testing.T) {
+	os.Clearenv()
+	cfg := Load()
+	if cfg.MaxUploadsPerDay != 100 || cfg.MaxMediaPerEntry != 10 {
+		t.Errorf("expected default limits 100 and 10, got %d and %d", cfg.MaxUploadsPerDay, cfg.MaxMediaPerEntry)
+	}
+
+	os.Setenv("MAX_UPLOADS_PER_DAY", "5")
+	os.Setenv("MAX_MEDIA_PER_ENTRY", "0")
+	defer os.Clearenv()
+	cfg = Load()
+	if cfg.MaxUploadsPerDay != 5 || cfg.MaxMediaPerEntry != 0 {
+		t.Errorf("expected limits 5 and 0, got %d and %d", cfg.MaxUploadsPerDay, cfg.MaxMediaPerEntry)
+	}
+}
+
+func TestLoad_SessionLeeway(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.SessionLeeway != 30 {
+		t.Errorf("expected default session leeway 30, got %d", cfg.SessionLeeway)
+	}
+
+	os.Setenv("SESSION_LEEWAY", "60")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.SessionLeeway != 60 {
+		t.Errorf("expected session leeway 60, got %d", cfg.SessionLeeway)
+	}
+}
+
+func TestLoad_ShutdownTimeout(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); cfg.ShutdownTimeout != 30 {
+		t.Errorf("expected default shutdown timeout 30, got %d", cfg.ShutdownTimeout)
+	}
+
+	os.Setenv("SHUTDOWN_TIMEOUT", "120")
+	defer os.Clearenv()
+	if cfg := Load(); cfg.ShutdownTimeout != 120 {
+		t.Errorf("expected shutdown timeout 120, got %d", cfg.ShutdownTimeout)
+	}
+}
+
+func TestLoad_PreviousSessionSecrets(t *testing.T) {
+	os.Clearenv()
+	if cfg := Load(); len(cfg.PreviousSessionSecrets) != 0 {
+		t.Errorf("expected no previous session secrets by default, got %v", cfg.PreviousSessionSecrets)
+	}
+
+	os.Setenv("SESSION_SECRETS", "old-secret-1, old-secret-2")
+	defer os.Clearenv()
+	cfg := Load()
+	if len(cfg.PreviousSessionSecrets) != 2 || cfg.PreviousSessionSecrets[0] != "old-secret-1" || cfg.PreviousSessionSecrets[1] != "old-secret-2" {
+		t.Errorf("expected two previous session secrets, got %v", cfg.PreviousSessionSecrets)
+	}
+}
+
+func TestLoad_RedirectOrigins(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("REDIRECT_ORIGINS", "https://app.example.edu, http://localhost:8081")
+	defer os.Clearenv()
+
+	cfg := Load()
+	if len(cfg.RedirectOrigins) != 2 || cfg.RedirectOrigins[0] != "https://app.example.edu" || cfg.RedirectOrigins[1] != "http://localhost:8081" {
+		t.Errorf("expected two redirect origins, got %v", cfg.RedirectOrigins)
+	}
+}
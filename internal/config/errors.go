@@ -8,4 +8,40 @@ var (
 
 	// ErrInsecureSessionSecret is returned when using default session secret in production
 	ErrInsecureSessionSecret = errors.New("session secret must be changed in production")
+
+	// ErrShortSessionSecret is returned when the session secret is shorter
+	// than SESSION_SECRET_MIN_LENGTH in production
+	ErrShortSessionSecret = errors.New("session secret is too short for production")
+
+	// ErrDemoModeInProduction is returned when demo login is enabled in production
+	ErrDemoModeInProduction = errors.New("demo mode must be disabled in production")
+
+	// ErrInvalidTimezone is returned when DEFAULT_TIMEZONE is not a known IANA zone
+	ErrInvalidTimezone = errors.New("default timezone must be a valid IANA zone name")
+
+	// ErrInvalidLTIStateStore is returned when LTI_STATE_STORE is not a known backend
+	ErrInvalidLTIStateStore = errors.New("LTI state store must be \"memory\" or \"database\"")
+
+	// ErrInvalidPublicBaseURL is returned when PUBLIC_BASE_URL is not an absolute http(s) URL
+	ErrInvalidPublicBaseURL = errors.New("public base URL must be an absolute http or https URL")
+
+	// ErrMissingPublicBaseURL is returned when PUBLIC_BASE_URL is unset in production,
+	// where the LTI launch URL must not be derived from request headers
+	ErrMissingPublicBaseURL = errors.New("public base URL required in production mode")
+
+	// ErrInvalidLogFormat is returned when LOG_FORMAT is not a known format
+	ErrInvalidLogFormat = errors.New("log format must be \"text\" or \"json\"")
+
+	// ErrInvalidLogLevel is returned when LOG_LEVEL is not a known level
+	ErrInvalidLogLevel = errors.New("log level must be \"debug\", \"info\", \"warn\" or \"error\"")
+
+	// ErrInvalidWebhookURL is returned when WEBHOOK_URL is not an absolute http(s) URL
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+
+	// ErrMissingWebhookSecret is returned when WEBHOOK_URL is set without WEBHOOK_SECRET,
+	// since receivers could not verify unsigned events
+	ErrMissingWebhookSecret = errors.New("webhook secret required when a webhook URL is set")
+
+	// ErrMissingSMTPFrom is returned when SMTP_HOST is set without SMTP_FROM
+	ErrMissingSMTPFrom = errors.New("SMTP from address required when an SMTP host is set")
 )
@@ -30,7 +30,8 @@ func Connect(cfg *config.Config) (*gorm.DB, error) {
 
 	// Configure GORM
 	gormConfig := &gorm.Config{
-		Logger: getLogger(cfg),
+		Logger:         getLogger(cfg),
+		TranslateError: true, // Surface constraint violations as gorm.ErrDuplicatedKey
 	}
 
 	db, err := gorm.Open(dialector, gormConfig)
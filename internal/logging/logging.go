@@ -0,0 +1,31 @@
+// Package logging builds the application's structured logger from config
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// ParseLevel parses a level name: debug, info, warn or error,
+// case-insensitively
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+// New creates a logger writing to w that drops records below level. Format
+// "json" writes one JSON object per line; anything else writes key=value
+// text. An unknown level logs at info.
+func New(w io.Writer, format, level string) *slog.Logger {
+	minLevel, err := ParseLevel(level)
+	if err != nil {
+		minLevel = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: minLevel}
+
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_HonorsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "text", "warn")
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	out := buf.String()
+	for _, dropped := range []string{"debug message", "info message"} {
+		if strings.Contains(out, dropped) {
+			t.Errorf("expected %q to be dropped at warn level, got %s", dropped, out)
+		}
+	}
+	for _, kept := range []string{"warn message", "error message"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %q to be logged at warn level, got %s", kept, out)
+		}
+	}
+}
+
+func TestNew_DebugLevel(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, "text", "debug").Debug("debug message")
+
+	if !strings.Contains(buf.String(), "debug message") {
+		t.Errorf("expected debug message at debug level, got %q", buf.String())
+	}
+}
+
+func TestNew_UnknownLevelUsesInfo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "text", "verbose")
+	logger.Debug("debug message")
+	logger.Info("info message")
+
+	if strings.Contains(buf.String(), "debug message") || !strings.Contains(buf.String(), "info message") {
+		t.Errorf("expected info level for an unknown level, got %q", buf.String())
+	}
+}
+
+func TestNew_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, "json", "info").Info("seeded countries", "count", 3)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "seeded countries" || record["level"] != "INFO" || record["count"] != float64(3) {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
@@ -2,8 +2,12 @@ package lti
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"globe-expedition-journal/internal/models"
 
@@ -15,41 +19,108 @@ import (
 type Handler struct {
 	db             *gorm.DB
 	platformRepo   *PlatformRepository
-	stateStore     *StateStore
+	stateStore     StateStorage
 	jwtValidator   *JWTValidator
 	sessionManager *SessionManager
 	frontendURL    string
+	deepLinkingURL string
+	launchURLs     launchURLResolver
+	redirects      redirectAllowlist
 }
 
 // HandlerConfig holds configuration for the LTI handler
 type HandlerConfig struct {
 	SessionSecret string
 	SessionMaxAge int
+	SessionLeeway time.Duration // Clock skew tolerated on session expiry
 	FrontendURL   string
+	StateStore    string // "memory" (default) or "database" for multi-instance deployments; also holds redeemed nonces
+	// DeepLinkingURL is where LtiDeepLinkingRequest launches are redirected;
+	// defaults to /deep-linking
+	DeepLinkingURL string
+	// PublicBaseURL is the canonical external URL of the app, e.g.
+	// https://journal.example.edu. When set, the launch redirect_uri is built
+	// from it and request headers are ignored.
+	PublicBaseURL string
+	// TrustedProxies lists proxy IPs or CIDR ranges whose X-Forwarded-Proto
+	// and X-Forwarded-Host headers are honored when PublicBaseURL is unset
+	TrustedProxies []string
+	// TrustForwardedHeaders honors X-Forwarded-* headers from any client.
+	// Only for development, since clients can forge them.
+	TrustForwardedHeaders bool
+	// RedirectOrigins lists origins such as https://app.example.edu that
+	// launches may redirect to besides the tool's own. Target link URIs
+	// elsewhere are replaced with FrontendURL.
+	RedirectOrigins []string
+	// PreviousSessionSecrets are retired secrets whose sessions are still
+	// accepted after a rotation
+	PreviousSessionSecrets []string
+	// SessionManager signs and validates sessions, so the API and launches
+	// can share one; built from the session settings above if nil
+	SessionManager *SessionManager
 }
 
+// defaultDeepLinkingURL is the frontend route for the deep-linking picker
+const defaultDeepLinkingURL = "/deep-linking"
+
 // NewHandler creates a new LTI handler
 func NewHandler(db *gorm.DB) *Handler {
 	return NewHandlerWithConfig(db, HandlerConfig{
 		SessionSecret: "change-me-in-production",
 		SessionMaxAge: 86400,
+		SessionLeeway: DefaultSessionLeeway,
 		FrontendURL:   "/",
 	})
 }
 
 // NewHandlerWithConfig creates a new LTI handler with config
 func NewHandlerWithConfig(db *gorm.DB, cfg HandlerConfig) *Handler {
+	deepLinkingURL := cfg.DeepLinkingURL
+	if deepLinkingURL == "" {
+		deepLinkingURL = defaultDeepLinkingURL
+	}
+	sessionManager := cfg.SessionManager
+	if sessionManager == nil {
+		sessionManager = NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge)
+	}
+	sessionManager.SetLeeway(cfg.SessionLeeway)
+	sessionManager.SetPreviousSecrets(cfg.PreviousSessionSecrets)
+	jwtValidator := NewJWTValidator()
+	if cfg.StateStore == "database" {
+		if cache, err := NewDBNonceCache(db); err == nil {
+			jwtValidator.SetNonceCache(cache)
+		} else {
+			slog.Warn("failed to set up database LTI nonce cache, using memory", "error", err)
+		}
+	}
 	return &Handler{
 		db:             db,
 		platformRepo:   NewPlatformRepository(db),
-		stateStore:     NewStateStore(),
-		jwtValidator:   NewJWTValidator(),
-		sessionManager: NewSessionManager(cfg.SessionSecret, cfg.SessionMaxAge),
+		stateStore:     newStateStorage(db, cfg.StateStore),
+		jwtValidator:   jwtValidator,
+		sessionManager: sessionManager,
 		frontendURL:    cfg.FrontendURL,
+		deepLinkingURL: deepLinkingURL,
+		launchURLs:     newLaunchURLResolver(cfg.PublicBaseURL, cfg.TrustedProxies, cfg.TrustForwardedHeaders),
+		redirects:      newRedirectAllowlist(cfg.RedirectOrigins),
 	}
 }
 
-// LoginInitiation handles the OIDC login initiation request from the platform
+// newStateStorage creates the configured state store, falling back to memory
+// if the database store cannot be set up
+func newStateStorage(db *gorm.DB, backend string) StateStorage {
+	if backend == "database" {
+		store, err := NewDBStateStore(db)
+		if err == nil {
+			return store
+		}
+		slog.Warn("failed to set up database LTI state store, using memory", "error", err)
+	}
+	return NewStateStore()
+}
+
+// LoginInitiation handles the OIDC login initiation request from the platform.
+// Every attempt is recorded in the launch audit log.
 // GET/POST /lti/login
 func (h *Handler) LoginInitiation(c *gin.Context) {
 	// Extract parameters (can come from query or form)
@@ -59,43 +130,53 @@ func (h *Handler) LoginInitiation(c *gin.Context) {
 	clientID := c.DefaultQuery("client_id", c.PostForm("client_id"))
 	ltiMessageHint := c.DefaultQuery("lti_message_hint", c.PostForm("lti_message_hint"))
 
+	event := &models.LaunchEvent{Stage: models.LaunchStageLogin, Issuer: iss}
+	defer h.recordLaunch(c, event)
+
 	// Validate required parameters
 	if iss == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing iss parameter"})
+		rejectLaunch(c, event, http.StatusBadRequest, "missing iss parameter")
 		return
 	}
 	if loginHint == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing login_hint parameter"})
+		rejectLaunch(c, event, http.StatusBadRequest, "missing login_hint parameter")
 		return
 	}
 	if targetLinkURI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing target_link_uri parameter"})
+		rejectLaunch(c, event, http.StatusBadRequest, "missing target_link_uri parameter")
 		return
 	}
 
+	// An off-site target is dropped so the launch falls back to the frontend
+	if !h.allowedRedirect(c.Request, targetLinkURI) {
+		slog.Warn("ignoring disallowed target_link_uri", "target_link_uri", targetLinkURI, "issuer", iss)
+		targetLinkURI = ""
+	}
+
 	// Find the platform by issuer
 	platform, err := h.platformRepo.FindByIssuer(iss)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown platform issuer"})
+		rejectLaunch(c, event, http.StatusBadRequest, "unknown platform issuer")
 		return
 	}
+	event.PlatformID = &platform.ID
 
 	// If client_id provided, verify it matches
 	if clientID != "" && clientID != platform.ClientID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "client_id mismatch"})
+		rejectLaunch(c, event, http.StatusBadRequest, "client_id mismatch")
 		return
 	}
 
 	// Generate state and nonce
 	state, err := GenerateState()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate state"})
+		rejectLaunch(c, event, http.StatusInternalServerError, "failed to generate state")
 		return
 	}
 
 	nonce, err := GenerateNonce()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate nonce"})
+		rejectLaunch(c, event, http.StatusInternalServerError, "failed to generate nonce")
 		return
 	}
 
@@ -109,12 +190,12 @@ func (h *Handler) LoginInitiation(c *gin.Context) {
 	// Build authorization redirect URL
 	authURL, err := url.Parse(platform.AuthEndpoint)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid auth endpoint"})
+		rejectLaunch(c, event, http.StatusInternalServerError, "invalid auth endpoint")
 		return
 	}
 
 	// Get the launch endpoint URL (where Canvas will redirect back)
-	launchURL := getLaunchURL(c.Request)
+	launchURL := h.launchURLs.launchURL(c.Request)
 
 	q := authURL.Query()
 	q.Set("scope", "openid")
@@ -132,68 +213,81 @@ func (h *Handler) LoginInitiation(c *gin.Context) {
 	authURL.RawQuery = q.Encode()
 
 	// Redirect to platform authorization endpoint
+	event.Success = true
 	c.Redirect(http.StatusFound, authURL.String())
 }
 
-// Launch handles the LTI launch callback with id_token
+// Launch handles the LTI launch callback with id_token.
+// Every attempt is recorded in the launch audit log.
 // POST /lti/launch
 func (h *Handler) Launch(c *gin.Context) {
 	// Get id_token and state from form post
 	idToken := c.PostForm("id_token")
 	state := c.PostForm("state")
 
+	event := &models.LaunchEvent{Stage: models.LaunchStageLaunch}
+	defer h.recordLaunch(c, event)
+
 	if idToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id_token"})
+		rejectLaunch(c, event, http.StatusBadRequest, "missing id_token")
 		return
 	}
 	if state == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing state"})
+		rejectLaunch(c, event, http.StatusBadRequest, "missing state")
 		return
 	}
 
 	// Retrieve and validate state
 	stateData, ok := h.stateStore.Get(state)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired state"})
+		rejectLaunch(c, event, http.StatusBadRequest, "invalid or expired state")
 		return
 	}
 
 	// Find platform by client ID
 	platform, err := h.platformRepo.FindByClientID(stateData.ClientID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "platform not found"})
+		rejectLaunch(c, event, http.StatusBadRequest, "platform not found")
 		return
 	}
+	event.PlatformID = &platform.ID
+	event.Issuer = platform.Issuer
 
 	// Validate the JWT token
 	claims, err := h.jwtValidator.ValidateToken(idToken, platform, stateData.Nonce)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("token validation failed: %v", err)})
+		rejectLaunch(c, event, http.StatusUnauthorized, fmt.Sprintf("token validation failed: %v", err))
 		return
 	}
+	event.Subject = claims.Subject
+	event.CourseID = claims.GetContextID()
+	event.MessageType = claims.MessageType
 
 	// Find or create user
 	user, err := h.findOrCreateUser(claims, platform)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process user"})
+		rejectLaunch(c, event, http.StatusInternalServerError, "failed to process user")
 		return
 	}
+	event.UserID = &user.ID
 
 	// Determine role
 	role := "learner"
 	if claims.IsInstructor() {
 		role = "instructor"
 	}
+	event.Role = role
 
-	// Create session token
-	sessionToken, err := h.sessionManager.CreateToken(
+	// Create session token, carrying the user's locale as the default for localized content
+	sessionToken, err := h.sessionManager.CreateTokenWithLocale(
 		user.ID,
 		claims.Subject,
 		claims.GetContextID(),
 		role,
+		user.Locale,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		rejectLaunch(c, event, http.StatusInternalServerError, "failed to create session")
 		return
 	}
 
@@ -208,12 +302,56 @@ func (h *Handler) Launch(c *gin.Context) {
 		true,                 // HttpOnly
 	)
 
-	// Redirect to frontend
-	redirectURL := h.frontendURL
-	if stateData.TargetLinkURI != "" {
-		redirectURL = stateData.TargetLinkURI
+	event.Success = true
+	c.Redirect(http.StatusFound, h.launchRedirectURL(c.Request, claims, stateData))
+}
+
+// launchRedirectURL picks where to send the browser after a successful launch.
+// Deep-linking requests go to the content picker with the platform's settings;
+// resource link launches go to the target link URI if it is allowed, or the
+// frontend.
+func (h *Handler) launchRedirectURL(r *http.Request, claims *LTIClaims, stateData *StateData) string {
+	if claims.IsDeepLinkingRequest() {
+		return h.deepLinkingRedirectURL(claims)
 	}
-	c.Redirect(http.StatusFound, redirectURL)
+
+	if stateData.TargetLinkURI != "" && h.allowedRedirect(r, stateData.TargetLinkURI) {
+		return stateData.TargetLinkURI
+	}
+	return h.frontendURL
+}
+
+// allowedRedirect reports whether a launch may redirect to target: a path or
+// URL on the tool's own host, or a URL on a configured redirect origin
+func (h *Handler) allowedRedirect(r *http.Request, target string) bool {
+	return h.redirects.allows(target, h.launchURLs.baseURL(r))
+}
+
+// deepLinkingRedirectURL builds the deep-linking UI URL, carrying the settings
+// the frontend needs to post content items back to the platform
+func (h *Handler) deepLinkingRedirectURL(claims *LTIClaims) string {
+	q := url.Values{}
+	if returnURL := claims.GetDeepLinkReturnURL(); returnURL != "" {
+		q.Set("deep_link_return_url", returnURL)
+	}
+	if acceptTypes := claims.GetDeepLinkAcceptTypes(); len(acceptTypes) > 0 {
+		q.Set("accept_types", strings.Join(acceptTypes, ","))
+	}
+	if data, ok := claims.DeepLinkingSettings["data"].(string); ok && data != "" {
+		q.Set("data", data)
+	}
+	if multiple, ok := claims.DeepLinkingSettings["accept_multiple"].(bool); ok {
+		q.Set("accept_multiple", strconv.FormatBool(multiple))
+	}
+
+	if len(q) == 0 {
+		return h.deepLinkingURL
+	}
+	sep := "?"
+	if strings.Contains(h.deepLinkingURL, "?") {
+		sep = "&"
+	}
+	return h.deepLinkingURL + sep + q.Encode()
 }
 
 // findOrCreateUser finds an existing user or creates a new one
@@ -231,6 +369,8 @@ func (h *Handler) findOrCreateUser(claims *LTIClaims, platform *Platform) (*mode
 			CanvasInstanceURL: platform.Issuer,
 			DisplayName:       claims.Name,
 			Email:             claims.Email,
+			Locale:            claims.Locale,
+			PictureURL:        claims.Picture,
 		}
 		if err := h.db.Create(&user).Error; err != nil {
 			return nil, err
@@ -252,6 +392,14 @@ func (h *Handler) findOrCreateUser(claims *LTIClaims, platform *Platform) (*mode
 		user.Email = claims.Email
 		updated = true
 	}
+	if claims.Locale != "" && user.Locale != claims.Locale {
+		user.Locale = claims.Locale
+		updated = true
+	}
+	if claims.Picture != "" && user.PictureURL != claims.Picture {
+		user.PictureURL = claims.Picture
+		updated = true
+	}
 	if updated {
 		h.db.Save(&user)
 	}
@@ -260,7 +408,7 @@ func (h *Handler) findOrCreateUser(claims *LTIClaims, platform *Platform) (*mode
 }
 
 // GetStateStore returns the state store (for testing)
-func (h *Handler) GetStateStore() *StateStore {
+func (h *Handler) GetStateStore() StateStorage {
 	return h.stateStore
 }
 
@@ -273,20 +421,3 @@ func (h *Handler) GetPlatformRepo() *PlatformRepository {
 func (h *Handler) GetSessionManager() *SessionManager {
 	return h.sessionManager
 }
-
-// getLaunchURL constructs the launch callback URL
-func getLaunchURL(r *http.Request) string {
-	scheme := "https"
-	if r.TLS == nil {
-		scheme = "http"
-	}
-	// Check for forwarded headers (behind proxy)
-	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
-		scheme = proto
-	}
-	host := r.Host
-	if fwdHost := r.Header.Get("X-Forwarded-Host"); fwdHost != "" {
-		host = fwdHost
-	}
-	return fmt.Sprintf("%s://%s/lti/launch", scheme, host)
-}
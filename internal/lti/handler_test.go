@@ -1,17 +1,22 @@
 package lti
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"globe-expedition-journal/internal/config"
 	"globe-expedition-journal/internal/database"
+	"globe-expedition-journal/internal/models"
 
+	"github.com/MicahParks/keyfunc/v3"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func init() {
@@ -29,8 +34,8 @@ func setupHandlerTestDB(t *testing.T) (*Handler, func()) {
 		t.Fatalf("failed to connect to test database: %v", err)
 	}
 
-	// Migrate platform table
-	db.AutoMigrate(&Platform{})
+	// Migrate platform, user and launch audit tables
+	db.AutoMigrate(&Platform{}, &models.User{}, &models.LaunchEvent{})
 
 	handler := NewHandler(db)
 
@@ -248,3 +253,314 @@ func TestLoginInitiation_ClientIDMismatch(t *testing.T) {
 		t.Errorf("expected error about client_id mismatch, got %s", w.Body.String())
 	}
 }
+
+// setupLaunchTest registers a platform whose keys are served from a local
+// key manager and stores a state for the launch, returning the signing key
+// manager and the state value
+func setupLaunchTest(t *testing.T, handler *Handler) (*KeyManager, string) {
+	t.Helper()
+
+	km, err := NewKeyManager()
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+	jwksJSON, err := km.GetJWKSJSON()
+	if err != nil {
+		t.Fatalf("failed to build JWKS: %v", err)
+	}
+	handler.jwtValidator.fetchKeyfunc = func(context.Context, string) (keyfunc.Keyfunc, error) {
+		return keyfunc.NewJWKSetJSON([]byte(jwksJSON))
+	}
+
+	platform := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-123",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	}
+	handler.GetPlatformRepo().Create(platform)
+
+	// The test frontend is served from its own host
+	handler.redirects = newRedirectAllowlist([]string{"https://app.com"})
+
+	handler.GetStateStore().Store("state-123", &StateData{
+		Nonce:         "nonce-123",
+		TargetLinkURI: "https://app.com/launch",
+		ClientID:      "client-123",
+	})
+
+	return km, "state-123"
+}
+
+// signLaunchToken builds a signed id_token for the test platform
+func signLaunchToken(t *testing.T, km *KeyManager, claims *LTIClaims) string {
+	t.Helper()
+
+	claims.Issuer = "https://canvas.example.com"
+	claims.Subject = "user-1"
+	claims.Audience = jwt.ClaimStrings{"client-123"}
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(5 * time.Minute))
+	claims.Nonce = "nonce-123"
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = km.GetKeyID()
+	signed, err := token.SignedString(km.GetPrivateKey())
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func postLaunch(handler *Handler, idToken, state string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/lti/launch", handler.Launch)
+
+	form := url.Values{}
+	form.Set("id_token", idToken)
+	form.Set("state", state)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/lti/launch", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLaunch_ResourceLinkRedirectsToTarget(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, state := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiResourceLinkRequest",
+		Version:     "1.3.0",
+	})
+
+	w := postLaunch(handler, idToken, state)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "https://app.com/launch" {
+		t.Errorf("expected redirect to target link URI, got %s", location)
+	}
+}
+
+func TestLaunch_DeepLinkingRedirectsToDeepLinkingPath(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, state := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiDeepLinkingRequest",
+		Version:     "1.3.0",
+		DeepLinkingSettings: map[string]interface{}{
+			"deep_link_return_url": "https://canvas.example.com/deep_link/return",
+			"accept_types":         []string{"ltiResourceLink", "link"},
+			"accept_multiple":      false,
+			"data":                 "opaque-platform-data",
+		},
+	})
+
+	w := postLaunch(handler, idToken, state)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+
+	redirectURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect URL: %v", err)
+	}
+	if redirectURL.Path != "/deep-linking" {
+		t.Errorf("expected redirect to /deep-linking, got %s", redirectURL.Path)
+	}
+
+	query := redirectURL.Query()
+	if query.Get("deep_link_return_url") != "https://canvas.example.com/deep_link/return" {
+		t.Errorf("unexpected deep_link_return_url: %s", query.Get("deep_link_return_url"))
+	}
+	if query.Get("accept_types") != "ltiResourceLink,link" {
+		t.Errorf("unexpected accept_types: %s", query.Get("accept_types"))
+	}
+	if query.Get("accept_multiple") != "false" {
+		t.Errorf("unexpected accept_multiple: %s", query.Get("accept_multiple"))
+	}
+	if query.Get("data") != "opaque-platform-data" {
+		t.Errorf("unexpected data: %s", query.Get("data"))
+	}
+
+	// Deep-linking launches still get a session
+	if !strings.Contains(w.Header().Get("Set-Cookie"), "session=") {
+		t.Error("expected session cookie to be set")
+	}
+}
+
+func TestLaunch_RejectsReplayedNonce(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, state := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{MessageType: "LtiResourceLinkRequest"})
+
+	if w := postLaunch(handler, idToken, state); w.Code != http.StatusFound {
+		t.Fatalf("expected first launch to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Even if the state survived, the same token cannot launch twice
+	handler.GetStateStore().Store(state, &StateData{Nonce: "nonce-123", ClientID: "client-123"})
+	w := postLaunch(handler, idToken, state)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 for a replayed launch, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "nonce already used") {
+		t.Errorf("expected a nonce replay error, got %s", w.Body.String())
+	}
+}
+
+func TestLaunchRedirectURL_CustomDeepLinkingURL(t *testing.T) {
+	handler := &Handler{frontendURL: "/", deepLinkingURL: "/app?view=picker"}
+	claims := &LTIClaims{
+		MessageType: "LtiDeepLinkingRequest",
+		DeepLinkingSettings: map[string]interface{}{
+			"deep_link_return_url": "https://canvas.example.com/return",
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/lti/launch", nil)
+	got := handler.launchRedirectURL(req, claims, &StateData{TargetLinkURI: "https://app.com/launch"})
+	want := "/app?view=picker&deep_link_return_url=https%3A%2F%2Fcanvas.example.com%2Freturn"
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestLaunch_StoresLocale(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, state := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiResourceLinkRequest",
+		Version:     "1.3.0",
+		Locale:      "fr-FR",
+	})
+
+	w := postLaunch(handler, idToken, state)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user models.User
+	if err := handler.db.Where("canvas_user_id = ?", "user-1").First(&user).Error; err != nil {
+		t.Fatalf("expected user to be created: %v", err)
+	}
+	if user.Locale != "fr-FR" {
+		t.Errorf("expected locale 'fr-FR', got '%s'", user.Locale)
+	}
+
+	// The session carries the locale so localized endpoints can default to it
+	var sessionToken string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session" {
+			sessionToken = cookie.Value
+		}
+	}
+	claims, err := handler.GetSessionManager().ValidateToken(sessionToken)
+	if err != nil {
+		t.Fatalf("failed to validate session token: %v", err)
+	}
+	if claims.Locale != "fr-FR" {
+		t.Errorf("expected session locale 'fr-FR', got '%s'", claims.Locale)
+	}
+}
+
+func TestFindOrCreateUser_UpdatesLocale(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	platform := &Platform{Issuer: "https://canvas.example.com", ClientID: "client-123"}
+	claims := &LTIClaims{Locale: "en-US"}
+	claims.Subject = "user-1"
+
+	if _, err := handler.findOrCreateUser(claims, platform); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// A later launch with a new locale updates it; one without keeps it
+	claims.Locale = "fr-FR"
+	user, err := handler.findOrCreateUser(claims, platform)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if user.Locale != "fr-FR" {
+		t.Errorf("expected locale 'fr-FR', got '%s'", user.Locale)
+	}
+
+	claims.Locale = ""
+	user, _ = handler.findOrCreateUser(claims, platform)
+	if user.Locale != "fr-FR" {
+		t.Errorf("expected locale to be kept when not provided, got '%s'", user.Locale)
+	}
+}
+
+func TestFindOrCreateUser_PictureURL(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	platform := &Platform{Issuer: "https://canvas.example.com", ClientID: "client-123"}
+	claims := &LTIClaims{Picture: "https://canvas.example.com/avatars/1.png"}
+	claims.Subject = "user-1"
+
+	user, err := handler.findOrCreateUser(claims, platform)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if user.PictureURL != "https://canvas.example.com/avatars/1.png" {
+		t.Errorf("expected picture URL to be set, got '%s'", user.PictureURL)
+	}
+
+	// A re-launch without a picture claim must not clear the stored avatar
+	claims.Picture = ""
+	if _, err := handler.findOrCreateUser(claims, platform); err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+
+	var stored models.User
+	handler.db.First(&stored, user.ID)
+	if stored.PictureURL != "https://canvas.example.com/avatars/1.png" {
+		t.Errorf("expected picture URL to be kept, got '%s'", stored.PictureURL)
+	}
+}
+
+func TestFindOrCreateUser_SoftDeletedUserStartsFresh(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	platform := &Platform{Issuer: "https://canvas.example.com", ClientID: "client-123"}
+	claims := &LTIClaims{Name: "Ada"}
+	claims.Subject = "user-1"
+
+	original, err := handler.findOrCreateUser(claims, platform)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	handler.db.Delete(&models.User{}, original.ID)
+
+	// Relaunching after account deletion creates a new account rather than
+	// reviving the deleted one
+	user, err := handler.findOrCreateUser(claims, platform)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if user.ID == original.ID {
+		t.Error("expected a new user, got the soft-deleted one")
+	}
+
+	var deleted models.User
+	handler.db.Unscoped().First(&deleted, original.ID)
+	if !deleted.DeletedAt.Valid {
+		t.Error("expected the original user to stay deleted")
+	}
+}
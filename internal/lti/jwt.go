@@ -3,8 +3,11 @@ package lti
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/MicahParks/jwkset"
 	"github.com/MicahParks/keyfunc/v3"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -14,9 +17,10 @@ type LTIClaims struct {
 	jwt.RegisteredClaims
 
 	// User info
-	Name   string `json:"name,omitempty"`
-	Email  string `json:"email,omitempty"`
-	Locale string `json:"locale,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Locale  string `json:"locale,omitempty"`
+	Picture string `json:"picture,omitempty"`
 
 	// LTI claims (using full URIs as keys)
 	MessageType        string                 `json:"https://purl.imsglobal.org/spec/lti/claim/message_type,omitempty"`
@@ -29,6 +33,9 @@ type LTIClaims struct {
 	LaunchPresentation map[string]interface{} `json:"https://purl.imsglobal.org/spec/lti/claim/launch_presentation,omitempty"`
 	Custom             map[string]interface{} `json:"https://purl.imsglobal.org/spec/lti/claim/custom,omitempty"`
 
+	// Deep linking settings, present on LtiDeepLinkingRequest launches
+	DeepLinkingSettings map[string]interface{} `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings,omitempty"`
+
 	// Nonce for replay protection
 	Nonce string `json:"nonce,omitempty"`
 
@@ -47,6 +54,40 @@ func (c *LTIClaims) GetContextID() string {
 	return ""
 }
 
+// IsDeepLinkingRequest returns true if this launch asks the tool to pick content
+func (c *LTIClaims) IsDeepLinkingRequest() bool {
+	return c.MessageType == "LtiDeepLinkingRequest"
+}
+
+// GetDeepLinkReturnURL returns the URL content items must be posted back to
+func (c *LTIClaims) GetDeepLinkReturnURL() string {
+	if c.DeepLinkingSettings == nil {
+		return ""
+	}
+	if u, ok := c.DeepLinkingSettings["deep_link_return_url"].(string); ok {
+		return u
+	}
+	return ""
+}
+
+// GetDeepLinkAcceptTypes returns the content item types the platform accepts
+func (c *LTIClaims) GetDeepLinkAcceptTypes() []string {
+	if c.DeepLinkingSettings == nil {
+		return nil
+	}
+	raw, ok := c.DeepLinkingSettings["accept_types"].([]interface{})
+	if !ok {
+		return nil
+	}
+	types := make([]string, 0, len(raw))
+	for _, t := range raw {
+		if s, ok := t.(string); ok {
+			types = append(types, s)
+		}
+	}
+	return types
+}
+
 // GetContextLabel returns the context (course) label if present
 func (c *LTIClaims) GetContextLabel() string {
 	if c.Context == nil {
@@ -96,18 +137,102 @@ func (c *LTIClaims) IsLearner() bool {
 	return false
 }
 
+// jwksCacheTTL is how long a platform's keys are used before being refetched
+const jwksCacheTTL = 1 * time.Hour
+
+// jwksStaleRetryInterval is how long stale keys keep being used after a
+// failed refresh before the platform is tried again
+const jwksStaleRetryInterval = 5 * time.Minute
+
+// JWKS fetch retry settings, so a platform that is briefly unavailable does
+// not fail the launch
+const (
+	jwksFetchAttempts = 3
+	jwksFetchBackoff  = 250 * time.Millisecond // Delay before the first retry, doubled after each
+	jwksFetchDeadline = 15 * time.Second       // For all attempts together, including backoff
+	jwksFetchTimeout  = 10 * time.Second       // Per attempt, cut short by the deadline
+)
+
+// ltiSigningMethods are the algorithms accepted on id_tokens. LTI 1.3
+// requires RS256; pinning it stops a token from choosing a weaker or
+// confusable algorithm (e.g. HS256 keyed with the platform's public key).
+var ltiSigningMethods = []string{"RS256"}
+
+// jwksCacheEntry is a cached keyfunc and when it was fetched
+type jwksCacheEntry struct {
+	keyfunc   keyfunc.Keyfunc
+	fetchedAt time.Time
+	failedAt  time.Time // Last failed refresh of these keys, if any
+}
+
+// fresh reports whether the keys can be used without refetching: they are
+// younger than ttl, or a refresh failed less than retryAfter ago
+func (e *jwksCacheEntry) fresh(ttl, retryAfter time.Duration) bool {
+	return time.Since(e.fetchedAt) < ttl || time.Since(e.failedAt) < retryAfter
+}
+
+// jwksFetch is a JWKS fetch in progress. Launches that need the same keys
+// wait for done instead of starting their own fetch.
+type jwksFetch struct {
+	done    chan struct{}
+	keyfunc keyfunc.Keyfunc
+	err     error
+}
+
 // JWTValidator validates LTI id_tokens
 type JWTValidator struct {
-	jwksCache map[string]keyfunc.Keyfunc
+	mu         sync.RWMutex
+	jwksCache  map[string]*jwksCacheEntry
+	fetching   map[string]*jwksFetch // In-flight fetches by JWKS URL
+	cacheTTL   time.Duration
+	staleRetry time.Duration
+
+	validMethods []string   // Accepted id_token signing algorithms
+	nonces       NonceCache // Rejects id_tokens whose nonce was already redeemed
+
+	// fetchKeyfunc loads the keys at a JWKS URL in one attempt, giving up
+	// when ctx is done (replaced in tests)
+	fetchKeyfunc func(ctx context.Context, jwksURL string) (keyfunc.Keyfunc, error)
+
+	fetchAttempts int
+	fetchBackoff  time.Duration
+	fetchDeadline time.Duration
 }
 
 // NewJWTValidator creates a new JWT validator
 func NewJWTValidator() *JWTValidator {
 	return &JWTValidator{
-		jwksCache: make(map[string]keyfunc.Keyfunc),
+		jwksCache:    make(map[string]*jwksCacheEntry),
+		fetching:     make(map[string]*jwksFetch),
+		cacheTTL:     jwksCacheTTL,
+		staleRetry:   jwksStaleRetryInterval,
+		validMethods: ltiSigningMethods,
+		nonces:       NewMemoryNonceCache(),
+		fetchKeyfunc: fetchJWKS,
+
+		fetchAttempts: jwksFetchAttempts,
+		fetchBackoff:  jwksFetchBackoff,
+		fetchDeadline: jwksFetchDeadline,
 	}
 }
 
+// SetNonceCache replaces the in-memory nonce replay cache, e.g. with a
+// DBNonceCache shared by every instance. A replaced MemoryNonceCache is closed.
+func (v *JWTValidator) SetNonceCache(cache NonceCache) {
+	if memory, ok := v.nonces.(*MemoryNonceCache); ok && memory != cache {
+		memory.Close()
+	}
+	v.nonces = cache
+}
+
+// InvalidateKeys drops the cached keys for a JWKS URL so the next launch
+// refetches them, e.g. after a platform announces a key rotation
+func (v *JWTValidator) InvalidateKeys(jwksURL string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.jwksCache, jwksURL)
+}
+
 // ValidateToken validates an LTI id_token and returns the claims
 func (v *JWTValidator) ValidateToken(tokenString string, platform *Platform, expectedNonce string) (*LTIClaims, error) {
 	// Get or create JWKS keyfunc for this platform
@@ -118,6 +243,7 @@ func (v *JWTValidator) ValidateToken(tokenString string, platform *Platform, exp
 
 	// Parse and validate the token
 	token, err := jwt.ParseWithClaims(tokenString, &LTIClaims{}, kf.KeyfuncCtx(context.Background()),
+		jwt.WithValidMethods(v.validMethods),
 		jwt.WithIssuer(platform.Issuer),
 		jwt.WithAudience(platform.ClientID),
 	)
@@ -140,23 +266,102 @@ func (v *JWTValidator) ValidateToken(tokenString string, platform *Platform, exp
 		return nil, fmt.Errorf("unsupported message type: %s", claims.MessageType)
 	}
 
+	// Redeem the nonce last, so only tokens that are otherwise valid use it up
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if v.nonces != nil && !v.nonces.Redeem(claims.Nonce, expiresAt) {
+		return nil, fmt.Errorf("nonce already used")
+	}
+
 	return claims, nil
 }
 
-// getKeyfunc gets or creates a JWKS keyfunc for the given endpoint
+// getKeyfunc gets or creates a JWKS keyfunc for the given endpoint,
+// refetching it once the cached copy is older than the cache TTL.
+// Concurrent launches for the same endpoint share a single fetch.
 func (v *JWTValidator) getKeyfunc(jwksURL string) (keyfunc.Keyfunc, error) {
-	if kf, ok := v.jwksCache[jwksURL]; ok {
-		return kf, nil
+	v.mu.RLock()
+	entry, ok := v.jwksCache[jwksURL]
+	v.mu.RUnlock()
+	if ok && entry.fresh(v.cacheTTL, v.staleRetry) {
+		return entry.keyfunc, nil
+	}
+
+	v.mu.Lock()
+	entry, ok = v.jwksCache[jwksURL]
+	if ok && entry.fresh(v.cacheTTL, v.staleRetry) {
+		v.mu.Unlock()
+		return entry.keyfunc, nil
 	}
+	if fetch, running := v.fetching[jwksURL]; running {
+		v.mu.Unlock()
+		<-fetch.done
+		return fetch.keyfunc, fetch.err
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	v.fetching[jwksURL] = fetch
+	v.mu.Unlock()
+
+	// Fetch without holding the lock, so a slow platform does not hold up
+	// launches for platforms whose keys are cached
+	fetch.keyfunc, fetch.err = v.fetchWithRetry(jwksURL)
+	now := time.Now()
+
+	v.mu.Lock()
+	delete(v.fetching, jwksURL)
+	if fetch.err == nil {
+		v.jwksCache[jwksURL] = &jwksCacheEntry{keyfunc: fetch.keyfunc, fetchedAt: now}
+	} else if ok {
+		// Keep using the stale keys rather than failing every launch, and
+		// record the failure so the platform is not refetched on every launch
+		slog.Warn("failed to refresh JWKS, using cached keys", "url", jwksURL, "error", fetch.err)
+		v.jwksCache[jwksURL] = &jwksCacheEntry{keyfunc: entry.keyfunc, fetchedAt: entry.fetchedAt, failedAt: now}
+		fetch.keyfunc, fetch.err = entry.keyfunc, nil
+	}
+	v.mu.Unlock()
+	close(fetch.done)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	return fetch.keyfunc, fetch.err
+}
+
+// fetchWithRetry fetches the keys at jwksURL, retrying failed attempts with
+// exponential backoff until the attempts or the deadline run out. The
+// deadline also cuts short an attempt that is still running when it passes.
+func (v *JWTValidator) fetchWithRetry(jwksURL string) (keyfunc.Keyfunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), v.fetchDeadline)
 	defer cancel()
+	deadline, _ := ctx.Deadline()
+	backoff := v.fetchBackoff
 
-	kf, err := keyfunc.NewDefaultCtx(ctx, []string{jwksURL})
+	for attempt := 1; ; attempt++ {
+		kf, err := v.fetchKeyfunc(ctx, jwksURL)
+		if err == nil {
+			return kf, nil
+		}
+		slog.Warn("JWKS fetch failed", "url", jwksURL, "attempt", attempt, "attempts", v.fetchAttempts, "error", err)
+
+		if attempt >= v.fetchAttempts || time.Now().Add(backoff).After(deadline) {
+			return nil, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// fetchJWKS loads the keys published at a JWKS URL, giving up after
+// jwksFetchTimeout or when ctx is done, whichever comes first. Unlike
+// keyfunc.NewDefaultCtx it fails when the keys cannot be fetched, rather than
+// returning an empty key set, and it starts no background refresh since
+// getKeyfunc refetches expired keys itself.
+func fetchJWKS(ctx context.Context, jwksURL string) (keyfunc.Keyfunc, error) {
+	storage, err := jwkset.NewStorageFromHTTP(jwksURL, jwkset.HTTPClientStorageOptions{
+		Ctx:         ctx,
+		HTTPTimeout: jwksFetchTimeout,
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	v.jwksCache[jwksURL] = kf
-	return kf, nil
+	return keyfunc.New(keyfunc.Options{Storage: storage})
 }
@@ -1,7 +1,23 @@
 package lti
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestLTIClaims_GetContextID(t *testing.T) {
@@ -224,3 +240,352 @@ func TestNewJWTValidator(t *testing.T) {
 		t.Error("expected jwksCache to be initialized")
 	}
 }
+
+func TestJWTValidator_SetNonceCache_ClosesMemoryCache(t *testing.T) {
+	v := NewJWTValidator()
+	memory, ok := v.nonces.(*MemoryNonceCache)
+	if !ok {
+		t.Fatalf("expected a memory nonce cache by default, got %T", v.nonces)
+	}
+
+	v.SetNonceCache(NewMemoryNonceCache())
+
+	select {
+	case <-memory.stop:
+	default:
+		t.Error("expected the replaced memory cache to be closed")
+	}
+}
+
+// stubKeyfunc returns a fetch function that counts calls and never hits the network
+func stubKeyfunc(calls *int32) func(context.Context, string) (keyfunc.Keyfunc, error) {
+	return func(ctx context.Context, jwksURL string) (keyfunc.Keyfunc, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(10 * time.Millisecond) // Widen the race window
+		return keyfunc.NewJWKSetJSON([]byte(`{"keys":[]}`))
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_Concurrent(t *testing.T) {
+	v := NewJWTValidator()
+	var calls int32
+	v.fetchKeyfunc = stubKeyfunc(&calls)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.getKeyfunc("https://canvas.example.com/jwks"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected a single JWKS fetch, got %d", calls)
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_RefreshesAfterTTL(t *testing.T) {
+	v := NewJWTValidator()
+	var calls int32
+	v.fetchKeyfunc = stubKeyfunc(&calls)
+	v.cacheTTL = time.Millisecond
+
+	v.getKeyfunc("https://canvas.example.com/jwks")
+	time.Sleep(5 * time.Millisecond)
+	v.getKeyfunc("https://canvas.example.com/jwks")
+
+	if calls != 2 {
+		t.Errorf("expected keys to be refetched after TTL, got %d fetches", calls)
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_StaleOnRefreshFailure(t *testing.T) {
+	v := NewJWTValidator()
+	var calls int32
+	v.fetchKeyfunc = stubKeyfunc(&calls)
+	v.cacheTTL = time.Millisecond
+
+	if _, err := v.getKeyfunc("https://canvas.example.com/jwks"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	v.fetchBackoff = time.Millisecond
+	v.fetchKeyfunc = func(context.Context, string) (keyfunc.Keyfunc, error) {
+		return nil, errors.New("platform unavailable")
+	}
+	if _, err := v.getKeyfunc("https://canvas.example.com/jwks"); err != nil {
+		t.Errorf("expected stale keys to be used when refresh fails, got %v", err)
+	}
+
+	// The failed refresh is not retried on every launch
+	var failedCalls int32
+	v.fetchKeyfunc = func(context.Context, string) (keyfunc.Keyfunc, error) {
+		atomic.AddInt32(&failedCalls, 1)
+		return nil, errors.New("platform unavailable")
+	}
+	if _, err := v.getKeyfunc("https://canvas.example.com/jwks"); err != nil {
+		t.Errorf("expected stale keys to be used, got %v", err)
+	}
+	if failedCalls != 0 {
+		t.Errorf("expected no refetch within the retry interval, got %d fetches", failedCalls)
+	}
+
+	v.staleRetry = 0
+	v.getKeyfunc("https://canvas.example.com/jwks")
+	if failedCalls == 0 {
+		t.Error("expected a refetch once the retry interval passed")
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_SlowFetchDoesNotBlockOtherPlatforms(t *testing.T) {
+	v := NewJWTValidator()
+	var calls int32
+	v.fetchKeyfunc = stubKeyfunc(&calls)
+	if _, err := v.getKeyfunc("https://cached.example.com/jwks"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	v.fetchKeyfunc = func(context.Context, string) (keyfunc.Keyfunc, error) {
+		close(started)
+		<-release
+		return keyfunc.NewJWKSetJSON([]byte(`{"keys":[]}`))
+	}
+	go v.getKeyfunc("https://slow.example.com/jwks")
+	<-started
+	defer close(release)
+
+	done := make(chan struct{})
+	go func() {
+		v.getKeyfunc("https://cached.example.com/jwks")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected cached keys to be served while another platform's fetch is in flight")
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_GivesUpAfterAttempts(t *testing.T) {
+	v := NewJWTValidator()
+	v.fetchBackoff = time.Millisecond
+	var calls int32
+	v.fetchKeyfunc = func(context.Context, string) (keyfunc.Keyfunc, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("platform unavailable")
+	}
+
+	if _, err := v.getKeyfunc("https://canvas.example.com/jwks"); err == nil {
+		t.Fatal("expected an error once every attempt failed")
+	}
+	if calls != jwksFetchAttempts {
+		t.Errorf("expected %d attempts, got %d", jwksFetchAttempts, calls)
+	}
+
+	// No retry starts after the deadline
+	calls = 0
+	v.fetchDeadline = 0
+	v.getKeyfunc("https://canvas.example.com/jwks")
+	if calls != 1 {
+		t.Errorf("expected a single attempt past the deadline, got %d", calls)
+	}
+}
+
+func TestJWTValidator_GetKeyfunc_DeadlineCutsShortHangingFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // Never answer
+	}))
+	defer server.Close()
+
+	v := NewJWTValidator()
+	v.fetchBackoff = time.Millisecond
+	v.fetchDeadline = 200 * time.Millisecond
+
+	start := time.Now()
+	if _, err := v.getKeyfunc(server.URL); err == nil {
+		t.Fatal("expected an error from a JWKS endpoint that never answers")
+	}
+	// Each attempt would otherwise wait for the full per-attempt timeout
+	if elapsed := time.Since(start); elapsed > v.fetchDeadline+500*time.Millisecond {
+		t.Errorf("expected the fetch to give up around the %v deadline, took %v", v.fetchDeadline, elapsed)
+	}
+}
+
+func TestJWTValidator_ValidateToken_RetriesFlakyJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(rsaJWKS(key)))
+	}))
+	defer server.Close()
+
+	platform := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-1",
+		JWKSEndpoint: server.URL,
+	}
+	v := NewJWTValidator()
+	v.fetchBackoff = time.Millisecond
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, LTIClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    platform.Issuer,
+			Audience:  jwt.ClaimStrings{platform.ClientID},
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Nonce:       "nonce-1",
+		MessageType: "LtiResourceLinkRequest",
+	})
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := v.ValidateToken(signed, platform, "nonce-1"); err != nil {
+		t.Fatalf("expected validation to succeed after retries, got %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 JWKS requests, got %d", got)
+	}
+}
+
+func TestJWTValidator_InvalidateKeys(t *testing.T) {
+	v := NewJWTValidator()
+	var calls int32
+	v.fetchKeyfunc = stubKeyfunc(&calls)
+
+	v.getKeyfunc("https://canvas.example.com/jwks")
+	v.getKeyfunc("https://canvas.example.com/jwks")
+	v.InvalidateKeys("https://canvas.example.com/jwks")
+	v.getKeyfunc("https://canvas.example.com/jwks")
+
+	if calls != 2 {
+		t.Errorf("expected invalidation to force a refetch, got %d fetches", calls)
+	}
+}
+
+// rsaJWKS returns the public half of key as a single-key JWKS with kid
+// "test-key". The JWK carries no "alg", so only the validator decides which
+// algorithms are accepted.
+func rsaJWKS(key *rsa.PrivateKey) string {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	return fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"test-key","n":%q,"e":%q}]}`, n, e)
+}
+
+// rsaKeyfunc returns a fetch function serving rsaJWKS(key)
+func rsaKeyfunc(t *testing.T, key *rsa.PrivateKey) func(context.Context, string) (keyfunc.Keyfunc, error) {
+	t.Helper()
+	jwks := rsaJWKS(key)
+	return func(context.Context, string) (keyfunc.Keyfunc, error) {
+		return keyfunc.NewJWKSetJSON([]byte(jwks))
+	}
+}
+
+func TestJWTValidator_ValidateToken_SigningMethods(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	platform := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-1",
+		JWKSEndpoint: "https://canvas.example.com/jwks",
+	}
+	v := NewJWTValidator()
+	v.fetchKeyfunc = rsaKeyfunc(t, key)
+
+	sign := func(method jwt.SigningMethod, signingKey interface{}) string {
+		claims := LTIClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    platform.Issuer,
+				Audience:  jwt.ClaimStrings{platform.ClientID},
+				Subject:   "user-1",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Nonce:       "nonce-1",
+			MessageType: "LtiResourceLinkRequest",
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("failed to sign %s token: %v", method.Alg(), err)
+		}
+		return signed
+	}
+
+	if _, err := v.ValidateToken(sign(jwt.SigningMethodRS256, key), platform, "nonce-1"); err != nil {
+		t.Fatalf("expected RS256 token to validate, got %v", err)
+	}
+
+	// HS256 keyed with the platform's public key is the classic
+	// algorithm-confusion attack; RS512 and none are simply not allowed
+	publicKey := []byte(fmt.Sprintf("%x", key.PublicKey.N))
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"RS512", sign(jwt.SigningMethodRS512, key)},
+		{"HS256", sign(jwt.SigningMethodHS256, publicKey)},
+		{"none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.ValidateToken(tt.token, platform, "nonce-1")
+			if err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+			if !strings.Contains(err.Error(), "signing method") {
+				t.Errorf("expected a signing method error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLTIClaims_DeepLinkingSettings(t *testing.T) {
+	claims := &LTIClaims{
+		MessageType: "LtiDeepLinkingRequest",
+		DeepLinkingSettings: map[string]interface{}{
+			"deep_link_return_url": "https://canvas.example.com/return",
+			"accept_types":         []interface{}{"ltiResourceLink", "html"},
+		},
+	}
+
+	if !claims.IsDeepLinkingRequest() {
+		t.Error("expected deep linking request")
+	}
+	if got := claims.GetDeepLinkReturnURL(); got != "https://canvas.example.com/return" {
+		t.Errorf("unexpected return URL: %s", got)
+	}
+	if got := claims.GetDeepLinkAcceptTypes(); len(got) != 2 || got[0] != "ltiResourceLink" {
+		t.Errorf("unexpected accept types: %v", got)
+	}
+
+	resourceLink := &LTIClaims{MessageType: "LtiResourceLinkRequest"}
+	if resourceLink.IsDeepLinkingRequest() {
+		t.Error("resource link request should not be deep linking")
+	}
+	if resourceLink.GetDeepLinkReturnURL() != "" || resourceLink.GetDeepLinkAcceptTypes() != nil {
+		t.Error("expected empty deep linking settings")
+	}
+}
@@ -0,0 +1,117 @@
+package lti
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page size bounds for the launch audit log
+const (
+	defaultLaunchPageSize = 50
+	maxLaunchPageSize     = 200
+)
+
+// LaunchListResponse represents a page of the launch audit log
+type LaunchListResponse struct {
+	Launches []models.LaunchEvent `json:"launches"`
+	Total    int64                `json:"total"`
+}
+
+// rejectLaunch writes an error response and marks the audit event as failed
+// with the same reason
+func rejectLaunch(c *gin.Context, event *models.LaunchEvent, status int, reason string) {
+	event.Error = reason
+	c.JSON(status, gin.H{"error": reason})
+}
+
+// recordLaunch stores an audit event. A failed write is only logged so the
+// audit log can never block a launch.
+func (h *Handler) recordLaunch(c *gin.Context, event *models.LaunchEvent) {
+	event.IPAddress = c.ClientIP()
+	if err := h.db.Create(event).Error; err != nil {
+		slog.Warn("failed to record LTI launch event", "stage", event.Stage, "error", err)
+	}
+}
+
+// ListLaunches returns the launch audit log, most recent first
+// GET /api/v1/admin/launches
+// Query params: platformId (optional) - only this platform's events,
+// from, to (optional) - RFC3339 bounds on the event time, both inclusive,
+// success (optional) - true or false to keep only successes or failures,
+// limit (optional) - 1 to 200, default 50, offset (optional)
+func (h *Handler) ListLaunches(c *gin.Context) {
+	query := h.db.Model(&models.LaunchEvent{})
+
+	if platformStr := c.Query("platformId"); platformStr != "" {
+		platformID, err := strconv.ParseUint(platformStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid platformId"})
+			return
+		}
+		query = query.Where("platform_id = ?", platformID)
+	}
+
+	for _, bound := range []struct{ param, cond string }{
+		{"from", "created_at >= ?"},
+		{"to", "created_at <= ?"},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.param + " parameter, use RFC3339 (2006-01-02T15:04:05Z)"})
+			return
+		}
+		query = query.Where(bound.cond, t.UTC())
+	}
+
+	if successStr := c.Query("success"); successStr != "" {
+		success, err := strconv.ParseBool(successStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+			return
+		}
+		query = query.Where("success = ?", success)
+	}
+
+	limit := defaultLaunchPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxLaunchPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = parsed
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		offset = parsed
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch launches"})
+		return
+	}
+
+	launches := []models.LaunchEvent{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&launches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch launches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LaunchListResponse{Launches: launches, Total: total})
+}
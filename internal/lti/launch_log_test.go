@@ -0,0 +1,174 @@
+package lti
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// launchEvents returns every recorded launch event, oldest first
+func launchEvents(t *testing.T, handler *Handler) []models.LaunchEvent {
+	t.Helper()
+	var events []models.LaunchEvent
+	if err := handler.db.Order("id ASC").Find(&events).Error; err != nil {
+		t.Fatalf("failed to load launch events: %v", err)
+	}
+	return events
+}
+
+func TestLaunch_RecordsSuccessfulLaunch(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, state := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiResourceLinkRequest",
+		Version:     "1.3.0",
+		Context:     map[string]interface{}{"id": "course-42"},
+	})
+
+	if w := postLaunch(handler, idToken, state); w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+
+	events := launchEvents(t, handler)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 launch event, got %d", len(events))
+	}
+	event := events[0]
+	if !event.Success || event.Error != "" {
+		t.Errorf("expected a successful event, got %+v", event)
+	}
+	if event.Stage != models.LaunchStageLaunch || event.Issuer != "https://canvas.example.com" {
+		t.Errorf("unexpected stage or issuer: %+v", event)
+	}
+	if event.PlatformID == nil || event.UserID == nil {
+		t.Fatalf("expected platform and user to be recorded, got %+v", event)
+	}
+	if event.Subject != "user-1" || event.CourseID != "course-42" || event.MessageType != "LtiResourceLinkRequest" {
+		t.Errorf("unexpected launch details: %+v", event)
+	}
+}
+
+func TestLaunch_RecordsFailedLaunch(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, _ := setupLaunchTest(t, handler)
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiResourceLinkRequest",
+		Version:     "1.3.0",
+	})
+
+	if w := postLaunch(handler, idToken, "unknown-state"); w.Code == http.StatusFound {
+		t.Fatal("expected launch with an unknown state to fail")
+	}
+
+	events := launchEvents(t, handler)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 launch event, got %d", len(events))
+	}
+	if events[0].Success || events[0].Error == "" {
+		t.Errorf("expected a failed event with a reason, got %+v", events[0])
+	}
+	if events[0].UserID != nil {
+		t.Errorf("expected no user on a failed launch, got %d", *events[0].UserID)
+	}
+}
+
+func TestLoginInitiation_RecordsUnknownPlatform(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.GET("/lti/login", handler.LoginInitiation)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lti/login?iss=https://unknown.com&login_hint=user123&target_link_uri=https://app.com", nil)
+	router.ServeHTTP(w, req)
+
+	events := launchEvents(t, handler)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 login event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Stage != models.LaunchStageLogin || event.Success || event.Issuer != "https://unknown.com" {
+		t.Errorf("unexpected login event: %+v", event)
+	}
+	if event.PlatformID != nil || event.Error != "unknown platform issuer" {
+		t.Errorf("expected unknown platform failure, got %+v", event)
+	}
+}
+
+func getLaunches(handler *Handler, query string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/admin/launches", handler.ListLaunches)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/launches"+query, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListLaunches_Filters(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	platformA, platformB := uint(1), uint(2)
+	now := time.Now().UTC()
+	handler.db.Create(&[]models.LaunchEvent{
+		{Stage: models.LaunchStageLaunch, PlatformID: &platformA, Success: true, CreatedAt: now.Add(-48 * time.Hour)},
+		{Stage: models.LaunchStageLaunch, PlatformID: &platformA, Error: "invalid state", CreatedAt: now.Add(-time.Hour)},
+		{Stage: models.LaunchStageLogin, PlatformID: &platformB, Success: true, CreatedAt: now},
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  int64
+	}{
+		{"all", "", 3},
+		{"by platform", "?platformId=1", 2},
+		{"from", "?from=" + now.Add(-2*time.Hour).Format(time.RFC3339), 2},
+		{"platform and range", "?platformId=1&from=" + now.Add(-2*time.Hour).Format(time.RFC3339) + "&to=" + now.Add(time.Minute).Format(time.RFC3339), 1},
+		{"failures", "?success=false", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getLaunches(handler, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var response LaunchListResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Total != tt.want || int64(len(response.Launches)) != tt.want {
+				t.Errorf("expected %d launches, got total %d with %d items", tt.want, response.Total, len(response.Launches))
+			}
+		})
+	}
+
+	// Most recent first
+	var response LaunchListResponse
+	json.Unmarshal(getLaunches(handler, "?limit=1").Body.Bytes(), &response)
+	if len(response.Launches) != 1 || response.Launches[0].Stage != models.LaunchStageLogin {
+		t.Errorf("expected the newest event first, got %+v", response.Launches)
+	}
+}
+
+func TestListLaunches_InvalidParams(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	for _, query := range []string{"?platformId=abc", "?from=yesterday", "?to=2024-01-01", "?success=maybe", "?limit=0", "?limit=500", "?offset=-1"} {
+		if w := getLaunches(handler, query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package lti
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// launchPath is the route platforms post launches back to
+const launchPath = "/lti/launch"
+
+// launchURLResolver builds the redirect_uri sent to platforms. It must match
+// the registered URL exactly, so forwarded headers are only honored when they
+// cannot be forged by the client.
+type launchURLResolver struct {
+	publicBaseURL  string         // Canonical base URL; overrides request headers when set
+	trustedProxies []netip.Prefix // Peers whose X-Forwarded-* headers are honored
+	trustForwarded bool           // Honor X-Forwarded-* from any peer (development only)
+}
+
+// newLaunchURLResolver parses the trusted proxy list, which may contain IP
+// addresses and CIDR ranges. Invalid entries are logged and skipped.
+func newLaunchURLResolver(publicBaseURL string, trustedProxies []string, trustForwarded bool) launchURLResolver {
+	resolver := launchURLResolver{
+		publicBaseURL:  strings.TrimRight(publicBaseURL, "/"),
+		trustForwarded: trustForwarded,
+	}
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				slog.Warn("ignoring invalid trusted proxy", "proxy", entry)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, prefix.Masked())
+	}
+	return resolver
+}
+
+// launchURL constructs the launch callback URL for a request
+func (l launchURLResolver) launchURL(r *http.Request) string {
+	return l.baseURL(r) + launchPath
+}
+
+// baseURL returns the external scheme and host the tool is reached at
+func (l launchURLResolver) baseURL(r *http.Request) string {
+	if l.publicBaseURL != "" {
+		return l.publicBaseURL
+	}
+
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	host := r.Host
+
+	// Forwarded headers are only trusted from a known proxy
+	if l.trustForwarded || l.fromTrustedProxy(r) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwdHost := r.Header.Get("X-Forwarded-Host"); fwdHost != "" {
+			host = fwdHost
+		}
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+// fromTrustedProxy reports whether the request's peer is a trusted proxy
+func (l launchURLResolver) fromTrustedProxy(r *http.Request) bool {
+	if len(l.trustedProxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectAllowlist decides which target link URIs a launch may redirect to,
+// so a forged login initiation cannot turn the launch into an open redirect
+type redirectAllowlist struct {
+	origins map[string]bool // Normalized scheme://host origins allowed besides the tool's own
+}
+
+// newRedirectAllowlist parses the extra origins launches may redirect to,
+// e.g. a frontend served from another host. Entries that are not http(s)
+// URLs are logged and skipped; any path on an entry is ignored.
+func newRedirectAllowlist(origins []string) redirectAllowlist {
+	allowlist := redirectAllowlist{origins: make(map[string]bool)}
+	for _, entry := range origins {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			slog.Warn("ignoring invalid redirect origin", "origin", entry)
+			continue
+		}
+		allowlist.origins[urlOrigin(u)] = true
+	}
+	return allowlist
+}
+
+// allows reports whether target is a path on the tool itself or an absolute
+// http(s) URL on toolOrigin or one of the allowed origins
+func (a redirectAllowlist) allows(target, toolOrigin string) bool {
+	// Browsers treat backslashes like slashes, so "/\evil.com" is off-host
+	if strings.Contains(target, "\\") {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return false
+	}
+	origin := urlOrigin(u)
+	if toolURL, err := url.Parse(toolOrigin); err == nil && origin == urlOrigin(toolURL) {
+		return true
+	}
+	return a.origins[origin]
+}
+
+// urlOrigin returns the lowercase scheme://host of u
+func urlOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
@@ -0,0 +1,225 @@
+package lti
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLaunchURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolver   launchURLResolver
+		remoteAddr string
+		tls        bool
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:     "request host",
+			resolver: newLaunchURLResolver("", nil, false),
+			want:     "http://app.internal/lti/launch",
+		},
+		{
+			name:     "request TLS",
+			resolver: newLaunchURLResolver("", nil, false),
+			tls:      true,
+			want:     "https://app.internal/lti/launch",
+		},
+		{
+			name:     "public base URL ignores forged headers",
+			resolver: newLaunchURLResolver("https://journal.example.edu/", []string{"192.0.2.0/24"}, true),
+			headers:  map[string]string{"X-Forwarded-Proto": "http", "X-Forwarded-Host": "evil.example"},
+			want:     "https://journal.example.edu/lti/launch",
+		},
+		{
+			name:     "forwarded headers ignored from untrusted peer",
+			resolver: newLaunchURLResolver("", []string{"10.0.0.0/8"}, false),
+			headers:  map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			want:     "http://app.internal/lti/launch",
+		},
+		{
+			name:       "forwarded headers honored from trusted proxy",
+			resolver:   newLaunchURLResolver("", []string{"10.0.0.0/8"}, false),
+			remoteAddr: "10.1.2.3:5555",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "journal.example.edu"},
+			want:       "https://journal.example.edu/lti/launch",
+		},
+		{
+			name:       "single trusted proxy address",
+			resolver:   newLaunchURLResolver("", []string{"10.1.2.3", "not-an-ip"}, false),
+			remoteAddr: "10.1.2.3:5555",
+			headers:    map[string]string{"X-Forwarded-Host": "journal.example.edu"},
+			want:       "http://journal.example.edu/lti/launch",
+		},
+		{
+			name:     "development trusts any peer",
+			resolver: newLaunchURLResolver("", nil, true),
+			headers:  map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "tunnel.example"},
+			want:     "https://tunnel.example/lti/launch",
+		},
+		{
+			name:     "invalid forwarded scheme ignored",
+			resolver: newLaunchURLResolver("", nil, true),
+			headers:  map[string]string{"X-Forwarded-Proto": "javascript"},
+			want:     "http://app.internal/lti/launch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://app.internal/lti/login", nil)
+			req.RemoteAddr = "192.0.2.10:1234"
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if got := tt.resolver.launchURL(req); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoginInitiation_PublicBaseURLIgnoresForgedHeaders(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+	handler.launchURLs = newLaunchURLResolver("https://journal.example.edu", nil, false)
+
+	handler.GetPlatformRepo().Create(&Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-123",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	})
+
+	router := gin.New()
+	router.GET("/lti/login", handler.LoginInitiation)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/lti/login?iss=https://canvas.example.com&login_hint=user123&target_link_uri=https://app.com/launch", nil)
+	req.Host = "localhost:8080"
+	req.Header.Set("X-Forwarded-Proto", "http")
+	req.Header.Set("X-Forwarded-Host", "evil.example")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d", w.Code)
+	}
+	redirectURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect URL: %v", err)
+	}
+	if got := redirectURL.Query().Get("redirect_uri"); got != "https://journal.example.edu/lti/launch" {
+		t.Errorf("expected configured redirect_uri, got %q", got)
+	}
+}
+
+func TestRedirectAllowlist_Allows(t *testing.T) {
+	allowlist := newRedirectAllowlist([]string{"https://App.example.edu/some/path", "not a url", "ftp://files.example.edu"})
+	toolOrigin := "https://journal.example.edu"
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"/", true},
+		{"/journal?view=map", true},
+		{"https://journal.example.edu/launch", true},
+		{"https://JOURNAL.example.edu/launch", true},
+		{"https://app.example.edu/launch", true},
+		{"http://app.example.edu/launch", false},
+		{"https://evil.example/launch", false},
+		{"https://journal.example.edu.evil.example/", false},
+		{"https://user@journal.example.edu/", false},
+		{"//evil.example/launch", false},
+		{"/\\evil.example/launch", false},
+		{"javascript:alert(1)", false},
+		{"ftp://files.example.edu/", false},
+		{"launch", false},
+	}
+
+	for _, tt := range tests {
+		if got := allowlist.allows(tt.target, toolOrigin); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestLoginInitiation_DropsOffHostTarget(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	handler.GetPlatformRepo().Create(&Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-123",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	})
+
+	router := gin.New()
+	router.GET("/lti/login", handler.LoginInitiation)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"https://evil.example/phish", ""},
+		{"http://localhost:8080/journal", "http://localhost:8080/journal"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/lti/login?iss=https://canvas.example.com&login_hint=user123&target_link_uri="+url.QueryEscape(tt.target), nil)
+		req.Host = "localhost:8080"
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusFound {
+			t.Fatalf("%s: expected status 302, got %d", tt.target, w.Code)
+		}
+		redirectURL, _ := url.Parse(w.Header().Get("Location"))
+		stateData, ok := handler.GetStateStore().Peek(redirectURL.Query().Get("state"))
+		if !ok {
+			t.Fatalf("%s: expected state to be stored", tt.target)
+		}
+		if stateData.TargetLinkURI != tt.want {
+			t.Errorf("%s: expected stored target %q, got %q", tt.target, tt.want, stateData.TargetLinkURI)
+		}
+	}
+}
+
+func TestLaunch_OffHostTargetFallsBackToFrontend(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	km, _ := setupLaunchTest(t, handler)
+	// State seeded with an arbitrary target, bypassing login initiation
+	handler.GetStateStore().Store("state-evil", &StateData{
+		Nonce:         "nonce-123",
+		TargetLinkURI: "https://evil.example/phish",
+		ClientID:      "client-123",
+	})
+	idToken := signLaunchToken(t, km, &LTIClaims{
+		MessageType: "LtiResourceLinkRequest",
+		Version:     "1.3.0",
+	})
+
+	w := postLaunch(handler, idToken, "state-evil")
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != handler.frontendURL {
+		t.Errorf("expected fallback to %s, got %s", handler.frontendURL, location)
+	}
+}
@@ -0,0 +1,88 @@
+package lti
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultNonceTTL is how long a nonce is remembered when its id_token has no
+// expiry. Tokens with one are remembered until they expire, since the
+// validator rejects them after that anyway.
+const defaultNonceTTL = 1 * time.Hour
+
+// NonceCache remembers redeemed id_token nonces so each launches only once,
+// even if the OIDC state it was issued with is replayed
+type NonceCache interface {
+	// Redeem marks nonce as used until expiresAt, returning false if it
+	// already was
+	Redeem(nonce string, expiresAt time.Time) bool
+}
+
+// MemoryNonceCache remembers nonces in memory. Like StateStore it only
+// protects a single server instance; use DBNonceCache for multi-instance
+// deployments.
+type MemoryNonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time // Nonce to when it may be forgotten
+
+	stop     chan struct{} // Closed to end the cleanup goroutine
+	stopOnce sync.Once
+}
+
+// NewMemoryNonceCache creates an in-memory nonce cache. Call Close when it
+// is no longer used to stop its cleanup goroutine.
+func NewMemoryNonceCache() *MemoryNonceCache {
+	cache := &MemoryNonceCache{
+		nonces: make(map[string]time.Time),
+		stop:   make(chan struct{}),
+	}
+	// Start cleanup goroutine
+	go cache.cleanup()
+	return cache
+}
+
+// Redeem marks nonce as used until expiresAt, returning false if it already was
+func (c *MemoryNonceCache) Redeem(nonce string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until, ok := c.nonces[nonce]; ok && time.Now().Before(until) {
+		return false
+	}
+	c.nonces[nonce] = nonceExpiry(expiresAt)
+	return true
+}
+
+// Close stops the cleanup goroutine. Safe to call more than once.
+func (c *MemoryNonceCache) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// cleanup forgets nonces whose tokens have expired, until Close is called
+func (c *MemoryNonceCache) cleanup() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		now := time.Now()
+		for nonce, until := range c.nonces {
+			if now.After(until) {
+				delete(c.nonces, nonce)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// nonceExpiry returns when a nonce redeemed with a token expiring at
+// expiresAt may be forgotten
+func nonceExpiry(expiresAt time.Time) time.Time {
+	if expiresAt.IsZero() {
+		return time.Now().Add(defaultNonceTTL)
+	}
+	return expiresAt
+}
@@ -0,0 +1,70 @@
+package lti
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NonceRecord is a redeemed id_token nonce
+type NonceRecord struct {
+	Nonce     string    `gorm:"primaryKey;size:255"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for NonceRecord
+func (NonceRecord) TableName() string {
+	return "lti_nonces"
+}
+
+// DBNonceCache remembers nonces in the database so a token redeemed on one
+// server instance cannot be replayed on another
+type DBNonceCache struct {
+	db *gorm.DB
+}
+
+// NewDBNonceCache creates a database-backed nonce cache, migrating its table
+func NewDBNonceCache(db *gorm.DB) (*DBNonceCache, error) {
+	if err := db.AutoMigrate(&NonceRecord{}); err != nil {
+		return nil, err
+	}
+
+	cache := &DBNonceCache{db: db}
+	// Start cleanup goroutine
+	go cache.cleanup()
+	return cache, nil
+}
+
+// Redeem marks nonce as used until expiresAt, returning false if it already
+// was. The insert is the check, so racing instances cannot both redeem it.
+// Database errors also return false, failing the launch closed.
+func (c *DBNonceCache) Redeem(nonce string, expiresAt time.Time) bool {
+	// An expired record would otherwise block the insert until cleanup runs
+	c.db.Where("nonce = ? AND expires_at < ?", nonce, time.Now()).Delete(&NonceRecord{})
+
+	record := NonceRecord{Nonce: nonce, ExpiresAt: nonceExpiry(expiresAt)}
+	result := c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		slog.Warn("failed to record LTI nonce", "error", result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// cleanup removes nonces whose tokens have expired
+func (c *DBNonceCache) cleanup() {
+	ticker := time.NewTicker(1 * time.Minute)
+	for range ticker.C {
+		c.deleteExpired()
+	}
+}
+
+// deleteExpired removes nonces past their expiry
+func (c *DBNonceCache) deleteExpired() {
+	err := c.db.Where("expires_at < ?", time.Now()).Delete(&NonceRecord{}).Error
+	if err != nil {
+		slog.Warn("failed to clean up LTI nonces", "error", err)
+	}
+}
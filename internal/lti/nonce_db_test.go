@@ -0,0 +1,72 @@
+package lti
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/config"
+	"globe-expedition-journal/internal/database"
+)
+
+func setupDBNonceCache(t *testing.T) (*DBNonceCache, func()) {
+	os.Clearenv()
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("DATABASE_URL", ":memory:")
+
+	db, err := database.Connect(config.Load())
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	cache, err := NewDBNonceCache(db)
+	if err != nil {
+		t.Fatalf("failed to create nonce cache: %v", err)
+	}
+
+	return cache, func() {
+		database.Close()
+		os.Clearenv()
+	}
+}
+
+func TestNonceRecordTableName(t *testing.T) {
+	if (NonceRecord{}).TableName() != "lti_nonces" {
+		t.Errorf("expected table name 'lti_nonces', got '%s'", NonceRecord{}.TableName())
+	}
+}
+
+func TestDBNonceCache_RedeemOnce(t *testing.T) {
+	cache, cleanup := setupDBNonceCache(t)
+	defer cleanup()
+	expires := time.Now().Add(5 * time.Minute)
+
+	if !cache.Redeem("nonce-1", expires) {
+		t.Fatal("expected first redemption to succeed")
+	}
+	if cache.Redeem("nonce-1", expires) {
+		t.Error("expected a replayed nonce to be rejected")
+	}
+	if !cache.Redeem("nonce-2", expires) {
+		t.Error("expected a different nonce to be redeemed")
+	}
+}
+
+func TestDBNonceCache_ExpiredNonces(t *testing.T) {
+	cache, cleanup := setupDBNonceCache(t)
+	defer cleanup()
+
+	cache.Redeem("nonce-1", time.Now().Add(-time.Second))
+	cache.Redeem("nonce-2", time.Now().Add(-time.Second))
+	if !cache.Redeem("nonce-1", time.Now().Add(time.Minute)) {
+		t.Error("expected an expired nonce to be redeemable again")
+	}
+
+	cache.deleteExpired()
+
+	var count int64
+	cache.db.Model(&NonceRecord{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected only the live nonce to remain, got %d", count)
+	}
+}
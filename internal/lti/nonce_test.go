@@ -0,0 +1,54 @@
+package lti
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceCache_RedeemOnce(t *testing.T) {
+	cache := NewMemoryNonceCache()
+	expires := time.Now().Add(5 * time.Minute)
+
+	if !cache.Redeem("nonce-1", expires) {
+		t.Fatal("expected first redemption to succeed")
+	}
+	if cache.Redeem("nonce-1", expires) {
+		t.Error("expected a replayed nonce to be rejected")
+	}
+	if !cache.Redeem("nonce-2", expires) {
+		t.Error("expected a different nonce to be redeemed")
+	}
+}
+
+func TestMemoryNonceCache_ForgetsExpiredNonces(t *testing.T) {
+	cache := NewMemoryNonceCache()
+
+	cache.Redeem("nonce-1", time.Now().Add(-time.Second))
+	if !cache.Redeem("nonce-1", time.Now().Add(time.Minute)) {
+		t.Error("expected an expired nonce to be forgotten")
+	}
+}
+
+func TestNonceExpiry_DefaultsWithoutTokenExpiry(t *testing.T) {
+	got := nonceExpiry(time.Time{})
+	if d := time.Until(got); d < defaultNonceTTL-time.Minute || d > defaultNonceTTL {
+		t.Errorf("expected the default TTL, got %v", d)
+	}
+
+	expires := time.Now().Add(5 * time.Minute)
+	if !nonceExpiry(expires).Equal(expires) {
+		t.Error("expected the token expiry to be used")
+	}
+}
+
+func TestMemoryNonceCache_Close(t *testing.T) {
+	cache := NewMemoryNonceCache()
+	cache.Close()
+	cache.Close() // Closing twice must not panic
+
+	select {
+	case <-cache.stop:
+	default:
+		t.Error("expected Close to stop the cleanup goroutine")
+	}
+}
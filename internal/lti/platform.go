@@ -1,6 +1,7 @@
 package lti
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -36,9 +37,25 @@ func NewPlatformRepository(db *gorm.DB) *PlatformRepository {
 	return &PlatformRepository{db: db}
 }
 
-// Create adds a new platform registration
+// Create adds a new platform registration. A soft-deleted platform still
+// holds its issuer in the unique index, so re-registering that issuer
+// restores the deleted row with the new settings instead of inserting one.
 func (r *PlatformRepository) Create(platform *Platform) error {
-	return r.db.Create(platform).Error
+	var deleted Platform
+	err := r.db.Unscoped().Where("issuer = ? AND deleted_at IS NOT NULL", platform.Issuer).First(&deleted).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.db.Create(platform).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	platform.ID = deleted.ID
+	platform.DeletedAt = gorm.DeletedAt{}
+	if platform.CreatedAt.IsZero() {
+		platform.CreatedAt = time.Now()
+	}
+	return r.db.Unscoped().Save(platform).Error
 }
 
 // FindByIssuer finds a platform by its issuer URL
@@ -0,0 +1,150 @@
+package lti
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// platformCheckTimeout bounds each endpoint probe in a connectivity test
+const platformCheckTimeout = 10 * time.Second
+
+// platformCheckClient is used to probe platform endpoints
+var platformCheckClient = &http.Client{Timeout: platformCheckTimeout}
+
+// EndpointCheck reports the reachability of one platform endpoint
+type EndpointCheck struct {
+	Name       string `json:"name"` // "jwks", "auth" or "token"
+	URL        string `json:"url"`
+	OK         bool   `json:"ok"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMS  int64  `json:"latencyMs"`
+	Keys       int    `json:"keys,omitempty"` // Usable keys in the JWKS, jwks only
+	Error      string `json:"error,omitempty"`
+}
+
+// PlatformCheckReport is the result of a platform connectivity test
+type PlatformCheckReport struct {
+	PlatformID uint            `json:"platformId"`
+	Issuer     string          `json:"issuer"`
+	OK         bool            `json:"ok"` // True if every endpoint passed
+	Endpoints  []EndpointCheck `json:"endpoints"`
+}
+
+// TestPlatform checks that a registered platform's endpoints are reachable
+// and that its JWKS contains usable keys, without performing a launch
+// POST /api/v1/admin/platforms/:id/test
+func (h *Handler) TestPlatform(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid platform ID"})
+		return
+	}
+
+	platform, err := h.platformRepo.FindByID(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "platform not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch platform"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.checkPlatform(c.Request.Context(), platform))
+}
+
+// checkPlatform probes each configured endpoint of a platform
+func (h *Handler) checkPlatform(ctx context.Context, platform *Platform) PlatformCheckReport {
+	report := PlatformCheckReport{
+		PlatformID: platform.ID,
+		Issuer:     platform.Issuer,
+		OK:         true,
+	}
+
+	jwks := probeEndpoint(ctx, "jwks", platform.JWKSEndpoint, true)
+	if jwks.OK {
+		h.checkJWKSKeys(ctx, &jwks)
+	}
+	report.Endpoints = append(report.Endpoints, jwks)
+
+	// The auth endpoint expects OIDC parameters, so any HTTP response means it is reachable
+	report.Endpoints = append(report.Endpoints, probeEndpoint(ctx, "auth", platform.AuthEndpoint, false))
+
+	if platform.TokenEndpoint != "" {
+		report.Endpoints = append(report.Endpoints, probeEndpoint(ctx, "token", platform.TokenEndpoint, false))
+	}
+
+	for _, endpoint := range report.Endpoints {
+		if !endpoint.OK {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// checkJWKSKeys loads the JWKS the same way launches do and counts its keys
+func (h *Handler) checkJWKSKeys(ctx context.Context, check *EndpointCheck) {
+	kf, err := h.jwtValidator.fetchKeyfunc(ctx, check.URL)
+	if err != nil {
+		check.OK = false
+		check.Error = fmt.Sprintf("failed to load key set: %v", err)
+		return
+	}
+
+	keys, err := kf.Storage().KeyReadAll(ctx)
+	if err != nil {
+		check.OK = false
+		check.Error = fmt.Sprintf("failed to read key set: %v", err)
+		return
+	}
+
+	check.Keys = len(keys)
+	if check.Keys == 0 {
+		check.OK = false
+		check.Error = "key set contains no usable keys"
+	}
+}
+
+// probeEndpoint issues a GET to url and records its status and latency.
+// With requireOK, only a 200 response counts as passing.
+func probeEndpoint(ctx context.Context, name, url string, requireOK bool) EndpointCheck {
+	check := EndpointCheck{Name: name, URL: url}
+	if url == "" {
+		check.Error = "endpoint not configured"
+		return check
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid URL: %v", err)
+		return check
+	}
+
+	start := time.Now()
+	resp, err := platformCheckClient.Do(req)
+	check.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp.Body.Close()
+
+	check.StatusCode = resp.StatusCode
+	if requireOK && resp.StatusCode != http.StatusOK {
+		check.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return check
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		check.Error = fmt.Sprintf("server error %d", resp.StatusCode)
+		return check
+	}
+
+	check.OK = true
+	return check
+}
@@ -0,0 +1,192 @@
+package lti
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newJWKSServer serves a JWKS with one freshly generated key
+func newJWKSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	km, err := NewKeyManager()
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+	jwksJSON, err := km.GetJWKSJSON()
+	if err != nil {
+		t.Fatalf("failed to build JWKS: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(jwksJSON))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newStatusServer responds to every request with status
+func newStatusServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postPlatformTest(handler *Handler, id string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/api/v1/admin/platforms/:id/test", handler.TestPlatform)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/admin/platforms/"+id+"/test", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func endpointByName(report PlatformCheckReport, name string) (EndpointCheck, bool) {
+	for _, endpoint := range report.Endpoints {
+		if endpoint.Name == name {
+			return endpoint, true
+		}
+	}
+	return EndpointCheck{}, false
+}
+
+func TestTestPlatform_Healthy(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	jwks := newJWKSServer(t)
+	// Auth endpoints reject bare GETs; that still proves they are reachable
+	auth := newStatusServer(t, http.StatusBadRequest, "missing parameters")
+	token := newStatusServer(t, http.StatusMethodNotAllowed, "")
+
+	platform := &Platform{
+		Issuer:        "https://canvas.example.com",
+		ClientID:      "client-123",
+		JWKSEndpoint:  jwks.URL,
+		AuthEndpoint:  auth.URL,
+		TokenEndpoint: token.URL,
+	}
+	handler.GetPlatformRepo().Create(platform)
+
+	w := postPlatformTest(handler, fmt.Sprint(platform.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var report PlatformCheckReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+
+	if !report.OK {
+		t.Errorf("expected platform to pass, got %+v", report)
+	}
+	if len(report.Endpoints) != 3 {
+		t.Fatalf("expected 3 endpoint checks, got %d", len(report.Endpoints))
+	}
+
+	jwksCheck, _ := endpointByName(report, "jwks")
+	if jwksCheck.Keys != 1 {
+		t.Errorf("expected 1 key, got %d", jwksCheck.Keys)
+	}
+	authCheck, _ := endpointByName(report, "auth")
+	if !authCheck.OK || authCheck.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected auth endpoint reachable with status 400, got %+v", authCheck)
+	}
+}
+
+func TestTestPlatform_Failures(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachableURL := unreachable.URL
+	unreachable.Close()
+
+	tests := []struct {
+		name      string
+		jwksURL   func(t *testing.T) string
+		authURL   func(t *testing.T) string
+		wantJWKS  bool
+		wantAuth  bool
+		wantError string
+	}{
+		{
+			name:     "JWKS server error",
+			jwksURL:  func(t *testing.T) string { return newStatusServer(t, http.StatusInternalServerError, "").URL },
+			authURL:  func(t *testing.T) string { return newStatusServer(t, http.StatusOK, "").URL },
+			wantAuth: true,
+		},
+		{
+			name:     "JWKS without keys",
+			jwksURL:  func(t *testing.T) string { return newStatusServer(t, http.StatusOK, `{"keys":[]}`).URL },
+			authURL:  func(t *testing.T) string { return newStatusServer(t, http.StatusOK, "").URL },
+			wantAuth: true,
+		},
+		{
+			name:     "auth unreachable",
+			jwksURL:  func(t *testing.T) string { return newJWKSServer(t).URL },
+			authURL:  func(t *testing.T) string { return unreachableURL },
+			wantJWKS: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, cleanup := setupHandlerTestDB(t)
+			defer cleanup()
+
+			platform := &Platform{
+				Issuer:       "https://canvas.example.com",
+				ClientID:     "client-123",
+				JWKSEndpoint: tt.jwksURL(t),
+				AuthEndpoint: tt.authURL(t),
+			}
+			handler.GetPlatformRepo().Create(platform)
+
+			w := postPlatformTest(handler, fmt.Sprint(platform.ID))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var report PlatformCheckReport
+			json.Unmarshal(w.Body.Bytes(), &report)
+
+			if report.OK {
+				t.Error("expected platform check to fail")
+			}
+			jwksCheck, _ := endpointByName(report, "jwks")
+			if jwksCheck.OK != tt.wantJWKS {
+				t.Errorf("expected jwks ok=%v, got %+v", tt.wantJWKS, jwksCheck)
+			}
+			authCheck, _ := endpointByName(report, "auth")
+			if authCheck.OK != tt.wantAuth {
+				t.Errorf("expected auth ok=%v, got %+v", tt.wantAuth, authCheck)
+			}
+			if _, ok := endpointByName(report, "token"); ok {
+				t.Error("expected no token check when no token endpoint is configured")
+			}
+		})
+	}
+}
+
+func TestTestPlatform_NotFound(t *testing.T) {
+	handler, cleanup := setupHandlerTestDB(t)
+	defer cleanup()
+
+	if w := postPlatformTest(handler, "999"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	if w := postPlatformTest(handler, "abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
@@ -308,3 +308,92 @@ func TestPlatformIssuerUnique(t *testing.T) {
 		t.Error("expected error for duplicate issuer")
 	}
 }
+
+func TestPlatformRepository_Create_ReregistersDeletedIssuer(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewPlatformRepository(db)
+
+	original := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-old",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+		Name:         "Old Canvas",
+	}
+	if err := repo.Create(original); err != nil {
+		t.Fatalf("failed to create platform: %v", err)
+	}
+	if err := repo.Delete(original.ID); err != nil {
+		t.Fatalf("failed to delete platform: %v", err)
+	}
+
+	again := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-new",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	}
+	if err := repo.Create(again); err != nil {
+		t.Fatalf("expected re-registering a deleted issuer to succeed, got %v", err)
+	}
+
+	found, err := repo.FindByIssuer("https://canvas.example.com")
+	if err != nil {
+		t.Fatalf("expected the re-registered platform to be found, got %v", err)
+	}
+	if found.ClientID != "client-new" || found.Name != "" {
+		t.Errorf("expected the new registration's settings, got %+v", found)
+	}
+	if found.CreatedAt.IsZero() {
+		t.Error("expected CreatedAt to be set")
+	}
+
+	var rows int64
+	db.Unscoped().Model(&Platform{}).Count(&rows)
+	if rows != 1 {
+		t.Errorf("expected the deleted row to be reused, got %d rows", rows)
+	}
+
+	// A live platform still blocks a duplicate issuer
+	if err := repo.Create(&Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-dup",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	}); err == nil {
+		t.Error("expected error for duplicate live issuer")
+	}
+}
+
+func TestPlatformRepository_Upsert_DeletedIssuer(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewPlatformRepository(db)
+
+	platform := &Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-123",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	}
+	repo.Create(platform)
+	repo.Delete(platform.ID)
+
+	err := repo.Upsert(&Platform{
+		Issuer:       "https://canvas.example.com",
+		ClientID:     "client-456",
+		JWKSEndpoint: "https://canvas.example.com/.well-known/jwks",
+		AuthEndpoint: "https://canvas.example.com/api/lti/authorize",
+	})
+	if err != nil {
+		t.Fatalf("expected upsert of a deleted issuer to succeed, got %v", err)
+	}
+
+	found, err := repo.FindByIssuer("https://canvas.example.com")
+	if err != nil || found.ClientID != "client-456" {
+		t.Errorf("expected the platform to be restored with client-456, got %+v (%v)", found, err)
+	}
+}
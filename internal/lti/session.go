@@ -1,7 +1,9 @@
 package lti
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,28 +17,123 @@ type SessionClaims struct {
 	CanvasID string `json:"canvas_id"`
 	CourseID string `json:"course_id,omitempty"`
 	Role     string `json:"role,omitempty"`
+	Locale   string `json:"locale,omitempty"` // Default locale from the launch, e.g. "fr-FR"
 }
 
+// sessionSigningMethod is the only algorithm session tokens are signed with
+// and the only one ValidateToken accepts
+var sessionSigningMethod = jwt.SigningMethodHS256
+
+// DefaultSessionLeeway is how far past expiry a session token is still
+// accepted, to tolerate clients whose clocks run slightly ahead
+const DefaultSessionLeeway = 30 * time.Second
+
+// ErrSecretTooShort is returned for a session secret below the required length
+var ErrSecretTooShort = errors.New("session secret is too short")
+
 // SessionManager handles session creation and validation
 type SessionManager struct {
-	secret []byte
-	maxAge time.Duration
+	secret   []byte
+	previous [][]byte // Retired secrets still accepted when validating
+	maxAge   time.Duration
+	leeway   time.Duration
+
+	mu      sync.RWMutex
+	revoked map[uint]time.Time // User ID -> time all earlier tokens were revoked
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager(secret string, maxAgeSeconds int) *SessionManager {
+	m, _ := NewSessionManagerWithMinLength(secret, maxAgeSeconds, 0)
+	return m
+}
+
+// NewSessionManagerWithMinLength creates a session manager, refusing secrets
+// shorter than minSecretLength bytes since they make tokens forgeable
+func NewSessionManagerWithMinLength(secret string, maxAgeSeconds, minSecretLength int) (*SessionManager, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("%w: %d bytes, need at least %d", ErrSecretTooShort, len(secret), minSecretLength)
+	}
 	return &SessionManager{
-		secret: []byte(secret),
-		maxAge: time.Duration(maxAgeSeconds) * time.Second,
+		secret:  []byte(secret),
+		maxAge:  time.Duration(maxAgeSeconds) * time.Second,
+		leeway:  DefaultSessionLeeway,
+		revoked: make(map[uint]time.Time),
+	}, nil
+}
+
+// SetLeeway sets the clock skew tolerated when checking a token's expiry
+// and not-before times. Negative values are treated as zero.
+func (m *SessionManager) SetLeeway(leeway time.Duration) {
+	if leeway < 0 {
+		leeway = 0
+	}
+	m.leeway = leeway
+}
+
+// SetPreviousSecrets sets retired secrets that tokens are still validated
+// against, so sessions signed before a secret rotation keep working until
+// they expire. New tokens are always signed with the primary secret.
+func (m *SessionManager) SetPreviousSecrets(secrets []string) {
+	m.previous = nil
+	for _, secret := range secrets {
+		if secret != "" {
+			m.previous = append(m.previous, []byte(secret))
+		}
+	}
+}
+
+// RevokeUser invalidates every token issued to a user up to now.
+// Revocations are held in memory until the tokens they cover have expired.
+func (m *SessionManager) RevokeUser(userID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, at := range m.revoked {
+		if now.Sub(at) > m.maxAge+m.leeway {
+			delete(m.revoked, id)
+		}
+	}
+	m.revoked[userID] = now
+}
+
+// isRevoked reports whether the claims were issued before their user was revoked
+func (m *SessionManager) isRevoked(claims *SessionClaims) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revokedAt, ok := m.revoked[claims.UserID]
+	if !ok {
+		return false
 	}
+	// IssuedAt has second precision, so a token from the same second is revoked too
+	return claims.IssuedAt == nil || !claims.IssuedAt.Time.After(revokedAt.Truncate(time.Second))
 }
 
 // CreateToken creates a new session token for a user
 func (m *SessionManager) CreateToken(userID uint, canvasID string, courseID string, role string) (string, error) {
+	return m.CreateTokenWithLocale(userID, canvasID, courseID, role, "")
+}
+
+// CreateTokenWithLocale creates a new session token that also carries the
+// user's default locale
+func (m *SessionManager) CreateTokenWithLocale(userID uint, canvasID string, courseID string, role string, locale string) (string, error) {
+	return m.CreateTokenWithTTL(userID, canvasID, courseID, role, locale, m.maxAge)
+}
+
+// CreateTokenWithTTL creates a session token like CreateTokenWithLocale that
+// expires after ttl instead of the manager's max age. Non-positive or longer
+// TTLs fall back to the max age, so a token never outlives a normal session.
+func (m *SessionManager) CreateTokenWithTTL(userID uint, canvasID string, courseID string, role string, locale string, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > m.maxAge {
+		ttl = m.maxAge
+	}
+
 	now := time.Now()
 	claims := SessionClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.maxAge)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
@@ -44,21 +141,23 @@ func (m *SessionManager) CreateToken(userID uint, canvasID string, courseID stri
 		CanvasID: canvasID,
 		CourseID: courseID,
 		Role:     role,
+		Locale:   locale,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(sessionSigningMethod, claims)
 	return token.SignedString(m.secret)
 }
 
-// ValidateToken validates a session token and returns the claims
+// ValidateToken validates a session token and returns the claims. The
+// token may be signed with the primary secret or any previous secret.
 func (m *SessionManager) ValidateToken(tokenString string) (*SessionClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &SessionClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	token, err := m.parse(tokenString, m.secret)
+	for _, secret := range m.previous {
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
 		}
-		return m.secret, nil
-	})
-
+		token, err = m.parse(tokenString, secret)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -68,5 +167,19 @@ func (m *SessionManager) ValidateToken(tokenString string) (*SessionClaims, erro
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	if m.isRevoked(claims) {
+		return nil, fmt.Errorf("session revoked")
+	}
+
 	return claims, nil
 }
+
+// parse parses and verifies a session token signed with secret
+func (m *SessionManager) parse(tokenString string, secret []byte) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &SessionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{sessionSigningMethod.Alg()}), jwt.WithLeeway(m.leeway))
+}
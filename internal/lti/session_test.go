@@ -1,8 +1,11 @@
 package lti
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestNewSessionManager(t *testing.T) {
@@ -15,6 +18,20 @@ func TestNewSessionManager(t *testing.T) {
 	}
 }
 
+func TestNewSessionManagerWithMinLength(t *testing.T) {
+	if _, err := NewSessionManagerWithMinLength("short-secret", 3600, 32); !errors.Is(err, ErrSecretTooShort) {
+		t.Errorf("expected ErrSecretTooShort, got %v", err)
+	}
+
+	sm, err := NewSessionManagerWithMinLength("a-secret-of-at-least-thirty-two-bytes", 3600, 32)
+	if err != nil {
+		t.Fatalf("expected a long enough secret to be accepted, got %v", err)
+	}
+	if sm.maxAge != time.Hour {
+		t.Errorf("expected maxAge to be 1 hour, got %v", sm.maxAge)
+	}
+}
+
 func TestSessionManager_CreateAndValidateToken(t *testing.T) {
 	sm := NewSessionManager("test-secret-key-12345", 3600)
 
@@ -71,9 +88,103 @@ func TestSessionManager_ValidateToken_WrongSecret(t *testing.T) {
 	}
 }
 
+func TestSessionManager_PreviousSecrets(t *testing.T) {
+	old := NewSessionManager("old-secret", 3600)
+	oldToken, err := old.CreateToken(1, "user", "course", "learner")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	sm := NewSessionManager("new-secret", 3600)
+	sm.SetPreviousSecrets([]string{"older-secret", "old-secret"})
+
+	claims, err := sm.ValidateToken(oldToken)
+	if err != nil {
+		t.Fatalf("expected token signed with a previous secret to validate: %v", err)
+	}
+	if claims.UserID != 1 {
+		t.Errorf("expected user ID 1, got %d", claims.UserID)
+	}
+
+	// New tokens are signed with the primary secret only
+	newToken, err := sm.CreateToken(2, "user", "course", "learner")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, err := NewSessionManager("new-secret", 3600).ValidateToken(newToken); err != nil {
+		t.Errorf("expected new token to be signed with the primary secret: %v", err)
+	}
+	if _, err := old.ValidateToken(newToken); err == nil {
+		t.Error("expected new token not to be signed with a previous secret")
+	}
+
+	// Secrets that were never configured are still rejected
+	stranger, _ := NewSessionManager("other-secret", 3600).CreateToken(3, "user", "course", "learner")
+	if _, err := sm.ValidateToken(stranger); err == nil {
+		t.Error("expected token signed with an unknown secret to be rejected")
+	}
+}
+
+func TestSessionManager_ValidateToken_UnexpectedAlgorithm(t *testing.T) {
+	sm := NewSessionManager("test-secret", 3600)
+
+	// Same secret and claims, but signed with an algorithm other than HS256
+	claims := SessionClaims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS384, jwt.SigningMethodHS512} {
+		token, err := jwt.NewWithClaims(method, claims).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		if _, err := sm.ValidateToken(token); err == nil {
+			t.Errorf("expected %s token to be rejected", method.Alg())
+		}
+	}
+}
+
+// expiredToken returns a token signed with "test-secret" that expired ago
+func expiredToken(t *testing.T, ago time.Duration) string {
+	t.Helper()
+	now := time.Now()
+	claims := SessionClaims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(-ago)),
+			IssuedAt:  jwt.NewNumericDate(now.Add(-ago - time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestSessionManager_ValidateToken_Leeway(t *testing.T) {
+	sm := NewSessionManager("test-secret", 3600)
+	sm.SetLeeway(60 * time.Second)
+
+	if _, err := sm.ValidateToken(expiredToken(t, 30*time.Second)); err != nil {
+		t.Errorf("expected token expired by 30s to validate within 60s leeway, got %v", err)
+	}
+	if _, err := sm.ValidateToken(expiredToken(t, 120*time.Second)); err == nil {
+		t.Error("expected token expired by 120s to be rejected")
+	}
+
+	sm.SetLeeway(0)
+	if _, err := sm.ValidateToken(expiredToken(t, 30*time.Second)); err == nil {
+		t.Error("expected expired token to be rejected without leeway")
+	}
+}
+
 func TestSessionManager_ValidateToken_ExpiredToken(t *testing.T) {
 	// Create session manager with 1 second expiry
 	sm := NewSessionManager("test-secret", 1)
+	sm.SetLeeway(0)
 
 	token, err := sm.CreateToken(1, "user", "course", "learner")
 	if err != nil {
@@ -109,3 +220,64 @@ func TestSessionManager_CreateToken_EmptyOptionalFields(t *testing.T) {
 		t.Errorf("expected empty Role, got '%s'", claims.Role)
 	}
 }
+
+func TestSessionManager_RevokeUser(t *testing.T) {
+	sm := NewSessionManager("test-secret", 3600)
+
+	revokedToken, _ := sm.CreateToken(1, "canvas-1", "", "learner")
+	otherToken, _ := sm.CreateToken(2, "canvas-2", "", "learner")
+
+	sm.RevokeUser(1)
+
+	if _, err := sm.ValidateToken(revokedToken); err == nil {
+		t.Error("expected revoked user's token to be rejected")
+	}
+	if _, err := sm.ValidateToken(otherToken); err != nil {
+		t.Errorf("expected other user's token to remain valid, got %v", err)
+	}
+}
+
+func TestSessionManager_CreateTokenWithLocale(t *testing.T) {
+	sm := NewSessionManager("test-secret", 3600)
+
+	token, err := sm.CreateTokenWithLocale(1, "canvas-1", "course-1", "learner", "fr-FR")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	claims, err := sm.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.Locale != "fr-FR" {
+		t.Errorf("expected locale 'fr-FR', got '%s'", claims.Locale)
+	}
+}
+
+func TestSessionManager_CreateTokenWithTTL(t *testing.T) {
+	sm := NewSessionManager("test-secret", 3600)
+
+	expiry := func(ttl time.Duration) time.Duration {
+		t.Helper()
+		token, err := sm.CreateTokenWithTTL(1, "canvas-1", "course-1", "learner", "", ttl)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		claims, err := sm.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("failed to validate token: %v", err)
+		}
+		return claims.ExpiresAt.Sub(claims.IssuedAt.Time)
+	}
+
+	if got := expiry(10 * time.Minute); got != 10*time.Minute {
+		t.Errorf("expected a 10 minute token, got %v", got)
+	}
+	// TTLs cannot extend a token past the session max age
+	if got := expiry(48 * time.Hour); got != time.Hour {
+		t.Errorf("expected a longer TTL to be capped at the max age, got %v", got)
+	}
+	if got := expiry(0); got != time.Hour {
+		t.Errorf("expected a zero TTL to use the max age, got %v", got)
+	}
+}
@@ -7,7 +7,22 @@ import (
 	"time"
 )
 
-// StateStore manages OIDC state and nonce for LTI launches
+// stateTTL is how long an OIDC state remains valid
+const stateTTL = 10 * time.Minute
+
+// StateStorage stores OIDC state between login initiation and launch
+type StateStorage interface {
+	// Store saves state data
+	Store(state string, data *StateData)
+	// Get retrieves and removes state data (one-time use)
+	Get(state string) (*StateData, bool)
+	// Peek retrieves state data without removing it
+	Peek(state string) (*StateData, bool)
+}
+
+// StateStore manages OIDC state and nonce for LTI launches in memory.
+// It only works when login and launch reach the same server instance;
+// use DBStateStore for multi-instance deployments.
 type StateStore struct {
 	mu     sync.RWMutex
 	states map[string]*StateData
@@ -83,7 +98,7 @@ func (s *StateStore) cleanup() {
 		s.mu.Lock()
 		now := time.Now()
 		for state, data := range s.states {
-			if now.Sub(data.CreatedAt) > 10*time.Minute {
+			if now.Sub(data.CreatedAt) > stateTTL {
 				delete(s.states, state)
 			}
 		}
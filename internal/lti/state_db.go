@@ -0,0 +1,104 @@
+package lti
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StateRecord is a stored OIDC state row
+type StateRecord struct {
+	State         string    `gorm:"primaryKey;size:255"`
+	Nonce         string    `gorm:"size:255;not null"`
+	TargetLinkURI string    `gorm:"size:1024"`
+	ClientID      string    `gorm:"size:255"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for StateRecord
+func (StateRecord) TableName() string {
+	return "lti_states"
+}
+
+// DBStateStore manages OIDC state in the database so that any server
+// instance can complete a launch started on another
+type DBStateStore struct {
+	db *gorm.DB
+}
+
+// NewDBStateStore creates a database-backed state store, migrating its table
+func NewDBStateStore(db *gorm.DB) (*DBStateStore, error) {
+	if err := db.AutoMigrate(&StateRecord{}); err != nil {
+		return nil, err
+	}
+
+	store := &DBStateStore{db: db}
+	// Start cleanup goroutine
+	go store.cleanup()
+	return store, nil
+}
+
+// Store saves state data. Failures are logged; the launch will then be
+// rejected as having an unknown state.
+func (s *DBStateStore) Store(state string, data *StateData) {
+	data.CreatedAt = time.Now()
+	record := StateRecord{
+		State:         state,
+		Nonce:         data.Nonce,
+		TargetLinkURI: data.TargetLinkURI,
+		ClientID:      data.ClientID,
+		CreatedAt:     data.CreatedAt,
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		slog.Warn("failed to store LTI state", "error", err)
+	}
+}
+
+// Get retrieves and removes state data (one-time use). When instances race
+// for the same state only the one whose delete succeeds gets the data.
+func (s *DBStateStore) Get(state string) (*StateData, bool) {
+	data, ok := s.Peek(state)
+	if !ok {
+		return nil, false
+	}
+
+	result := s.db.Where("state = ?", state).Delete(&StateRecord{})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return nil, false
+	}
+	return data, true
+}
+
+// Peek retrieves state data without removing it
+func (s *DBStateStore) Peek(state string) (*StateData, bool) {
+	var record StateRecord
+	err := s.db.Where("state = ? AND created_at > ?", state, time.Now().Add(-stateTTL)).
+		First(&record).Error
+	if err != nil {
+		return nil, false
+	}
+
+	return &StateData{
+		Nonce:         record.Nonce,
+		TargetLinkURI: record.TargetLinkURI,
+		ClientID:      record.ClientID,
+		CreatedAt:     record.CreatedAt,
+	}, true
+}
+
+// cleanup removes expired states (older than 10 minutes)
+func (s *DBStateStore) cleanup() {
+	ticker := time.NewTicker(1 * time.Minute)
+	for range ticker.C {
+		s.deleteExpired()
+	}
+}
+
+// deleteExpired removes states older than stateTTL
+func (s *DBStateStore) deleteExpired() {
+	err := s.db.Where("created_at < ?", time.Now().Add(-stateTTL)).Delete(&StateRecord{}).Error
+	if err != nil {
+		slog.Warn("failed to clean up LTI states", "error", err)
+	}
+}
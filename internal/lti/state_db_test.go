@@ -0,0 +1,120 @@
+package lti
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"globe-expedition-journal/internal/config"
+	"globe-expedition-journal/internal/database"
+)
+
+func setupDBStateStore(t *testing.T) (*DBStateStore, func()) {
+	os.Clearenv()
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("DATABASE_URL", ":memory:")
+
+	db, err := database.Connect(config.Load())
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	store, err := NewDBStateStore(db)
+	if err != nil {
+		t.Fatalf("failed to create state store: %v", err)
+	}
+
+	return store, func() {
+		database.Close()
+		os.Clearenv()
+	}
+}
+
+func TestStateRecordTableName(t *testing.T) {
+	if (StateRecord{}).TableName() != "lti_states" {
+		t.Errorf("expected table name 'lti_states', got '%s'", StateRecord{}.TableName())
+	}
+}
+
+func TestDBStateStore_StoreAndGet(t *testing.T) {
+	store, cleanup := setupDBStateStore(t)
+	defer cleanup()
+
+	store.Store("state-1", &StateData{Nonce: "nonce-1", TargetLinkURI: "https://app.example.com/launch", ClientID: "client-1"})
+
+	data, ok := store.Get("state-1")
+	if !ok {
+		t.Fatal("expected state to be found")
+	}
+	if data.Nonce != "nonce-1" || data.TargetLinkURI != "https://app.example.com/launch" || data.ClientID != "client-1" {
+		t.Errorf("unexpected state data: %+v", data)
+	}
+
+	// One-time use: the row is gone
+	var count int64
+	store.db.Model(&StateRecord{}).Where("state = ?", "state-1").Count(&count)
+	if count != 0 {
+		t.Errorf("expected state row to be deleted, found %d", count)
+	}
+	if _, ok := store.Get("state-1"); ok {
+		t.Error("expected second Get to fail")
+	}
+}
+
+func TestDBStateStore_Peek(t *testing.T) {
+	store, cleanup := setupDBStateStore(t)
+	defer cleanup()
+
+	store.Store("state-1", &StateData{Nonce: "nonce-1"})
+
+	if _, ok := store.Peek("state-1"); !ok {
+		t.Fatal("expected state to be found")
+	}
+	if _, ok := store.Peek("state-1"); !ok {
+		t.Error("expected Peek not to remove the state")
+	}
+}
+
+func TestDBStateStore_GetNotFound(t *testing.T) {
+	store, cleanup := setupDBStateStore(t)
+	defer cleanup()
+
+	if _, ok := store.Get("missing"); ok {
+		t.Error("expected unknown state not to be found")
+	}
+}
+
+func TestDBStateStore_Expired(t *testing.T) {
+	store, cleanup := setupDBStateStore(t)
+	defer cleanup()
+
+	store.db.Create(&StateRecord{State: "old", Nonce: "n", CreatedAt: time.Now().Add(-stateTTL - time.Minute)})
+	store.Store("fresh", &StateData{Nonce: "n"})
+
+	if _, ok := store.Get("old"); ok {
+		t.Error("expected expired state to be rejected")
+	}
+
+	store.deleteExpired()
+
+	var count int64
+	store.db.Model(&StateRecord{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected only the fresh state to remain, got %d rows", count)
+	}
+}
+
+func TestNewHandlerWithConfig_DatabaseStateStore(t *testing.T) {
+	store, cleanup := setupDBStateStore(t)
+	defer cleanup()
+
+	handler := NewHandlerWithConfig(store.db, HandlerConfig{StateStore: "database"})
+	if _, ok := handler.GetStateStore().(*DBStateStore); !ok {
+		t.Errorf("expected database state store, got %T", handler.GetStateStore())
+	}
+
+	handler = NewHandlerWithConfig(store.db, HandlerConfig{})
+	if _, ok := handler.GetStateStore().(*StateStore); !ok {
+		t.Errorf("expected memory state store by default, got %T", handler.GetStateStore())
+	}
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecipient is returned when a message's To is not an email address
+var ErrInvalidRecipient = errors.New("invalid recipient address")
+
+// Message is a plain text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NopSender discards every message. It is used when no SMTP server is
+// configured, so tests and development never send mail.
+type NopSender struct{}
+
+// Send discards msg
+func (NopSender) Send(Message) error {
+	return nil
+}
+
+// Config holds SMTP delivery configuration
+type Config struct {
+	Host     string        // SMTP server; mail is disabled if empty
+	Port     int           // SMTP port, usually 587 for STARTTLS
+	Username string        // Authenticates with PLAIN auth when set
+	Password string        // Password for Username
+	From     string        // Sender address
+	Timeout  time.Duration // Limit on connecting and sending one message
+}
+
+// DefaultConfig returns default delivery settings for host
+func DefaultConfig(host string) Config {
+	return Config{
+		Host:    host,
+		Port:    587,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// NewSender returns an SMTP sender for cfg, or a NopSender if no host is set
+func NewSender(cfg Config) Sender {
+	if cfg.Host == "" {
+		return NopSender{}
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+// SMTPSender delivers mail through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS
+type SMTPSender struct {
+	cfg Config
+}
+
+// Send delivers msg, returning once the server has accepted it
+func (s *SMTPSender) Send(msg Message) error {
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return ErrInvalidRecipient
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port)), s.cfg.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if s.cfg.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMessage(s.cfg.From, to.Address, msg, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage renders msg with its headers. The subject is encoded so it
+// cannot inject headers, and line endings are normalized to CRLF.
+func buildMessage(from, to string, msg Message, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
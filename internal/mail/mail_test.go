@@ -0,0 +1,153 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewSender_NopWithoutHost(t *testing.T) {
+	sender := NewSender(DefaultConfig(""))
+	if _, ok := sender.(NopSender); !ok {
+		t.Fatalf("expected NopSender without a host, got %T", sender)
+	}
+	if err := sender.Send(Message{To: "ada@example.edu", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Errorf("expected NopSender to succeed, got %v", err)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	msg := string(buildMessage("journal@example.edu", "ada@example.edu", Message{
+		Subject: "New comment\r\nBcc: victim@example.com",
+		Body:    "Line one\nLine two",
+	}, date))
+
+	header, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("expected a blank line after the headers, got %q", msg)
+	}
+	if strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("subject injected a header: %q", header)
+	}
+	for _, want := range []string{"From: journal@example.edu", "To: ada@example.edu", "Date: Sat, 01 Jun 2024 12:00:00 +0000"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("expected header %q in %q", want, header)
+		}
+	}
+	if body != "Line one\r\nLine two" {
+		t.Errorf("expected CRLF line endings, got %q", body)
+	}
+}
+
+// fakeSMTPServer accepts one SMTP session and records the message data
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	rcpt     string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpt = strings.TrimSpace(line[len("RCPT TO:"):])
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.listener.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	cfg := DefaultConfig(host)
+	cfg.Port = port
+	cfg.From = "journal@example.edu"
+	cfg.Timeout = 5 * time.Second
+
+	sender := NewSender(cfg)
+	err := sender.Send(Message{To: "Ada <ada@example.edu>", Subject: "New comment", Body: "Great photo!"})
+	if err != nil {
+		t.Fatalf("expected message to be sent, got %v", err)
+	}
+	<-server.done
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.rcpt != "<ada@example.edu>" {
+		t.Errorf("expected recipient <ada@example.edu>, got %q", server.rcpt)
+	}
+	if !strings.Contains(server.data, "Subject: New comment\r\n") || !strings.HasSuffix(server.data, "Great photo!\r\n") {
+		t.Errorf("unexpected message data: %q", server.data)
+	}
+}
+
+func TestSMTPSender_InvalidRecipient(t *testing.T) {
+	sender := NewSender(DefaultConfig("mail.example.edu"))
+	if err := sender.Send(Message{To: "not an address"}); err != ErrInvalidRecipient {
+		t.Errorf("expected ErrInvalidRecipient, got %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 const (
@@ -80,6 +82,38 @@ func OptionalAuthMiddleware(sessionManager *lti.SessionManager) gin.HandlerFunc
 	}
 }
 
+// RequireActiveUser creates a middleware that rejects sessions whose user no
+// longer exists or has been soft-deleted. Revocation on account deletion is
+// held in memory, so this also covers tokens presented after a restart or to
+// another instance. Must run after AuthMiddleware.
+func RequireActiveUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authentication required",
+			})
+			return
+		}
+
+		var count int64
+		if err := db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to verify session",
+			})
+			return
+		}
+		if count == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired session",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequireRole creates a middleware that requires a specific role
 func RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -131,6 +165,39 @@ func extractToken(c *gin.Context) string {
 	return authHeader
 }
 
+// RequireCourse creates a middleware for course-scoped routes. It requires
+// the session to have been launched from a course and rejects requests whose
+// courseId query parameter names a different one, so that course-scoped
+// routes cannot be used to reach another course's data. Handlers behind it
+// read the course from the session. Combine it with RequireInstructor for
+// instructor-only course routes.
+func RequireCourse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		courseID, ok := GetCourseID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authentication required",
+			})
+			return
+		}
+
+		if courseID == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "session is not associated with a course",
+			})
+			return
+		}
+		if requested := c.Query("courseId"); requested != "" && requested != courseID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "access to this course is not permitted",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserID retrieves the user ID from the context
 func GetUserID(c *gin.Context) (uint, bool) {
 	val, exists := c.Get(ContextKeyUserID)
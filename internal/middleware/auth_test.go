@@ -6,8 +6,11 @@ import (
 	"testing"
 
 	"globe-expedition-journal/internal/lti"
+	"globe-expedition-journal/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -202,6 +205,52 @@ func TestOptionalAuthMiddleware_InvalidToken(t *testing.T) {
 	}
 }
 
+func TestRequireActiveUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	active := models.User{CanvasUserID: "canvas-1", CanvasInstanceURL: "https://canvas.example.com"}
+	deleted := models.User{CanvasUserID: "canvas-2", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(&active)
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	sm := createTestSessionManager()
+	router := gin.New()
+	router.Use(AuthMiddleware(sm), RequireActiveUser(db))
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   int
+	}{
+		{"active user", active.ID, http.StatusOK},
+		{"soft-deleted user", deleted.ID, http.StatusUnauthorized},
+		{"unknown user", 999, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: createTestToken(sm, tt.userID, "canvas", "course-1", "learner")})
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
 func TestRequireRole_Authorized(t *testing.T) {
 	sm := createTestSessionManager()
 	token := createTestToken(sm, 1, "canvas", "course", "instructor")
@@ -285,6 +334,60 @@ func TestRequireInstructor(t *testing.T) {
 	}
 }
 
+func TestRequireCourse(t *testing.T) {
+	sm := createTestSessionManager()
+
+	router := gin.New()
+	router.GET("/course/report", AuthMiddleware(sm), RequireInstructor(), RequireCourse(), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{"session course", createTestToken(sm, 1, "canvas", "course-a", "instructor"), "/course/report", http.StatusOK},
+		{"matching course", createTestToken(sm, 1, "canvas", "course-a", "instructor"), "/course/report?courseId=course-a", http.StatusOK},
+		{"other course", createTestToken(sm, 1, "canvas", "course-a", "instructor"), "/course/report?courseId=course-b", http.StatusForbidden},
+		{"no course in session", createTestToken(sm, 1, "canvas", "", "instructor"), "/course/report", http.StatusForbidden},
+		{"learner in course", createTestToken(sm, 2, "canvas", "course-a", "learner"), "/course/report", http.StatusForbidden},
+		{"unauthenticated", "", "/course/report", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireCourse_NoAuth(t *testing.T) {
+	router := gin.New()
+	router.GET("/course", RequireCourse(), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/course", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
+
 func TestGetUserID(t *testing.T) {
 	sm := createTestSessionManager()
 	token := createTestToken(sm, 42, "canvas", "course", "learner")
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyRetryAfter is the Retry-After hint, in seconds, sent with writes
+// rejected in read-only mode
+const ReadOnlyRetryAfter = "300"
+
+// ReadOnly creates a middleware for maintenance windows that rejects writes
+// with 503 while letting GET, HEAD and OPTIONS requests through. Requests to
+// the exempt paths, e.g. logout, are always allowed.
+func ReadOnly(exempt ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		allowed[path] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if allowed[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", ReadOnlyRetryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "the journal is in read-only maintenance mode; changes cannot be saved right now",
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadOnly(t *testing.T) {
+	router := gin.New()
+	router.Use(ReadOnly("/api/v1/logout"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/visits", ok)
+	router.HEAD("/api/v1/visits", ok)
+	router.POST("/api/v1/visits", ok)
+	router.PUT("/api/v1/visits/1", ok)
+	router.PATCH("/api/v1/scrapbook/entries/1/pin", ok)
+	router.DELETE("/api/v1/visits/1", ok)
+	router.POST("/api/v1/logout", ok)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/v1/visits", http.StatusOK},
+		{http.MethodHead, "/api/v1/visits", http.StatusOK},
+		{http.MethodPost, "/api/v1/visits", http.StatusServiceUnavailable},
+		{http.MethodPut, "/api/v1/visits/1", http.StatusServiceUnavailable},
+		{http.MethodPatch, "/api/v1/scrapbook/entries/1/pin", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/visits/1", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/logout", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != ReadOnlyRetryAfter {
+				t.Errorf("expected Retry-After %s, got %q", ReadOnlyRetryAfter, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carries the request ID in requests and responses
+	RequestIDHeader = "X-Request-ID"
+	// ContextKeyRequestID is the context key for the request ID
+	ContextKeyRequestID = "request_id"
+
+	// maxRequestIDLength bounds client-supplied request IDs
+	maxRequestIDLength = 128
+)
+
+// RequestID creates a middleware that assigns each request an ID, taken from
+// the X-Request-ID header when the client sends a usable one and generated
+// otherwise. The ID is stored in the context and echoed in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(ContextKeyRequestID, id)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+// RequestLogger creates a middleware that writes one structured log line per
+// request with its ID, method, path, status and latency
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("request_id", c.GetString(ContextKeyRequestID)),
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := GetUserID(c); ok {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(userID)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// GetRequestID retrieves the request ID from the context
+func GetRequestID(c *gin.Context) (string, bool) {
+	id := c.GetString(ContextKeyRequestID)
+	return id, id != ""
+}
+
+// validRequestID reports whether a client-supplied ID is safe to log and echo:
+// non-empty, bounded and limited to printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
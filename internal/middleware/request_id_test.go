@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID_Generated(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		id, _ := GetRequestID(c)
+		c.String(http.StatusOK, id)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 {
+		t.Fatalf("expected a generated 32-character request ID, got %q", id)
+	}
+	if w.Body.String() != id {
+		t.Errorf("expected context request ID %q, got %q", id, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if w.Header().Get(RequestIDHeader) == id {
+		t.Error("expected a new request ID per request")
+	}
+}
+
+func TestRequestID_FromHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"valid", "abc-123", true},
+		{"contains space", "abc 123", false},
+		{"control character", "abc\x01", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.keep && got != tt.header {
+				t.Errorf("expected request ID %q, got %q", tt.header, got)
+			}
+			if !tt.keep && (got == tt.header || got == "") {
+				t.Errorf("expected a generated request ID, got %q", got)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	router := gin.New()
+	router.Use(RequestID(), RequestLogger(logger))
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test?q=1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON log line, got %q: %v", buf.String(), err)
+	}
+
+	want := map[string]any{
+		"msg":        "request",
+		"request_id": "req-42",
+		"method":     "GET",
+		"path":       "/test",
+		"status":     float64(http.StatusTeapot),
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, line[key])
+		}
+	}
+	if _, ok := line["latency_ms"]; !ok {
+		t.Error("expected latency_ms in log line")
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Comment is instructor feedback on a learner's scrapbook entry
+type Comment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	EntryID      uint      `gorm:"not null;index" json:"entry_id"`
+	AuthorUserID uint      `gorm:"not null;index" json:"author_user_id"`
+	Body         string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	Author User `gorm:"foreignKey:AuthorUserID" json:"author,omitempty"`
+}
+
+// TableName specifies the table name for Comment
+func (Comment) TableName() string {
+	return "comments"
+}
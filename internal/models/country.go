@@ -1,11 +1,19 @@
 package models
 
+import "time"
+
 // Country represents a country in the world
 type Country struct {
-	ID      uint   `gorm:"primaryKey" json:"id"`
-	Name    string `gorm:"size:255;not null" json:"name"`
-	ISOCode string `gorm:"size:3;uniqueIndex;not null" json:"iso_code"` // ISO 3166-1 alpha-2 or alpha-3
-	Region  string `gorm:"size:100" json:"region"`                      // e.g., "Europe", "Asia", "Africa"
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	ISOCode   string    `gorm:"size:3;uniqueIndex;not null" json:"iso_code"` // ISO 3166-1 alpha-2 or alpha-3
+	ISOCode3  string    `gorm:"size:3;index" json:"iso_code3,omitempty"`     // ISO 3166-1 alpha-3, e.g. "FRA"
+	Region    string    `gorm:"size:100" json:"region"`                      // e.g., "Europe", "Asia", "Africa"
+	Aliases   string    `gorm:"size:1024" json:"aliases,omitempty"`          // Comma-separated alternate names for search, e.g. "USA,America"
+	Latitude  *float64  `json:"latitude,omitempty"`                          // Approximate centroid, used to infer the country of a check-in
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	// Relationships
 	Visits []Visit `gorm:"foreignKey:CountryID" json:"visits,omitempty"`
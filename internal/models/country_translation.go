@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CountryTranslation holds a country's name in a specific locale
+type CountryTranslation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CountryID uint      `gorm:"not null;uniqueIndex:idx_country_translations_locale" json:"country_id"`
+	Locale    string    `gorm:"size:35;not null;uniqueIndex:idx_country_translations_locale" json:"locale"` // Normalized BCP 47 tag, e.g. "fr" or "pt-br"
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CountryTranslation
+func (CountryTranslation) TableName() string {
+	return "country_translations"
+}
+
+// NormalizeLocale lowercases a locale tag and uses "-" as the separator,
+// so "fr_FR" and "fr-FR" are stored and looked up the same way
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeCandidates returns the locales to try for a tag, most specific first,
+// e.g. "fr-ca" -> ["fr-ca", "fr"]
+func localeCandidates(locale string) []string {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return nil
+	}
+	candidates := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	return candidates
+}
+
+// CountryTranslationRepository handles database operations for country translations
+type CountryTranslationRepository struct {
+	db *gorm.DB
+}
+
+// NewCountryTranslationRepository creates a new country translation repository
+func NewCountryTranslationRepository(db *gorm.DB) *CountryTranslationRepository {
+	return &CountryTranslationRepository{db: db}
+}
+
+// Upsert sets a country's name for a locale, replacing any existing translation
+func (r *CountryTranslationRepository) Upsert(countryID uint, locale, name string) error {
+	translation := CountryTranslation{
+		CountryID: countryID,
+		Locale:    NormalizeLocale(locale),
+		Name:      name,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "country_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+	}).Create(&translation).Error
+}
+
+// NamesForLocale returns translated names keyed by country ID for the given
+// countries. A regional locale such as "fr-CA" falls back to "fr". Countries
+// without a translation are absent from the map.
+func (r *CountryTranslationRepository) NamesForLocale(locale string, countryIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string)
+	candidates := localeCandidates(locale)
+	if len(candidates) == 0 || len(countryIDs) == 0 {
+		return names, nil
+	}
+
+	var translations []CountryTranslation
+	if err := r.db.Where("locale IN ? AND country_id IN ?", candidates, countryIDs).
+		Find(&translations).Error; err != nil {
+		return nil, err
+	}
+
+	// Prefer the most specific locale for each country
+	rank := make(map[uint]int)
+	for _, t := range translations {
+		pos := indexOf(candidates, t.Locale)
+		if best, ok := rank[t.CountryID]; ok && best <= pos {
+			continue
+		}
+		rank[t.CountryID] = pos
+		names[t.CountryID] = t.Name
+	}
+	return names, nil
+}
+
+// indexOf returns the position of s in list, or len(list) if absent
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return len(list)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/database"
+)
+
+func TestCountryTranslationTableName(t *testing.T) {
+	ct := CountryTranslation{}
+	if ct.TableName() != "country_translations" {
+		t.Errorf("expected table name 'country_translations', got '%s'", ct.TableName())
+	}
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := map[string]string{
+		"fr":      "fr",
+		"fr-FR":   "fr-fr",
+		"pt_BR":   "pt-br",
+		" de ":    "de",
+		"":        "",
+		"zh-Hant": "zh-hant",
+	}
+	for input, want := range tests {
+		if got := NormalizeLocale(input); got != want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCountryTranslationRepository_NamesForLocale(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db := database.GetDB()
+	france := Country{Name: "France", ISOCode: "FR", Region: "Europe"}
+	germany := Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
+	japan := Country{Name: "Japan", ISOCode: "JP", Region: "Asia"}
+	db.Create(&france)
+	db.Create(&germany)
+	db.Create(&japan)
+
+	repo := NewCountryTranslationRepository(db)
+	repo.Upsert(germany.ID, "fr", "Allemagne")
+	repo.Upsert(japan.ID, "fr", "Japon")
+	repo.Upsert(japan.ID, "fr-CA", "Japon (CA)")
+	ids := []uint{france.ID, germany.ID, japan.ID}
+
+	names, err := repo.NamesForLocale("fr-CA", ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names[japan.ID] != "Japon (CA)" {
+		t.Errorf("expected regional translation, got %q", names[japan.ID])
+	}
+	if names[germany.ID] != "Allemagne" {
+		t.Errorf("expected fallback to base language, got %q", names[germany.ID])
+	}
+	if _, ok := names[france.ID]; ok {
+		t.Error("expected no entry for an untranslated country")
+	}
+
+	names, _ = repo.NamesForLocale("es", ids)
+	if len(names) != 0 {
+		t.Errorf("expected no translations for 'es', got %v", names)
+	}
+}
+
+func TestCountryTranslationRepository_UpsertReplaces(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db := database.GetDB()
+	germany := Country{Name: "Germany", ISOCode: "DE", Region: "Europe"}
+	db.Create(&germany)
+
+	repo := NewCountryTranslationRepository(db)
+	if err := repo.Upsert(germany.ID, "de", "Deutschlnd"); err != nil {
+		t.Fatalf("failed to create translation: %v", err)
+	}
+	if err := repo.Upsert(germany.ID, "DE", "Deutschland"); err != nil {
+		t.Fatalf("failed to update translation: %v", err)
+	}
+
+	var count int64
+	db.Model(&CountryTranslation{}).Where("country_id = ?", germany.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 translation, got %d", count)
+	}
+
+	names, _ := repo.NamesForLocale("de", []uint{germany.ID})
+	if names[germany.ID] != "Deutschland" {
+		t.Errorf("expected updated name, got %q", names[germany.ID])
+	}
+}
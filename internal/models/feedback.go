@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Feedback is an instructor's grade and note on a learner's journal in a
+// course. There is at most one per learner per course.
+type Feedback struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	CourseID         string    `gorm:"size:255;not null;uniqueIndex:idx_feedback_course_learner" json:"course_id"`
+	LearnerUserID    uint      `gorm:"not null;uniqueIndex:idx_feedback_course_learner;index" json:"learner_user_id"`
+	InstructorUserID uint      `gorm:"not null" json:"instructor_user_id"` // Instructor who last updated it
+	Score            *float64  `json:"score,omitempty"`                    // Percentage from 0 to 100; unset if ungraded
+	Comment          string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	Instructor User `gorm:"foreignKey:InstructorUserID" json:"instructor,omitempty"`
+}
+
+// TableName specifies the table name for Feedback
+func (Feedback) TableName() string {
+	return "feedback"
+}
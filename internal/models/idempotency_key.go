@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey records the resource created for a client-supplied Idempotency-Key
+// so retried create requests can return the original resource
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_scope" json:"user_id"`
+	Key          string    `gorm:"size:255;not null;uniqueIndex:idx_idempotency_keys_scope" json:"key"`
+	ResourceType string    `gorm:"size:50;not null;uniqueIndex:idx_idempotency_keys_scope" json:"resource_type"` // e.g., "visit", "scrapbook_entry"
+	ResourceID   uint      `gorm:"not null" json:"resource_id"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
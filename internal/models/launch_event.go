@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Launch event stages
+const (
+	LaunchStageLogin  = "login"  // OIDC login initiation
+	LaunchStageLaunch = "launch" // id_token launch callback
+)
+
+// LaunchEvent is an audit record of one LTI login initiation or launch,
+// kept for failed attempts as well as successful ones
+type LaunchEvent struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Stage       string    `gorm:"size:20;not null" json:"stage"`
+	PlatformID  *uint     `gorm:"index" json:"platform_id,omitempty"` // Unset if the platform could not be identified
+	Issuer      string    `gorm:"size:512" json:"issuer,omitempty"`
+	UserID      *uint     `gorm:"index" json:"user_id,omitempty"`
+	Subject     string    `gorm:"size:255" json:"subject,omitempty"` // Platform user ID from the sub claim
+	CourseID    string    `gorm:"size:255" json:"course_id,omitempty"`
+	MessageType string    `gorm:"size:64" json:"message_type,omitempty"`
+	Role        string    `gorm:"size:20" json:"role,omitempty"` // Session role granted, "learner" or "instructor"
+	Success     bool      `gorm:"not null" json:"success"`
+	Error       string    `gorm:"size:1024" json:"error,omitempty"` // Why the attempt failed
+	IPAddress   string    `gorm:"size:64" json:"ip_address,omitempty"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for LaunchEvent
+func (LaunchEvent) TableName() string {
+	return "launch_events"
+}
@@ -7,5 +7,12 @@ func AllModels() []interface{} {
 		&Country{},
 		&Visit{},
 		&ScrapbookEntry{},
+		&ScrapbookMedia{},
+		&IdempotencyKey{},
+		&CountryTranslation{},
+		&Upload{},
+		&LaunchEvent{},
+		&Comment{},
+		&Feedback{},
 	}
 }
@@ -34,8 +34,8 @@ func setupTestDB(t *testing.T) func() {
 
 func TestAllModels(t *testing.T) {
 	models := AllModels()
-	if len(models) != 4 {
-		t.Errorf("expected 4 models, got %d", len(models))
+	if len(models) != 11 {
+		t.Errorf("expected 11 models, got %d", len(models))
 	}
 }
 
@@ -46,6 +46,34 @@ func TestUserTableName(t *testing.T) {
 	}
 }
 
+func TestUploadTableName(t *testing.T) {
+	u := Upload{}
+	if u.TableName() != "uploads" {
+		t.Errorf("expected table name 'uploads', got '%s'", u.TableName())
+	}
+}
+
+func TestLaunchEventTableName(t *testing.T) {
+	e := LaunchEvent{}
+	if e.TableName() != "launch_events" {
+		t.Errorf("expected table name 'launch_events', got '%s'", e.TableName())
+	}
+}
+
+func TestCommentTableName(t *testing.T) {
+	c := Comment{}
+	if c.TableName() != "comments" {
+		t.Errorf("expected table name 'comments', got '%s'", c.TableName())
+	}
+}
+
+func TestFeedbackTableName(t *testing.T) {
+	f := Feedback{}
+	if f.TableName() != "feedback" {
+		t.Errorf("expected table name 'feedback', got '%s'", f.TableName())
+	}
+}
+
 func TestCountryTableName(t *testing.T) {
 	c := Country{}
 	if c.TableName() != "countries" {
@@ -60,6 +88,35 @@ func TestVisitTableName(t *testing.T) {
 	}
 }
 
+func TestIdempotencyKeyTableName(t *testing.T) {
+	k := IdempotencyKey{}
+	if k.TableName() != "idempotency_keys" {
+		t.Errorf("expected table name 'idempotency_keys', got '%s'", k.TableName())
+	}
+}
+
+func TestIdempotencyKeyUniquePerUser(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	key1 := IdempotencyKey{UserID: 1, Key: "abc", ResourceType: "visit", ResourceID: 1}
+	if err := database.GetDB().Create(&key1).Error; err != nil {
+		t.Fatalf("failed to create idempotency key: %v", err)
+	}
+
+	// Same key for a different user is allowed
+	key2 := IdempotencyKey{UserID: 2, Key: "abc", ResourceType: "visit", ResourceID: 2}
+	if err := database.GetDB().Create(&key2).Error; err != nil {
+		t.Errorf("expected same key for another user to succeed: %v", err)
+	}
+
+	// Same key for the same user and resource type is rejected
+	key3 := IdempotencyKey{UserID: 1, Key: "abc", ResourceType: "visit", ResourceID: 3}
+	if err := database.GetDB().Create(&key3).Error; err == nil {
+		t.Error("expected error for duplicate idempotency key")
+	}
+}
+
 func TestUserCreate(t *testing.T) {
 	cleanup := setupTestDB(t)
 	defer cleanup()
@@ -11,19 +11,23 @@ type ScrapbookEntry struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `gorm:"not null;index" json:"user_id"`
 	CountryID uint           `gorm:"not null;index" json:"country_id"`
+	CourseID  string         `gorm:"size:255;index" json:"course_id,omitempty"` // LTI context the entry was created in
 	Title     string         `gorm:"size:255;not null" json:"title"`
 	Notes     string         `gorm:"type:text" json:"notes,omitempty"`
 	MediaURL  string         `gorm:"size:512" json:"media_url,omitempty"`
 	MediaType string         `gorm:"size:50" json:"media_type,omitempty"`
 	Tags      string         `gorm:"size:500" json:"tags,omitempty"` // Comma-separated tags
-	VisitedAt time.Time      `json:"visited_at,omitempty"`
+	VisitedAt time.Time      `json:"visited_at,omitempty"`           // Always stored in UTC
+	Pinned    bool           `gorm:"not null;default:false" json:"pinned"`
+	SortOrder *int           `json:"sort_order,omitempty"` // Optional manual position, lowest first
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
-	User    User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Country Country `gorm:"foreignKey:CountryID" json:"country,omitempty"`
+	User    User             `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Country Country          `gorm:"foreignKey:CountryID" json:"country,omitempty"`
+	Media   []ScrapbookMedia `gorm:"foreignKey:EntryID" json:"media,omitempty"`
 }
 
 // TableName specifies the table name for ScrapbookEntry
@@ -31,6 +35,50 @@ func (ScrapbookEntry) TableName() string {
 	return "scrapbook_entries"
 }
 
+// UniqueEntryTitleIndex is the name of the optional index allowing one live
+// entry title per country per user
+const UniqueEntryTitleIndex = "idx_scrapbook_entries_user_country_title"
+
+// ScrapbookEntryTitleIndex declares UniqueEntryTitleIndex on the
+// scrapbook_entries table. It is kept off ScrapbookEntry so the index is only
+// created when enabled; soft-deleted entries do not count as duplicates.
+type ScrapbookEntryTitleIndex struct {
+	UserID    uint   `gorm:"uniqueIndex:idx_scrapbook_entries_user_country_title,where:deleted_at IS NULL"`
+	CountryID uint   `gorm:"uniqueIndex:idx_scrapbook_entries_user_country_title"`
+	Title     string `gorm:"size:255;uniqueIndex:idx_scrapbook_entries_user_country_title"`
+}
+
+// TableName specifies the table name for ScrapbookEntryTitleIndex
+func (ScrapbookEntryTitleIndex) TableName() string {
+	return "scrapbook_entries"
+}
+
+// MigrateUniqueEntryTitles adds the unique index on scrapbook entry user,
+// country and title. If live entries already break it the index is not
+// created and false is returned, so the duplicates can be cleaned up first.
+func MigrateUniqueEntryTitles(db *gorm.DB) (bool, error) {
+	migrator := db.Migrator()
+	if migrator.HasIndex(&ScrapbookEntryTitleIndex{}, UniqueEntryTitleIndex) {
+		return true, nil
+	}
+
+	var duplicates int64
+	err := db.Raw("SELECT COUNT(*) FROM (SELECT 1 FROM scrapbook_entries WHERE deleted_at IS NULL " +
+		"GROUP BY user_id, country_id, title HAVING COUNT(*) > 1) AS duplicates").
+		Scan(&duplicates).Error
+	if err != nil {
+		return false, err
+	}
+	if duplicates > 0 {
+		return false, nil
+	}
+
+	if err := migrator.CreateIndex(&ScrapbookEntryTitleIndex{}, UniqueEntryTitleIndex); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // BeforeCreate hook to set timestamps
 func (s *ScrapbookEntry) BeforeCreate(tx *gorm.DB) error {
 	now := time.Now()
@@ -1,10 +1,13 @@
 package models
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"globe-expedition-journal/internal/database"
+
+	"gorm.io/gorm"
 )
 
 func TestScrapbookEntryTableName(t *testing.T) {
@@ -96,6 +99,52 @@ func TestScrapbookEntryWithMedia(t *testing.T) {
 	}
 }
 
+func TestScrapbookMediaTableName(t *testing.T) {
+	m := ScrapbookMedia{}
+	if m.TableName() != "scrapbook_media" {
+		t.Errorf("expected table name 'scrapbook_media', got '%s'", m.TableName())
+	}
+}
+
+func TestScrapbookEntryWithMediaItems(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := User{CanvasUserID: "12345", CanvasInstanceURL: "https://canvas.example.com"}
+	database.GetDB().Create(&user)
+
+	country := Country{Name: "Japan", ISOCode: "JP", Region: "Asia"}
+	database.GetDB().Create(&country)
+
+	entry := ScrapbookEntry{
+		UserID:    user.ID,
+		CountryID: country.ID,
+		Title:     "Kyoto",
+		Media: []ScrapbookMedia{
+			{URL: "/uploads/temple.jpg", Type: "image/jpeg", Position: 1},
+			{URL: "/uploads/gate.png", Type: "image/png", Position: 0},
+		},
+	}
+	if err := database.GetDB().Create(&entry).Error; err != nil {
+		t.Fatalf("failed to create scrapbook entry with media items: %v", err)
+	}
+
+	var loaded ScrapbookEntry
+	database.GetDB().Preload("Media", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&loaded, entry.ID)
+
+	if len(loaded.Media) != 2 {
+		t.Fatalf("expected 2 media items, got %d", len(loaded.Media))
+	}
+	if loaded.Media[0].URL != "/uploads/gate.png" || loaded.Media[1].URL != "/uploads/temple.jpg" {
+		t.Errorf("expected media ordered by position, got %+v", loaded.Media)
+	}
+	if loaded.Media[0].EntryID != entry.ID {
+		t.Errorf("expected media to belong to entry %d, got %d", entry.ID, loaded.Media[0].EntryID)
+	}
+}
+
 func TestScrapbookEntryWithRelationships(t *testing.T) {
 	cleanup := setupTestDB(t)
 	defer cleanup()
@@ -345,3 +394,44 @@ func TestScrapbookEntryFilterByTag(t *testing.T) {
 		t.Errorf("expected 2 entries with 'museum' tag, got %d", len(entries))
 	}
 }
+
+func TestMigrateUniqueEntryTitles(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+	db := database.GetDB()
+
+	user := User{CanvasUserID: "12345", CanvasInstanceURL: "https://canvas.example.com"}
+	db.Create(&user)
+	country := Country{Name: "France", ISOCode: "FR", Region: "Europe"}
+	db.Create(&country)
+
+	first := ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris"}
+	db.Create(&first)
+	db.Create(&ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris"})
+
+	// Existing duplicates leave the index off
+	created, err := MigrateUniqueEntryTitles(db)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if created || db.Migrator().HasIndex(&ScrapbookEntryTitleIndex{}, UniqueEntryTitleIndex) {
+		t.Fatal("expected the index to be skipped while duplicates exist")
+	}
+
+	// Soft-deleted entries are not duplicates
+	db.Delete(&first)
+	if created, err = MigrateUniqueEntryTitles(db); err != nil || !created {
+		t.Fatalf("expected the index to be created, got %v, %v", created, err)
+	}
+	if created, err = MigrateUniqueEntryTitles(db); err != nil || !created {
+		t.Errorf("expected a second migration to be a no-op, got %v, %v", created, err)
+	}
+
+	err = db.Create(&ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Paris"}).Error
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+	if err := db.Create(&ScrapbookEntry{UserID: user.ID, CountryID: country.ID, Title: "Lyon"}).Error; err != nil {
+		t.Errorf("expected a different title to be accepted, got %v", err)
+	}
+}
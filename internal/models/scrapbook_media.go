@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ScrapbookMedia is one photo or other media item attached to a scrapbook
+// entry. An entry's items are shown in ascending position.
+type ScrapbookMedia struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EntryID   uint      `gorm:"not null;index" json:"entry_id"`
+	URL       string    `gorm:"size:512;not null" json:"url"`
+	Type      string    `gorm:"size:50" json:"type,omitempty"` // MIME type, e.g. "image/jpeg"
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ScrapbookMedia
+func (ScrapbookMedia) TableName() string {
+	return "scrapbook_media"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Upload records a file a user uploaded to storage
+type Upload struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	UserID    uint           `gorm:"not null;index:idx_uploads_user_created" json:"user_id"`
+	EntryID   *uint          `gorm:"index" json:"entry_id,omitempty"` // Scrapbook entry the file was uploaded for, if any
+	Filename  string         `gorm:"size:255;not null;uniqueIndex" json:"filename"`
+	URL       string         `gorm:"size:512;not null" json:"url"`
+	MimeType  string         `gorm:"size:50" json:"mime_type"`
+	Size      int64          `gorm:"not null" json:"size"`
+	CreatedAt time.Time      `gorm:"index:idx_uploads_user_created" json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"` // Deleted uploads still count toward the daily limit
+}
+
+// TableName specifies the table name for Upload
+func (Upload) TableName() string {
+	return "uploads"
+}
@@ -13,6 +13,8 @@ type User struct {
 	CanvasInstanceURL string         `gorm:"size:512;not null" json:"canvas_instance_url"`
 	DisplayName       string         `gorm:"size:255" json:"display_name"`
 	Email             string         `gorm:"size:255" json:"email"`
+	Locale            string         `gorm:"size:35" json:"locale"`        // BCP 47 tag from the LTI launch, e.g. "fr-FR"
+	PictureURL        string         `gorm:"size:1024" json:"picture_url"` // Avatar from the LTI picture claim
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
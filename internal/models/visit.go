@@ -11,8 +11,11 @@ type Visit struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `gorm:"not null;index" json:"user_id"`
 	CountryID uint           `gorm:"not null;index" json:"country_id"`
-	VisitedAt time.Time      `gorm:"not null" json:"visited_at"`
+	CourseID  string         `gorm:"size:255;index" json:"course_id,omitempty"` // LTI context the visit was created in
+	VisitedAt time.Time      `gorm:"not null" json:"visited_at"`                // Always stored in UTC
 	Notes     string         `gorm:"type:text" json:"notes,omitempty"`
+	Latitude  *float64       `json:"latitude,omitempty"` // Where the visit was checked in, if reported
+	Longitude *float64       `json:"longitude,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
@@ -0,0 +1,49 @@
+package seed
+
+import (
+	"log/slog"
+
+	"globe-expedition-journal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countryAliases maps ISO code -> comma-separated alternate names people search by
+var countryAliases = map[string]string{
+	"GB": "UK,Britain,Great Britain,England,Scotland,Wales,Northern Ireland",
+	"NL": "Holland,The Netherlands",
+	"CZ": "Czechia",
+	"CH": "Swiss Confederation",
+	"KR": "Korea,Republic of Korea",
+	"CN": "PRC,People's Republic of China",
+	"TW": "Republic of China,Formosa",
+	"VN": "Viet Nam",
+	"US": "USA,United States of America,America",
+	"AE": "UAE,Emirates",
+	"TR": "Türkiye,Turkiye",
+	"SA": "KSA",
+	"ZA": "RSA",
+	"IE": "Eire,Republic of Ireland",
+	"NZ": "Aotearoa",
+}
+
+// CountryAliases sets the seeded alternate names on countries that have none,
+// so aliases reach databases seeded before they existed without overwriting
+// ones edited by an admin
+func CountryAliases(db *gorm.DB) error {
+	seeded := 0
+	for code, aliases := range countryAliases {
+		result := db.Model(&models.Country{}).
+			Where("iso_code = ? AND (aliases IS NULL OR aliases = '')", code).
+			Update("aliases", aliases)
+		if result.Error != nil {
+			return result.Error
+		}
+		seeded += int(result.RowsAffected)
+	}
+
+	if seeded > 0 {
+		slog.Info("seeded country aliases", "countries", seeded)
+	}
+	return nil
+}
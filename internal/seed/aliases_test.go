@@ -0,0 +1,53 @@
+package seed
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+)
+
+func TestCountryAliases(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := Countries(db); err != nil {
+		t.Fatalf("failed to seed countries: %v", err)
+	}
+	if err := CountryAliases(db); err != nil {
+		t.Fatalf("failed to seed aliases: %v", err)
+	}
+
+	var us models.Country
+	db.Where("iso_code = ?", "US").First(&us)
+	if us.Aliases != countryAliases["US"] {
+		t.Errorf("expected US aliases %q, got %q", countryAliases["US"], us.Aliases)
+	}
+}
+
+func TestCountryAliases_KeepsExisting(t *testing.T) {
+	db := setupTestDB(t)
+	Countries(db)
+
+	db.Model(&models.Country{}).Where("iso_code = ?", "GB").Update("aliases", "Blighty")
+	if err := CountryAliases(db); err != nil {
+		t.Fatalf("failed to seed aliases: %v", err)
+	}
+
+	var gb models.Country
+	db.Where("iso_code = ?", "GB").First(&gb)
+	if gb.Aliases != "Blighty" {
+		t.Errorf("expected edited aliases to be kept, got %q", gb.Aliases)
+	}
+}
+
+func TestCountryAliases_CoverSeededCountries(t *testing.T) {
+	db := setupTestDB(t)
+	Countries(db)
+
+	for code := range countryAliases {
+		var count int64
+		db.Model(&models.Country{}).Where("iso_code = ?", code).Count(&count)
+		if count != 1 {
+			t.Errorf("aliases given for %s, which is not a seeded country", code)
+		}
+	}
+}
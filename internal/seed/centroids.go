@@ -0,0 +1,68 @@
+package seed
+
+import (
+	"log/slog"
+
+	"globe-expedition-journal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countryCentroids maps ISO 3166-1 alpha-2 -> approximate {latitude, longitude}
+// of the geographic centre of each seeded country
+var countryCentroids = map[string][2]float64{
+	// Europe
+	"FR": {46.2276, 2.2137}, "DE": {51.1657, 10.4515}, "IT": {41.8719, 12.5674},
+	"ES": {40.4637, -3.7492}, "GB": {55.3781, -3.4360}, "NL": {52.1326, 5.2913},
+	"BE": {50.5039, 4.4699}, "CH": {46.8182, 8.2275}, "AT": {47.5162, 14.5501},
+	"PT": {39.3999, -8.2245}, "GR": {39.0742, 21.8243}, "SE": {60.1282, 18.6435},
+	"NO": {60.4720, 8.4689}, "DK": {56.2639, 9.5018}, "FI": {61.9241, 25.7482},
+	"IE": {53.4129, -8.2439}, "PL": {51.9194, 19.1451}, "CZ": {49.8175, 15.4730},
+	"HU": {47.1625, 19.5033}, "HR": {45.1000, 15.2000},
+
+	// Asia
+	"JP": {36.2048, 138.2529}, "CN": {35.8617, 104.1954}, "KR": {35.9078, 127.7669},
+	"IN": {20.5937, 78.9629}, "TH": {15.8700, 100.9925}, "VN": {14.0583, 108.2772},
+	"ID": {-0.7893, 113.9213}, "MY": {4.2105, 101.9758}, "SG": {1.3521, 103.8198},
+	"PH": {12.8797, 121.7740}, "TW": {23.6978, 120.9605},
+
+	// North America
+	"US": {37.0902, -95.7129}, "CA": {56.1304, -106.3468}, "MX": {23.6345, -102.5528},
+
+	// South America
+	"BR": {-14.2350, -51.9253}, "AR": {-38.4161, -63.6167}, "CL": {-35.6751, -71.5430},
+	"CO": {4.5709, -74.2973}, "PE": {-9.1900, -75.0152}, "EC": {-1.8312, -78.1834},
+
+	// Africa
+	"ZA": {-30.5595, 22.9375}, "EG": {26.8206, 30.8025}, "MA": {31.7917, -7.0926},
+	"KE": {-0.0236, 37.9062}, "NG": {9.0820, 8.6753}, "GH": {7.9465, -1.0232},
+	"TZ": {-6.3690, 34.8888},
+
+	// Oceania
+	"AU": {-25.2744, 133.7751}, "NZ": {-40.9006, 174.8860}, "FJ": {-16.5782, 179.4144},
+
+	// Middle East
+	"AE": {23.4241, 53.8478}, "IL": {31.0461, 34.8516}, "TR": {38.9637, 35.2433},
+	"SA": {23.8859, 45.0792}, "JO": {30.5852, 36.2384},
+}
+
+// CountryCentroids sets the centroid coordinates on seeded countries that
+// have none, so databases seeded before the columns existed are backfilled
+// without overwriting coordinates set by an admin
+func CountryCentroids(db *gorm.DB) error {
+	seeded := 0
+	for code, centroid := range countryCentroids {
+		result := db.Model(&models.Country{}).
+			Where("iso_code = ? AND (latitude IS NULL OR longitude IS NULL)", code).
+			Updates(map[string]interface{}{"latitude": centroid[0], "longitude": centroid[1]})
+		if result.Error != nil {
+			return result.Error
+		}
+		seeded += int(result.RowsAffected)
+	}
+
+	if seeded > 0 {
+		slog.Info("seeded country centroids", "countries", seeded)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package seed
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+)
+
+func TestCountryCentroids(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := Countries(db); err != nil {
+		t.Fatalf("failed to seed countries: %v", err)
+	}
+	if err := CountryCentroids(db); err != nil {
+		t.Fatalf("failed to seed centroids: %v", err)
+	}
+
+	var missing int64
+	db.Model(&models.Country{}).Where("latitude IS NULL OR longitude IS NULL").Count(&missing)
+	if missing != 0 {
+		t.Errorf("expected every seeded country to have a centroid, %d missing", missing)
+	}
+
+	var br models.Country
+	db.Where("iso_code = ?", "BR").First(&br)
+	if br.Latitude == nil || br.Longitude == nil || *br.Latitude >= 0 || *br.Longitude >= 0 {
+		t.Errorf("expected BR centroid in the south-western hemisphere, got %v, %v", br.Latitude, br.Longitude)
+	}
+}
+
+func TestCountryCentroids_KeepsExisting(t *testing.T) {
+	db := setupTestDB(t)
+	Countries(db)
+
+	db.Model(&models.Country{}).Where("iso_code = ?", "FR").
+		Updates(map[string]interface{}{"latitude": 48.8566, "longitude": 2.3522})
+	if err := CountryCentroids(db); err != nil {
+		t.Fatalf("failed to seed centroids: %v", err)
+	}
+
+	var fr models.Country
+	db.Where("iso_code = ?", "FR").First(&fr)
+	if fr.Latitude == nil || *fr.Latitude != 48.8566 {
+		t.Errorf("expected edited centroid to be kept, got %v", fr.Latitude)
+	}
+}
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"log/slog"
+
+	"globe-expedition-journal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countryISOCodes3 maps ISO 3166-1 alpha-2 -> alpha-3 for the seeded countries
+var countryISOCodes3 = map[string]string{
+	// Europe
+	"FR": "FRA", "DE": "DEU", "IT": "ITA", "ES": "ESP", "GB": "GBR",
+	"NL": "NLD", "BE": "BEL", "CH": "CHE", "AT": "AUT", "PT": "PRT",
+	"GR": "GRC", "SE": "SWE", "NO": "NOR", "DK": "DNK", "FI": "FIN",
+	"IE": "IRL", "PL": "POL", "CZ": "CZE", "HU": "HUN", "HR": "HRV",
+
+	// Asia
+	"JP": "JPN", "CN": "CHN", "KR": "KOR", "IN": "IND", "TH": "THA",
+	"VN": "VNM", "ID": "IDN", "MY": "MYS", "SG": "SGP", "PH": "PHL",
+	"TW": "TWN",
+
+	// North America
+	"US": "USA", "CA": "CAN", "MX": "MEX",
+
+	// South America
+	"BR": "BRA", "AR": "ARG", "CL": "CHL", "CO": "COL", "PE": "PER",
+	"EC": "ECU",
+
+	// Africa
+	"ZA": "ZAF", "EG": "EGY", "MA": "MAR", "KE": "KEN", "NG": "NGA",
+	"GH": "GHA", "TZ": "TZA",
+
+	// Oceania
+	"AU": "AUS", "NZ": "NZL", "FJ": "FJI",
+
+	// Middle East
+	"AE": "ARE", "IL": "ISR", "TR": "TUR", "SA": "SAU", "JO": "JOR",
+}
+
+// CountryISOCodes3 sets the alpha-3 code on seeded countries that have none,
+// so databases seeded before the column existed are backfilled without
+// overwriting codes set by an admin
+func CountryISOCodes3(db *gorm.DB) error {
+	seeded := 0
+	for code, code3 := range countryISOCodes3 {
+		result := db.Model(&models.Country{}).
+			Where("iso_code = ? AND (iso_code3 IS NULL OR iso_code3 = '')", code).
+			Update("iso_code3", code3)
+		if result.Error != nil {
+			return result.Error
+		}
+		seeded += int(result.RowsAffected)
+	}
+
+	if seeded > 0 {
+		slog.Info("seeded country alpha-3 codes", "countries", seeded)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package seed
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+)
+
+func TestCountryISOCodes3(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := Countries(db); err != nil {
+		t.Fatalf("failed to seed countries: %v", err)
+	}
+	if err := CountryISOCodes3(db); err != nil {
+		t.Fatalf("failed to seed alpha-3 codes: %v", err)
+	}
+
+	var missing int64
+	db.Model(&models.Country{}).Where("iso_code3 IS NULL OR iso_code3 = ''").Count(&missing)
+	if missing != 0 {
+		t.Errorf("expected every seeded country to have an alpha-3 code, %d missing", missing)
+	}
+
+	var fr models.Country
+	db.Where("iso_code = ?", "FR").First(&fr)
+	if fr.ISOCode3 != "FRA" {
+		t.Errorf("expected FR alpha-3 code FRA, got %q", fr.ISOCode3)
+	}
+}
+
+func TestCountryISOCodes3_KeepsExisting(t *testing.T) {
+	db := setupTestDB(t)
+	Countries(db)
+
+	db.Model(&models.Country{}).Where("iso_code = ?", "GB").Update("iso_code3", "UKX")
+	if err := CountryISOCodes3(db); err != nil {
+		t.Fatalf("failed to seed alpha-3 codes: %v", err)
+	}
+
+	var gb models.Country
+	db.Where("iso_code = ?", "GB").First(&gb)
+	if gb.ISOCode3 != "UKX" {
+		t.Errorf("expected edited alpha-3 code to be kept, got %q", gb.ISOCode3)
+	}
+}
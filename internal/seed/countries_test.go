@@ -101,3 +101,54 @@ func TestCountries_AllRegions(t *testing.T) {
 		}
 	}
 }
+
+func TestReseedCountries(t *testing.T) {
+	db := setupTestDB(t)
+
+	// An older catalog: one country renamed since, one with admin-set aliases
+	// and one added by an admin that is not in the catalog
+	db.Create(&models.Country{Name: "Czechia (old)", ISOCode: "CZ", Region: "Europe"})
+	db.Create(&models.Country{Name: "France", ISOCode: "FR", Region: "Europe", Aliases: "Hexagone"})
+	db.Create(&models.Country{Name: "Kosovo", ISOCode: "XK", Region: "Europe"})
+
+	result, err := ReseedCountries(db)
+	if err != nil {
+		t.Fatalf("failed to reseed countries: %v", err)
+	}
+	if result.Added != len(countryCatalog)-2 {
+		t.Errorf("expected %d countries added, got %d", len(countryCatalog)-2, result.Added)
+	}
+	if result.Updated != 2 {
+		t.Errorf("expected 2 countries updated, got %d", result.Updated)
+	}
+
+	var count int64
+	db.Model(&models.Country{}).Count(&count)
+	if int(count) != len(countryCatalog)+1 {
+		t.Errorf("expected the catalog plus the admin's country, got %d", count)
+	}
+
+	var cz, fr, jp, xk models.Country
+	db.Where("iso_code = ?", "CZ").First(&cz)
+	db.Where("iso_code = ?", "FR").First(&fr)
+	db.Where("iso_code = ?", "JP").First(&jp)
+	db.Where("iso_code = ?", "XK").First(&xk)
+	if cz.Name != "Czech Republic" || cz.ISOCode3 != "CZE" {
+		t.Errorf("expected CZ to be updated from the catalog, got %+v", cz)
+	}
+	if fr.Aliases != "Hexagone" || fr.ISOCode3 != "FRA" {
+		t.Errorf("expected FR's aliases kept and alpha-3 filled, got %+v", fr)
+	}
+	if jp.Name != "Japan" || jp.Region != "Asia" || jp.ISOCode3 != "JPN" || jp.Aliases != countryAliases["JP"] {
+		t.Errorf("expected JP to be added with catalog data, got %+v", jp)
+	}
+	if xk.Name != "Kosovo" {
+		t.Errorf("expected a country outside the catalog to be left alone, got %+v", xk)
+	}
+
+	// Reseeding an up-to-date table changes nothing
+	result, err = ReseedCountries(db)
+	if err != nil || result != (CatalogResult{}) {
+		t.Errorf("expected no changes on a second reseed, got %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"globe-expedition-journal/internal/config"
+	"globe-expedition-journal/internal/lti"
+
+	"gorm.io/gorm"
+)
+
+// Platform registers the LTI platform described by the LTI_* settings, so
+// single-platform deployments work without an admin call. It does nothing
+// when no issuer is configured. Running it again updates the platform's
+// settings in place, keeping a name given by an admin.
+func Platform(db *gorm.DB, cfg *config.Config) error {
+	if cfg.LTIIssuer == "" {
+		return nil
+	}
+
+	var missing []string
+	for _, setting := range []struct{ env, value string }{
+		{"LTI_CLIENT_ID", cfg.LTIClientID},
+		{"LTI_JWKS_ENDPOINT", cfg.LTIJWKSEndpoint},
+		{"LTI_AUTH_ENDPOINT", cfg.LTIAuthEndpoint},
+	} {
+		if setting.value == "" {
+			missing = append(missing, setting.env)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("LTI_ISSUER is set but missing %s", strings.Join(missing, ", "))
+	}
+
+	if err := db.AutoMigrate(&lti.Platform{}); err != nil {
+		return err
+	}
+
+	platform := &lti.Platform{
+		Issuer:        cfg.LTIIssuer,
+		ClientID:      cfg.LTIClientID,
+		DeploymentID:  cfg.LTIDeploymentID,
+		JWKSEndpoint:  cfg.LTIJWKSEndpoint,
+		AuthEndpoint:  cfg.LTIAuthEndpoint,
+		TokenEndpoint: cfg.LTITokenEndpoint,
+		Name:          cfg.LTIIssuer,
+	}
+
+	repo := lti.NewPlatformRepository(db)
+	if existing, err := repo.FindByIssuer(cfg.LTIIssuer); err == nil && existing.Name != "" {
+		platform.Name = existing.Name
+	}
+	if err := repo.Upsert(platform); err != nil {
+		return err
+	}
+
+	slog.Info("seeded LTI platform", "issuer", platform.Issuer)
+	return nil
+}
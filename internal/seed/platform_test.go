@@ -0,0 +1,86 @@
+package seed
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/config"
+	"globe-expedition-journal/internal/lti"
+)
+
+// platformConfig returns a fully-specified LTI config
+func platformConfig() *config.Config {
+	return &config.Config{
+		LTIIssuer:        "https://canvas.example.edu",
+		LTIClientID:      "10000000000001",
+		LTIDeploymentID:  "1:abc",
+		LTIJWKSEndpoint:  "https://canvas.example.edu/api/lti/security/jwks",
+		LTIAuthEndpoint:  "https://canvas.example.edu/api/lti/authorize_redirect",
+		LTITokenEndpoint: "https://canvas.example.edu/login/oauth2/token",
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := platformConfig()
+
+	if err := Platform(db, cfg); err != nil {
+		t.Fatalf("failed to seed platform: %v", err)
+	}
+
+	platform, err := lti.NewPlatformRepository(db).FindByIssuer(cfg.LTIIssuer)
+	if err != nil {
+		t.Fatalf("expected platform to be registered: %v", err)
+	}
+	if platform.ClientID != cfg.LTIClientID || platform.DeploymentID != cfg.LTIDeploymentID ||
+		platform.JWKSEndpoint != cfg.LTIJWKSEndpoint || platform.AuthEndpoint != cfg.LTIAuthEndpoint ||
+		platform.TokenEndpoint != cfg.LTITokenEndpoint {
+		t.Errorf("unexpected platform: %+v", platform)
+	}
+}
+
+func TestPlatform_Idempotent(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := platformConfig()
+	Platform(db, cfg)
+
+	db.Model(&lti.Platform{}).Where("issuer = ?", cfg.LTIIssuer).Update("name", "Canvas")
+	cfg.LTIClientID = "10000000000002"
+	if err := Platform(db, cfg); err != nil {
+		t.Fatalf("failed to reseed platform: %v", err)
+	}
+
+	var platforms []lti.Platform
+	db.Find(&platforms)
+	if len(platforms) != 1 {
+		t.Fatalf("expected 1 platform, got %d", len(platforms))
+	}
+	if platforms[0].ClientID != "10000000000002" {
+		t.Errorf("expected client ID to be updated, got %q", platforms[0].ClientID)
+	}
+	if platforms[0].Name != "Canvas" {
+		t.Errorf("expected admin-given name to be kept, got %q", platforms[0].Name)
+	}
+}
+
+func TestPlatform_EmptyConfig(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := Platform(db, &config.Config{}); err != nil {
+		t.Fatalf("expected no error without LTI config, got %v", err)
+	}
+	if db.Migrator().HasTable(&lti.Platform{}) {
+		t.Error("expected nothing to be written without LTI config")
+	}
+}
+
+func TestPlatform_IncompleteConfig(t *testing.T) {
+	db := setupTestDB(t)
+
+	err := Platform(db, &config.Config{LTIIssuer: "https://canvas.example.edu", LTIClientID: "1"})
+	if err == nil {
+		t.Fatal("expected error for incomplete LTI config")
+	}
+	if want := "LTI_ISSUER is set but missing LTI_JWKS_ENDPOINT, LTI_AUTH_ENDPOINT"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
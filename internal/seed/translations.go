@@ -0,0 +1,223 @@
+package seed
+
+import (
+	"log/slog"
+
+	"globe-expedition-journal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countryNames maps locale -> ISO code -> localized country name
+var countryNames = map[string]map[string]string{
+	"de": {
+		"FR": "Frankreich",
+		"DE": "Deutschland",
+		"IT": "Italien",
+		"ES": "Spanien",
+		"GB": "Vereinigtes Königreich",
+		"NL": "Niederlande",
+		"BE": "Belgien",
+		"CH": "Schweiz",
+		"AT": "Österreich",
+		"PT": "Portugal",
+		"GR": "Griechenland",
+		"SE": "Schweden",
+		"NO": "Norwegen",
+		"DK": "Dänemark",
+		"FI": "Finnland",
+		"IE": "Irland",
+		"PL": "Polen",
+		"CZ": "Tschechien",
+		"HU": "Ungarn",
+		"HR": "Kroatien",
+		"JP": "Japan",
+		"CN": "China",
+		"KR": "Südkorea",
+		"IN": "Indien",
+		"TH": "Thailand",
+		"VN": "Vietnam",
+		"ID": "Indonesien",
+		"MY": "Malaysia",
+		"SG": "Singapur",
+		"PH": "Philippinen",
+		"TW": "Taiwan",
+		"US": "Vereinigte Staaten",
+		"CA": "Kanada",
+		"MX": "Mexiko",
+		"BR": "Brasilien",
+		"AR": "Argentinien",
+		"CL": "Chile",
+		"CO": "Kolumbien",
+		"PE": "Peru",
+		"EC": "Ecuador",
+		"ZA": "Südafrika",
+		"EG": "Ägypten",
+		"MA": "Marokko",
+		"KE": "Kenia",
+		"NG": "Nigeria",
+		"GH": "Ghana",
+		"TZ": "Tansania",
+		"AU": "Australien",
+		"NZ": "Neuseeland",
+		"FJ": "Fidschi",
+		"AE": "Vereinigte Arabische Emirate",
+		"IL": "Israel",
+		"TR": "Türkei",
+		"SA": "Saudi-Arabien",
+		"JO": "Jordanien",
+	},
+	"es": {
+		"FR": "Francia",
+		"DE": "Alemania",
+		"IT": "Italia",
+		"ES": "España",
+		"GB": "Reino Unido",
+		"NL": "Países Bajos",
+		"BE": "Bélgica",
+		"CH": "Suiza",
+		"AT": "Austria",
+		"PT": "Portugal",
+		"GR": "Grecia",
+		"SE": "Suecia",
+		"NO": "Noruega",
+		"DK": "Dinamarca",
+		"FI": "Finlandia",
+		"IE": "Irlanda",
+		"PL": "Polonia",
+		"CZ": "República Checa",
+		"HU": "Hungría",
+		"HR": "Croacia",
+		"JP": "Japón",
+		"CN": "China",
+		"KR": "Corea del Sur",
+		"IN": "India",
+		"TH": "Tailandia",
+		"VN": "Vietnam",
+		"ID": "Indonesia",
+		"MY": "Malasia",
+		"SG": "Singapur",
+		"PH": "Filipinas",
+		"TW": "Taiwán",
+		"US": "Estados Unidos",
+		"CA": "Canadá",
+		"MX": "México",
+		"BR": "Brasil",
+		"AR": "Argentina",
+		"CL": "Chile",
+		"CO": "Colombia",
+		"PE": "Perú",
+		"EC": "Ecuador",
+		"ZA": "Sudáfrica",
+		"EG": "Egipto",
+		"MA": "Marruecos",
+		"KE": "Kenia",
+		"NG": "Nigeria",
+		"GH": "Ghana",
+		"TZ": "Tanzania",
+		"AU": "Australia",
+		"NZ": "Nueva Zelanda",
+		"FJ": "Fiyi",
+		"AE": "Emiratos Árabes Unidos",
+		"IL": "Israel",
+		"TR": "Turquía",
+		"SA": "Arabia Saudita",
+		"JO": "Jordania",
+	},
+	"fr": {
+		"FR": "France",
+		"DE": "Allemagne",
+		"IT": "Italie",
+		"ES": "Espagne",
+		"GB": "Royaume-Uni",
+		"NL": "Pays-Bas",
+		"BE": "Belgique",
+		"CH": "Suisse",
+		"AT": "Autriche",
+		"PT": "Portugal",
+		"GR": "Grèce",
+		"SE": "Suède",
+		"NO": "Norvège",
+		"DK": "Danemark",
+		"FI": "Finlande",
+		"IE": "Irlande",
+		"PL": "Pologne",
+		"CZ": "République tchèque",
+		"HU": "Hongrie",
+		"HR": "Croatie",
+		"JP": "Japon",
+		"CN": "Chine",
+		"KR": "Corée du Sud",
+		"IN": "Inde",
+		"TH": "Thaïlande",
+		"VN": "Viêt Nam",
+		"ID": "Indonésie",
+		"MY": "Malaisie",
+		"SG": "Singapour",
+		"PH": "Philippines",
+		"TW": "Taïwan",
+		"US": "États-Unis",
+		"CA": "Canada",
+		"MX": "Mexique",
+		"BR": "Brésil",
+		"AR": "Argentine",
+		"CL": "Chili",
+		"CO": "Colombie",
+		"PE": "Pérou",
+		"EC": "Équateur",
+		"ZA": "Afrique du Sud",
+		"EG": "Égypte",
+		"MA": "Maroc",
+		"KE": "Kenya",
+		"NG": "Nigeria",
+		"GH": "Ghana",
+		"TZ": "Tanzanie",
+		"AU": "Australie",
+		"NZ": "Nouvelle-Zélande",
+		"FJ": "Fidji",
+		"AE": "Émirats arabes unis",
+		"IL": "Israël",
+		"TR": "Turquie",
+		"SA": "Arabie saoudite",
+		"JO": "Jordanie",
+	},
+}
+
+// CountryTranslations populates localized names for the seeded countries.
+// Countries must be seeded first; unknown ISO codes are skipped.
+func CountryTranslations(db *gorm.DB) error {
+	var count int64
+	db.Model(&models.CountryTranslation{}).Count(&count)
+	if count > 0 {
+		slog.Info("country translations already seeded", "count", count)
+		return nil
+	}
+
+	var countries []models.Country
+	if err := db.Find(&countries).Error; err != nil {
+		return err
+	}
+	idsByCode := make(map[string]uint, len(countries))
+	for _, country := range countries {
+		idsByCode[country.ISOCode] = country.ID
+	}
+
+	repo := models.NewCountryTranslationRepository(db)
+	seeded := 0
+	for locale, names := range countryNames {
+		for code, name := range names {
+			id, ok := idsByCode[code]
+			if !ok {
+				continue
+			}
+			if err := repo.Upsert(id, locale, name); err != nil {
+				slog.Warn("failed to seed country translation", "locale", locale, "code", code, "error", err)
+				continue
+			}
+			seeded++
+		}
+	}
+
+	slog.Info("seeded country translations", "count", seeded)
+	return nil
+}
@@ -0,0 +1,73 @@
+package seed
+
+import (
+	"testing"
+
+	"globe-expedition-journal/internal/models"
+)
+
+func TestCountryTranslations(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.CountryTranslation{})
+
+	if err := Countries(db); err != nil {
+		t.Fatalf("failed to seed countries: %v", err)
+	}
+	if err := CountryTranslations(db); err != nil {
+		t.Fatalf("failed to seed translations: %v", err)
+	}
+
+	var countryCount, translationCount int64
+	db.Model(&models.Country{}).Count(&countryCount)
+	db.Model(&models.CountryTranslation{}).Count(&translationCount)
+
+	if translationCount != countryCount*int64(len(countryNames)) {
+		t.Errorf("expected every country translated into %d locales, got %d translations for %d countries",
+			len(countryNames), translationCount, countryCount)
+	}
+
+	var germany models.Country
+	db.Where("iso_code = ?", "DE").First(&germany)
+	names, err := models.NewCountryTranslationRepository(db).NamesForLocale("fr-FR", []uint{germany.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names[germany.ID] != "Allemagne" {
+		t.Errorf("expected 'Allemagne', got %q", names[germany.ID])
+	}
+}
+
+func TestCountryTranslations_Idempotent(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&models.CountryTranslation{})
+
+	Countries(db)
+	CountryTranslations(db)
+
+	var first int64
+	db.Model(&models.CountryTranslation{}).Count(&first)
+
+	CountryTranslations(db)
+
+	var second int64
+	db.Model(&models.CountryTranslation{}).Count(&second)
+	if first != second {
+		t.Errorf("seeding should be idempotent, got %d then %d translations", first, second)
+	}
+}
+
+func TestCountryTranslations_CoverSeededCountries(t *testing.T) {
+	db := setupTestDB(t)
+	Countries(db)
+
+	var codes []string
+	db.Model(&models.Country{}).Pluck("iso_code", &codes)
+
+	for locale, names := range countryNames {
+		for _, code := range codes {
+			if names[code] == "" {
+				t.Errorf("missing %s translation for %s", locale, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// errMalformedImage is returned when an image's structure cannot be parsed
+var errMalformedImage = errors.New("malformed image")
+
+// isAnimatedFile reports whether the GIF or WebP file at path has more than
+// one frame. Other MIME types are never considered animated.
+func isAnimatedFile(path, mimeType string) (bool, error) {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType != "image/gif" && mimeType != "image/webp" {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	if mimeType == "image/gif" {
+		return isAnimatedGIF(bufio.NewReader(file))
+	}
+	return isAnimatedWebP(file)
+}
+
+// isAnimatedGIF walks the GIF block structure without decoding pixel data
+// and reports whether more than one image descriptor is present
+func isAnimatedGIF(r *bufio.Reader) (bool, error) {
+	// Header (6 bytes) and logical screen descriptor (7 bytes)
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false, errMalformedImage
+	}
+	if !bytes.HasPrefix(header, []byte("GIF87a")) && !bytes.HasPrefix(header, []byte("GIF89a")) {
+		return false, errMalformedImage
+	}
+	if err := skipColorTable(r, header[10]); err != nil {
+		return false, err
+	}
+
+	frames := 0
+	for {
+		introducer, err := r.ReadByte()
+		if err != nil {
+			return false, errMalformedImage
+		}
+
+		switch introducer {
+		case 0x21: // Extension: label followed by data sub-blocks
+			if _, err := r.ReadByte(); err != nil {
+				return false, errMalformedImage
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return false, err
+			}
+		case 0x2C: // Image descriptor
+			frames++
+			if frames > 1 {
+				return true, nil
+			}
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(r, descriptor); err != nil {
+				return false, errMalformedImage
+			}
+			if err := skipColorTable(r, descriptor[8]); err != nil {
+				return false, err
+			}
+			// LZW minimum code size, then image data sub-blocks
+			if _, err := r.ReadByte(); err != nil {
+				return false, errMalformedImage
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return false, err
+			}
+		case 0x3B: // Trailer
+			return false, nil
+		default:
+			return false, errMalformedImage
+		}
+	}
+}
+
+// skipColorTable skips a global or local color table if flags indicate one
+func skipColorTable(r *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	size := 3 * (1 << ((flags & 0x07) + 1))
+	if _, err := r.Discard(size); err != nil {
+		return errMalformedImage
+	}
+	return nil
+}
+
+// skipSubBlocks skips GIF data sub-blocks up to and including the terminator
+func skipSubBlocks(r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return errMalformedImage
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := r.Discard(int(size)); err != nil {
+			return errMalformedImage
+		}
+	}
+}
+
+// isAnimatedWebP checks the VP8X extended header for the animation flag.
+// Simple (VP8/VP8L) WebP files cannot hold more than one frame.
+func isAnimatedWebP(r io.Reader) (bool, error) {
+	// RIFF header (12 bytes), chunk header (8 bytes), VP8X flags (1 byte)
+	header := make([]byte, 21)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, errMalformedImage
+	}
+	header = header[:n]
+	if len(header) < 16 || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return false, errMalformedImage
+	}
+	if string(header[12:16]) != "VP8X" {
+		return false, nil
+	}
+	if len(header) < 21 {
+		return false, errMalformedImage
+	}
+
+	const animationFlag = 0x02
+	return header[20]&animationFlag != 0, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// makeGIF encodes a GIF with the given number of frames
+func makeGIF(t *testing.T, frames int) []byte {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), palette))
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("failed to encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// makeWebP builds a minimal WebP header with the given VP8X flags
+func makeWebP(chunk string, flags byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	data = append(data, []byte(chunk)...)
+	data = append(data, 0x0A, 0x00, 0x00, 0x00, flags)
+	return append(data, make([]byte, 9)...)
+}
+
+func TestIsAnimatedGIF(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   int
+		animated bool
+	}{
+		{"single frame", 1, false},
+		{"two frames", 2, true},
+		{"many frames", 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			animated, err := isAnimatedGIF(bufio.NewReader(bytes.NewReader(makeGIF(t, tt.frames))))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if animated != tt.animated {
+				t.Errorf("expected animated=%v, got %v", tt.animated, animated)
+			}
+		})
+	}
+}
+
+func TestIsAnimatedGIF_Malformed(t *testing.T) {
+	_, err := isAnimatedGIF(bufio.NewReader(bytes.NewReader([]byte("not a gif at all"))))
+	if err == nil {
+		t.Error("expected error for malformed gif")
+	}
+}
+
+func TestIsAnimatedWebP(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		animated bool
+		wantErr  bool
+	}{
+		{"simple lossy", makeWebP("VP8 ", 0), false, false},
+		{"extended still", makeWebP("VP8X", 0x10), false, false},
+		{"extended animated", makeWebP("VP8X", 0x02), true, false},
+		{"not webp", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), false, true},
+		{"truncated", []byte("RIFF"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			animated, err := isAnimatedWebP(bytes.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if animated != tt.animated {
+				t.Errorf("expected animated=%v, got %v", tt.animated, animated)
+			}
+		})
+	}
+}
@@ -36,40 +36,97 @@ func (s *LocalStorage) Upload(filename string, content io.Reader, size int64) (s
 	if ext == "" {
 		ext = ".bin"
 	}
-	uniqueName := uuid.New().String() + ext
+	uniqueName := s.uniqueFilename(filename, ext)
 
-	// Create full path
-	fullPath := filepath.Join(s.config.UploadsDir, uniqueName)
+	if err := s.writeAtomic(uniqueName, content, nil); err != nil {
+		return "", err
+	}
+
+	return s.GetURL(uniqueName), nil
+}
 
-	// Create file
-	file, err := os.Create(fullPath)
+// tempFilePrefix starts the names of uploads still being written. Such names
+// never match IsStoredFilename, so a partial upload is never served.
+const tempFilePrefix = ".upload-"
+
+// writeAtomic copies at most MaxFileSize bytes of content to a temporary file
+// in the uploads directory and renames it to name only once it is complete,
+// so an upload interrupted mid-write, by a dropped client or a shutdown,
+// never leaves a partial file under a stored name. check, if not nil,
+// inspects the complete temporary file before it is renamed.
+func (s *LocalStorage) writeAtomic(name string, content io.Reader, check func(path string) error) error {
+	file, err := os.CreateTemp(s.config.UploadsDir, tempFilePrefix+"*")
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
+	tempPath := file.Name()
+	stored := false
+	defer func() {
+		if !stored {
+			os.Remove(tempPath)
+		}
+	}()
 
 	// Copy content with size limit
 	written, err := io.CopyN(file, content, s.config.MaxFileSize+1)
 	if err != nil && err != io.EOF {
-		os.Remove(fullPath) // Cleanup on error
-		return "", fmt.Errorf("failed to write file: %w", err)
+		file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	// Double-check size (in case Content-Length header was wrong)
 	if written > s.config.MaxFileSize {
-		os.Remove(fullPath)
-		return "", ErrFileTooLarge
+		return ErrFileTooLarge
 	}
 
-	return s.GetURL(uniqueName), nil
+	if check != nil {
+		if err := check(tempPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tempPath, filepath.Join(s.config.UploadsDir, name)); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	stored = true
+	return nil
+}
+
+// uniqueFilename returns a new stored filename with extension ext. With
+// PreserveOriginalNames, it is prefixed with the sanitized original name.
+func (s *LocalStorage) uniqueFilename(original, ext string) string {
+	name := uuid.New().String() + ext
+	if s.config.PreserveOriginalNames {
+		if prefix := namePrefix(original); prefix != "" {
+			name = prefix + "-" + name
+		}
+	}
+	return name
 }
 
 // UploadWithMimeType stores a file with proper extension based on MIME type
 func (s *LocalStorage) UploadWithMimeType(content io.Reader, size int64, mimeType string) (string, error) {
-	// Validate file type
+	return s.UploadWithOriginalName(content, size, mimeType, "")
+}
+
+// UploadWithOriginalName stores a file like UploadWithMimeType. The original
+// filename is only used to name the stored file when PreserveOriginalNames
+// is set; it never chooses the directory or extension.
+func (s *LocalStorage) UploadWithOriginalName(content io.Reader, size int64, mimeType, original string) (string, error) {
+	// Validate file type, and the original extension when there is one
 	if !s.config.IsAllowedType(mimeType) {
 		return "", ErrInvalidFileType
 	}
+	if ext := filepath.Ext(SanitizeFilename(original)); ext != "" && !s.config.IsAllowedExtension(ext) {
+		return "", ErrInvalidFileType
+	}
 
 	// Validate file size
 	if size > s.config.MaxFileSize {
@@ -81,33 +138,40 @@ func (s *LocalStorage) UploadWithMimeType(content io.Reader, size int64, mimeTyp
 	if ext == "" {
 		return "", ErrInvalidFileType
 	}
-	uniqueName := uuid.New().String() + ext
-
-	// Create full path
-	fullPath := filepath.Join(s.config.UploadsDir, uniqueName)
-
-	// Create file
-	file, err := os.Create(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	// Copy content with size limit
-	written, err := io.CopyN(file, content, s.config.MaxFileSize+1)
-	if err != nil && err != io.EOF {
-		os.Remove(fullPath)
-		return "", fmt.Errorf("failed to write file: %w", err)
+	uniqueName := s.uniqueFilename(original, ext)
+
+	// Inspect the file for extra frames if animations are not allowed
+	var check func(path string) error
+	if s.config.RejectAnimated {
+		check = func(path string) error {
+			animated, err := isAnimatedFile(path, mimeType)
+			if err != nil || animated {
+				return ErrInvalidFileType
+			}
+			return nil
+		}
 	}
 
-	if written > s.config.MaxFileSize {
-		os.Remove(fullPath)
-		return "", ErrFileTooLarge
+	if err := s.writeAtomic(uniqueName, content, check); err != nil {
+		return "", err
 	}
 
 	return s.GetURL(uniqueName), nil
 }
 
+// Open returns a reader for a file in local storage
+func (s *LocalStorage) Open(filename string) (io.ReadCloser, error) {
+	filename = filepath.Base(filename)
+	f, err := os.Open(filepath.Join(s.config.UploadsDir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return f, nil
+}
+
 // Delete removes a file from local storage
 func (s *LocalStorage) Delete(filename string) error {
 	// Extract just the filename from URL if needed
@@ -138,6 +202,23 @@ func (s *LocalStorage) Exists(filename string) bool {
 	return err == nil
 }
 
+// FileInfo returns the size of a file in local storage. Directories and
+// other non-regular files are reported as missing.
+func (s *LocalStorage) FileInfo(filename string) (int64, error) {
+	filename = filepath.Base(filename)
+	info, err := os.Stat(filepath.Join(s.config.UploadsDir, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrFileNotFound
+		}
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return 0, ErrFileNotFound
+	}
+	return info.Size(), nil
+}
+
 // GetFilePath returns the full filesystem path for a file
 func (s *LocalStorage) GetFilePath(filename string) string {
 	filename = filepath.Base(filename)
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -179,6 +180,57 @@ func TestLocalStorage_UploadWithMimeType_FileTooLarge(t *testing.T) {
 	}
 }
 
+// interruptedReader yields part of an upload, calls midway while the upload
+// is being written, then fails like a client that disconnects mid-request
+type interruptedReader struct {
+	data    []byte
+	midway  func()
+	yielded bool
+}
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	if r.yielded {
+		r.midway()
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.yielded = true
+	return copy(p, r.data), nil
+}
+
+func TestLocalStorage_UploadWithMimeType_Interrupted(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var midway []string
+	content := &interruptedReader{
+		data: bytes.Repeat([]byte("x"), 4096),
+		midway: func() {
+			entries, _ := os.ReadDir(storage.config.UploadsDir)
+			for _, e := range entries {
+				midway = append(midway, e.Name())
+			}
+		},
+	}
+
+	_, err := storage.UploadWithMimeType(content, 8192, "image/jpeg")
+	if err == nil {
+		t.Fatal("expected an error for an interrupted upload")
+	}
+
+	// The partial upload is only ever written under a name that is not served
+	if len(midway) != 1 {
+		t.Fatalf("expected one file while writing, found %v", midway)
+	}
+	if IsStoredFilename(midway[0]) {
+		t.Errorf("partial upload is visible under a stored name: %s", midway[0])
+	}
+
+	entries, _ := os.ReadDir(storage.config.UploadsDir)
+	if len(entries) != 0 {
+		t.Errorf("expected interrupted upload to be removed, found %d files", len(entries))
+	}
+}
+
 func TestLocalStorage_Delete(t *testing.T) {
 	storage, cleanup := setupTestStorage(t)
 	defer cleanup()
@@ -216,6 +268,29 @@ func TestLocalStorage_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestLocalStorage_Open(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	content := []byte("test content")
+	url, _ := storage.Upload("test.jpg", bytes.NewReader(content), int64(len(content)))
+
+	rc, err := storage.Open(filepath.Base(url))
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer rc.Close()
+
+	read, _ := io.ReadAll(rc)
+	if !bytes.Equal(read, content) {
+		t.Errorf("expected %q, got %q", content, read)
+	}
+
+	if _, err := storage.Open("nonexistent.jpg"); err != ErrFileNotFound {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+}
+
 func TestLocalStorage_Exists(t *testing.T) {
 	storage, cleanup := setupTestStorage(t)
 	defer cleanup()
@@ -234,6 +309,31 @@ func TestLocalStorage_Exists(t *testing.T) {
 	}
 }
 
+func TestLocalStorage_FileInfo(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	url, err := storage.UploadWithMimeType(strings.NewReader("test content"), 12, "image/jpeg")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	size, err := storage.FileInfo(filepath.Base(url))
+	if err != nil {
+		t.Fatalf("FileInfo failed: %v", err)
+	}
+	if size != 12 {
+		t.Errorf("expected size 12, got %d", size)
+	}
+
+	if _, err := storage.FileInfo("nonexistent.jpg"); err != ErrFileNotFound {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+	if _, err := storage.FileInfo("."); err != ErrFileNotFound {
+		t.Errorf("expected ErrFileNotFound for the uploads directory, got %v", err)
+	}
+}
+
 func TestLocalStorage_GetURL(t *testing.T) {
 	storage, cleanup := setupTestStorage(t)
 	defer cleanup()
@@ -267,6 +367,8 @@ func TestConfig_IsAllowedType(t *testing.T) {
 		{"image/png", true},
 		{"image/gif", true},
 		{"image/webp", true},
+		{"image/heic", true},
+		{"image/heif", true},
 		{"IMAGE/JPEG", true}, // Case insensitive
 		{"application/pdf", false},
 		{"text/html", false},
@@ -281,6 +383,57 @@ func TestConfig_IsAllowedType(t *testing.T) {
 	}
 }
 
+func TestConfig_IsAllowedExtension(t *testing.T) {
+	config := DefaultConfig()
+	if !config.IsAllowedExtension(".svg") {
+		t.Error("expected any extension to be allowed without a list")
+	}
+
+	config.AllowedExtensions = []string{".jpg", "PNG", " .webp "}
+	tests := []struct {
+		ext     string
+		allowed bool
+	}{
+		{".jpg", true},
+		{".JPG", true}, // Case insensitive
+		{".png", true},
+		{"png", true},
+		{".webp", true},
+		{".jpeg", false},
+		{".svg", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if result := config.IsAllowedExtension(tt.ext); result != tt.allowed {
+			t.Errorf("IsAllowedExtension(%q) = %v, want %v", tt.ext, result, tt.allowed)
+		}
+	}
+}
+
+func TestLocalStorage_UploadWithOriginalName_AllowedExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultConfig()
+	config.UploadsDir = tempDir
+	config.AllowedExtensions = []string{".jpg", ".png"}
+	storage, err := NewLocalStorage(config)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	// The declared type is allowed, but the name's extension is not
+	if _, err := storage.UploadWithOriginalName(strings.NewReader("<svg/>"), 6, "image/png", "logo.svg"); err != ErrInvalidFileType {
+		t.Errorf("expected ErrInvalidFileType for .svg, got %v", err)
+	}
+	if _, err := storage.UploadWithOriginalName(strings.NewReader("test"), 4, "image/jpeg", "Photo.JPG"); err != nil {
+		t.Errorf("expected .JPG to be allowed, got %v", err)
+	}
+	// Names without an extension are only checked by MIME type
+	if _, err := storage.UploadWithOriginalName(strings.NewReader("test"), 4, "image/jpeg", "photo"); err != nil {
+		t.Errorf("expected a name without an extension to be allowed, got %v", err)
+	}
+}
+
 func TestGetExtensionForMimeType(t *testing.T) {
 	tests := []struct {
 		mimeType string
@@ -290,6 +443,8 @@ func TestGetExtensionForMimeType(t *testing.T) {
 		{"image/png", ".png"},
 		{"image/gif", ".gif"},
 		{"image/webp", ".webp"},
+		{"image/heic", ".heic"},
+		{"image/heif", ".heif"},
 		{"application/pdf", ""},
 		{"", ""},
 	}
@@ -302,6 +457,30 @@ func TestGetExtensionForMimeType(t *testing.T) {
 	}
 }
 
+func TestGetMimeTypeForExtension(t *testing.T) {
+	tests := []struct {
+		ext      string
+		mimeType string
+	}{
+		{".jpg", "image/jpeg"},
+		{".JPEG", "image/jpeg"},
+		{".png", "image/png"},
+		{".gif", "image/gif"},
+		{".webp", "image/webp"},
+		{".heic", "image/heic"},
+		{".heif", "image/heif"},
+		{".html", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		result := GetMimeTypeForExtension(tt.ext)
+		if result != tt.mimeType {
+			t.Errorf("GetMimeTypeForExtension(%s) = %s, want %s", tt.ext, result, tt.mimeType)
+		}
+	}
+}
+
 func TestSanitizeFilename(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -311,6 +490,8 @@ func TestSanitizeFilename(t *testing.T) {
 		{"my file.jpg", "my_file.jpg"},
 		{"../../../etc/passwd", "passwd"},
 		{"/path/to/file.jpg", "file.jpg"},
+		{`..\..\windows\win.ini`, "win.ini"},
+		{"..", ""},
 	}
 
 	for _, tt := range tests {
@@ -320,3 +501,147 @@ func TestSanitizeFilename(t *testing.T) {
 		}
 	}
 }
+
+func TestNamePrefix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Paris Eiffel.JPG", "paris-eiffel"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\Louvre (2).png`, "louvre-2"},
+		{"__--__.jpg", ""},
+		{"..", ""},
+		{strings.Repeat("a", 80) + ".jpg", strings.Repeat("a", maxNamePrefixLength)},
+	}
+
+	for _, tt := range tests {
+		if result := namePrefix(tt.input); result != tt.expected {
+			t.Errorf("namePrefix(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestIsStoredFilename(t *testing.T) {
+	const id = "3f2c1b4a-9d8e-4f7a-b6c5-1a2b3c4d5e6f"
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{id + ".jpg", true},
+		{id + ".heic", true},
+		{"paris-eiffel-" + id + ".png", true},
+		{id, false},
+		{id + ".txt", false},
+		{"nonexistent.jpg", false},
+		{"../" + id + ".jpg", false},
+		{`..\` + id + ".jpg", false},
+		{"Paris-" + id + ".jpg", false},
+		{"-" + id + ".jpg", false},
+		{"..", false},
+	}
+
+	for _, tt := range tests {
+		if result := IsStoredFilename(tt.input); result != tt.expected {
+			t.Errorf("IsStoredFilename(%q) = %v, want %v", tt.input, result, tt.expected)
+		}
+	}
+
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+	url, err := storage.UploadWithOriginalName(strings.NewReader("test"), 4, "image/jpeg", "Paris Eiffel.JPG")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if name := filepath.Base(url); !IsStoredFilename(name) {
+		t.Errorf("expected stored name %q to match", name)
+	}
+}
+
+func TestLocalStorage_UploadWithOriginalName(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	content := []byte("fake jpeg")
+
+	// Original names are ignored unless the option is set
+	url, err := storage.UploadWithOriginalName(bytes.NewReader(content), int64(len(content)), "image/jpeg", "Paris Eiffel.jpg")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if strings.Contains(url, "paris") {
+		t.Errorf("expected a bare UUID name by default, got %s", url)
+	}
+
+	storage.config.PreserveOriginalNames = true
+	url, err = storage.UploadWithOriginalName(bytes.NewReader(content), int64(len(content)), "image/jpeg", "../../Paris Eiffel.png")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	name := filepath.Base(url)
+	if !strings.HasPrefix(name, "paris-eiffel-") || !strings.HasSuffix(name, ".jpg") {
+		t.Errorf("expected paris-eiffel-<uuid>.jpg, got %s", name)
+	}
+	if url != "/uploads/"+name {
+		t.Errorf("expected the file to stay in the uploads directory, got %s", url)
+	}
+	if !storage.Exists(name) {
+		t.Error("expected stored file to exist")
+	}
+}
+
+func TestLocalStorage_UploadWithMimeType_HEIC(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+	storage.config.AllowedTypes = DefaultConfig().AllowedTypes
+
+	content := []byte("\x00\x00\x00\x18ftypheic")
+
+	url, err := storage.UploadWithMimeType(bytes.NewReader(content), int64(len(content)), "image/heic")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if !strings.HasSuffix(url, ".heic") {
+		t.Errorf("URL should end with .heic, got %s", url)
+	}
+}
+
+func TestLocalStorage_UploadWithMimeType_RejectAnimated(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+	storage.config.RejectAnimated = true
+
+	animated := makeGIF(t, 3)
+	_, err := storage.UploadWithMimeType(bytes.NewReader(animated), int64(len(animated)), "image/gif")
+	if err != ErrInvalidFileType {
+		t.Errorf("expected ErrInvalidFileType for animated gif, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(storage.config.UploadsDir)
+	if len(entries) != 0 {
+		t.Errorf("expected rejected upload to be removed, found %d files", len(entries))
+	}
+
+	still := makeGIF(t, 1)
+	if _, err := storage.UploadWithMimeType(bytes.NewReader(still), int64(len(still)), "image/gif"); err != nil {
+		t.Errorf("expected single-frame gif to be accepted, got %v", err)
+	}
+
+	animatedWebP := makeWebP("VP8X", 0x02)
+	_, err = storage.UploadWithMimeType(bytes.NewReader(animatedWebP), int64(len(animatedWebP)), "image/webp")
+	if err != ErrInvalidFileType {
+		t.Errorf("expected ErrInvalidFileType for animated webp, got %v", err)
+	}
+}
+
+func TestLocalStorage_UploadWithMimeType_AnimatedAllowedByDefault(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	animated := makeGIF(t, 3)
+	if _, err := storage.UploadWithMimeType(bytes.NewReader(animated), int64(len(animated)), "image/gif"); err != nil {
+		t.Errorf("expected animated gif to be accepted without RejectAnimated, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -19,6 +20,9 @@ type Storage interface {
 	// Upload stores a file and returns its URL
 	Upload(filename string, content io.Reader, size int64) (string, error)
 
+	// Open returns a reader for a stored file; the caller must close it
+	Open(filename string) (io.ReadCloser, error)
+
 	// Delete removes a file from storage
 	Delete(filename string) error
 
@@ -27,6 +31,10 @@ type Storage interface {
 
 	// Exists checks if a file exists in storage
 	Exists(filename string) bool
+
+	// FileInfo returns the size of a stored file in bytes, or
+	// ErrFileNotFound if there is no such file
+	FileInfo(filename string) (int64, error)
 }
 
 // Config holds storage configuration
@@ -36,6 +44,16 @@ type Config struct {
 	MaxFileSize  int64    // Maximum file size in bytes
 	AllowedTypes []string // Allowed MIME types
 	BaseURL      string   // Base URL for serving files
+
+	// AllowedExtensions optionally restricts the extension of the uploaded
+	// filename, e.g. ".jpg". MIME types are still checked; empty allows any.
+	AllowedExtensions []string
+
+	RejectAnimated bool // Reject GIF/WebP uploads with more than one frame
+
+	// PreserveOriginalNames prefixes stored filenames with a sanitized form
+	// of the uploaded name, e.g. "paris-eiffel-<uuid>.jpg"
+	PreserveOriginalNames bool
 }
 
 // DefaultConfig returns default storage configuration
@@ -49,6 +67,8 @@ func DefaultConfig() Config {
 			"image/png",
 			"image/gif",
 			"image/webp",
+			"image/heic",
+			"image/heif",
 		},
 		BaseURL: "/uploads",
 	}
@@ -65,6 +85,22 @@ func (c Config) IsAllowedType(mimeType string) bool {
 	return false
 }
 
+// IsAllowedExtension checks if a filename extension is in the allowed
+// list, ignoring case and a missing leading dot. Every extension is allowed
+// when no list is configured.
+func (c Config) IsAllowedExtension(ext string) bool {
+	if len(c.AllowedExtensions) == 0 {
+		return true
+	}
+	ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+	for _, allowed := range c.AllowedExtensions {
+		if strings.TrimPrefix(strings.ToLower(strings.TrimSpace(allowed)), ".") == ext {
+			return true
+		}
+	}
+	return false
+}
+
 // GetExtensionForMimeType returns the file extension for a MIME type
 func GetExtensionForMimeType(mimeType string) string {
 	switch strings.ToLower(mimeType) {
@@ -76,6 +112,31 @@ func GetExtensionForMimeType(mimeType string) string {
 		return ".gif"
 	case "image/webp":
 		return ".webp"
+	case "image/heic":
+		return ".heic"
+	case "image/heif":
+		return ".heif"
+	default:
+		return ""
+	}
+}
+
+// GetMimeTypeForExtension returns the MIME type of a stored file's
+// extension, or "" if the extension is not one uploads are stored under
+func GetMimeTypeForExtension(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".heic":
+		return "image/heic"
+	case ".heif":
+		return "image/heif"
 	default:
 		return ""
 	}
@@ -83,9 +144,57 @@ func GetExtensionForMimeType(mimeType string) string {
 
 // SanitizeFilename removes potentially dangerous characters from filenames
 func SanitizeFilename(filename string) string {
-	// Get just the base name without path
-	filename = filepath.Base(filename)
+	// Get just the base name without path, treating Windows separators as
+	// separators too
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == ".." || filename == "/" {
+		return ""
+	}
 	// Replace spaces and special chars
 	filename = strings.ReplaceAll(filename, " ", "_")
 	return filename
 }
+
+// storedNamePattern matches the stem of a stored filename: a UUID,
+// optionally prefixed by a name slug from namePrefix
+var storedNamePattern = regexp.MustCompile(`^(?:[a-z0-9]+(?:-[a-z0-9]+)*-)?[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// IsStoredFilename reports whether name has the shape of a filename this
+// package stores uploads under, e.g. "<uuid>.jpg" or "paris-eiffel-<uuid>.jpg".
+// Paths and names with unknown extensions never match.
+func IsStoredFilename(name string) bool {
+	ext := filepath.Ext(name)
+	if GetMimeTypeForExtension(ext) == "" {
+		return false
+	}
+	return storedNamePattern.MatchString(strings.TrimSuffix(name, ext))
+}
+
+// maxNamePrefixLength bounds the original-name prefix of a stored filename
+const maxNamePrefixLength = 50
+
+// namePrefix reduces an uploaded filename to a lowercase slug of letters,
+// digits and hyphens for use in a stored filename, e.g. "Paris Eiffel.JPG"
+// becomes "paris-eiffel". Returns "" if nothing usable remains.
+func namePrefix(original string) string {
+	name := SanitizeFilename(original)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+			if b.Len() >= maxNamePrefixLength {
+				break
+			}
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
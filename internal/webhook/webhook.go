@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event types sent to the webhook
+const (
+	EventVisitCreated          = "visit.created"
+	EventScrapbookEntryCreated = "scrapbook_entry.created"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed
+// with "sha256=", computed with the shared secret
+const SignatureHeader = "X-Webhook-Signature"
+
+// Event is the JSON payload POSTed to the webhook
+type Event struct {
+	Type       string    `json:"type"`
+	UserID     uint      `json:"userId"`
+	ResourceID uint      `json:"resourceId"`
+	CountryID  uint      `json:"countryId"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Config holds webhook delivery configuration
+type Config struct {
+	URL         string        // Endpoint that receives events
+	Secret      string        // Shared secret used to sign payloads
+	MaxAttempts int           // Deliveries tried before giving up
+	Backoff     time.Duration // Delay before the first retry, doubled after each
+	Timeout     time.Duration // Per-attempt HTTP timeout
+	Logger      *slog.Logger  // Delivery failures are logged here; slog.Default() if nil
+}
+
+// DefaultConfig returns default delivery settings for url and secret
+func DefaultConfig(url, secret string) Config {
+	return Config{
+		URL:         url,
+		Secret:      secret,
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Notifier delivers events to a webhook in the background. A nil Notifier
+// is valid and drops every event, so callers need not check whether
+// webhooks are configured.
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+	wg     sync.WaitGroup
+}
+
+// NewNotifier creates a notifier for cfg, or returns nil if no URL is set
+func NewNotifier(cfg Config) *Notifier {
+	if cfg.URL == "" {
+		return nil
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+	}
+}
+
+// Notify sends event asynchronously; it never blocks on delivery
+func (n *Notifier) Notify(event Event) {
+	if n == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(event)
+	}()
+}
+
+// Wait blocks until all pending deliveries have finished
+func (n *Notifier) Wait() {
+	if n == nil {
+		return
+	}
+	n.wg.Wait()
+}
+
+// deliver POSTs event, retrying with exponential backoff on failure
+func (n *Notifier) deliver(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Error("failed to encode webhook event", "type", event.Type, "error", err)
+		return
+	}
+
+	backoff := n.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		err = n.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= n.cfg.MaxAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	n.logger.Warn("webhook delivery failed",
+		"type", event.Type, "resourceId", event.ResourceID, "attempts", n.cfg.MaxAttempts, "error", err)
+}
+
+// post sends one signed delivery; any non-2xx response is an error
+func (n *Notifier) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
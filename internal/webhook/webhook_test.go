@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConfig returns a config for url that retries without delay
+func testConfig(url string) Config {
+	cfg := DefaultConfig(url, "test-secret")
+	cfg.Backoff = time.Millisecond
+	return cfg
+}
+
+func TestNewNotifier_DisabledWithoutURL(t *testing.T) {
+	n := NewNotifier(DefaultConfig("", "test-secret"))
+	if n != nil {
+		t.Fatal("expected nil notifier without a URL")
+	}
+
+	// A nil notifier drops events without panicking
+	n.Notify(Event{Type: EventVisitCreated})
+	n.Wait()
+}
+
+func TestNotifier_DeliversSignedEvent(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		body      []byte
+		signature string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(testConfig(server.URL))
+	n.Notify(Event{Type: EventVisitCreated, UserID: 7, ResourceID: 42, CountryID: 3})
+	n.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("failed to decode delivered event: %v", err)
+	}
+	if event.Type != EventVisitCreated || event.UserID != 7 || event.ResourceID != 42 || event.CountryID != 3 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("expected timestamp to be set")
+	}
+	if signature != Sign("test-secret", body) {
+		t.Errorf("expected signature %q, got %q", Sign("test-secret", body), signature)
+	}
+}
+
+func TestNotifier_RetriesFailedDelivery(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(testConfig(server.URL))
+	n.Notify(Event{Type: EventScrapbookEntryCreated})
+	n.Wait()
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected delivery on the third attempt, got %d attempts", got)
+	}
+}
+
+func TestNotifier_GivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.MaxAttempts = 2
+	n := NewNotifier(cfg)
+	n.Notify(Event{Type: EventVisitCreated})
+	n.Wait()
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestNotifier_NotifyDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(testConfig(server.URL))
+
+	done := make(chan struct{})
+	go func() {
+		n.Notify(Event{Type: EventVisitCreated})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on delivery")
+	}
+
+	close(release)
+	n.Wait()
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256("key", "The quick brown fox jumps over the lazy dog")
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got := Sign("key", []byte("The quick brown fox jumps over the lazy dog")); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}